@@ -0,0 +1,349 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"ednevnik-backend/constants"
+	wpmodels "ednevnik-backend/models/workspace"
+	"ednevnik-backend/util"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// maxAuditPayload is the largest request or response body stored in the
+// audit log, bigger bodies are recorded without payload
+const maxAuditPayload = 64 << 10
+
+// auditPrivateRoutes are routes whose payloads carry secrets that can not be
+// redacted by key, only the request itself is recorded
+var auditPrivateRoutes = []string{"/api/common/two_factor"}
+
+// auditRecord is shared between the audit middleware and the handler through
+// the request context
+type auditRecord struct {
+	before interface{}
+}
+
+// setAuditBefore records the state of an entity before a handler changes it
+func setAuditBefore(r *http.Request, before interface{}) {
+	if record, ok := r.Context().Value(constants.AuditKey).(*auditRecord); ok {
+		record.before = before
+	}
+}
+
+// auditResponseWriter keeps the status code and the start of the response
+// body for the audit log
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.truncated {
+		if w.body.Len()+len(data) > maxAuditPayload {
+			w.truncated = true
+			w.body.Reset()
+		} else {
+			w.body.Write(data)
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// AuditMiddleware records every mutating request of a logged in account in
+// the audit log, together with the request body, the state before the change
+// when the handler sets it and the response. Requests made while
+// impersonating are recorded even when they only read. Recording never fails
+// the request, errors are only logged.
+func AuditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := util.GetClaimsFromContext(r)
+		if !ok || r.Method == http.MethodOptions ||
+			(isReadRequest(r) && claims.ImpersonationID == 0) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requestBody := readAuditRequestBody(r)
+		record := &auditRecord{}
+		ctx := context.WithValue(r.Context(), constants.AuditKey, record)
+		recorder := &auditResponseWriter{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		entry := buildAuditEntry(r, claims, requestBody)
+		entry.StatusCode = recorder.status
+		if entry.StatusCode == 0 {
+			entry.StatusCode = http.StatusOK
+		}
+		if !isPrivateAuditRoute(entry.Route) {
+			entry.RequestData = util.RedactAuditPayload(requestBody)
+			if record.before != nil {
+				if before, err := json.Marshal(record.before); err == nil {
+					entry.BeforeData = util.RedactAuditPayload(before)
+				}
+			}
+			contentType := recorder.Header().Get("Content-Type")
+			if !isReadRequest(r) && !recorder.truncated && entry.StatusCode < 300 &&
+				strings.HasPrefix(contentType, "application/json") {
+				entry.AfterData = util.RedactAuditPayload(recorder.body.Bytes())
+			}
+		}
+
+		if err := util.InsertAuditEntry(entry, DbWorkspace); err != nil {
+			log.Printf("audit: %v", err)
+		}
+	})
+}
+
+// readAuditRequestBody reads a JSON request body and puts it back for the
+// handler. Multipart uploads and bodies over the limit are not read.
+func readAuditRequestBody(r *http.Request) []byte {
+	if r.Body == nil ||
+		strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxAuditPayload+1))
+	if err != nil {
+		return nil
+	}
+	if len(body) > maxAuditPayload {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		return nil
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body
+}
+
+// buildAuditEntry fills the actor, route, entity and tenant of an entry
+func buildAuditEntry(
+	r *http.Request, claims *wpmodels.Claims, requestBody []byte,
+) wpmodels.AuditEntry {
+	entry := wpmodels.AuditEntry{
+		ActorAccountID: claims.AccountID,
+		ActorRole:      claims.AccountType,
+		IPAddress:      util.GetRequestIP(r),
+		Method:         r.Method,
+		Route:          r.URL.Path,
+	}
+	if claims.ImpersonatorAccountID != 0 {
+		impersonatorAccountID := claims.ImpersonatorAccountID
+		entry.ImpersonatorAccountID = &impersonatorAccountID
+	}
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			entry.Route = template
+		}
+	}
+	entry.Action = auditAction(entry.Route)
+	entry.Entity = auditEntity(entry.Route, entry.Action)
+
+	var body map[string]interface{}
+	_ = json.Unmarshal(requestBody, &body)
+
+	vars := mux.Vars(r)
+	entry.EntityID = auditEntityID(entry.Entity, vars, body)
+
+	if tenantID, ok := auditTenantID(entry.Entity, vars, body); ok {
+		entry.TenantID = &tenantID
+	} else if claims.TenantAdminTenantID != 0 {
+		tenantID := claims.TenantAdminTenantID
+		entry.TenantID = &tenantID
+	}
+	return entry
+}
+
+// auditAction names the action of a route template, e.g.
+// /api/teacher/update_grade/{tenant_id} becomes update_grade
+func auditAction(route string) string {
+	segments := strings.Split(strings.Trim(route, "/"), "/")
+	if len(segments) > 2 && segments[0] == "api" {
+		segments = segments[2:]
+	}
+	var parts []string
+	for _, segment := range segments {
+		if segment != "" && !strings.HasPrefix(segment, "{") {
+			parts = append(parts, segment)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// auditEntity returns the entity of a route template. Routes that are not
+// mapped, e.g. paths without a route, fall back to the action.
+func auditEntity(route, action string) string {
+	if entity, ok := AuditEntityOf(route); ok {
+		return entity
+	}
+	return action
+}
+
+// auditEntityID takes the entity ID from the route variables or from the
+// request body
+func auditEntityID(
+	entity string, vars map[string]string, body map[string]interface{},
+) string {
+	for _, key := range []string{entity + "_id", entity + "_code", "id"} {
+		if value, ok := vars[key]; ok {
+			return value
+		}
+	}
+	for _, key := range []string{"id", entity + "_id"} {
+		if value, ok := auditBodyValue(body, key); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// auditTenantID takes the tenant from the route variables or from the
+// request body
+func auditTenantID(
+	entity string, vars map[string]string, body map[string]interface{},
+) (int, bool) {
+	value, ok := vars["tenant_id"]
+	if !ok && entity == "tenant" {
+		value, ok = vars["id"]
+	}
+	if !ok {
+		value, ok = auditBodyValue(body, "tenant_id")
+	}
+	if !ok {
+		return 0, false
+	}
+	tenantID, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	return tenantID, true
+}
+
+// auditBodyValue returns a number or string field of a JSON object as string
+func auditBodyValue(body map[string]interface{}, key string) (string, bool) {
+	switch value := body[key].(type) {
+	case float64:
+		if value == 0 {
+			return "", false
+		}
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case string:
+		return value, value != ""
+	}
+	return "", false
+}
+
+// isReadRequest reports whether a request only reads data
+func isReadRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
+// isPrivateAuditRoute reports whether the payloads of a route are not stored
+func isPrivateAuditRoute(route string) bool {
+	for _, private := range auditPrivateRoutes {
+		if strings.Contains(route, private) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAuditLogHandler returns the audit log of all tenants, filtered by the
+// query parameters tenant_id, actor_account_id, impersonator_account_id,
+// entity, entity_id, action, from, to, limit and offset (super admin only)
+func GetAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := util.GetAuditEntries(filter, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// GetTenantAuditLogHandler returns the audit log of a tenant, with the same
+// filters as GetAuditLogHandler
+func GetTenantAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := strconv.Atoi(mux.Vars(r)["tenant_id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.TenantID = tenantID
+
+	entries, err := util.GetAuditEntries(filter, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// parseAuditFilter reads the audit log filter from the query parameters
+func parseAuditFilter(r *http.Request) (wpmodels.AuditFilter, error) {
+	query := r.URL.Query()
+	filter := wpmodels.AuditFilter{
+		Entity:   query.Get("entity"),
+		EntityID: query.Get("entity_id"),
+		Action:   query.Get("action"),
+		From:     query.Get("from"),
+		To:       query.Get("to"),
+	}
+
+	numbers := map[string]*int{
+		"tenant_id":               &filter.TenantID,
+		"actor_account_id":        &filter.ActorAccountID,
+		"impersonator_account_id": &filter.ImpersonatorAccountID,
+		"limit":                   &filter.Limit,
+		"offset":                  &filter.Offset,
+	}
+	for key, target := range numbers {
+		value := query.Get(key)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s", key)
+		}
+		*target = number
+	}
+	return filter, nil
+}
@@ -0,0 +1,196 @@
+package api
+
+// auditRouteEntities maps every route template to the entity it reads or
+// changes. Reads are recorded too while impersonating, so read routes are
+// listed as well. A new route has to be added here, the endpoints tests fail
+// otherwise.
+var auditRouteEntities = map[string]string{
+	// Sessions
+	"/login":               "session",
+	"/parent-login":        "session",
+	"/login/2fa":           "session",
+	"/login/2fa/setup":     "session",
+	"/login/2fa/confirm":   "session",
+	"/login/oidc/start":    "session",
+	"/login/oidc/callback": "session",
+	"/refresh":             "session",
+	"/logout":              "session",
+	"/logout-all":          "session",
+	"/reissue-token":       "session",
+
+	// Common
+	"/api/common/cantons":                                       "canton",
+	"/api/common/change_password":                               "account",
+	"/api/common/chat":                                          "chat",
+	"/api/common/forgot_password":                               "account",
+	"/api/common/get_subjects_for_curriculum/{curriculum_code}": "curriculum",
+	"/api/common/impersonation/end":                             "account",
+	"/api/common/notification_preferences":                      "notification",
+	"/api/common/notifications":                                 "notification",
+	"/api/common/notifications/read_all":                        "notification",
+	"/api/common/notifications/{notification_id}/read":          "notification",
+	"/api/common/pupil_section_invites/{pupil_id}":              "invite",
+	"/api/common/register_parent":                               "parent",
+	"/api/common/register_pupil":                                "pupil",
+	"/api/common/register_teacher":                              "teacher",
+	"/api/common/reset_password":                                "account",
+	"/api/common/respond_to_section_invite":                     "invite",
+	"/api/common/two_factor":                                    "two_factor",
+	"/api/common/two_factor/confirm":                            "two_factor",
+	"/api/common/two_factor/disable":                            "two_factor",
+	"/api/common/two_factor/recovery_codes":                     "two_factor",
+	"/api/common/two_factor/setup":                              "two_factor",
+	"/api/common/verify_account":                                "account",
+
+	// Parents
+	"/api/parent/children": "pupil",
+	"/api/parent/gradebook_metadata/{tenant_id}/{section_id}/{pupil_id}": "section",
+	"/api/parent/link_pupil":                                                         "pupil",
+	"/api/parent/schedule/{tenant_id}/{section_id}/{pupil_id}":                       "schedule",
+	"/api/parent/section_grades/{tenant_id}/{section_id}/{pupil_id}/{semester_code}": "grade",
+	"/api/parent/unlink_pupil/{pupil_id}":                                            "pupil",
+
+	// Pupils
+	"/api/pupil/attendance_excuse/{tenant_id}/{section_id}/{pupil_id}":                        "attendance_excuse",
+	"/api/pupil/attendance_excuse_attachment/{tenant_id}/{section_id}/{pupil_id}/{excuse_id}": "attendance_excuse",
+	"/api/pupil/attendance_excuses/{tenant_id}/{section_id}/{pupil_id}":                       "attendance_excuse",
+	"/api/pupil/behaviour_grade_history/{tenant_id}/{behaviour_grade_id}":                     "behaviour_grade",
+	"/api/pupil/behaviour_grades/{tenant_id}/{section_id}":                                    "behaviour_grade",
+	"/api/pupil/behaviour_grades/{tenant_id}/{section_id}/{pupil_id}":                         "behaviour_grade",
+	"/api/pupil/certificate/{tenant_id}/{section_id}/{pupil_id}":                              "certificate",
+	"/api/pupil/certificate_pdf/{tenant_id}/{section_id}/{pupil_id}":                          "certificate",
+	"/api/pupil/descriptive_assessments/{tenant_id}/{section_id}":                             "descriptive_assessment",
+	"/api/pupil/descriptive_assessments/{tenant_id}/{section_id}/{pupil_id}":                  "descriptive_assessment",
+	"/api/pupil/get_absent_attendances_for_pupil/{tenant_id}/{section_id}/{pupil_id}":         "attendance",
+	"/api/pupil/grade_edit_history/{tenant_id}/{grade_id}":                                    "grade",
+	"/api/pupil/pedagogical_measures/{tenant_id}/{section_id}/{pupil_id}":                     "pedagogical_measure",
+	"/api/pupil/planned_exams/{tenant_id}/{section_id}":                                       "planned_exam",
+	"/api/pupil/planned_exams/{tenant_id}/{section_id}/{pupil_id}":                            "planned_exam",
+	"/api/pupil/profile":                           "pupil",
+	"/api/pupil/schedule/{tenant_id}/{section_id}": "schedule",
+	"/api/pupil/section_grades_for_pupil/{tenant_id}/{section_id}/{semester_code}": "grade",
+	"/api/pupil/sections/{pupil_id}/{archived}":                                    "section",
+	"/api/pupil/statistics_fields/{pupil_id}":                                      "pupil",
+	"/api/pupil/update_general_data":                                               "pupil",
+	"/api/pupil/update_statistics_fields/{pupil_id}":                               "pupil",
+
+	// Super admins
+	"/api/superadmin/all_domains":                              "domain",
+	"/api/superadmin/assign_curriculums_to_tenant/{tenant_id}": "curriculum",
+	"/api/superadmin/audit_log":                                "audit_log",
+	"/api/superadmin/domain_create":                            "domain",
+	"/api/superadmin/domains_delete":                           "domain",
+	"/api/superadmin/impersonate/{account_id}":                 "account",
+	"/api/superadmin/impersonations":                           "account",
+	"/api/superadmin/jobs":                                     "job",
+	"/api/superadmin/jobs/{job_id}":                            "job",
+	"/api/superadmin/jobs/{job_id}/retry":                      "job",
+	"/api/superadmin/locked_accounts":                          "account",
+	"/api/superadmin/npp_semesters":                            "semester",
+	"/api/superadmin/npp_semesters_update":                     "semester",
+	"/api/superadmin/oidc_providers":                           "provider",
+	"/api/superadmin/oidc_providers/{provider_id}":             "provider",
+	"/api/superadmin/tenant":                                   "tenant",
+	"/api/superadmin/tenant/{id}":                              "tenant",
+	"/api/superadmin/tenants":                                  "tenant",
+	"/api/superadmin/unassign_curriculum_from_tenant/{tenant_id}/{curriculum_code}": "curriculum",
+	"/api/superadmin/unlock_account/{account_id}":                                   "account",
+
+	// Teachers
+	"/api/teacher/archive_section/{tenant_id}/{section_id}":                                            "section",
+	"/api/teacher/attendance_excuses/{tenant_id}/{section_id}":                                         "attendance_excuse",
+	"/api/teacher/complete_gradebook/{tenant_id}/{section_id}":                                         "grade",
+	"/api/teacher/create_grade/{tenant_id}":                                                            "grade",
+	"/api/teacher/create_grades_bulk/{tenant_id}/{section_id}/{subject_code}/{semester_code}":          "grade",
+	"/api/teacher/create_lesson/{tenant_id}":                                                           "lesson",
+	"/api/teacher/decide_attendance_excuses/{tenant_id}/{section_id}":                                  "attendance_excuse",
+	"/api/teacher/delete_grade/{tenant_id}":                                                            "grade",
+	"/api/teacher/delete_lesson/{tenant_id}/{lesson_id}":                                               "lesson",
+	"/api/teacher/delete_pupil_invite":                                                                 "invite",
+	"/api/teacher/descriptive_assessment_history/{tenant_id}/{section_id}/{descriptive_assessment_id}": "descriptive_assessment",
+	"/api/teacher/descriptive_assessments/{tenant_id}/{section_id}/{subject_code}/{semester_code}":     "descriptive_assessment",
+	"/api/teacher/final_grade_proposals/{tenant_id}/{section_id}/{subject_code}/{semester_code}":       "grade",
+	"/api/teacher/get_absent_attendances_for_section/{tenant_id}/{section_id}":                         "attendance",
+	"/api/teacher/get_lessons_for_section/{tenant_id}/{section_id}":                                    "lesson",
+	"/api/teacher/get_teacher/{id}":                                                                    "teacher",
+	"/api/teacher/gradebook_ledger/verify/{tenant_id}/{section_id}":                                    "gradebook_ledger",
+	"/api/teacher/gradebook_metadata/{tenant_id}/{section_id}":                                         "section",
+	"/api/teacher/handle_attendance_action/{tenant_id}":                                                "attendance",
+	"/api/teacher/handle_invite":                                                                       "invite",
+	"/api/teacher/invites/{teacher_id}":                                                                "invite",
+	"/api/teacher/pedagogical_measures/{tenant_id}/{section_id}":                                       "pedagogical_measure",
+	"/api/teacher/planned_exams/{tenant_id}/{section_id}":                                              "planned_exam",
+	"/api/teacher/planned_exams/{tenant_id}/{section_id}/{planned_exam_id}":                            "planned_exam",
+	"/api/teacher/pupils/{tenant_id}/{section_id}":                                                     "pupil",
+	"/api/teacher/remedial_exam/{tenant_id}/{remedial_exam_id}":                                        "remedial_exam",
+	"/api/teacher/remedial_exam_results/{tenant_id}/{remedial_exam_id}":                                "remedial_exam",
+	"/api/teacher/remedial_exams/{tenant_id}":                                                          "remedial_exam",
+	"/api/teacher/schedule/{teacher_id}":                                                               "schedule",
+	"/api/teacher/section_certificates/{tenant_id}/{section_id}":                                       "certificate",
+	"/api/teacher/section_grades_for_subject/{tenant_id}/{section_id}/{subject_code}/{semester_code}":  "grade",
+	"/api/teacher/section_report/{tenant_id}/{section_id}/{semester_code}":                             "section",
+	"/api/teacher/sections/{teacher_id}/{archived}":                                                    "section",
+	"/api/teacher/send_section_invite/{tenant_id}/{section_id}":                                        "invite",
+	"/api/teacher/subject_report/{tenant_id}/{section_id}/{subject_code}/{semester_code}":              "section",
+	"/api/teacher/unassign_pupil_from_section/{tenant_id}/{section_id}/{pupil_id}":                     "pupil",
+	"/api/teacher/unenroll_pupil_from_section/{tenant_id}/{section_id}/{pupil_id}":                     "pupil",
+	"/api/teacher/update_behaviour_grade/{tenant_id}":                                                  "behaviour_grade",
+	"/api/teacher/update_grade/{tenant_id}":                                                            "grade",
+	"/api/teacher/update_lesson/{tenant_id}/{lesson_id}":                                               "lesson",
+
+	// Tenant admins
+	"/api/tenant_admin/absence_thresholds/{tenant_id}":                       "absence_threshold",
+	"/api/tenant_admin/audit_log/{tenant_id}":                                "audit_log",
+	"/api/tenant_admin/create_classroom/{tenant_id}":                         "classroom",
+	"/api/tenant_admin/delete_classroom/{tenant_id}/{classroom_code}":        "classroom",
+	"/api/tenant_admin/delete_teacher_from_tenant/{tenant_id}/{teacher_id}":  "teacher",
+	"/api/tenant_admin/delete_teacher_invite":                                "invite",
+	"/api/tenant_admin/descriptive_assessment_classes/{tenant_id}":           "descriptive_assessment",
+	"/api/tenant_admin/exam_limits/{tenant_id}":                              "exam_limit",
+	"/api/tenant_admin/get_all_classrooms/{tenant_id}":                       "classroom",
+	"/api/tenant_admin/get_curriculums_for_assignment/{tenant_id}":           "curriculum",
+	"/api/tenant_admin/get_curriculums_for_tenant/{tenant_id}":               "curriculum",
+	"/api/tenant_admin/gradebook_ledger/seal/{tenant_id}/{section_id}":       "gradebook_ledger",
+	"/api/tenant_admin/grading_schemes/{tenant_id}":                          "grading_scheme",
+	"/api/tenant_admin/locked_accounts/{tenant_id}":                          "account",
+	"/api/tenant_admin/notification_webhook/{tenant_id}":                     "notification_webhook",
+	"/api/tenant_admin/parent_access_code/{tenant_id}/{pupil_id}":            "pupil",
+	"/api/tenant_admin/pupil_accounts":                                       "pupil",
+	"/api/tenant_admin/pupils":                                               "pupil",
+	"/api/tenant_admin/pupils/{pupil_id}":                                    "pupil",
+	"/api/tenant_admin/remedial_candidates/{tenant_id}/{section_id}":         "remedial_exam",
+	"/api/tenant_admin/remedial_exams/{tenant_id}":                           "remedial_exam",
+	"/api/tenant_admin/remedial_exams/{tenant_id}/{remedial_exam_id}":        "remedial_exam",
+	"/api/tenant_admin/schedule_create/{tenant_id}/{section_id}":             "schedule",
+	"/api/tenant_admin/section/{section_id}":                                 "section",
+	"/api/tenant_admin/section/{tenant_id}/{section_id}":                     "section",
+	"/api/tenant_admin/section_create/{tenant_id}":                           "section",
+	"/api/tenant_admin/section_creation_metadata/{tenant_id}":                "section",
+	"/api/tenant_admin/sections/{tenant_id}/{archived}":                      "section",
+	"/api/tenant_admin/semester_locks/{tenant_id}":                           "semester",
+	"/api/tenant_admin/semester_locks/{tenant_id}/{semester_code}/lock":      "semester",
+	"/api/tenant_admin/semester_locks/{tenant_id}/{semester_code}/unlock":    "semester",
+	"/api/tenant_admin/teacher":                                              "teacher",
+	"/api/tenant_admin/teacher/{id}":                                         "teacher",
+	"/api/tenant_admin/teacher_invite_data/{tenant_id}":                      "invite",
+	"/api/tenant_admin/teacher_invites/{tenant_id}":                          "invite",
+	"/api/tenant_admin/teacher_section_assignments/{tenant_id}/{teacher_id}": "teacher",
+	"/api/tenant_admin/teachers":                                             "teacher",
+	"/api/tenant_admin/teachers_per_tenant/{tenant_id}":                      "teacher",
+	"/api/tenant_admin/tenant/{id}":                                          "tenant",
+	"/api/tenant_admin/tenant_semesters/{tenant_id}":                         "semester",
+	"/api/tenant_admin/tenant_semesters_update":                              "semester",
+	"/api/tenant_admin/tenants_for_pupil/{pupil_id}":                         "tenant",
+	"/api/tenant_admin/tenants_for_teacher/{teacher_id}":                     "tenant",
+	"/api/tenant_admin/two_factor_policy/{tenant_id}":                        "two_factor",
+	"/api/tenant_admin/unlock_account/{tenant_id}/{account_id}":              "account",
+	"/api/tenant_admin/update_classroom/{tenant_id}/{classroom_code}":        "classroom",
+	"/api/tenant_admin/year_rollover/{tenant_id}":                            "year_rollover",
+}
+
+// AuditEntityOf returns the entity of a route template and whether the route
+// is mapped
+func AuditEntityOf(route string) (string, bool) {
+	entity, ok := auditRouteEntities[route]
+	return entity, ok
+}
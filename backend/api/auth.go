@@ -4,15 +4,22 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"ednevnik-backend/constants"
+	"ednevnik-backend/models/interfaces"
 	wpmodels "ednevnik-backend/models/workspace"
 	"ednevnik-backend/util"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
 )
 
 // JwtKey is the secret key used to sign JWT tokens
@@ -22,6 +29,20 @@ var JwtKey []byte
 // DbWorkspace is the connection to the workspace database
 var DbWorkspace *sql.DB
 
+// Access tokens are short-lived and carry the session ID; refresh tokens are
+// persisted (hashed) in account_sessions and rotate on every use
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// staleClaimsHeader is set on responses to requests made with a token whose
+// tenant membership is outdated, the client should call /reissue-token
+const staleClaimsHeader = "X-Token-Stale"
+
+// errSessionRevoked is returned when a valid JWT belongs to a revoked session
+var errSessionRevoked = errors.New("session revoked")
+
 // Login allows user login
 func Login(w http.ResponseWriter, r *http.Request) {
 	var req wpmodels.AuthRequest
@@ -36,83 +57,86 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	domains, err := util.GetAllDomainsHelper(DbWorkspace)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	ipAddress, allowed := checkLoginIPAllowed(w, r)
+	if !allowed {
 		return
 	}
 
-	// Check if the email ends with a valid domain
-	validDomain := false
-	for _, domain := range domains {
-		if strings.HasSuffix(req.Email, domain.Domain) {
-			validDomain = true
-			break
-		}
+	user, err := util.GetUserByEmail(email, DbWorkspace)
+	if err != nil {
+		recordFailedLogin(email, 0, ipAddress, "password")
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
 	}
-	if !validDomain {
-		http.Error(w, "Invalid domain", http.StatusBadGateway)
+
+	accountID, err := user.GetAccountID(DbWorkspace)
+	if err != nil {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	user, err := util.GetUserByEmail(email, DbWorkspace)
+	lockoutDelay, err := util.GetAccountLockoutDelay(accountID, DbWorkspace)
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// A locked account gets the same response as an unknown email, so the
+	// lockout does not reveal that the account exists. The attempt only
+	// counts against the IP address, it does not extend the lockout.
+	if lockoutDelay > 0 {
+		recordFailedLogin(email, 0, ipAddress, "password")
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
+	// Parents use their private email, everyone else must use a school domain
+	if user.GetAccountType(DbWorkspace) != "parent" {
+		domains, err := util.GetAllDomainsHelper(DbWorkspace)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Check if the email ends with a valid domain
+		validDomain := false
+		for _, domain := range domains {
+			if strings.HasSuffix(req.Email, domain.Domain) {
+				validDomain = true
+				break
+			}
+		}
+		if !validDomain {
+			http.Error(w, "Invalid domain", http.StatusBadGateway)
+			return
+		}
+	}
+
 	// Compare hashed password using bcrypt
 	if err := util.ComparePassword(user.GetPassword(), password); err != nil {
+		recordFailedLogin(email, accountID, ipAddress, "password")
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	userTenantIDs, err := user.GetTenantIDs(DbWorkspace)
+	claims, err := buildClaimsForUser(user)
 	if err != nil {
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	userAccountID, err := user.GetAccountID(DbWorkspace)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+	// Accounts with a second factor get their tokens from /login/2fa, the
+	// login is recorded as successful only after the second factor
+	if challenged := requireSecondFactor(w, claims, "password"); challenged {
 		return
 	}
 
-	accountType := user.GetAccountType(DbWorkspace)
-
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &wpmodels.Claims{
-		ID:          user.GetID(),
-		Name:        user.GetName(),
-		LastName:    user.GetLastName(),
-		Email:       user.GetEmail(),
-		Phone:       user.GetPhone(),
-		AccountType: accountType,
-		AccountID:   userAccountID,
-		TenantIDs:   userTenantIDs,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
-	}
-	if accountType == "tenant_admin" {
-		tenantID, err := util.GetTenantIDForTenantAdmin(
-			user.GetID(), DbWorkspace,
-		)
-		if err != nil {
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
-			return
-		}
-		claims.TenantAdminTenantID = tenantID
+	if err := util.RecordSuccessfulLogin(
+		email, accountID, ipAddress, "password", DbWorkspace,
+	); err != nil {
+		log.Printf("error recording login of account %d: %v", accountID, err)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(JwtKey)
-	if err != nil {
-		http.Error(w, "Could not create token", http.StatusInternalServerError)
-		return
-	}
-	w.Write([]byte(tokenString))
+	issueTokens(w, r, claims, "password")
 }
 
 // ParentLogin allows parent login using access code
@@ -132,55 +156,403 @@ func ParentLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ipAddress, allowed := checkLoginIPAllowed(w, r)
+	if !allowed {
+		return
+	}
+
 	// Get pupil by parent access code
 	pupil, err := util.GetGlobalPupilByParentAccessCode(parentAccessCode, DbWorkspace)
 	if err != nil {
+		recordFailedLogin("", 0, ipAddress, "parent_access_code")
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
 	if pupil == nil {
+		recordFailedLogin("", 0, ipAddress, "parent_access_code")
 		http.Error(w, "Invalid parent access code", http.StatusUnauthorized)
 		return
 	}
 
-	pupilTenantIDs, err := pupil.GetTenantIDs(DbWorkspace)
+	claims, err := buildClaimsForUser(pupil)
+	if err != nil {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	if err := util.RecordSuccessfulLogin(
+		"", claims.AccountID, ipAddress, "parent_access_code", DbWorkspace,
+	); err != nil {
+		log.Printf("error recording login of account %d: %v", claims.AccountID, err)
+	}
+
+	issueTokens(w, r, claims, "parent_access_code")
+}
+
+// checkLoginIPAllowed returns the client IP address of a login request and
+// whether it may attempt a login. The address comes from GetRequestIP, so
+// forwarded addresses only count when they were set by a trusted proxy. If
+// the address has to wait it responds with Too Many Requests.
+func checkLoginIPAllowed(w http.ResponseWriter, r *http.Request) (string, bool) {
+	ipAddress := util.GetRequestIP(r)
+	delay, err := util.GetLoginIPDelay(ipAddress, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return ipAddress, false
+	}
+	if delay > 0 {
+		tooManyLoginAttempts(w, delay)
+		return ipAddress, false
+	}
+	return ipAddress, true
+}
+
+// tooManyLoginAttempts responds with Too Many Requests and a Retry-After
+// header
+func tooManyLoginAttempts(w http.ResponseWriter, delay time.Duration) {
+	seconds := int(math.Ceil(delay.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, fmt.Sprintf(
+		"previše neuspješnih pokušaja prijave - pokušajte ponovo za %d s", seconds,
+	), http.StatusTooManyRequests)
+}
+
+// recordFailedLogin records a failed login, logging errors so the response
+// stays the same
+func recordFailedLogin(email string, accountID int, ipAddress, loginType string) {
+	if err := util.RecordFailedLogin(
+		email, accountID, ipAddress, loginType, DbWorkspace,
+	); err != nil {
+		log.Printf("error recording failed login: %v", err)
+	}
+}
+
+// RefreshToken exchanges a refresh token for a new access and refresh token
+// pair. Claims are rebuilt from the database so they reflect the current
+// state of the account.
+func RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req wpmodels.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.RefreshToken == "" {
+		http.Error(w, "Missing refresh token", http.StatusBadRequest)
+		return
+	}
+
+	newRefreshToken, err := util.GenerateRefreshToken()
+	if err != nil {
+		http.Error(w, "Could not create token", http.StatusInternalServerError)
+		return
+	}
+
+	session, err := util.RotateAccountSession(
+		req.RefreshToken, newRefreshToken, refreshTokenTTL, DbWorkspace,
+	)
+	if err != nil {
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := util.GetUserByAccountID(session.AccountID, DbWorkspace)
+	if err != nil {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := buildClaimsForUser(user)
 	if err != nil {
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
+	claims.SessionID = session.ID
 
-	pupilAccountID, err := pupil.GetAccountID(DbWorkspace)
+	accessToken, accessExpiresAt, err := signAccessToken(claims)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		http.Error(w, "Could not create token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wpmodels.TokenResponse{
+		AccessToken:      accessToken,
+		RefreshToken:     newRefreshToken,
+		AccessExpiresAt:  accessExpiresAt.Unix(),
+		RefreshExpiresAt: time.Now().Add(refreshTokenTTL).Unix(),
+	})
+}
+
+// ReissueToken returns a fresh access token for the current session with
+// claims rebuilt from the database. The refresh token is left untouched.
+func ReissueToken(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	accountType := pupil.GetAccountType(DbWorkspace)
+	user, err := util.GetUserByAccountID(claims.AccountID, DbWorkspace)
+	if err != nil {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	newClaims, err := buildClaimsForUser(user)
+	if err != nil {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	newClaims.SessionID = claims.SessionID
+
+	// Impersonation tokens stay impersonation tokens and keep their expiry
+	expiresAt := time.Now().Add(accessTokenTTL)
+	if claims.ImpersonationID != 0 {
+		newClaims.ImpersonationID = claims.ImpersonationID
+		newClaims.ImpersonatorAccountID = claims.ImpersonatorAccountID
+		newClaims.ReadOnly = claims.ReadOnly
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	accessToken, accessExpiresAt, err := signAccessTokenUntil(newClaims, expiresAt)
+	if err != nil {
+		http.Error(w, "Could not create token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Del(staleClaimsHeader)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wpmodels.TokenResponse{
+		AccessToken:     accessToken,
+		AccessExpiresAt: accessExpiresAt.Unix(),
+	})
+}
+
+// Logout revokes the session the request was made with. Impersonation
+// tokens only end the impersonation, the session of the superadmin stays.
+func Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if claims.ImpersonationID != 0 {
+		EndImpersonationHandler(w, r)
+		return
+	}
+
+	if err := util.RevokeAccountSession(claims.SessionID, DbWorkspace); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAllDevices revokes every session of the logged in account
+func LogoutAllDevices(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := util.RevokeAllAccountSessions(claims.AccountID, DbWorkspace); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// buildClaimsForUser collects all data that goes into the access token
+func buildClaimsForUser(user interfaces.User) (*wpmodels.Claims, error) {
+	userTenantIDs, err := user.GetTenantIDs(DbWorkspace)
+	if err != nil {
+		return nil, err
+	}
+
+	userAccountID, err := user.GetAccountID(DbWorkspace)
+	if err != nil {
+		return nil, err
+	}
+
+	membershipVersion, err := util.GetAccountMembershipVersion(
+		userAccountID, DbWorkspace,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	accountType := user.GetAccountType(DbWorkspace)
 
-	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &wpmodels.Claims{
-		ID:          pupil.ID,
-		Name:        pupil.Name,
-		LastName:    pupil.LastName,
-		Email:       pupil.Email,
-		Phone:       pupil.PhoneNumber,
+		ID:          user.GetID(),
+		Name:        user.GetName(),
+		LastName:    user.GetLastName(),
+		Email:       user.GetEmail(),
+		Phone:       user.GetPhone(),
 		AccountType: accountType,
-		AccountID:   pupilAccountID,
-		TenantIDs:   pupilTenantIDs,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+		AccountID:   userAccountID,
+		TenantIDs:   userTenantIDs,
+
+		MembershipVersion: membershipVersion,
+	}
+	if accountType == "tenant_admin" {
+		tenantID, err := util.GetTenantIDForTenantAdmin(
+			user.GetID(), DbWorkspace,
+		)
+		if err != nil {
+			return nil, err
+		}
+		claims.TenantAdminTenantID = tenantID
+	}
+
+	return claims, nil
+}
+
+// signAccessToken sets the expiry on the claims and signs them
+func signAccessToken(claims *wpmodels.Claims) (string, time.Time, error) {
+	return signAccessTokenUntil(claims, time.Now().Add(accessTokenTTL))
+}
+
+// signAccessTokenUntil signs the claims with the given expiry
+func signAccessTokenUntil(
+	claims *wpmodels.Claims, expirationTime time.Time,
+) (string, time.Time, error) {
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expirationTime),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString(JwtKey)
 	if err != nil {
-		http.Error(w, "Could not create token", http.StatusInternalServerError)
+		return "", time.Time{}, err
+	}
+	return tokenString, expirationTime, nil
+}
+
+// issueTokens opens a new session for the claims and writes the token pair
+func issueTokens(
+	w http.ResponseWriter, r *http.Request, claims *wpmodels.Claims, loginType string,
+) {
+	tokens, err := createTokens(r, claims, loginType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Write([]byte(tokenString))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// createTokens opens a new session for the claims and returns the token pair
+func createTokens(
+	r *http.Request, claims *wpmodels.Claims, loginType string,
+) (*wpmodels.TokenResponse, error) {
+	refreshToken, err := util.GenerateRefreshToken()
+	if err != nil {
+		return nil, fmt.Errorf("could not create token")
+	}
+
+	sessionID, err := util.CreateAccountSession(
+		claims.AccountID,
+		loginType,
+		refreshToken,
+		r.UserAgent(),
+		util.GetRequestIP(r),
+		refreshTokenTTL,
+		DbWorkspace,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not create session")
+	}
+	claims.SessionID = sessionID
+
+	accessToken, accessExpiresAt, err := signAccessToken(claims)
+	if err != nil {
+		return nil, fmt.Errorf("could not create token")
+	}
+
+	return &wpmodels.TokenResponse{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		AccessExpiresAt:  accessExpiresAt.Unix(),
+		RefreshExpiresAt: time.Now().Add(refreshTokenTTL).Unix(),
+	}, nil
+}
+
+// validateAccessToken parses the JWT and makes sure its session is still
+// active, so revoked tokens stop working before they expire. Impersonation
+// tokens are checked against their impersonation instead. When tenant
+// membership changed after the token was issued the tenant IDs are reloaded
+// and the client is told to reissue its token.
+func validateAccessToken(
+	w http.ResponseWriter, tokenStr string,
+) (*wpmodels.Claims, error) {
+	claims, err := util.ParseAndValidateJWT(tokenStr, JwtKey)
+	if err != nil {
+		return nil, err
+	}
+	if claims == nil {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	var active bool
+	var membershipVersion int
+	if claims.ImpersonationID != 0 {
+		active, membershipVersion, err = util.GetImpersonationStatus(
+			claims.ImpersonationID, DbWorkspace,
+		)
+	} else {
+		active, membershipVersion, err = util.GetAccountSessionStatus(
+			claims.SessionID, DbWorkspace,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, errSessionRevoked
+	}
+
+	if membershipVersion != claims.MembershipVersion {
+		if err := reloadTenantMembership(claims); err != nil {
+			return nil, err
+		}
+		w.Header().Set(staleClaimsHeader, "true")
+	}
+
+	return claims, nil
+}
+
+// reloadTenantMembership replaces the tenant IDs in the claims with the ones
+// currently stored in the database
+func reloadTenantMembership(claims *wpmodels.Claims) error {
+	user, err := util.GetUserByAccountID(claims.AccountID, DbWorkspace)
+	if err != nil {
+		return err
+	}
+	liveClaims, err := buildClaimsForUser(user)
+	if err != nil {
+		return err
+	}
+	claims.TenantIDs = liveClaims.TenantIDs
+	claims.TenantAdminTenantID = liveClaims.TenantAdminTenantID
+	claims.MembershipVersion = liveClaims.MembershipVersion
+	return nil
+}
+
+// writeTokenError responds with 401 and tells revoked sessions apart from
+// malformed or expired tokens
+func writeTokenError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errSessionRevoked) {
+		http.Error(w, "Session revoked", http.StatusUnauthorized)
+		return
+	}
+	http.Error(w, "Invalid token", http.StatusUnauthorized)
 }
 
 // AuthMiddleware is used for user authentication, one of roles:
@@ -192,9 +564,9 @@ func AuthMiddleware(next http.HandlerFunc, accountTypes []string) http.HandlerFu
 			http.Error(w, "Missing token", http.StatusUnauthorized)
 			return
 		}
-		claims, err := util.ParseAndValidateJWT(tokenStr, JwtKey)
+		claims, err := validateAccessToken(w, tokenStr)
 		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			writeTokenError(w, err)
 			return
 		}
 
@@ -211,6 +583,14 @@ func AuthMiddleware(next http.HandlerFunc, accountTypes []string) http.HandlerFu
 			return
 		}
 
+		// Tenant scoped routes are checked against live membership so a
+		// removed user loses access without waiting for the token to expire
+		if tenantID, ok := mux.Vars(r)["tenant_id"]; ok &&
+			!util.ClaimsHaveTenantAccess(claims, tenantID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, constants.ClaimsKey, claims)
 		next(w, r.WithContext(ctx))
@@ -221,7 +601,8 @@ func AuthMiddleware(next http.HandlerFunc, accountTypes []string) http.HandlerFu
 // for the logged in user
 func UserWorkspaceDBMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/login" || r.URL.Path == "/parent-login" {
+		if r.URL.Path == "/login" || r.URL.Path == "/parent-login" ||
+			r.URL.Path == "/refresh" {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -231,9 +612,9 @@ func UserWorkspaceDBMiddleware(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		}
-		claims, err := util.ParseAndValidateJWT(tokenStr, JwtKey)
+		claims, err := validateAccessToken(w, tokenStr)
 		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			writeTokenError(w, err)
 			return
 		}
 		userDB, err := util.GetOrCreateDBConnection("ednevnik_workspace", claims.AccountType)
@@ -7,6 +7,7 @@ import (
 	"ednevnik-backend/tenantfactory"
 	"ednevnik-backend/util"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -96,6 +97,52 @@ func VerifyAccount(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// ForgotPasswordHandler sends a password reset link to the email of an
+// account. It always responds with OK so it can not be used to find out
+// which emails have an account.
+func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var request wpmodels.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	err := util.RequestPasswordReset(
+		request.Email, util.GetRequestIP(r), DbWorkspace,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// ResetPasswordHandler sets a new password with a reset token and signs the
+// account out of every device
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var request wpmodels.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	accountID, err := util.ResetPassword(
+		request.Token, request.NewPassword, request.ConfirmPassword, DbWorkspace,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := util.RevokeAllAccountSessions(accountID, DbWorkspace); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
 // GetPupilSectionInvites returns all sections invites for a specific pupil
 func GetPupilSectionInvites(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -179,6 +226,10 @@ func PupilInviteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if old, err := tenantInstance.GetPupilSectionInvite(inviteRequest.InviteID); err == nil {
+		setAuditBefore(r, old)
+	}
+
 	if inviteRequest.Action == "accept" {
 		err = tenantInstance.AcceptPupilSectionInvite(
 			fmt.Sprintf("%d", inviteRequest.InviteID),
@@ -252,6 +303,14 @@ func ChangeAccountPasswordHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Sign out every other device that still holds the old password session
+	if err := util.RevokeOtherAccountSessions(
+		claims.AccountID, claims.SessionID, DbWorkspace,
+	); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -331,3 +390,21 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(chatResponse)
 }
+
+// gradebookErrorStatus maps errors of writes into the gradebook to a status
+// code, a locked semester or exceeded exam limits are a conflict and a date
+// outside of it or numeric grades in a descriptively assessed section a bad
+// request
+func gradebookErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, util.ErrSemesterLocked),
+		errors.Is(err, util.ErrExamLimitExceeded):
+		return http.StatusConflict
+	case errors.Is(err, util.ErrOutsideSemester),
+		errors.Is(err, util.ErrDescriptiveSection),
+		errors.Is(err, util.ErrPupilNotInSection):
+		return http.StatusBadRequest
+	default:
+		return fallback
+	}
+}
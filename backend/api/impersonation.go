@@ -0,0 +1,191 @@
+package api
+
+import (
+	wpmodels "ednevnik-backend/models/workspace"
+	"ednevnik-backend/util"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// impersonationTTL is how long an impersonation token is valid. It can not be
+// refreshed, the superadmin starts a new impersonation instead.
+const impersonationTTL = 30 * time.Minute
+
+// impersonationReadOnlyRoutes can be called with a read-only impersonation
+// token even though they are not GET requests
+var impersonationReadOnlyRoutes = []string{
+	"/logout",
+	"/reissue-token",
+	"/api/common/impersonation/end",
+}
+
+// impersonationBlockedRoutes change the credentials of the impersonated
+// account and are refused for every impersonation token
+var impersonationBlockedRoutes = []string{
+	"/logout-all",
+	"/api/common/change_password",
+	"/api/common/two_factor",
+}
+
+// ImpersonateHandler issues a token to view the app as another account. The
+// token carries the claims of the account together with the superadmin and
+// is read-only unless writes are allowed explicitly. Other superadmins can
+// not be impersonated.
+func ImpersonateHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	targetAccountID, err := strconv.Atoi(mux.Vars(r)["account_id"])
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	var req wpmodels.ImpersonationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	user, err := util.GetUserByAccountID(targetAccountID, DbWorkspace)
+	if err != nil {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if user.GetAccountType(DbWorkspace) == "root" {
+		http.Error(
+			w,
+			"Nije moguće pregledati aplikaciju kao drugi superadmin",
+			http.StatusForbidden,
+		)
+		return
+	}
+
+	targetClaims, err := buildClaimsForUser(user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	readOnly := !req.AllowWrites
+	impersonationID, err := util.CreateImpersonation(
+		claims.AccountID,
+		targetAccountID,
+		claims.SessionID,
+		readOnly,
+		req.Reason,
+		util.GetRequestIP(r),
+		impersonationTTL,
+		DbWorkspace,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	targetClaims.SessionID = claims.SessionID
+	targetClaims.ImpersonationID = impersonationID
+	targetClaims.ImpersonatorAccountID = claims.AccountID
+	targetClaims.ReadOnly = readOnly
+
+	accessToken, accessExpiresAt, err := signAccessTokenUntil(
+		targetClaims, time.Now().Add(impersonationTTL),
+	)
+	if err != nil {
+		http.Error(w, "Could not create token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(wpmodels.ImpersonationResponse{
+		ImpersonationID: impersonationID,
+		AccessToken:     accessToken,
+		AccessExpiresAt: accessExpiresAt.Unix(),
+		ReadOnly:        readOnly,
+		AccountID:       targetClaims.AccountID,
+		AccountType:     targetClaims.AccountType,
+		Name:            targetClaims.Name,
+		LastName:        targetClaims.LastName,
+	})
+}
+
+// EndImpersonationHandler ends the impersonation the request was made with,
+// the impersonation token stops working right away
+func EndImpersonationHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if claims.ImpersonationID == 0 {
+		http.Error(w, "Not impersonating", http.StatusBadRequest)
+		return
+	}
+
+	if err := util.EndImpersonation(claims.ImpersonationID, DbWorkspace); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetImpersonationsHandler returns the latest impersonations (super admin only)
+func GetImpersonationsHandler(w http.ResponseWriter, r *http.Request) {
+	impersonations, err := util.GetImpersonations(100, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(impersonations)
+}
+
+// ImpersonationMiddleware refuses requests an impersonation token is not
+// allowed to make. Read-only tokens can only read, and no impersonation token
+// can change the credentials of the impersonated account.
+func ImpersonationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := util.GetClaimsFromContext(r)
+		if !ok || claims.ImpersonationID == 0 || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		for _, route := range impersonationBlockedRoutes {
+			if strings.HasPrefix(r.URL.Path, route) {
+				http.Error(
+					w,
+					"Ova akcija nije dozvoljena tokom pregleda kao drugi korisnik",
+					http.StatusForbidden,
+				)
+				return
+			}
+		}
+
+		if claims.ReadOnly && !isReadRequest(r) &&
+			!slices.Contains(impersonationReadOnlyRoutes, r.URL.Path) {
+			http.Error(
+				w,
+				"Pregled kao drugi korisnik je samo za čitanje",
+				http.StatusForbidden,
+			)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"ednevnik-backend/notification"
+	"ednevnik-backend/util"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// notificationPageSize is the number of notifications returned by the inbox
+const notificationPageSize = 50
+
+// GetNotificationsHandler returns the inbox of the logged in account. With
+// unread=1 only unread notifications are returned.
+func GetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	unreadOnly := r.URL.Query().Get("unread") == "1"
+	items, err := notification.GetInbox(
+		claims.AccountID, unreadOnly, notificationPageSize, DbWorkspace,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	unreadCount, err := notification.GetUnreadCount(claims.AccountID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"notifications": items,
+		"unread_count":  unreadCount,
+	})
+}
+
+// MarkNotificationReadHandler marks a notification of the logged in account
+// as read
+func MarkNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	notificationID, err := strconv.Atoi(vars["notification_id"])
+	if err != nil {
+		http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if old, err := notification.GetInboxItem(claims.AccountID, notificationID, DbWorkspace); err == nil {
+		setAuditBefore(r, old)
+	}
+
+	err = notification.MarkRead(claims.AccountID, notificationID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MarkAllNotificationsReadHandler marks every notification of the logged in
+// account as read
+func MarkAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if unread, err := notification.GetUnreadCount(claims.AccountID, DbWorkspace); err == nil {
+		setAuditBefore(r, map[string]int{"unread_count": unread})
+	}
+
+	if err := notification.MarkAllRead(claims.AccountID, DbWorkspace); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetNotificationPreferencesHandler returns the notification preferences of
+// the logged in account
+func GetNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	preferences, err := notification.GetPreferences(claims.AccountID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preferences)
+}
+
+// UpdateNotificationPreferencesHandler stores the notification preferences of
+// the logged in account and returns all of them
+func UpdateNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var preferences []notification.Preference
+	if err := json.NewDecoder(r.Body).Decode(&preferences); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if old, err := notification.GetPreferences(claims.AccountID, DbWorkspace); err == nil {
+		setAuditBefore(r, old)
+	}
+
+	err := notification.SetPreferences(claims.AccountID, preferences, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updatedPreferences, err := notification.GetPreferences(claims.AccountID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedPreferences)
+}
+
+// tenantWebhookRequest is the body of UpdateTenantWebhookHandler
+type tenantWebhookRequest struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+// GetTenantWebhookHandler returns the notification webhook of a tenant
+// without its secret, or null if the tenant has none
+func GetTenantWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := strconv.ParseInt(mux.Vars(r)["tenant_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := notification.GetTenantWebhook(tenantID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// UpdateTenantWebhookHandler sets the notification webhook of a tenant. An
+// empty secret keeps the stored one.
+func UpdateTenantWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := strconv.ParseInt(mux.Vars(r)["tenant_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req tenantWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	before, err := notification.GetTenantWebhook(tenantID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	setAuditBefore(r, before)
+
+	err = notification.SetTenantWebhook(tenantID, req.URL, req.Secret, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := notification.GetTenantWebhook(tenantID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// DeleteTenantWebhookHandler removes the notification webhook of a tenant
+func DeleteTenantWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := strconv.ParseInt(mux.Vars(r)["tenant_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	before, err := notification.GetTenantWebhook(tenantID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	setAuditBefore(r, before)
+
+	if err := notification.DeleteTenantWebhook(tenantID, DbWorkspace); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
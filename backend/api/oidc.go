@@ -0,0 +1,321 @@
+package api
+
+import (
+	"database/sql"
+	wpmodels "ednevnik-backend/models/workspace"
+	"ednevnik-backend/oidc"
+	"ednevnik-backend/util"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// oidcClient caches the discovery documents and keys of the providers
+var oidcClient = oidc.NewClient()
+
+// oidcRedirectURL is the page of the frontend the provider redirects back to.
+// The page posts the code and state to /login/oidc/callback.
+func oidcRedirectURL() string {
+	if redirectURL := os.Getenv("OIDC_REDIRECT_URL"); redirectURL != "" {
+		return redirectURL
+	}
+	return strings.TrimSuffix(os.Getenv("FRONTEND_URL"), "/") + "/login/oidc/callback"
+}
+
+// OIDCStartHandler starts a single sign-on login for the provider of the
+// email domain and returns the URL to send the user to
+func OIDCStartHandler(w http.ResponseWriter, r *http.Request) {
+	email := r.URL.Query().Get("email")
+	if email == "" {
+		http.Error(w, "Missing email", http.StatusBadRequest)
+		return
+	}
+
+	provider, err := util.GetOIDCProviderForEmail(email, DbWorkspace)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Prijava putem SSO nije dostupna za ovu domenu", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	metadata, err := oidcClient.Discover(provider.IssuerURL)
+	if err != nil {
+		http.Error(w, "SSO provider is unavailable", http.StatusBadGateway)
+		return
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		http.Error(w, "Could not start login", http.StatusInternalServerError)
+		return
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		http.Error(w, "Could not start login", http.StatusInternalServerError)
+		return
+	}
+	verifier, challenge, err := oidc.GeneratePKCE()
+	if err != nil {
+		http.Error(w, "Could not start login", http.StatusInternalServerError)
+		return
+	}
+
+	if err := util.CreateOIDCLoginState(
+		state, provider.ID, verifier, nonce, DbWorkspace,
+	); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	config := util.OIDCConfig(provider, oidcRedirectURL())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wpmodels.OIDCStartResponse{
+		AuthorizationURL: oidc.AuthCodeURL(metadata, config, state, nonce, challenge),
+		ProviderName:     provider.DisplayName,
+	})
+}
+
+// OIDCCallbackHandler finishes a single sign-on login. The code is exchanged
+// with the PKCE verifier, the ID token is verified and its subject or email
+// is mapped to an existing account. Accounts are never created here.
+func OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	var req wpmodels.OIDCCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Code == "" || req.State == "" {
+		http.Error(w, "Missing code or state", http.StatusBadRequest)
+		return
+	}
+
+	ipAddress, allowed := checkLoginIPAllowed(w, r)
+	if !allowed {
+		return
+	}
+
+	providerID, verifier, nonce, err := util.ConsumeOIDCLoginState(req.State, DbWorkspace)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Invalid or expired login state", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	provider, err := util.GetOIDCProviderByID(providerID, DbWorkspace)
+	if err != nil || !provider.Enabled {
+		http.Error(w, "SSO provider is disabled", http.StatusBadRequest)
+		return
+	}
+
+	config := util.OIDCConfig(provider, oidcRedirectURL())
+	metadata, err := oidcClient.Discover(provider.IssuerURL)
+	if err != nil {
+		http.Error(w, "SSO provider is unavailable", http.StatusBadGateway)
+		return
+	}
+	tokens, err := oidcClient.Exchange(metadata, config, req.Code, verifier)
+	if err != nil {
+		log.Printf("oidc: provider %d: %v", provider.ID, err)
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	idToken, err := oidcClient.VerifyIDToken(metadata, config, tokens.IDToken, nonce)
+	if err != nil {
+		log.Printf("oidc: provider %d: %v", provider.ID, err)
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	accountID, err := oidcAccountID(provider, idToken)
+	if err != nil {
+		recordFailedLogin(idToken.Email, 0, ipAddress, "oidc")
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	user, err := util.GetUserByAccountID(accountID, DbWorkspace)
+	if err != nil {
+		recordFailedLogin(idToken.Email, 0, ipAddress, "oidc")
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	lockoutDelay, err := util.GetAccountLockoutDelay(accountID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if lockoutDelay > 0 {
+		tooManyLoginAttempts(w, lockoutDelay)
+		return
+	}
+
+	if err := util.LinkAccountIdentity(
+		accountID, provider.ID, idToken.Subject, idToken.Email, DbWorkspace,
+	); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	claims, err := buildClaimsForUser(user)
+	if err != nil {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	if challenged := requireSecondFactor(w, claims, "oidc"); challenged {
+		return
+	}
+
+	if err := util.RecordSuccessfulLogin(
+		user.GetEmail(), accountID, ipAddress, "oidc", DbWorkspace,
+	); err != nil {
+		log.Printf("error recording login of account %d: %v", accountID, err)
+	}
+
+	issueTokens(w, r, claims, "oidc")
+}
+
+// oidcAccountID maps a verified ID token to an account. A subject that was
+// linked before wins, otherwise the email of the provider domain is looked
+// up, but only if the provider says it is verified.
+func oidcAccountID(
+	provider *wpmodels.OIDCProvider, idToken *oidc.IDTokenClaims,
+) (int, error) {
+	accountID, err := util.GetAccountIDForIdentity(provider.ID, idToken.Subject, DbWorkspace)
+	if err == nil {
+		return accountID, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	if !idToken.HasVerifiedEmail() {
+		return 0, errors.New("nalog kod SSO provajdera nema potvrđenu email adresu")
+	}
+	if !util.EmailMatchesOIDCDomain(idToken.Email, provider.Domain) {
+		return 0, errors.New("email adresa ne pripada domeni SSO provajdera")
+	}
+
+	err = DbWorkspace.QueryRow(
+		`SELECT id FROM accounts WHERE email = ?`, idToken.Email,
+	).Scan(&accountID)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("ne postoji nalog za ovu email adresu")
+	}
+	if err != nil {
+		return 0, err
+	}
+	return accountID, nil
+}
+
+// GetOIDCProvidersHandler returns all single sign-on providers (super admin only)
+func GetOIDCProvidersHandler(w http.ResponseWriter, r *http.Request) {
+	providers, err := util.GetOIDCProviders(DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(providers)
+}
+
+// CreateOIDCProviderHandler adds a single sign-on provider for an email
+// domain (super admin only)
+func CreateOIDCProviderHandler(w http.ResponseWriter, r *http.Request) {
+	var provider wpmodels.OIDCProvider
+	if err := json.NewDecoder(r.Body).Decode(&provider); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := util.ValidateOIDCProvider(&provider); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	providerID, err := util.CreateOIDCProvider(provider, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	provider.ID = providerID
+	provider.ClientSecret = ""
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(provider)
+}
+
+// UpdateOIDCProviderHandler updates a single sign-on provider, an empty
+// client secret keeps the stored one (super admin only)
+func UpdateOIDCProviderHandler(w http.ResponseWriter, r *http.Request) {
+	providerID, err := strconv.Atoi(mux.Vars(r)["provider_id"])
+	if err != nil {
+		http.Error(w, "Invalid provider ID", http.StatusBadRequest)
+		return
+	}
+
+	var provider wpmodels.OIDCProvider
+	if err := json.NewDecoder(r.Body).Decode(&provider); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := util.ValidateOIDCProvider(&provider); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if oldProvider, err := util.GetOIDCProviderByID(providerID, DbWorkspace); err == nil {
+		oldProvider.ClientSecret = ""
+		setAuditBefore(r, oldProvider)
+	}
+
+	err = util.UpdateOIDCProvider(providerID, provider, DbWorkspace)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Provider not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	provider.ID = providerID
+	provider.ClientSecret = ""
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(provider)
+}
+
+// DeleteOIDCProviderHandler removes a single sign-on provider (super admin only)
+func DeleteOIDCProviderHandler(w http.ResponseWriter, r *http.Request) {
+	providerID, err := strconv.Atoi(mux.Vars(r)["provider_id"])
+	if err != nil {
+		http.Error(w, "Invalid provider ID", http.StatusBadRequest)
+		return
+	}
+
+	if oldProvider, err := util.GetOIDCProviderByID(providerID, DbWorkspace); err == nil {
+		oldProvider.ClientSecret = ""
+		setAuditBefore(r, oldProvider)
+	}
+
+	if err := util.DeleteOIDCProvider(providerID, DbWorkspace); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
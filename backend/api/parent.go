@@ -0,0 +1,204 @@
+package api
+
+import (
+	"database/sql"
+	wpmodels "ednevnik-backend/models/workspace"
+	"ednevnik-backend/util"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterParent is used to create a new parent account
+func RegisterParent(w http.ResponseWriter, r *http.Request) {
+	var parent wpmodels.Parent
+	if err := json.NewDecoder(r.Body).Decode(&parent); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if parent.Password == "" {
+		http.Error(w, "Password required", http.StatusBadRequest)
+		return
+	}
+
+	err := util.RegisterParent(parent, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// GetParentChildrenHandler returns all pupils linked to the logged in parent
+func GetParentChildrenHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userWorkspaceDb, ok := util.GetUserWorkspaceDBFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	children, err := util.GetChildrenForParent(claims.ID, userWorkspaceDb)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(children)
+}
+
+// LinkPupilToParentHandler links a pupil to the logged in parent using the
+// parent access code of the pupil
+func LinkPupilToParentHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req wpmodels.ParentLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.ParentAccessCode == "" {
+		http.Error(w, "Missing parent access code", http.StatusBadRequest)
+		return
+	}
+
+	// Parents only have read access, links are written by the service user
+	workspaceDB, err := util.GetOrCreateDBConnectionServiceReader("ednevnik_workspace")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	child, err := util.LinkPupilToParent(claims.ID, req.ParentAccessCode, workspaceDB)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(child)
+}
+
+// UnlinkPupilFromParentHandler removes a pupil from the logged in parent
+func UnlinkPupilFromParentHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pupilID, err := strconv.Atoi(vars["pupil_id"])
+	if err != nil {
+		http.Error(w, "Invalid pupil ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	workspaceDB, err := util.GetOrCreateDBConnectionServiceReader("ednevnik_workspace")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if children, err := util.GetChildrenForParent(claims.ID, workspaceDB); err == nil {
+		for _, child := range children {
+			if child.PupilID == pupilID {
+				setAuditBefore(r, child)
+				break
+			}
+		}
+	}
+
+	err = util.UnlinkPupilFromParent(claims.ID, pupilID, workspaceDB)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateParentAccessCodeHandler generates a new parent access code for a
+// pupil of the tenant, the old code stops working
+func RegenerateParentAccessCodeHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+	pupilID, err := strconv.Atoi(vars["pupil_id"])
+	if err != nil {
+		http.Error(w, "Invalid pupil ID", http.StatusBadRequest)
+		return
+	}
+
+	workspaceDB, err := util.GetOrCreateDBConnectionServiceReader("ednevnik_workspace")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if old, err := util.GetParentAccess(pupilID, tenantID, workspaceDB); err == nil {
+		setAuditBefore(r, old)
+	}
+
+	code, err := util.RegenerateParentAccessCode(pupilID, tenantID, workspaceDB)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Pupil not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wpmodels.ParentAccessCodeResponse{
+		PupilID:          pupilID,
+		ParentAccessCode: code,
+	})
+}
+
+// RevokeParentAccessCodeHandler revokes the parent access code of a pupil of
+// the tenant and unlinks all parents of the pupil
+func RevokeParentAccessCodeHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+	pupilID, err := strconv.Atoi(vars["pupil_id"])
+	if err != nil {
+		http.Error(w, "Invalid pupil ID", http.StatusBadRequest)
+		return
+	}
+
+	workspaceDB, err := util.GetOrCreateDBConnectionServiceReader("ednevnik_workspace")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if old, err := util.GetParentAccess(pupilID, tenantID, workspaceDB); err == nil {
+		setAuditBefore(r, old)
+	}
+
+	err = util.RevokeParentAccessCode(pupilID, tenantID, workspaceDB)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Pupil not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
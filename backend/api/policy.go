@@ -0,0 +1,331 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	tenantmodels "ednevnik-backend/models/tenant"
+	wpmodels "ednevnik-backend/models/workspace"
+	"ednevnik-backend/tenantfactory"
+	"ednevnik-backend/util"
+
+	"github.com/gorilla/mux"
+)
+
+// Policy decides whether the caller may access the resources named by the
+// route variables. Policies only grant access, a request is allowed when at
+// least one policy of the route grants it.
+type Policy func(claims *wpmodels.Claims, vars map[string]string) (bool, error)
+
+// policyStore answers the membership questions the policies are decided on
+type policyStore interface {
+	IsTeacherAssignedToSection(tenantID string, teacherID, sectionID int) (bool, error)
+	IsHomeroomTeacherOfSection(tenantID string, teacherID, sectionID int) (bool, error)
+	IsPupilEnrolledInSection(tenantID string, pupilID, sectionID int) (bool, error)
+	IsRemedialCommissionMember(tenantID string, examID, teacherID int) (bool, error)
+	IsParentOfPupil(parentID, pupilID int) (bool, error)
+	GetPupilTenantIDs(pupilID int) ([]string, error)
+}
+
+// policyMemberships is the store the policies read from, tests replace it
+var policyMemberships policyStore = databasePolicyStore{}
+
+// databasePolicyStore reads memberships from the workspace database and the
+// tenant databases with the service reader
+type databasePolicyStore struct{}
+
+func (databasePolicyStore) IsTeacherAssignedToSection(
+	tenantID string, teacherID, sectionID int,
+) (bool, error) {
+	tenantInstance, err := tenantfactory.ServiceReader(tenantID)
+	if err != nil {
+		return false, err
+	}
+	return tenantInstance.IsTeacherAssignedToSection(teacherID, sectionID)
+}
+
+func (databasePolicyStore) IsHomeroomTeacherOfSection(
+	tenantID string, teacherID, sectionID int,
+) (bool, error) {
+	tenantInstance, err := tenantfactory.ServiceReader(tenantID)
+	if err != nil {
+		return false, err
+	}
+	return tenantInstance.IsHomeroomTeacherOfSection(teacherID, sectionID)
+}
+
+func (databasePolicyStore) IsPupilEnrolledInSection(
+	tenantID string, pupilID, sectionID int,
+) (bool, error) {
+	tenantInstance, err := tenantfactory.ServiceReader(tenantID)
+	if err != nil {
+		return false, err
+	}
+	return tenantInstance.IsPupilEnrolledInSection(pupilID, sectionID)
+}
+
+func (databasePolicyStore) IsRemedialCommissionMember(
+	tenantID string, examID, teacherID int,
+) (bool, error) {
+	tenantInstance, err := tenantfactory.ServiceReader(tenantID)
+	if err != nil {
+		return false, err
+	}
+	return tenantInstance.IsRemedialCommissionMember(examID, teacherID)
+}
+
+func (databasePolicyStore) IsParentOfPupil(parentID, pupilID int) (bool, error) {
+	return util.IsParentOfPupil(parentID, pupilID, DbWorkspace)
+}
+
+func (databasePolicyStore) GetPupilTenantIDs(pupilID int) ([]string, error) {
+	return tenantmodels.Pupil{ID: pupilID}.GetTenantIDs(DbWorkspace)
+}
+
+// PolicyMiddleware enforces route level policies. It must be wrapped by
+// AuthMiddleware so the claims are present in the request context. Root is
+// allowed everywhere.
+func PolicyMiddleware(next http.HandlerFunc, policies ...Policy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := util.GetClaimsFromContext(r)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		allowed, err := evaluatePolicies(claims, mux.Vars(r), policies)
+		if err != nil {
+			log.Printf("Policy check failed for %s: %v", r.URL.Path, err)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if !allowed {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// requireStoredSectionAccess applies policies to a section that is not part of
+// the route, the section of a stored row or of a new row in the request
+// body. The pupil is passed for rows that belong to a pupil, 0 otherwise.
+// The error response is written when access is denied.
+func requireStoredSectionAccess(
+	w http.ResponseWriter,
+	r *http.Request,
+	tenantID string,
+	sectionID, pupilID int,
+	policies ...Policy,
+) bool {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+
+	vars := map[string]string{
+		"tenant_id":  tenantID,
+		"section_id": strconv.Itoa(sectionID),
+	}
+	if pupilID != 0 {
+		vars["pupil_id"] = strconv.Itoa(pupilID)
+	}
+
+	allowed, err := evaluatePolicies(claims, vars, policies)
+	if err != nil {
+		log.Printf("Policy check failed for %s: %v", r.URL.Path, err)
+	}
+	if err != nil || !allowed {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// evaluatePolicies returns true when root makes the request or any of the
+// policies grants access
+func evaluatePolicies(
+	claims *wpmodels.Claims, vars map[string]string, policies []Policy,
+) (bool, error) {
+	if claims.AccountType == "root" {
+		return true, nil
+	}
+	for _, policy := range policies {
+		granted, err := policy(claims, vars)
+		if err != nil {
+			return false, err
+		}
+		if granted {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// TenantAdminOfTenant grants access to the admin of the tenant in the route
+func TenantAdminOfTenant(claims *wpmodels.Claims, vars map[string]string) (bool, error) {
+	tenantID, ok := vars["tenant_id"]
+	if !ok {
+		return false, nil
+	}
+	return isAdminOfTenant(claims, tenantID), nil
+}
+
+// TeacherOfSection grants access to teachers that teach in the section of
+// the route or are its homeroom teacher, and to the tenant admin
+func TeacherOfSection(claims *wpmodels.Claims, vars map[string]string) (bool, error) {
+	return checkSectionTeacher(claims, vars, false)
+}
+
+// HomeroomTeacherOfSection grants access to the homeroom teacher of the
+// section in the route and to the tenant admin
+func HomeroomTeacherOfSection(claims *wpmodels.Claims, vars map[string]string) (bool, error) {
+	return checkSectionTeacher(claims, vars, true)
+}
+
+// RemedialCommissionMember grants access to the teachers on the commission of
+// the remedial exam in the route and to the tenant admin
+func RemedialCommissionMember(claims *wpmodels.Claims, vars map[string]string) (bool, error) {
+	if claims.AccountType != "tenant_admin" && claims.AccountType != "teacher" {
+		return false, nil
+	}
+
+	tenantID, ok := vars["tenant_id"]
+	if !ok || tenantID == "" {
+		return false, nil
+	}
+	if isAdminOfTenant(claims, tenantID) {
+		return true, nil
+	}
+	examID, err := strconv.Atoi(vars["remedial_exam_id"])
+	if err != nil {
+		return false, nil
+	}
+
+	return policyMemberships.IsRemedialCommissionMember(tenantID, examID, claims.ID)
+}
+
+// PupilEnrolledInSection grants pupils access to sections they are enrolled
+// in. When the route names a pupil it has to be the logged in pupil, which
+// also covers parents logged in with the access code of their child.
+func PupilEnrolledInSection(claims *wpmodels.Claims, vars map[string]string) (bool, error) {
+	if claims.AccountType != "pupil" {
+		return false, nil
+	}
+	if pupilID, ok := vars["pupil_id"]; ok && pupilID != fmt.Sprintf("%d", claims.ID) {
+		return false, nil
+	}
+
+	tenantID, sectionID, ok := sectionRouteVars(vars)
+	if !ok {
+		return false, nil
+	}
+	return policyMemberships.IsPupilEnrolledInSection(tenantID, claims.ID, sectionID)
+}
+
+// PupilSelf grants pupils (and parents logged in as their child) access to
+// routes about themselves
+func PupilSelf(claims *wpmodels.Claims, vars map[string]string) (bool, error) {
+	pupilID, ok := vars["pupil_id"]
+	if !ok {
+		return false, nil
+	}
+	return claims.AccountType == "pupil" &&
+		pupilID == fmt.Sprintf("%d", claims.ID), nil
+}
+
+// ParentOfPupil grants parents access to routes about their linked children.
+// For section routes the child also has to be enrolled in the section.
+func ParentOfPupil(claims *wpmodels.Claims, vars map[string]string) (bool, error) {
+	if claims.AccountType != "parent" {
+		return false, nil
+	}
+	pupilID, err := strconv.Atoi(vars["pupil_id"])
+	if err != nil {
+		return false, nil
+	}
+
+	linked, err := policyMemberships.IsParentOfPupil(claims.ID, pupilID)
+	if err != nil || !linked {
+		return false, err
+	}
+
+	if _, ok := vars["section_id"]; !ok {
+		return true, nil
+	}
+	tenantID, sectionID, ok := sectionRouteVars(vars)
+	if !ok {
+		return false, nil
+	}
+	return policyMemberships.IsPupilEnrolledInSection(tenantID, pupilID, sectionID)
+}
+
+// StaffOfPupilTenant grants tenant admins and teachers access to routes about
+// a pupil that belongs to one of their tenants
+func StaffOfPupilTenant(claims *wpmodels.Claims, vars map[string]string) (bool, error) {
+	if claims.AccountType != "tenant_admin" && claims.AccountType != "teacher" {
+		return false, nil
+	}
+	pupilID, err := strconv.Atoi(vars["pupil_id"])
+	if err != nil {
+		return false, nil
+	}
+
+	tenantIDs, err := policyMemberships.GetPupilTenantIDs(pupilID)
+	if err != nil {
+		return false, err
+	}
+	for _, tenantID := range tenantIDs {
+		if util.ClaimsHaveTenantAccess(claims, tenantID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkSectionTeacher is shared by the section teacher policies. Tenant admin
+// accounts are also teachers, so admins of other tenants are checked like
+// any other teacher.
+func checkSectionTeacher(
+	claims *wpmodels.Claims, vars map[string]string, homeroomOnly bool,
+) (bool, error) {
+	if claims.AccountType != "tenant_admin" && claims.AccountType != "teacher" {
+		return false, nil
+	}
+
+	tenantID, sectionID, ok := sectionRouteVars(vars)
+	if !ok {
+		return false, nil
+	}
+	if isAdminOfTenant(claims, tenantID) {
+		return true, nil
+	}
+
+	if homeroomOnly {
+		return policyMemberships.IsHomeroomTeacherOfSection(tenantID, claims.ID, sectionID)
+	}
+	return policyMemberships.IsTeacherAssignedToSection(tenantID, claims.ID, sectionID)
+}
+
+// isAdminOfTenant checks if the claims belong to the admin of a tenant
+func isAdminOfTenant(claims *wpmodels.Claims, tenantID string) bool {
+	return claims.AccountType == "tenant_admin" &&
+		fmt.Sprintf("%d", claims.TenantAdminTenantID) == tenantID
+}
+
+// sectionRouteVars reads the tenant and section IDs from the route
+func sectionRouteVars(vars map[string]string) (string, int, bool) {
+	tenantID, ok := vars["tenant_id"]
+	if !ok || tenantID == "" {
+		return "", 0, false
+	}
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		return "", 0, false
+	}
+	return tenantID, sectionID, true
+}
@@ -0,0 +1,311 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ednevnik-backend/constants"
+	wpmodels "ednevnik-backend/models/workspace"
+
+	"github.com/gorilla/mux"
+)
+
+// fakePolicyStore keeps memberships in memory. Section IDs repeat across
+// tenants on purpose, every tenant database numbers its sections from 1.
+type fakePolicyStore struct {
+	teachers     map[string]bool
+	homerooms    map[string]bool
+	pupils       map[string]bool
+	commissions  map[string]bool
+	parents      map[string]bool
+	pupilTenants map[int][]string
+	err          error
+}
+
+func membershipKey(parts ...any) string {
+	return fmt.Sprint(parts...)
+}
+
+func (s *fakePolicyStore) IsTeacherAssignedToSection(
+	tenantID string, teacherID, sectionID int,
+) (bool, error) {
+	return s.teachers[membershipKey(tenantID, "/", teacherID, "/", sectionID)] ||
+		s.homerooms[membershipKey(tenantID, "/", teacherID, "/", sectionID)], s.err
+}
+
+func (s *fakePolicyStore) IsHomeroomTeacherOfSection(
+	tenantID string, teacherID, sectionID int,
+) (bool, error) {
+	return s.homerooms[membershipKey(tenantID, "/", teacherID, "/", sectionID)], s.err
+}
+
+func (s *fakePolicyStore) IsPupilEnrolledInSection(
+	tenantID string, pupilID, sectionID int,
+) (bool, error) {
+	return s.pupils[membershipKey(tenantID, "/", pupilID, "/", sectionID)], s.err
+}
+
+func (s *fakePolicyStore) IsRemedialCommissionMember(
+	tenantID string, examID, teacherID int,
+) (bool, error) {
+	return s.commissions[membershipKey(tenantID, "/", examID, "/", teacherID)], s.err
+}
+
+func (s *fakePolicyStore) IsParentOfPupil(parentID, pupilID int) (bool, error) {
+	return s.parents[membershipKey(parentID, "/", pupilID)], s.err
+}
+
+func (s *fakePolicyStore) GetPupilTenantIDs(pupilID int) ([]string, error) {
+	return s.pupilTenants[pupilID], s.err
+}
+
+// Tenant 1 and tenant 2 both have a section 1. Teacher 10 teaches in section
+// 1 of tenant 1 and is the homeroom teacher of section 2 of tenant 1, teacher
+// 20 teaches in section 1 of tenant 2. Teacher 30 administers tenant 1.
+// Pupil 100 is in section 1 of tenant 1, pupil 200 in section 1 of tenant 2.
+// Parent 1000 is the parent of pupil 100.
+func newFakePolicyStore() *fakePolicyStore {
+	return &fakePolicyStore{
+		teachers: map[string]bool{
+			"1/10/1": true,
+			"2/20/1": true,
+		},
+		homerooms: map[string]bool{
+			"1/10/2": true,
+		},
+		pupils: map[string]bool{
+			"1/100/1": true,
+			"2/200/1": true,
+		},
+		commissions: map[string]bool{
+			"1/5/10": true,
+		},
+		parents: map[string]bool{
+			"1000/100": true,
+		},
+		pupilTenants: map[int][]string{
+			100: {"1"},
+			200: {"2"},
+		},
+	}
+}
+
+func useFakePolicyStore(t *testing.T) *fakePolicyStore {
+	t.Helper()
+	store := newFakePolicyStore()
+	previous := policyMemberships
+	policyMemberships = store
+	t.Cleanup(func() { policyMemberships = previous })
+	return store
+}
+
+var (
+	rootClaims    = &wpmodels.Claims{ID: 1, AccountType: "root"}
+	teacher10     = &wpmodels.Claims{ID: 10, AccountType: "teacher", TenantIDs: []string{"1"}}
+	teacher20     = &wpmodels.Claims{ID: 20, AccountType: "teacher", TenantIDs: []string{"2"}}
+	admin30       = &wpmodels.Claims{ID: 30, AccountType: "tenant_admin", TenantAdminTenantID: 1, TenantIDs: []string{"1"}}
+	pupil100      = &wpmodels.Claims{ID: 100, AccountType: "pupil", TenantIDs: []string{"1"}}
+	pupil200      = &wpmodels.Claims{ID: 200, AccountType: "pupil", TenantIDs: []string{"2"}}
+	parent1000    = &wpmodels.Claims{ID: 1000, AccountType: "parent", TenantIDs: []string{"1"}}
+	otherParent   = &wpmodels.Claims{ID: 2000, AccountType: "parent", TenantIDs: []string{"2"}}
+	sectionOne    = map[string]string{"tenant_id": "1", "section_id": "1"}
+	sectionTwo    = map[string]string{"tenant_id": "1", "section_id": "2"}
+	otherTenantOf = map[string]string{"tenant_id": "2", "section_id": "1"}
+)
+
+func TestPolicies(t *testing.T) {
+	useFakePolicyStore(t)
+
+	tests := []struct {
+		name   string
+		policy Policy
+		claims *wpmodels.Claims
+		vars   map[string]string
+		want   bool
+	}{
+		{"tenant admin of own tenant", TenantAdminOfTenant, admin30, map[string]string{"tenant_id": "1"}, true},
+		{"tenant admin of other tenant", TenantAdminOfTenant, admin30, map[string]string{"tenant_id": "2"}, false},
+		{"teacher is not tenant admin", TenantAdminOfTenant, teacher10, map[string]string{"tenant_id": "1"}, false},
+		{"tenant admin without tenant", TenantAdminOfTenant, admin30, map[string]string{}, false},
+
+		{"teacher of section", TeacherOfSection, teacher10, sectionOne, true},
+		{"homeroom teacher counts as teacher", TeacherOfSection, teacher10, sectionTwo, true},
+		{"teacher of same section ID in other tenant", TeacherOfSection, teacher10, otherTenantOf, false},
+		{"teacher of other tenant", TeacherOfSection, teacher20, sectionOne, false},
+		{"tenant admin of section tenant", TeacherOfSection, admin30, sectionOne, true},
+		{"tenant admin of other tenant section", TeacherOfSection, admin30, otherTenantOf, false},
+		{"pupil is not teacher", TeacherOfSection, pupil100, sectionOne, false},
+		{"parent is not teacher", TeacherOfSection, parent1000, sectionOne, false},
+		{"teacher with invalid section", TeacherOfSection, teacher10, map[string]string{"tenant_id": "1", "section_id": "x"}, false},
+
+		{"homeroom teacher of section", HomeroomTeacherOfSection, teacher10, sectionTwo, true},
+		{"subject teacher is not homeroom", HomeroomTeacherOfSection, teacher10, sectionOne, false},
+		{"homeroom in other tenant", HomeroomTeacherOfSection, teacher10, map[string]string{"tenant_id": "2", "section_id": "2"}, false},
+		{"tenant admin as homeroom", HomeroomTeacherOfSection, admin30, sectionTwo, true},
+		{"tenant admin of other tenant as homeroom", HomeroomTeacherOfSection, admin30, otherTenantOf, false},
+
+		{"commission member", RemedialCommissionMember, teacher10, map[string]string{"tenant_id": "1", "remedial_exam_id": "5"}, true},
+		{"commission of other tenant", RemedialCommissionMember, teacher10, map[string]string{"tenant_id": "2", "remedial_exam_id": "5"}, false},
+		{"not on commission", RemedialCommissionMember, teacher20, map[string]string{"tenant_id": "1", "remedial_exam_id": "5"}, false},
+		{"tenant admin for commission", RemedialCommissionMember, admin30, map[string]string{"tenant_id": "1", "remedial_exam_id": "6"}, true},
+		{"pupil for commission", RemedialCommissionMember, pupil100, map[string]string{"tenant_id": "1", "remedial_exam_id": "5"}, false},
+
+		{"pupil in own section", PupilEnrolledInSection, pupil100, sectionOne, true},
+		{"pupil in same section ID of other tenant", PupilEnrolledInSection, pupil100, otherTenantOf, false},
+		{"pupil in other section", PupilEnrolledInSection, pupil100, sectionTwo, false},
+		{"pupil naming other pupil", PupilEnrolledInSection, pupil100, map[string]string{"tenant_id": "1", "section_id": "1", "pupil_id": "200"}, false},
+		{"pupil naming themselves", PupilEnrolledInSection, pupil100, map[string]string{"tenant_id": "1", "section_id": "1", "pupil_id": "100"}, true},
+		{"teacher is not pupil", PupilEnrolledInSection, teacher10, sectionOne, false},
+
+		{"pupil self", PupilSelf, pupil100, map[string]string{"pupil_id": "100"}, true},
+		{"pupil other", PupilSelf, pupil100, map[string]string{"pupil_id": "200"}, false},
+		{"teacher as pupil self", PupilSelf, &wpmodels.Claims{ID: 100, AccountType: "teacher"}, map[string]string{"pupil_id": "100"}, false},
+		{"pupil self without pupil", PupilSelf, pupil100, map[string]string{}, false},
+
+		{"parent of pupil", ParentOfPupil, parent1000, map[string]string{"pupil_id": "100"}, true},
+		{"parent of other pupil", ParentOfPupil, parent1000, map[string]string{"pupil_id": "200"}, false},
+		{"unlinked parent", ParentOfPupil, otherParent, map[string]string{"pupil_id": "100"}, false},
+		{"parent of pupil in section", ParentOfPupil, parent1000, map[string]string{"tenant_id": "1", "section_id": "1", "pupil_id": "100"}, true},
+		{"parent of pupil in other section", ParentOfPupil, parent1000, map[string]string{"tenant_id": "1", "section_id": "2", "pupil_id": "100"}, false},
+		{"parent of pupil in other tenant section", ParentOfPupil, parent1000, map[string]string{"tenant_id": "2", "section_id": "1", "pupil_id": "100"}, false},
+		{"pupil as parent", ParentOfPupil, pupil100, map[string]string{"pupil_id": "100"}, false},
+
+		{"teacher of pupil tenant", StaffOfPupilTenant, teacher10, map[string]string{"pupil_id": "100"}, true},
+		{"teacher of other tenant pupil", StaffOfPupilTenant, teacher10, map[string]string{"pupil_id": "200"}, false},
+		{"tenant admin of pupil tenant", StaffOfPupilTenant, admin30, map[string]string{"pupil_id": "100"}, true},
+		{"tenant admin of other tenant pupil", StaffOfPupilTenant, admin30, map[string]string{"pupil_id": "200"}, false},
+		{"staff for unknown pupil", StaffOfPupilTenant, teacher10, map[string]string{"pupil_id": "999"}, false},
+		{"pupil as staff", StaffOfPupilTenant, pupil100, map[string]string{"pupil_id": "100"}, false},
+		{"parent as staff", StaffOfPupilTenant, parent1000, map[string]string{"pupil_id": "100"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy(tt.claims, tt.vars)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluatePolicies(t *testing.T) {
+	store := useFakePolicyStore(t)
+
+	tests := []struct {
+		name     string
+		claims   *wpmodels.Claims
+		vars     map[string]string
+		policies []Policy
+		want     bool
+	}{
+		{"root without policies", rootClaims, otherTenantOf, nil, true},
+		{"teacher without policies", teacher10, sectionOne, nil, false},
+		{"second policy grants", pupil100, sectionOne, []Policy{TeacherOfSection, PupilEnrolledInSection}, true},
+		{"no policy grants", pupil200, sectionOne, []Policy{TeacherOfSection, PupilEnrolledInSection}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evaluatePolicies(tt.claims, tt.vars, tt.policies)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	store.err = errors.New("database down")
+	allowed, err := evaluatePolicies(teacher10, sectionOne, []Policy{TeacherOfSection})
+	if err == nil || allowed {
+		t.Errorf("lookup errors must deny access, got %v, %v", allowed, err)
+	}
+}
+
+func TestPolicyMiddleware(t *testing.T) {
+	useFakePolicyStore(t)
+
+	tests := []struct {
+		name   string
+		claims *wpmodels.Claims
+		want   int
+	}{
+		{"teacher of section", teacher10, http.StatusOK},
+		{"teacher of other tenant", teacher20, http.StatusForbidden},
+		{"pupil of section", pupil100, http.StatusForbidden},
+		{"root", rootClaims, http.StatusOK},
+		{"no claims", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := PolicyMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}, TeacherOfSection)
+
+			request := requestWithClaims(tt.claims)
+			request = mux.SetURLVars(request, sectionOne)
+			recorder := httptest.NewRecorder()
+			handler(recorder, request)
+			if recorder.Code != tt.want {
+				t.Errorf("got status %d, want %d", recorder.Code, tt.want)
+			}
+		})
+	}
+}
+
+// Write routes take the section from the request body or from the stored
+// row, the section of the route is not involved
+func TestRequireStoredSectionAccess(t *testing.T) {
+	useFakePolicyStore(t)
+
+	tests := []struct {
+		name      string
+		claims    *wpmodels.Claims
+		tenantID  string
+		sectionID int
+		pupilID   int
+		policies  []Policy
+		want      bool
+	}{
+		{"teacher of stored section", teacher10, "1", 1, 0, []Policy{TeacherOfSection}, true},
+		{"teacher of other section", teacher20, "1", 1, 0, []Policy{TeacherOfSection}, false},
+		{"teacher of same section ID in other tenant", teacher10, "2", 1, 0, []Policy{TeacherOfSection}, false},
+		{"tenant admin of other tenant", admin30, "2", 1, 0, []Policy{TeacherOfSection}, false},
+		{"pupil reading own grade", pupil100, "1", 1, 100, []Policy{TeacherOfSection, PupilEnrolledInSection}, true},
+		{"pupil reading grade of classmate", pupil100, "1", 1, 101, []Policy{TeacherOfSection, PupilEnrolledInSection}, false},
+		{"pupil writing", pupil100, "1", 1, 0, []Policy{TeacherOfSection}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			got := requireStoredSectionAccess(
+				recorder, requestWithClaims(tt.claims),
+				tt.tenantID, tt.sectionID, tt.pupilID, tt.policies...,
+			)
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if !got && recorder.Code != http.StatusForbidden {
+				t.Errorf("got status %d, want %d", recorder.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func requestWithClaims(claims *wpmodels.Claims) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	if claims == nil {
+		return request
+	}
+	return request.WithContext(
+		context.WithValue(request.Context(), constants.ClaimsKey, claims),
+	)
+}
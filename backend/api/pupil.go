@@ -1,11 +1,13 @@
 package api
 
 import (
+	"database/sql"
 	tenantmodels "ednevnik-backend/models/tenant"
 	"ednevnik-backend/tenantfactory"
 	"ednevnik-backend/util"
 	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
 	"strconv"
 
@@ -286,6 +288,47 @@ func GetCertificateDataHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(certificate)
 }
 
+// GetCertificatePDFHandler returns the certificate of a pupil in a section
+// as a PDF document
+func GetCertificatePDFHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	pupilID, err := strconv.Atoi(vars["pupil_id"])
+	if err != nil {
+		http.Error(w, "Invalid pupil ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	pdf, fileName, err := tenantInstance.GetCertificatePDF(sectionID, pupilID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Certificate data not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set(
+		"Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName),
+	)
+	w.Write(pdf)
+}
+
 // GetGradeEditHistoryHandler retrieves grade edit history
 func GetGradeEditHistoryHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -310,11 +353,22 @@ func GetGradeEditHistoryHandler(w http.ResponseWriter, r *http.Request) {
 	gradeIDInt, err := strconv.Atoi(gradeID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	grades, err := tenantInstance.GetGradeEditHistory(gradeIDInt)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The history also covers deleted grades, so the section and pupil are
+	// taken from it
+	if len(grades) > 0 && !requireStoredSectionAccess(
+		w, r, tenantID, grades[0].SectionID, grades[0].PupilID,
+		TeacherOfSection, PupilEnrolledInSection,
+	) {
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -354,6 +408,13 @@ func GetBehaviourGradeHistoryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(history) > 0 && !requireStoredSectionAccess(
+		w, r, tenantID, history[0].SectionID, history[0].PupilID,
+		TeacherOfSection, PupilEnrolledInSection,
+	) {
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(history)
 }
@@ -420,6 +481,7 @@ func UpdateLoggedInPupilHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	setAuditBefore(r, oldPupil)
 
 	// First update global settings
 	err = util.UpdatePupilGlobalRecord(
@@ -543,6 +605,10 @@ func UpdatePupilStatisticsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if old, err := util.GetPupilStatisticsFieldsByPupilID(pupilIDInt, workspaceDB); err == nil {
+		setAuditBefore(r, old)
+	}
+
 	updatedStats, err := util.UpdateStatisticsFieldsForPupil(
 		pupilIDInt,
 		&newStatisticsData,
@@ -556,3 +622,240 @@ func UpdatePupilStatisticsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updatedStats)
 }
+
+// CreateAttendanceExcuseHandler submits an excuse for absences of a pupil,
+// used by the pupil and their parents
+func CreateAttendanceExcuseHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+	sectionIDInt, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+	pupilIDInt, err := strconv.Atoi(vars["pupil_id"])
+	if err != nil {
+		http.Error(w, "Invalid pupil ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// The attachment is sent base64 encoded, which makes the body larger than
+	// the attachment itself
+	r.Body = http.MaxBytesReader(w, r.Body, 2*util.MaxExcuseAttachmentSize)
+	var excuse tenantmodels.AttendanceExcuse
+	if err := json.NewDecoder(r.Body).Decode(&excuse); err != nil {
+		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		return
+	}
+	excuse.PupilID = pupilIDInt
+	excuse.SectionID = sectionIDInt
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	newExcuse, err := tenantInstance.CreateAttendanceExcuse(excuse, claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newExcuse)
+}
+
+// GetAttendanceExcusesForPupilHandler returns all excuses submitted for a
+// pupil in a section
+func GetAttendanceExcusesForPupilHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+	sectionIDInt, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+	pupilIDInt, err := strconv.Atoi(vars["pupil_id"])
+	if err != nil {
+		http.Error(w, "Invalid pupil ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	excuses, err := tenantInstance.GetAttendanceExcusesForPupil(pupilIDInt, sectionIDInt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(excuses)
+}
+
+// GetAttendanceExcuseAttachmentHandler downloads the attachment of an excuse
+func GetAttendanceExcuseAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+	sectionIDInt, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+	pupilIDInt, err := strconv.Atoi(vars["pupil_id"])
+	if err != nil {
+		http.Error(w, "Invalid pupil ID", http.StatusBadRequest)
+		return
+	}
+	excuseIDInt, err := strconv.Atoi(vars["excuse_id"])
+	if err != nil {
+		http.Error(w, "Invalid excuse ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	excuse, err := tenantInstance.GetAttendanceExcuseAttachment(
+		excuseIDInt, pupilIDInt, sectionIDInt,
+	)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	contentType := excuse.AttachmentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set(
+		"Content-Disposition",
+		mime.FormatMediaType("attachment", map[string]string{"filename": excuse.AttachmentName}),
+	)
+	w.Write(excuse.Attachment)
+}
+
+// GetPedagogicalMeasuresForPupilHandler returns the pedagogical measures
+// issued to a pupil in a section
+func GetPedagogicalMeasuresForPupilHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+	sectionIDInt, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+	pupilIDInt, err := strconv.Atoi(vars["pupil_id"])
+	if err != nil {
+		http.Error(w, "Invalid pupil ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	measures, err := tenantInstance.GetPedagogicalMeasuresForPupil(pupilIDInt, sectionIDInt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(measures)
+}
+
+// GetPupilDescriptiveAssessmentsHandler returns the descriptive assessments of
+// a pupil in a section. Pupils get their own assessments, teachers and
+// parents the ones of the pupil in the route.
+func GetPupilDescriptiveAssessmentsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	pupilID := claims.ID
+	if pupilIDVar, ok := vars["pupil_id"]; ok {
+		pupilID, err = strconv.Atoi(pupilIDVar)
+		if err != nil {
+			http.Error(w, "Invalid pupil ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	assessments, err := tenantInstance.GetDescriptiveAssessmentsForPupil(pupilID, sectionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assessments)
+}
+
+// GetPupilPlannedExamsHandler returns the upcoming exams of the section of a
+// pupil
+func GetPupilPlannedExamsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	exams, err := tenantInstance.GetUpcomingPlannedExams(sectionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exams)
+}
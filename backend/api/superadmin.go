@@ -1,12 +1,14 @@
 package api
 
 import (
+	"ednevnik-backend/jobs"
 	wpmodels "ednevnik-backend/models/workspace"
 	"ednevnik-backend/tenantfactory"
 	"ednevnik-backend/util"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 )
@@ -200,6 +202,10 @@ func UpdateTenant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if old, err := util.GetTenantByID(id, userWorkspaceDb); err == nil {
+		setAuditBefore(r, old)
+	}
+
 	// Check if tenant with same email already exists
 	emailDuplicateCheckQuery := `SELECT COUNT(*) FROM tenant WHERE email = ?
 	AND id <> ?`
@@ -320,6 +326,10 @@ func DeleteTenant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if old, err := util.GetTenantByID(id, userWorkspaceDb); err == nil {
+		setAuditBefore(r, old)
+	}
+
 	// Drop the tenant database
 	err = tenantInstance.DropDB()
 	if err != nil {
@@ -447,6 +457,10 @@ func AssignCurriculumsToTenant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if old, err := tenantInstance.GetCurriculumsForTenant(); err == nil {
+		setAuditBefore(r, old)
+	}
+
 	err = tenantInstance.AssignCurriculumsToTenant(curriculumCodes)
 	if err != nil {
 		http.Error(w, "Failed to assign curriculums: "+err.Error(), http.StatusInternalServerError)
@@ -506,6 +520,10 @@ func UnassignCurriculumsFromTenant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if old, err := tenantInstance.GetCurriculumsForTenant(); err == nil {
+		setAuditBefore(r, old)
+	}
+
 	err = tenantInstance.UnassignCurriculumFromTenant(curriculumCode)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -538,6 +556,15 @@ func UpdateNPPSemesterDates(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if semesters, err := util.GetAllNPPSemesters(userWorkspaceDb); err == nil {
+		for _, semester := range semesters {
+			if semester.NPPCode == req.NPPCode && semester.SemesterCode == req.SemesterCode {
+				setAuditBefore(r, semester)
+				break
+			}
+		}
+	}
+
 	updatedSemester, err := util.UpdateNPPSemesterDates(
 		userWorkspaceDb, req.NPPCode, req.SemesterCode, req.StartDate, req.EndDate,
 	)
@@ -628,6 +655,15 @@ func DeleteGlobalDomainHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if domains, err := util.GetGlobalDomainsHelper(userWorkspaceDb); err == nil {
+		for _, old := range domains {
+			if old.Domain == domain.Domain {
+				setAuditBefore(r, old)
+				break
+			}
+		}
+	}
+
 	err := util.DeleteGlobalDomainHelper(userWorkspaceDb, domain.Domain)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -636,3 +672,120 @@ func DeleteGlobalDomainHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetJobsHandler returns the background job counts and the latest jobs,
+// optionally filtered by status, e.g. status=dead for failed jobs
+func GetJobsHandler(w http.ResponseWriter, r *http.Request) {
+	userWorkspaceDb, ok := util.GetUserWorkspaceDBFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != jobs.StatusPending && status != jobs.StatusRunning &&
+		status != jobs.StatusCompleted && status != jobs.StatusDead {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	counts, err := jobs.CountJobs(userWorkspaceDb)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jobList, err := jobs.ListJobs(status, 100, userWorkspaceDb)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"counts": counts,
+		"jobs":   jobList,
+	})
+}
+
+// GetJobHandler returns a background job by id
+func GetJobHandler(w http.ResponseWriter, r *http.Request) {
+	userWorkspaceDb, ok := util.GetUserWorkspaceDBFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	jobID, err := strconv.Atoi(mux.Vars(r)["job_id"])
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := jobs.GetJob(jobID, userWorkspaceDb)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// RetryJobHandler puts a dead background job back into the queue
+func RetryJobHandler(w http.ResponseWriter, r *http.Request) {
+	userWorkspaceDb, ok := util.GetUserWorkspaceDBFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	jobID, err := strconv.Atoi(mux.Vars(r)["job_id"])
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	if old, err := jobs.GetJob(jobID, userWorkspaceDb); err == nil {
+		setAuditBefore(r, old)
+	}
+
+	if err := jobs.RetryJob(jobID, userWorkspaceDb); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAllLockedAccountsHandler returns every locked account
+func GetAllLockedAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	accounts, err := util.GetLockedAccounts(0, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(accounts)
+}
+
+// UnlockAnyAccountHandler clears the failed logins of any account
+func UnlockAnyAccountHandler(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.Atoi(mux.Vars(r)["account_id"])
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	if old, err := util.GetAccountLockout(accountID, DbWorkspace); err == nil {
+		setAuditBefore(r, old)
+	}
+
+	if err := util.UnlockAccount(accountID, DbWorkspace); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
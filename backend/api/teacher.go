@@ -1,11 +1,14 @@
 package api
 
 import (
+	"database/sql"
 	tenantmodels "ednevnik-backend/models/tenant"
 	wpmodels "ednevnik-backend/models/workspace"
 	"ednevnik-backend/tenantfactory"
+	"ednevnik-backend/tenantshared"
 	"ednevnik-backend/util"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -135,6 +138,10 @@ func DeletePupilInviteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if old, err := tenantInstance.GetPupilSectionInvite(request.InviteID); err == nil {
+		setAuditBefore(r, old)
+	}
+
 	err = tenantInstance.DeletePupilInvite(
 		request.InviteID,
 		request.PupilID,
@@ -190,6 +197,8 @@ func UnassignPupilFromSection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setSectionPupilAuditBefore(r, tenantInstance, sectionID, pupilID)
+
 	err = tenantInstance.DeletePupilFromSection(pupilID, sectionID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -285,6 +294,15 @@ func TeacherInviteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if invites, err := tenantInstance.GetAllTeacherInvites(); err == nil {
+		for _, invite := range invites {
+			if invite.ID == inviteRequest.InviteID {
+				setAuditBefore(r, invite)
+				break
+			}
+		}
+	}
+
 	if inviteRequest.Action == "accept" {
 		err = tenantInstance.AcceptTeacherSectionInvite(
 			fmt.Sprintf("%d", inviteRequest.InviteID),
@@ -404,6 +422,12 @@ func CreateSectionLessonHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !requireStoredSectionAccess(
+		w, r, tenantID, lessonData.LessonData.SectionID, 0, TeacherOfSection,
+	) {
+		return
+	}
+
 	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -412,7 +436,7 @@ func CreateSectionLessonHandler(w http.ResponseWriter, r *http.Request) {
 
 	newLesson, err := tenantInstance.CreateSectionLesson(lessonData, claims.ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), gradebookErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -463,9 +487,27 @@ func UpdateLessonHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	oldLesson, err := tenantInstance.GetLessonByID(lessonIDInt)
+	if err != nil {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+	if !requireStoredSectionAccess(
+		w, r, tenantID, oldLesson.LessonData.SectionID, 0, TeacherOfSection,
+	) {
+		return
+	}
+	// Moving the lesson to another section also needs access to that section
+	newSectionID := lessonData.LessonData.SectionID
+	if newSectionID != 0 && newSectionID != oldLesson.LessonData.SectionID &&
+		!requireStoredSectionAccess(w, r, tenantID, newSectionID, 0, TeacherOfSection) {
+		return
+	}
+	setAuditBefore(r, oldLesson)
+
 	updatedLesson, err := tenantInstance.UpdateLesson(lessonIDInt, lessonData, claims.ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), gradebookErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -491,6 +533,12 @@ func DeleteLessonHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -503,9 +551,21 @@ func DeleteLessonHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = tenantInstance.DeleteLesson(lessonIDInt)
+	oldLesson, err := tenantInstance.GetLessonByID(lessonIDInt)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+	if !requireStoredSectionAccess(
+		w, r, tenantID, oldLesson.LessonData.SectionID, 0, TeacherOfSection,
+	) {
+		return
+	}
+	setAuditBefore(r, oldLesson)
+
+	err = tenantInstance.DeleteLesson(lessonIDInt, claims.ID)
+	if err != nil {
+		http.Error(w, err.Error(), gradebookErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -571,9 +631,25 @@ func HandleAttendanceActionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lesson, err := tenantInstance.GetLessonByID(action.LessonID)
+	if err != nil {
+		http.Error(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+	if !requireStoredSectionAccess(
+		w, r, tenantID, lesson.LessonData.SectionID, 0, TeacherOfSection,
+	) {
+		return
+	}
+	for _, attendance := range lesson.PupilAttendanceData {
+		if attendance.PupilID == action.PupilID {
+			setAuditBefore(r, attendance)
+		}
+	}
+
 	err = tenantInstance.HandleAttendanceAction(action)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), gradebookErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -617,7 +693,10 @@ func GradebookMetadataHandler(w http.ResponseWriter, r *http.Request) {
 	var subjects []wpmodels.Subject
 	var pupilCount int
 
-	if claims.AccountType != "pupil" {
+	// Pupils and parents only need the semesters of the section
+	readOnly := claims.AccountType == "pupil" || claims.AccountType == "parent"
+
+	if !readOnly {
 
 		subjects, err = tenantInstance.GetSubjectsForSection(
 			sectionIDInt, claims,
@@ -647,7 +726,7 @@ func GradebookMetadataHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if claims.AccountType != "pupil" {
+	if !readOnly {
 		json.NewEncoder(w).Encode(responseData{
 			Subjects:         subjects,
 			PupilCount:       pupilCount,
@@ -735,6 +814,10 @@ func CreateGradeHandler(w http.ResponseWriter, r *http.Request) {
 
 	grade.TeacherID = claims.ID
 
+	if !requireStoredSectionAccess(w, r, tenantID, grade.SectionID, 0, TeacherOfSection) {
+		return
+	}
+
 	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -743,7 +826,7 @@ func CreateGradeHandler(w http.ResponseWriter, r *http.Request) {
 
 	createdGrade, err := tenantInstance.CreateGrade(&grade)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), gradebookErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -752,6 +835,58 @@ func CreateGradeHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(createdGrade)
 }
 
+// CreateGradesBulkHandler creates grades for many pupils of a section in one
+// transaction. When an entry is invalid nothing is saved and the errors of
+// the entries are returned.
+func CreateGradesBulkHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var entries []tenantmodels.BulkGradeEntry
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		return
+	}
+	if len(entries) == 0 {
+		http.Error(w, "No grades to create", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tenantInstance.CreateGradesBulk(
+		sectionID, vars["semester_code"], vars["subject_code"], claims.ID, entries,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), gradebookErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(result.Errors) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
 // DeleteGradeHandler deletes a grade for a pupil in a section and subject.
 func DeleteGradeHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -779,9 +914,19 @@ func DeleteGradeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	oldGrade, err := tenantInstance.GetGradeByID(grade.ID)
+	if err != nil {
+		http.Error(w, "Grade not found", http.StatusNotFound)
+		return
+	}
+	if !requireStoredSectionAccess(w, r, tenantID, oldGrade.SectionID, 0, TeacherOfSection) {
+		return
+	}
+	setAuditBefore(r, oldGrade)
+
 	gradesAfterDeletion, err := tenantInstance.DeleteGrade(&grade, claims.ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), gradebookErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -819,9 +964,19 @@ func UpdateGradeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	oldGrade, err := tenantInstance.GetGradeByID(grade.ID)
+	if err != nil {
+		http.Error(w, "Grade not found", http.StatusNotFound)
+		return
+	}
+	if !requireStoredSectionAccess(w, r, tenantID, oldGrade.SectionID, 0, TeacherOfSection) {
+		return
+	}
+	setAuditBefore(r, oldGrade)
+
 	updatedGradeItems, err := tenantInstance.UpdateGrade(&grade)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), gradebookErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -870,8 +1025,18 @@ func GetPupilGradesForSectionPupilHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Pupils read their own grades, parents name the child in the route
+	pupilID := claims.ID
+	if routePupilID, ok := vars["pupil_id"]; ok {
+		pupilID, err = strconv.Atoi(routePupilID)
+		if err != nil {
+			http.Error(w, "Invalid pupil ID", http.StatusBadRequest)
+			return
+		}
+	}
+
 	grades, err := tenantInstance.GetPupilGradesForSectionPupil(
-		sectionIDInt, claims.ID, semesterCode,
+		sectionIDInt, pupilID, semesterCode,
 	)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -911,11 +1076,25 @@ func UpdatePupilBehaviourGradeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	oldBehaviourGrade, err := tenantInstance.GetBehaviourGradeByID(
+		behaviourGradesToupdate.ID,
+	)
+	if err != nil {
+		http.Error(w, "Behaviour grade not found", http.StatusNotFound)
+		return
+	}
+	if !requireStoredSectionAccess(
+		w, r, tenantID, oldBehaviourGrade.SectionID, 0, TeacherOfSection,
+	) {
+		return
+	}
+	setAuditBefore(r, oldBehaviourGrade)
+
 	updatedBehaviourGrade, err := tenantInstance.UpdatePupilBehaviourGrade(
 		behaviourGradesToupdate, claims.ID,
 	)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), gradebookErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -923,7 +1102,8 @@ func UpdatePupilBehaviourGradeHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(updatedBehaviourGrade)
 }
 
-// ArchiveSectionHandler archives a section with a specific section ID
+// ArchiveSectionHandler queues archiving of a section with a specific
+// section ID. The section is archived in the background.
 func ArchiveSectionHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	tenantID := vars["tenant_id"]
@@ -951,13 +1131,17 @@ func ArchiveSectionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if old, err := tenantInstance.GetSectionByID(sectionID); err == nil {
+		setAuditBefore(r, old)
+	}
+
 	err = tenantInstance.ArchiveSection(sectionIDInt)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	w.WriteHeader(http.StatusAccepted)
 }
 
 // GetCompleteGradebookDataHandler returns complete gradebook data for a section.
@@ -1037,6 +1221,8 @@ func UnenrollPupilFromSectionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setSectionPupilAuditBefore(r, tenantInstance, sectionID, pupilID)
+
 	err = tenantInstance.UnenrollPupilFromSection(pupilIDInt, sectionIDInt)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -1045,3 +1231,715 @@ func UnenrollPupilFromSectionHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetAttendanceExcusesForSectionHandler returns the excuses of a section for
+// the homeroom teacher, optionally filtered by the status query parameter
+func GetAttendanceExcusesForSectionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+	sectionIDInt, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", "pending", "approved", "rejected":
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	excuses, err := tenantInstance.GetAttendanceExcusesForSection(sectionIDInt, status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(excuses)
+}
+
+// DecideAttendanceExcusesHandler approves or rejects several excuses of a
+// section at once. Either all excuses are decided or none.
+func DecideAttendanceExcusesHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+	sectionIDInt, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var decision tenantmodels.AttendanceExcuseDecision
+	if err := json.NewDecoder(r.Body).Decode(&decision); err != nil {
+		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		return
+	}
+	if decision.Decision != "approved" && decision.Decision != "rejected" {
+		http.Error(w, "Invalid decision", http.StatusBadRequest)
+		return
+	}
+	if len(decision.ExcuseIDs) == 0 {
+		http.Error(w, "No excuses selected", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if excuses, err := tenantInstance.GetAttendanceExcusesForSection(sectionIDInt, ""); err == nil {
+		var old []tenantmodels.AttendanceExcuse
+		for _, excuse := range excuses {
+			for _, excuseID := range decision.ExcuseIDs {
+				if excuse.ID == excuseID {
+					old = append(old, excuse)
+					break
+				}
+			}
+		}
+		setAuditBefore(r, old)
+	}
+
+	err = tenantInstance.DecideAttendanceExcuses(sectionIDInt, decision, claims)
+	if err != nil {
+		http.Error(w, err.Error(), gradebookErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPedagogicalMeasuresForSectionHandler returns the pedagogical measures
+// issued to pupils of a section
+func GetPedagogicalMeasuresForSectionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+	sectionIDInt, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	measures, err := tenantInstance.GetPedagogicalMeasuresForSection(sectionIDInt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(measures)
+}
+
+// GetFinalGradeProposalsHandler proposes final grades for the pupils of a
+// section in a subject and semester
+func GetFinalGradeProposalsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	proposals, err := tenantInstance.GetFinalGradeProposals(
+		sectionID, vars["semester_code"], vars["subject_code"],
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(proposals)
+}
+
+// AcceptFinalGradesHandler saves the final grades the teacher accepted for
+// pupils of a section in bulk
+func AcceptFinalGradesHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var accepted []tenantmodels.AcceptedFinalGrade
+	if err := json.NewDecoder(r.Body).Decode(&accepted); err != nil {
+		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		return
+	}
+	if len(accepted) == 0 {
+		http.Error(w, "No final grades to accept", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if old, err := tenantInstance.GetFinalGradeProposals(
+		sectionID, vars["semester_code"], vars["subject_code"],
+	); err == nil {
+		setAuditBefore(r, old)
+	}
+
+	result, err := tenantInstance.AcceptFinalGrades(
+		sectionID, vars["semester_code"], vars["subject_code"], claims.ID, accepted,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), gradebookErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetDescriptiveAssessmentsHandler returns the pupils of a section with their
+// descriptive assessments in a subject and semester
+func GetDescriptiveAssessmentsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	assessments, err := tenantInstance.GetDescriptiveAssessmentsForSectionSubject(
+		sectionID, vars["semester_code"], vars["subject_code"],
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assessments)
+}
+
+// SaveDescriptiveAssessmentHandler creates or updates the descriptive
+// assessment of a pupil in a subject and semester
+func SaveDescriptiveAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var assessment tenantmodels.DescriptiveAssessment
+	if err := json.NewDecoder(r.Body).Decode(&assessment); err != nil {
+		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		return
+	}
+	assessment.SectionID = sectionID
+	assessment.SubjectCode = vars["subject_code"]
+	assessment.SemesterCode = vars["semester_code"]
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if groups, err := tenantInstance.GetDescriptiveAssessmentsForSectionSubject(
+		sectionID, assessment.SemesterCode, assessment.SubjectCode,
+	); err == nil {
+		for _, group := range groups {
+			if group.Pupil.ID == assessment.PupilID {
+				setAuditBefore(r, group.Assessment)
+				break
+			}
+		}
+	}
+
+	savedAssessment, err := tenantInstance.SaveDescriptiveAssessment(assessment, claims.ID)
+	if err != nil {
+		http.Error(w, err.Error(), gradebookErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(savedAssessment)
+}
+
+// GetDescriptiveAssessmentHistoryHandler returns the earlier versions of a
+// descriptive assessment
+func GetDescriptiveAssessmentHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	assessmentID, err := strconv.Atoi(vars["descriptive_assessment_id"])
+	if err != nil {
+		http.Error(w, "Invalid descriptive assessment ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	history, err := tenantInstance.GetDescriptiveAssessmentHistory(sectionID, assessmentID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Descriptive assessment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// GetPlannedExamsHandler returns the exams booked in a section, optionally
+// between the date_from and date_to query dates
+func GetPlannedExamsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	exams, err := tenantInstance.GetPlannedExamsForSection(
+		sectionID, query.Get("date_from"), query.Get("date_to"),
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exams)
+}
+
+// CreatePlannedExamHandler books an exam in the calendar of a section
+func CreatePlannedExamHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var exam tenantmodels.PlannedExam
+	if err := json.NewDecoder(r.Body).Decode(&exam); err != nil {
+		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		return
+	}
+	exam.SectionID = sectionID
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	createdExam, err := tenantInstance.CreatePlannedExam(exam, claims.ID)
+	if err != nil {
+		http.Error(w, err.Error(), gradebookErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdExam)
+}
+
+// DeletePlannedExamHandler cancels a booked exam of a section
+func DeletePlannedExamHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	examID, err := strconv.Atoi(vars["planned_exam_id"])
+	if err != nil {
+		http.Error(w, "Invalid planned exam ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	// Root and the tenant admin can cancel any exam
+	teacherID := 0
+	if claims.AccountType == "teacher" {
+		teacherID = claims.ID
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if oldExam, err := tenantInstance.GetPlannedExamByID(examID); err == nil {
+		setAuditBefore(r, oldExam)
+	}
+
+	err = tenantInstance.DeletePlannedExam(sectionID, examID, teacherID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Planned exam not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, util.ErrNotExamOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSectionReportHandler returns the success report of a section in a
+// semester
+func GetSectionReportHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	report, err := tenantInstance.GetSectionReport(sectionID, vars["semester_code"])
+	if err == sql.ErrNoRows {
+		http.Error(w, "Section or semester not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetSubjectReportHandler returns the report of a section in a subject and
+// semester
+func GetSubjectReportHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	report, err := tenantInstance.GetSubjectReport(
+		sectionID, vars["subject_code"], vars["semester_code"],
+	)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Section, subject or semester not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetTeacherRemedialExamsHandler returns the remedial exams the teacher is on
+// the commission of
+func GetTeacherRemedialExamsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	exams, err := tenantInstance.GetRemedialExamsForTeacher(claims.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exams)
+}
+
+// GetRemedialExamHandler returns a remedial exam with its commission and
+// pupils
+func GetRemedialExamHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	examID, err := strconv.Atoi(vars["remedial_exam_id"])
+	if err != nil {
+		http.Error(w, "Invalid remedial exam ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	exam, err := tenantInstance.GetRemedialExamByID(examID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Remedial exam not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exam)
+}
+
+// RecordRemedialExamResultsHandler records the results of pupils on a
+// remedial exam
+func RecordRemedialExamResultsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	examID, err := strconv.Atoi(vars["remedial_exam_id"])
+	if err != nil {
+		http.Error(w, "Invalid remedial exam ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var entries []tenantmodels.RemedialResultEntry
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if oldExam, err := tenantInstance.GetRemedialExamByID(examID); err == nil {
+		setAuditBefore(r, oldExam)
+	}
+
+	exam, err := tenantInstance.RecordRemedialExamResults(examID, claims.ID, entries)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Remedial exam not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exam)
+}
+
+// VerifyGradebookLedgerHandler verifies the grades, lessons and behaviour
+// grades of a section against the signed gradebook ledger
+func VerifyGradebookLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	verification, err := tenantInstance.VerifyGradebookLedger(sectionID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Section not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verification)
+}
+
+// GetSectionCertificatesHandler returns the certificates of all pupils of a
+// section as a ZIP file of PDF documents
+func GetSectionCertificatesHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	archive, err := tenantInstance.GetSectionCertificatesZip(sectionID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Section not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"svjedocanstva_%d.zip\"", sectionID),
+	)
+	w.Write(archive)
+}
+
+// setSectionPupilAuditBefore records the pupil of a section a handler is
+// about to change
+func setSectionPupilAuditBefore(
+	r *http.Request, tenantInstance tenantshared.ITenant, sectionID, pupilID string,
+) {
+	pupils, err := tenantInstance.GetPupilsForSection(sectionID, true)
+	if err != nil {
+		return
+	}
+	for _, pupil := range pupils.Pupils {
+		if strconv.Itoa(pupil.ID) == pupilID {
+			setAuditBefore(r, pupil)
+			return
+		}
+	}
+}
@@ -6,11 +6,14 @@ import (
 	tenantmodels "ednevnik-backend/models/tenant"
 	wpmodels "ednevnik-backend/models/workspace"
 	"ednevnik-backend/tenantfactory"
+	"ednevnik-backend/tenantshared"
 	"ednevnik-backend/util"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -151,6 +154,10 @@ func DeleteSection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if oldSection, err := tenantInstance.GetSectionByID(sectionID); err == nil {
+		setAuditBefore(r, oldSection)
+	}
+
 	err = tenantInstance.DeleteTenantSection(sectionID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -184,6 +191,10 @@ func UpdateSection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if oldSection, err := tenantInstance.GetSectionByID(sectionID); err == nil {
+		setAuditBefore(r, oldSection)
+	}
+
 	newSection, err := tenantInstance.UpdateTenantSection(
 		sectionUpdate, sectionID,
 	)
@@ -285,6 +296,8 @@ func DeletePupil(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setAuditBefore(r, pupil)
+
 	pupilTenantIDs, err := pupil.GetTenantIDs(workspaceDB)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -344,6 +357,8 @@ func UpdatePupil(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setAuditBefore(r, oldPupil)
+
 	// First update global settings
 	err = util.UpdatePupilGlobalRecord(
 		fmt.Sprintf("%d", oldPupil.ID),
@@ -382,11 +397,12 @@ func UpdatePupil(w http.ResponseWriter, r *http.Request) {
 // UpdateTenantSemesterDates TODO: Add description
 func UpdateTenantSemesterDates(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		TenantID     int    `json:"tenant_id"`
-		SemesterCode string `json:"semester_code"`
-		StartDate    string `json:"start_date"`
-		EndDate      string `json:"end_date"`
-		NPPCode      string `json:"npp_code"`
+		TenantID      int    `json:"tenant_id"`
+		SemesterCode  string `json:"semester_code"`
+		StartDate     string `json:"start_date"`
+		EndDate       string `json:"end_date"`
+		NPPCode       string `json:"npp_code"`
+		LockGraceDays *int   `json:"lock_grace_days"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
@@ -396,6 +412,10 @@ func UpdateTenantSemesterDates(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing required fields", http.StatusBadRequest)
 		return
 	}
+	if req.LockGraceDays != nil && *req.LockGraceDays < 0 {
+		http.Error(w, "Broj dana za zaključavanje ne može biti negativan", http.StatusBadRequest)
+		return
+	}
 
 	tenantInstance, err := tenantfactory.TenantFactory(fmt.Sprintf("%d", req.TenantID), r)
 	if err != nil {
@@ -403,8 +423,16 @@ func UpdateTenantSemesterDates(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if semesters, err := tenantInstance.GetSemestersForTenant(); err == nil {
+		for _, semester := range semesters {
+			if semester.SemesterCode == req.SemesterCode {
+				setAuditBefore(r, semester)
+			}
+		}
+	}
+
 	updatedSemester, err := tenantInstance.UpdateTenantSemesterDates(
-		req.SemesterCode, req.StartDate, req.EndDate, req.NPPCode,
+		req.SemesterCode, req.StartDate, req.EndDate, req.NPPCode, req.LockGraceDays,
 	)
 	if err != nil {
 		http.Error(w, "Failed to update semester: "+err.Error(), http.StatusInternalServerError)
@@ -544,6 +572,10 @@ func HandleTeacherSectionAssignments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if oldData, err := tenantInstance.GetDataForTeacherInviteForTenant(); err == nil {
+		setAuditBefore(r, teacherInviteDataForTeacher(oldData, teacherID))
+	}
+
 	newData, newTeachers, err := tenantInstance.HandleTeacherSectionAssignments(
 		teacherID, assignmentRequest,
 	)
@@ -698,6 +730,8 @@ func UpdateTeacher(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setAuditBefore(r, oldTeacher)
+
 	var teacher wpmodels.Teacher
 	if err := json.NewDecoder(r.Body).Decode(&teacher); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
@@ -738,6 +772,8 @@ func DeleteTeacher(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setAuditBefore(r, teacherToDelete)
+
 	tenants, err := util.GetTenantsForTeacher(teacherToDelete, userWorkspaceDb)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -821,6 +857,15 @@ func DeleteTeacherInviteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if invites, err := tenantInstance.GetAllTeacherInvites(); err == nil {
+		for _, invite := range invites {
+			if invite.ID == request.InviteID {
+				setAuditBefore(r, invite)
+				break
+			}
+		}
+	}
+
 	err = tenantInstance.DeleteTeacherInvite(
 		request.InviteID,
 		request.TeacherID,
@@ -861,6 +906,15 @@ func DeleteTeacherForTenantHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if teachers, err := tenantInstance.GetTeachersForTenant(); err == nil {
+		for _, teacher := range teachers {
+			if strconv.Itoa(teacher.ID) == teacherID {
+				setAuditBefore(r, teacher)
+				break
+			}
+		}
+	}
+
 	err = tenantInstance.DeleteTeacherFromTenant(teacherID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -939,6 +993,10 @@ func CreateScheduleHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if oldSchedule, err := tenantInstance.GetScheduleForSection(sectionID); err == nil {
+		setAuditBefore(r, oldSchedule)
+	}
+
 	err = tenantInstance.CreateSchedule(data, sectionID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -1040,6 +1098,8 @@ func UpdateClassroomHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setClassroomAuditBefore(r, tenantInstance, oldCode)
+
 	err = tenantInstance.UpdateClassroom(classroom, oldCode)
 	if err != nil {
 		if util.DuplicatePrimaryKeyHelper(err) {
@@ -1074,6 +1134,8 @@ func DeleteClassroomHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setClassroomAuditBefore(r, tenantInstance, classroomID)
+
 	err = tenantInstance.DeleteClassroom(classroomID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -1083,6 +1145,36 @@ func DeleteClassroomHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// setClassroomAuditBefore records the classroom a handler is about to change
+func setClassroomAuditBefore(
+	r *http.Request, tenantInstance tenantshared.ITenant, classroomCode string,
+) {
+	classrooms, err := tenantInstance.GetAllClassroomsForTenant()
+	if err != nil {
+		return
+	}
+	for _, classroom := range classrooms {
+		if classroom.Code == classroomCode {
+			setAuditBefore(r, classroom)
+			return
+		}
+	}
+}
+
+// teacherInviteDataForTeacher keeps the section assignments and invites of
+// one teacher
+func teacherInviteDataForTeacher(
+	data []commonmodels.DataForTeacherSectionInvite, teacherID int,
+) []commonmodels.DataForTeacherSectionInvite {
+	teacherData := []commonmodels.DataForTeacherSectionInvite{}
+	for _, item := range data {
+		if item.Teacher.ID == teacherID {
+			teacherData = append(teacherData, item)
+		}
+	}
+	return teacherData
+}
+
 // GetClassroomsForTenantHandler TODO: Add description
 func GetClassroomsForTenantHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -1228,3 +1320,613 @@ func GetTenantsForTeacherHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(tenants)
 }
+
+// GetAbsenceThresholdsHandler returns the absence thresholds of the tenant
+func GetAbsenceThresholdsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	thresholds, err := tenantInstance.GetAbsenceThresholds()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(thresholds)
+}
+
+// UpdateAbsenceThresholdsHandler replaces the absence thresholds of the tenant
+func UpdateAbsenceThresholdsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	var thresholds []tenantmodels.AbsenceThreshold
+	if err := json.NewDecoder(r.Body).Decode(&thresholds); err != nil {
+		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if oldThresholds, err := tenantInstance.GetAbsenceThresholds(); err == nil {
+		setAuditBefore(r, oldThresholds)
+	}
+
+	updatedThresholds, err := tenantInstance.SetAbsenceThresholds(thresholds)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedThresholds)
+}
+
+// GetGradingSchemesHandler returns the grading schemes of the tenant
+func GetGradingSchemesHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	schemes, err := tenantInstance.GetGradingSchemes()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schemes)
+}
+
+// UpdateGradingSchemesHandler replaces the grading schemes of the tenant
+func UpdateGradingSchemesHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	var schemes []tenantmodels.GradingScheme
+	if err := json.NewDecoder(r.Body).Decode(&schemes); err != nil {
+		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if oldSchemes, err := tenantInstance.GetGradingSchemes(); err == nil {
+		setAuditBefore(r, oldSchemes)
+	}
+
+	updatedSchemes, err := tenantInstance.SetGradingSchemes(schemes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedSchemes)
+}
+
+// GetDescriptiveAssessmentClassesHandler returns the classes the tenant
+// assesses descriptively
+func GetDescriptiveAssessmentClassesHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	classCodes, err := tenantInstance.GetDescriptiveAssessmentClasses()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(classCodes)
+}
+
+// UpdateDescriptiveAssessmentClassesHandler replaces the classes the tenant
+// assesses descriptively
+func UpdateDescriptiveAssessmentClassesHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	var classCodes []string
+	if err := json.NewDecoder(r.Body).Decode(&classCodes); err != nil {
+		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if oldClassCodes, err := tenantInstance.GetDescriptiveAssessmentClasses(); err == nil {
+		setAuditBefore(r, oldClassCodes)
+	}
+
+	updatedClassCodes, err := tenantInstance.SetDescriptiveAssessmentClasses(classCodes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedClassCodes)
+}
+
+// GetExamLimitsHandler returns the exam limits of the tenant
+func GetExamLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	limits, err := tenantInstance.GetExamLimits()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(limits)
+}
+
+// UpdateExamLimitsHandler replaces the exam limits of the tenant
+func UpdateExamLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	var limits tenantmodels.ExamLimits
+	if err := json.NewDecoder(r.Body).Decode(&limits); err != nil {
+		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if oldLimits, err := tenantInstance.GetExamLimits(); err == nil {
+		setAuditBefore(r, oldLimits)
+	}
+
+	updatedLimits, err := tenantInstance.SetExamLimits(limits)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedLimits)
+}
+
+// GetSemesterLocksHandler returns the lock status of the semesters of the
+// tenant
+func GetSemesterLocksHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	locks, err := tenantInstance.GetSemesterLocks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(locks)
+}
+
+// LockSemesterHandler locks the grades and attendance of a semester
+func LockSemesterHandler(w http.ResponseWriter, r *http.Request) {
+	setSemesterLock(w, r, true)
+}
+
+// UnlockSemesterHandler unlocks the grades and attendance of a semester
+// until it is locked again
+func UnlockSemesterHandler(w http.ResponseWriter, r *http.Request) {
+	setSemesterLock(w, r, false)
+}
+
+func setSemesterLock(w http.ResponseWriter, r *http.Request, locked bool) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+	semesterCode := vars["semester_code"]
+
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req tenantmodels.SemesterLockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		http.Error(w, "Razlog je obavezan", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	locks, err := tenantInstance.GetSemesterLocks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	found := false
+	for _, lock := range locks {
+		if lock.SemesterCode == semesterCode {
+			setAuditBefore(r, lock)
+			found = true
+			break
+		}
+	}
+	if !found {
+		http.Error(w, "Semester not found", http.StatusNotFound)
+		return
+	}
+
+	updatedLocks, err := tenantInstance.SetSemesterLock(
+		semesterCode, locked, req.Reason, claims.AccountID,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedLocks)
+}
+
+// GetLockedAccountsHandler returns the locked accounts of teachers, pupils
+// and parents of the tenant
+func GetLockedAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID, err := strconv.Atoi(vars["tenant_id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	accounts, err := util.GetLockedAccounts(tenantID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(accounts)
+}
+
+// UnlockAccountHandler clears the failed logins of an account of the tenant
+func UnlockAccountHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID, err := strconv.Atoi(vars["tenant_id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+	accountID, err := strconv.Atoi(vars["account_id"])
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	belongs, err := util.AccountBelongsToTenant(accountID, tenantID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !belongs {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if lockout, err := util.GetAccountLockout(accountID, DbWorkspace); err == nil {
+		setAuditBefore(r, lockout)
+	}
+
+	if err := util.UnlockAccount(accountID, DbWorkspace); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetRemedialCandidatesHandler returns the pupils of a section that have to
+// take a remedial or class exam
+func GetRemedialCandidatesHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	candidates, err := tenantInstance.GetRemedialCandidates(sectionID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Section not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), gradebookErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(candidates)
+}
+
+// GetRemedialExamsHandler returns the remedial exams of the tenant, the
+// section_id query parameter limits them to one section
+func GetRemedialExamsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID := 0
+	if value := r.URL.Query().Get("section_id"); value != "" {
+		var err error
+		sectionID, err = strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid section ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	exams, err := tenantInstance.GetRemedialExams(sectionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exams)
+}
+
+// CreateRemedialExamHandler schedules a remedial exam with its commission for
+// pupils of a section
+func CreateRemedialExamHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request tenantmodels.RemedialExamRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	exam, err := tenantInstance.CreateRemedialExam(request, claims.AccountID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Section not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), gradebookErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(exam)
+}
+
+// DeleteRemedialExamHandler cancels a remedial exam that has no results yet
+func DeleteRemedialExamHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	examID, err := strconv.Atoi(vars["remedial_exam_id"])
+	if err != nil {
+		http.Error(w, "Invalid remedial exam ID", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if oldExam, err := tenantInstance.GetRemedialExamByID(examID); err == nil {
+		setAuditBefore(r, oldExam)
+	}
+
+	err = tenantInstance.DeleteRemedialExam(examID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Remedial exam not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PreviewYearRolloverHandler returns the proposed sections of the next school
+// year without committing them. The school year and the teacher carry over
+// options are read from the query parameters.
+func PreviewYearRolloverHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	query := r.URL.Query()
+	request := tenantmodels.RolloverRequest{
+		FromYear:              query.Get("from_year"),
+		CarryHomeroomTeachers: query.Get("carry_homeroom_teachers") == "true",
+		CarrySubjectTeachers:  query.Get("carry_subject_teachers") == "true",
+	}
+	if request.FromYear == "" {
+		http.Error(w, "Missing from_year", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	plan, err := tenantInstance.PreviewYearRollover(request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+// CommitYearRolloverHandler creates the sections of the next school year and
+// moves the pupils and teachers into them
+func CommitYearRolloverHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	var request tenantmodels.RolloverRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON data", http.StatusBadRequest)
+		return
+	}
+	if request.FromYear == "" {
+		http.Error(w, "Missing from_year", http.StatusBadRequest)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if preview, err := tenantInstance.PreviewYearRollover(request); err == nil {
+		setAuditBefore(r, preview)
+	}
+
+	plan, err := tenantInstance.CommitYearRollover(request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+// SealGradebookLedgerHandler signs the grades, lessons and behaviour grades
+// of a section that were written before the gradebook ledger existed
+func SealGradebookLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID := vars["tenant_id"]
+
+	sectionID, err := strconv.Atoi(vars["section_id"])
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenantInstance, err := tenantfactory.TenantFactory(tenantID, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if oldVerification, err := tenantInstance.VerifyGradebookLedger(sectionID); err == nil {
+		setAuditBefore(r, oldVerification)
+	}
+
+	verification, err := tenantInstance.SealGradebookLedger(sectionID, claims.ID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Section not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, util.ErrLedgerAlreadySealed) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verification)
+}
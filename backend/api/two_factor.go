@@ -0,0 +1,498 @@
+package api
+
+import (
+	"crypto/sha256"
+	wpmodels "ednevnik-backend/models/workspace"
+	"ednevnik-backend/util"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+)
+
+// MFA tokens carry a password login to the second factor step
+const mfaTokenTTL = 5 * time.Minute
+
+// MFA token purposes
+const (
+	mfaPurposeVerify = "verify"
+	mfaPurposeEnroll = "enroll"
+)
+
+// mfaClaims are the claims of an MFA token
+type mfaClaims struct {
+	AccountID int    `json:"account_id"`
+	LoginType string `json:"login_type"`
+	Purpose   string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// mfaKey derives the key MFA tokens are signed with. It differs from the
+// access token key, so an MFA token can never pass as an access token.
+func mfaKey() []byte {
+	sum := sha256.Sum256(append([]byte("mfa:"), JwtKey...))
+	return sum[:]
+}
+
+// requireSecondFactor responds with a second factor challenge if the account
+// has a second factor or must enroll one. It returns true if it responded.
+func requireSecondFactor(
+	w http.ResponseWriter, claims *wpmodels.Claims, loginType string,
+) bool {
+	enabled, err := util.GetTwoFactorEnabled(claims.AccountID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return true
+	}
+
+	purpose := mfaPurposeVerify
+	if !enabled {
+		required, err := util.IsTwoFactorRequired(
+			claims.AccountID, claims.AccountType, DbWorkspace,
+		)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return true
+		}
+		if !required {
+			return false
+		}
+		purpose = mfaPurposeEnroll
+	}
+
+	expiresAt := time.Now().Add(mfaTokenTTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, mfaClaims{
+		AccountID: claims.AccountID,
+		LoginType: loginType,
+		Purpose:   purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+	tokenString, err := token.SignedString(mfaKey())
+	if err != nil {
+		http.Error(w, "Could not create token", http.StatusInternalServerError)
+		return true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wpmodels.SecondFactorChallenge{
+		MFARequired:        enabled,
+		EnrollmentRequired: !enabled,
+		MFAToken:           tokenString,
+		ExpiresAt:          expiresAt.Unix(),
+	})
+	return true
+}
+
+// parseMFAToken validates an MFA token issued for the given purpose
+func parseMFAToken(tokenStr, purpose string) (*mfaClaims, error) {
+	token, err := jwt.ParseWithClaims(
+		tokenStr, &mfaClaims{},
+		func(token *jwt.Token) (interface{}, error) {
+			return mfaKey(), nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+	)
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("invalid MFA token")
+	}
+	claims, ok := token.Claims.(*mfaClaims)
+	if !ok || claims.Purpose != purpose {
+		return nil, fmt.Errorf("invalid MFA token")
+	}
+	return claims, nil
+}
+
+// completeSecondFactorLogin builds the claims of the account and opens the
+// session once the second factor is verified
+func completeSecondFactorLogin(
+	r *http.Request, mfa *mfaClaims,
+) (*wpmodels.TokenResponse, error) {
+	user, err := util.GetUserByAccountID(mfa.AccountID, DbWorkspace)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := buildClaimsForUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := util.RecordSuccessfulLogin(
+		user.GetEmail(), mfa.AccountID, util.GetRequestIP(r), mfa.LoginType,
+		DbWorkspace,
+	); err != nil {
+		log.Printf("error recording login of account %d: %v", mfa.AccountID, err)
+	}
+
+	return createTokens(r, claims, mfa.LoginType)
+}
+
+// LoginSecondFactor finishes a login with a TOTP or recovery code. Failed
+// codes count as failed logins of the account.
+func LoginSecondFactor(w http.ResponseWriter, r *http.Request) {
+	var req wpmodels.SecondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	mfa, err := parseMFAToken(req.MFAToken, mfaPurposeVerify)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	ipAddress, allowed := checkSecondFactorAllowed(w, r, mfa.AccountID)
+	if !allowed {
+		return
+	}
+
+	if err := util.VerifySecondFactor(mfa.AccountID, req.Code, DbWorkspace); err != nil {
+		recordFailedLogin("", mfa.AccountID, ipAddress, mfa.LoginType)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := completeSecondFactorLogin(r, mfa)
+	if err != nil {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// LoginSecondFactorSetup starts the enrollment of an account that must use a
+// second factor but has none yet
+func LoginSecondFactorSetup(w http.ResponseWriter, r *http.Request) {
+	var req wpmodels.SecondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	mfa, err := parseMFAToken(req.MFAToken, mfaPurposeEnroll)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	startTwoFactorEnrollment(w, mfa.AccountID)
+}
+
+// LoginSecondFactorConfirm confirms the enrollment started with
+// LoginSecondFactorSetup and finishes the login. The recovery codes are
+// returned together with the tokens. Wrong codes count as failed logins.
+func LoginSecondFactorConfirm(w http.ResponseWriter, r *http.Request) {
+	var req wpmodels.SecondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	mfa, err := parseMFAToken(req.MFAToken, mfaPurposeEnroll)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	ipAddress, allowed := checkSecondFactorAllowed(w, r, mfa.AccountID)
+	if !allowed {
+		return
+	}
+
+	recoveryCodes, err := util.ConfirmTwoFactorEnrollment(
+		mfa.AccountID, req.Code, DbWorkspace,
+	)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidSecondFactorCode) {
+			recordFailedLogin("", mfa.AccountID, ipAddress, mfa.LoginType)
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := completeSecondFactorLogin(r, mfa)
+	if err != nil {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	tokens.RecoveryCodes = recoveryCodes
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// GetTwoFactorStatusHandler tells whether the logged in account uses and
+// must use a second factor
+func GetTwoFactorStatusHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	enabled, err := util.GetTwoFactorEnabled(claims.AccountID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	required, err := util.IsTwoFactorRequired(
+		claims.AccountID, claims.AccountType, DbWorkspace,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wpmodels.TwoFactorStatus{
+		Enabled:  enabled,
+		Required: required,
+	})
+}
+
+// SetupTwoFactorHandler starts the enrollment of the logged in account
+func SetupTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	startTwoFactorEnrollment(w, claims.AccountID)
+}
+
+// ConfirmTwoFactorHandler enables the second factor of the logged in account
+// and returns its recovery codes. Wrong codes count as failed logins.
+func ConfirmTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req wpmodels.SecondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	ipAddress, allowed := checkSecondFactorAllowed(w, r, claims.AccountID)
+	if !allowed {
+		return
+	}
+
+	recoveryCodes, err := util.ConfirmTwoFactorEnrollment(
+		claims.AccountID, req.Code, DbWorkspace,
+	)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidSecondFactorCode) {
+			recordFailedLogin("", claims.AccountID, ipAddress, twoFactorLoginType)
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recoveryCodes)
+}
+
+// RegenerateRecoveryCodesHandler replaces the recovery codes of the logged
+// in account after checking a current code. Wrong codes count as failed
+// logins.
+func RegenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req wpmodels.SecondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !verifyAccountSecondFactor(w, r, claims.AccountID, req.Code) {
+		return
+	}
+
+	recoveryCodes, err := util.RegenerateRecoveryCodes(claims.AccountID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recoveryCodes)
+}
+
+// DisableTwoFactorHandler removes the second factor of the logged in account
+// after checking a current code. Accounts that must use a second factor can
+// not disable it. Wrong codes count as failed logins.
+func DisableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := util.GetClaimsFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req wpmodels.SecondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	required, err := util.IsTwoFactorRequired(
+		claims.AccountID, claims.AccountType, DbWorkspace,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if required {
+		http.Error(w, "dvofaktorska autentifikacija je obavezna za ovaj nalog", http.StatusForbidden)
+		return
+	}
+
+	if !verifyAccountSecondFactor(w, r, claims.AccountID, req.Code) {
+		return
+	}
+
+	if err := util.DisableTwoFactor(claims.AccountID, DbWorkspace); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTwoFactorPolicyHandler returns the two-factor policy of the tenant
+func GetTwoFactorPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := strconv.Atoi(mux.Vars(r)["tenant_id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	required, err := util.GetTenantTwoFactorPolicy(tenantID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wpmodels.TwoFactorPolicy{RequireTeacher2FA: required})
+}
+
+// UpdateTwoFactorPolicyHandler sets if teachers of the tenant must use two
+// factor authentication. Teachers without a second factor are asked to
+// enroll on their next login.
+func UpdateTwoFactorPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := strconv.Atoi(mux.Vars(r)["tenant_id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var policy wpmodels.TwoFactorPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if required, err := util.GetTenantTwoFactorPolicy(tenantID, DbWorkspace); err == nil {
+		setAuditBefore(r, wpmodels.TwoFactorPolicy{RequireTeacher2FA: required})
+	}
+
+	if err := util.SetTenantTwoFactorPolicy(
+		tenantID, policy.RequireTeacher2FA, DbWorkspace,
+	); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// twoFactorLoginType is recorded for codes checked from the settings of a
+// logged in account
+const twoFactorLoginType = "two_factor"
+
+// checkSecondFactorAllowed applies the login throttling to a second factor
+// code: neither the IP address nor the account may have to wait. It returns
+// the client IP address.
+func checkSecondFactorAllowed(
+	w http.ResponseWriter, r *http.Request, accountID int,
+) (string, bool) {
+	ipAddress, allowed := checkLoginIPAllowed(w, r)
+	if !allowed {
+		return ipAddress, false
+	}
+	lockoutDelay, err := util.GetAccountLockoutDelay(accountID, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return ipAddress, false
+	}
+	if lockoutDelay > 0 {
+		tooManyLoginAttempts(w, lockoutDelay)
+		return ipAddress, false
+	}
+	return ipAddress, true
+}
+
+// verifyAccountSecondFactor checks a code of a logged in account with the
+// same throttling as a login. It responds and returns false if the code can
+// not be checked or does not match.
+func verifyAccountSecondFactor(
+	w http.ResponseWriter, r *http.Request, accountID int, code string,
+) bool {
+	ipAddress, allowed := checkSecondFactorAllowed(w, r, accountID)
+	if !allowed {
+		return false
+	}
+	err := util.VerifySecondFactor(accountID, code, DbWorkspace)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidSecondFactorCode) {
+			recordFailedLogin("", accountID, ipAddress, twoFactorLoginType)
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// startTwoFactorEnrollment creates a new secret for the account and writes
+// it with the provisioning URI
+func startTwoFactorEnrollment(w http.ResponseWriter, accountID int) {
+	var email string
+	err := DbWorkspace.QueryRow(
+		`SELECT email FROM accounts WHERE id = ?`, accountID,
+	).Scan(&email)
+	if err != nil {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+
+	secret, uri, err := util.StartTwoFactorEnrollment(accountID, email, DbWorkspace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wpmodels.TwoFactorSetup{
+		Secret:     secret,
+		OTPAuthURL: uri,
+	})
+}
@@ -0,0 +1,446 @@
+// Package certificate renders pupil certificates (svjedočanstvo) as PDF
+// documents, with the wording of the canton and type of the school.
+package certificate
+
+import (
+	"archive/zip"
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	commonmodels "ednevnik-backend/models/common"
+)
+
+//go:embed templates/*
+var templateFS embed.FS
+
+var labelTemplates = template.Must(
+	template.New("certificate").Option("missingkey=zero").
+		ParseFS(templateFS, "templates/certificate.tmpl"),
+)
+
+// File is a rendered certificate
+type File struct {
+	Name string
+	Data []byte
+}
+
+// certificateLayout keeps the labels of a certificate and the position of
+// the next line while the certificate is drawn
+type certificateLayout struct {
+	doc    *pdfDocument
+	y      float64
+	prefix []string
+	data   map[string]string
+}
+
+// Render draws the certificate of a pupil as a PDF document
+func Render(certificate *commonmodels.Certificate) ([]byte, error) {
+	data := certificateData(certificate)
+	layout := &certificateLayout{
+		doc:  &pdfDocument{},
+		y:    marginTop,
+		data: data,
+		prefix: []string{
+			certificate.Tenant.CantonCode + "_" + certificate.Tenant.TenantType + "_",
+			certificate.Tenant.CantonCode + "_",
+			certificate.Tenant.TenantType + "_",
+			"",
+		},
+	}
+	layout.doc.newPage()
+
+	if err := layout.drawHeading(); err != nil {
+		return nil, err
+	}
+	if err := layout.drawResults(certificate); err != nil {
+		return nil, err
+	}
+	if err := layout.drawSummary(certificate); err != nil {
+		return nil, err
+	}
+	if err := layout.drawFooter(certificate); err != nil {
+		return nil, err
+	}
+
+	return layout.doc.bytes()
+}
+
+// FileName names the PDF of a certificate with ASCII characters only
+func FileName(certificate *commonmodels.Certificate) string {
+	return fmt.Sprintf(
+		"svjedocanstvo_%s_%s_%d.pdf",
+		asciiName(certificate.Pupil.LastName), asciiName(certificate.Pupil.Name),
+		certificate.Pupil.ID,
+	)
+}
+
+// Archive packs rendered certificates into a ZIP file. The notes are added
+// as a text file when there are any, e.g. pupils whose certificate could
+// not be rendered.
+func Archive(files []File, notes []string) ([]byte, error) {
+	var out bytes.Buffer
+	writer := zip.NewWriter(&out)
+
+	for _, file := range files {
+		entry, err := writer.Create(file.Name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := entry.Write(file.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(notes) > 0 {
+		entry, err := writer.Create("napomene.txt")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := entry.Write([]byte(strings.Join(notes, "\n") + "\n")); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// drawHeading draws the school, the title and the introduction
+func (l *certificateLayout) drawHeading() error {
+	header, err := l.label("header", nil)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(header, "\n") {
+		l.doc.textCenter(l.y, fontRegular, 10, strings.TrimSpace(line))
+		l.y += 13
+	}
+
+	school, err := l.label("school", nil)
+	if err != nil {
+		return err
+	}
+	l.y += 10
+	for i, line := range strings.Split(school, "\n") {
+		if i == 0 {
+			l.doc.textCenter(l.y, fontBold, 13, strings.TrimSpace(line))
+			l.y += 16
+			continue
+		}
+		l.doc.textCenter(l.y, fontRegular, 10, strings.TrimSpace(line))
+		l.y += 13
+	}
+
+	title, err := l.label("title", nil)
+	if err != nil {
+		return err
+	}
+	l.y += 36
+	l.doc.textCenter(l.y, fontBold, 24, title)
+
+	subtitle, err := l.label("subtitle", nil)
+	if err != nil {
+		return err
+	}
+	l.y += 22
+	l.doc.textCenter(l.y, fontRegular, 12, subtitle)
+
+	course, err := l.label("course", nil)
+	if err != nil {
+		return err
+	}
+	if course != "" {
+		l.y += 16
+		l.doc.textCenter(l.y, fontRegular, 10, course)
+	}
+
+	intro, err := l.label("intro", nil)
+	if err != nil {
+		return err
+	}
+	l.y += 19
+	l.paragraph(intro, fontRegular, 11, 15)
+	return nil
+}
+
+// drawResults draws the final grades, or the descriptive assessments of
+// descriptively assessed classes, and the remedial exams
+func (l *certificateLayout) drawResults(certificate *commonmodels.Certificate) error {
+	right := pageWidth - marginRight
+
+	if len(certificate.FinalGrades) > 0 || len(certificate.DescriptiveAssessments) == 0 {
+		subjectsHeading, err := l.label("subjects_heading", nil)
+		if err != nil {
+			return err
+		}
+		gradeHeading, err := l.label("grade_heading", nil)
+		if err != nil {
+			return err
+		}
+
+		l.y += 10
+		l.doc.text(marginLeft, l.y, fontBold, 10, subjectsHeading)
+		l.doc.textRight(right, l.y, fontBold, 10, gradeHeading)
+		l.y += 5
+		l.doc.line(marginLeft, l.y, right, l.y)
+
+		for _, grade := range certificate.FinalGrades {
+			gradeLabel, err := l.label(fmt.Sprintf("grade_%d", grade.Grade), nil)
+			if err != nil {
+				return err
+			}
+			l.ensureSpace(18)
+			l.y += 14
+			l.doc.text(marginLeft, l.y, fontRegular, 10, grade.SubjectName)
+			l.doc.textRight(right, l.y, fontRegular, 10, gradeLabel)
+			l.y += 4
+			l.doc.line(marginLeft, l.y, right, l.y)
+		}
+	}
+
+	for _, assessment := range certificate.DescriptiveAssessments {
+		l.ensureSpace(40)
+		l.y += 18
+		l.doc.text(marginLeft, l.y, fontBold, 11, assessment.SubjectName)
+		l.y += 4
+		parts := []struct{ key, text string }{
+			{"achievements", assessment.Achievements},
+			{"effort", assessment.Effort},
+			{"recommendations", assessment.Recommendations},
+		}
+		for _, part := range parts {
+			if strings.TrimSpace(part.text) == "" {
+				continue
+			}
+			text, err := l.label(part.key, map[string]string{"text": part.text})
+			if err != nil {
+				return err
+			}
+			l.paragraph(text, fontRegular, 10, 13)
+		}
+	}
+
+	if len(certificate.RemedialExams) > 0 {
+		l.y += 10
+	}
+	for _, exam := range certificate.RemedialExams {
+		grade := "-"
+		if exam.Grade > 0 {
+			grade = fmt.Sprintf("%d", exam.Grade)
+		}
+		text, err := l.label("remedial", map[string]string{
+			"subject_name": exam.SubjectName,
+			"exam_type":    exam.ExamType,
+			"exam_date":    formatDate(exam.ExamDate),
+			"grade":        grade,
+		})
+		if err != nil {
+			return err
+		}
+		l.paragraph(text, fontRegular, 9, 12)
+	}
+	return nil
+}
+
+// drawSummary draws the behaviour and the general success
+func (l *certificateLayout) drawSummary(certificate *commonmodels.Certificate) error {
+	behaviour, err := l.label("behaviour", nil)
+	if err != nil {
+		return err
+	}
+	l.ensureSpace(50)
+	l.y += 26
+	l.doc.text(marginLeft, l.y, fontRegular, 11, behaviour)
+
+	summary := "success_descriptive"
+	extra := map[string]string{}
+	switch {
+	case !certificate.Passed:
+		summary = "failed"
+	case certificate.GraduateGrade >= 1 && certificate.GraduateGrade <= 5:
+		summary = "success"
+		success, err := l.label(fmt.Sprintf("grade_%d", certificate.GraduateGrade), nil)
+		if err != nil {
+			return err
+		}
+		extra["success"] = success
+		extra["average"] = strings.Replace(
+			fmt.Sprintf("%.2f", certificate.AverageGrade), ".", ",", 1,
+		)
+	}
+
+	text, err := l.label(summary, extra)
+	if err != nil {
+		return err
+	}
+	l.y += 5
+	l.paragraph(text, fontBold, 11, 15)
+	return nil
+}
+
+// drawFooter draws the place and date and the signature lines of the
+// homeroom teacher and the director
+func (l *certificateLayout) drawFooter(certificate *commonmodels.Certificate) error {
+	placeDate, err := l.label("place_date", nil)
+	if err != nil {
+		return err
+	}
+	homeroomLabel, err := l.label("homeroom_label", nil)
+	if err != nil {
+		return err
+	}
+	stamp, err := l.label("stamp", nil)
+	if err != nil {
+		return err
+	}
+	directorLabel, err := l.label("director_label", nil)
+	if err != nil {
+		return err
+	}
+
+	l.ensureSpace(110)
+	l.y += 36
+	l.doc.text(marginLeft, l.y, fontRegular, 10, placeDate)
+
+	const signatureWidth = 170.0
+	left := marginLeft
+	right := pageWidth - marginRight - signatureWidth
+
+	l.y += 50
+	l.signatureName(left, signatureWidth, certificate.Section.HomeroomTeacherFullName)
+	l.signatureName(right, signatureWidth, certificate.Tenant.DirectorName)
+	l.doc.textCenter(l.y, fontRegular, 10, stamp)
+	l.y += 6
+	l.doc.line(left, l.y, left+signatureWidth, l.y)
+	l.doc.line(right, l.y, right+signatureWidth, l.y)
+	l.y += 12
+	l.signatureName(left, signatureWidth, homeroomLabel)
+	l.signatureName(right, signatureWidth, directorLabel)
+	return nil
+}
+
+// signatureName centers text over a signature line
+func (l *certificateLayout) signatureName(x, width float64, text string) {
+	offset := (width - textWidth(text, fontRegular, 9)) / 2
+	l.doc.text(x+offset, l.y, fontRegular, 9, text)
+}
+
+// paragraph draws wrapped text below the current line
+func (l *certificateLayout) paragraph(text, font string, size, lineHeight float64) {
+	for _, line := range wrapText(text, font, size, contentWidth) {
+		l.ensureSpace(lineHeight)
+		l.y += lineHeight
+		l.doc.text(marginLeft, l.y, font, size, line)
+	}
+}
+
+// ensureSpace starts a new page when the height does not fit on the current
+// one
+func (l *certificateLayout) ensureSpace(height float64) {
+	if l.y+height > pageHeight-marginBottom {
+		l.doc.newPage()
+		l.y = marginTop
+	}
+}
+
+// label executes the most specific template of a label, with extra data on
+// top of the certificate data
+func (l *certificateLayout) label(key string, extra map[string]string) (string, error) {
+	data := l.data
+	if len(extra) > 0 {
+		data = make(map[string]string, len(l.data)+len(extra))
+		for name, value := range l.data {
+			data[name] = value
+		}
+		for name, value := range extra {
+			data[name] = value
+		}
+	}
+
+	for _, prefix := range l.prefix {
+		if labelTemplates.Lookup(prefix+key) == nil {
+			continue
+		}
+		var out bytes.Buffer
+		if err := labelTemplates.ExecuteTemplate(&out, prefix+key, data); err != nil {
+			return "", fmt.Errorf("error rendering certificate label %s: %v", key, err)
+		}
+		return strings.TrimSpace(out.String()), nil
+	}
+	return "", fmt.Errorf("unknown certificate label: %s", key)
+}
+
+// certificateData fills the template data of a certificate
+func certificateData(certificate *commonmodels.Certificate) map[string]string {
+	tenant := certificate.Tenant
+	section := certificate.Section
+	pupil := certificate.Pupil
+
+	specialization := tenant.Specialization
+	if specialization == "" {
+		specialization = "regular"
+	}
+	courseName := certificate.CourseName
+	if courseName == "" {
+		courseName = section.CourseName
+	}
+
+	return map[string]string{
+		"tenant_name":     tenant.TenantName,
+		"tenant_type":     tenant.TenantType,
+		"tenant_city":     tenant.TenantCity,
+		"address":         tenant.Address,
+		"canton_code":     tenant.CantonCode,
+		"canton_name":     tenant.CantonName,
+		"director_name":   tenant.DirectorName,
+		"specialization":  specialization,
+		"class_code":      section.ClassCode,
+		"section_code":    section.SectionCode,
+		"year":            section.Year,
+		"curriculum_name": section.CurriculumName,
+		"course_name":     courseName,
+		"homeroom":        section.HomeroomTeacherFullName,
+		"pupil_name":      pupil.Name,
+		"pupil_last_name": pupil.LastName,
+		"gender":          pupil.Gender,
+		"date_of_birth":   formatDate(pupil.DateOfBirth),
+		"place_of_birth":  pupil.PlaceOfBirth,
+		"behaviour":       certificate.BehaviourGrade.Behaviour,
+		"issue_date":      time.Now().Format("02.01.2006."),
+	}
+}
+
+// formatDate writes a database date the way it is written on documents,
+// other values are returned unchanged
+func formatDate(value string) string {
+	if len(value) >= 10 {
+		if date, err := time.Parse("2006-01-02", value[:10]); err == nil {
+			return date.Format("02.01.2006.")
+		}
+	}
+	return value
+}
+
+// asciiReplacer transliterates the Bosnian letters of names
+var asciiReplacer = strings.NewReplacer(
+	"č", "c", "ć", "c", "đ", "dj", "š", "s", "ž", "z",
+	"Č", "C", "Ć", "C", "Đ", "Dj", "Š", "S", "Ž", "Z",
+)
+
+// asciiName makes a name safe for file names
+func asciiName(name string) string {
+	name = asciiReplacer.Replace(strings.TrimSpace(name))
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-':
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,275 @@
+package certificate
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+)
+
+// A4 page size and margins in points
+const (
+	pageWidth    = 595.28
+	pageHeight   = 841.89
+	marginLeft   = 56.0
+	marginRight  = 56.0
+	marginTop    = 56.0
+	marginBottom = 56.0
+	contentWidth = pageWidth - marginLeft - marginRight
+)
+
+// Fonts of the document. The standard Helvetica fonts are not embedded,
+// every PDF reader ships them.
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+)
+
+// fontEncoding is WinAnsiEncoding with the Bosnian letters missing from it
+// put in place of letters that do not appear in Bosnian texts, the same
+// positions Windows-1250 uses for them
+const fontEncoding = `<< /Type /Encoding /BaseEncoding /WinAnsiEncoding
+/Differences [198 /Cacute 200 /Ccaron 208 /Dcroat 230 /cacute 232 /ccaron 240 /dcroat] >>`
+
+// encodingOverrides are the codes changed by fontEncoding
+var encodingOverrides = map[rune]byte{
+	'Ć': 0xC6, 'Č': 0xC8, 'Đ': 0xD0, 'ć': 0xE6, 'č': 0xE8, 'đ': 0xF0,
+}
+
+// winAnsiSpecials are the WinAnsiEncoding codes outside of Latin-1
+var winAnsiSpecials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, 'Š': 0x8A, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96,
+	'—': 0x97, 'š': 0x9A, 'ž': 0x9E,
+}
+
+// encodeText converts text to the codes of fontEncoding. Characters the
+// fonts can not show are replaced with a question mark.
+func encodeText(text string) []byte {
+	encoded := make([]byte, 0, len(text))
+	for _, r := range text {
+		if code, ok := encodingOverrides[r]; ok {
+			encoded = append(encoded, code)
+			continue
+		}
+		if code, ok := winAnsiSpecials[r]; ok {
+			encoded = append(encoded, code)
+			continue
+		}
+		switch {
+		case r >= 0x20 && r < 0x7F:
+			encoded = append(encoded, byte(r))
+		case r >= 0xA0 && r <= 0xFF && !isOverriddenCode(byte(r)):
+			encoded = append(encoded, byte(r))
+		default:
+			encoded = append(encoded, '?')
+		}
+	}
+	return encoded
+}
+
+// isOverriddenCode reports whether a Latin-1 code shows another letter in
+// fontEncoding
+func isOverriddenCode(code byte) bool {
+	for _, overridden := range encodingOverrides {
+		if overridden == code {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeText writes encoded text as a PDF string literal
+func escapeText(encoded []byte) string {
+	var out strings.Builder
+	out.WriteByte('(')
+	for _, b := range encoded {
+		switch b {
+		case '(', ')', '\\':
+			out.WriteByte('\\')
+			out.WriteByte(b)
+		default:
+			out.WriteByte(b)
+		}
+	}
+	out.WriteByte(')')
+	return out.String()
+}
+
+// Widths of the ASCII characters from space to tilde in thousandths of the
+// font size, from the metrics of the standard fonts
+var (
+	regularWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	boldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// baseLetters maps letters with diacritics to the letter with the same width
+var baseLetters = map[rune]rune{
+	'Č': 'C', 'Ć': 'C', 'Đ': 'D', 'Š': 'S', 'Ž': 'Z',
+	'č': 'c', 'ć': 'c', 'đ': 'd', 'š': 's', 'ž': 'z',
+	'„': ',', '“': '"', '”': '"', '‘': '\'', '’': '\'', '–': '-', '—': 'W',
+}
+
+// textWidth measures text in points
+func textWidth(text string, font string, size float64) float64 {
+	widths := &regularWidths
+	if font == fontBold {
+		widths = &boldWidths
+	}
+
+	total := 0
+	for _, r := range text {
+		if base, ok := baseLetters[r]; ok {
+			r = base
+		}
+		if r >= 0x20 && r < 0x7F {
+			total += widths[r-0x20]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// wrapText splits text into lines that fit the width, breaking at spaces
+func wrapText(text string, font string, size, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && textWidth(candidate, font, size) > width {
+				lines = append(lines, line)
+				line = word
+				continue
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// pdfDocument builds a PDF of A4 pages from text and lines
+type pdfDocument struct {
+	pages []*bytes.Buffer
+}
+
+// newPage starts a new page, the following drawing goes to it
+func (d *pdfDocument) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+// page returns the content of the current page
+func (d *pdfDocument) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.newPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// text draws text with its baseline at y, measured from the top of the page
+func (d *pdfDocument) text(x, y float64, font string, size float64, text string) {
+	fmt.Fprintf(
+		d.page(), "BT /%s %.1f Tf %.2f %.2f Td %s Tj ET\n",
+		font, size, x, pageHeight-y, escapeText(encodeText(text)),
+	)
+}
+
+// textCenter draws text centered between the margins
+func (d *pdfDocument) textCenter(y float64, font string, size float64, text string) {
+	x := marginLeft + (contentWidth-textWidth(text, font, size))/2
+	d.text(x, y, font, size, text)
+}
+
+// textRight draws text ending at x
+func (d *pdfDocument) textRight(x, y float64, font string, size float64, text string) {
+	d.text(x-textWidth(text, font, size), y, font, size, text)
+}
+
+// line draws a thin line, coordinates measured from the top of the page
+func (d *pdfDocument) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(
+		d.page(), "0.6 w %.2f %.2f m %.2f %.2f l S\n",
+		x1, pageHeight-y1, x2, pageHeight-y2,
+	)
+}
+
+// bytes writes the document. Page contents are compressed.
+func (d *pdfDocument) bytes() ([]byte, error) {
+	if len(d.pages) == 0 {
+		d.newPage()
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+	writeObject := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	// Objects 1 to 5 are fixed, each page adds its page and content objects
+	var kids []string
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 6+2*i))
+	}
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf(
+		"<< /Type /Pages /Kids [%s] /Count %d >>",
+		strings.Join(kids, " "), len(d.pages),
+	))
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding 5 0 R >>")
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding 5 0 R >>")
+	writeObject(fontEncoding)
+
+	for i, content := range d.pages {
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		if _, err := writer.Write(content.Bytes()); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+
+		writeObject(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+				"/Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, fontRegular, fontBold, 7+2*i,
+		))
+		writeObject(fmt.Sprintf(
+			"<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream",
+			compressed.Len(), compressed.Bytes(),
+		))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(
+		&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, xref,
+	)
+	return out.Bytes(), nil
+}
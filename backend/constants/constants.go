@@ -9,4 +9,6 @@ const (
 	UserWorkspaceDBKey wpmodels.ContextKey = "userWorkspaceDb"
 	// ClaimsKey contains relevant context key
 	ClaimsKey wpmodels.ContextKey = "claims"
+	// AuditKey holds the audit record of a mutating request
+	AuditKey wpmodels.ContextKey = "audit"
 )
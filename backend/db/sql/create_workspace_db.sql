@@ -81,7 +81,10 @@ CREATE TABLE accounts (
     password VARCHAR(255) NOT NULL,
     created_by_teacher_id INT, -- NULL if the account is created via registration
     account_type ENUM('root', 'tenant_admin', 'teacher', 'pupil', 'parent') NOT NULL, -- staf is for teachers, admin, etc.
-    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    -- Incremented whenever tenant membership of the account changes, tokens
+    -- carrying an older version have stale tenant_ids
+    membership_version INT NOT NULL DEFAULT 0
 );
 
 -- Kreiranje tabele teachers
@@ -124,6 +127,8 @@ CREATE TABLE tenant (
     absence_display ENUM ('card', 'table') DEFAULT 'card',
     classroom_display ENUM ('card', 'table') DEFAULT 'card',
     specialization ENUM ('regular', 'religion', 'musical') DEFAULT 'regular',
+    -- Teachers of the tenant must use two-factor authentication
+    require_teacher_2fa BOOLEAN NOT NULL DEFAULT FALSE,
     FOREIGN KEY (canton_code) REFERENCES cantons(canton_code),
     FOREIGN KEY (tenant_admin_id) REFERENCES teachers(id) ON DELETE CASCADE
 );
@@ -253,10 +258,12 @@ CREATE TABLE tenant_semester (
     start_date DATE,
     end_date DATE,
     npp_code VARCHAR(20),
+    lock_grace_days INT NOT NULL DEFAULT 14,
     PRIMARY KEY(tenant_id, semester_code, npp_code),
     FOREIGN KEY (tenant_id) REFERENCES tenant(id) ON DELETE CASCADE,
     FOREIGN KEY (semester_code) REFERENCES semester(semester_code),
-    FOREIGN KEY (npp_code) REFERENCES npp(npp_code)
+    FOREIGN KEY (npp_code) REFERENCES npp(npp_code),
+    CONSTRAINT check_tenant_semester_lock_grace CHECK (lock_grace_days >= 0)
 );
 
 CREATE TABLE curriculum_tenant (
@@ -304,7 +311,9 @@ CREATE TABLE pupil_global (
     has_hifz BOOLEAN,
     special_honors BOOLEAN,
     account_id INT,
-    parent_access_code VARCHAR(36) NOT NULL DEFAULT (UUID()),
+    -- Used by parents to link the pupil to their account, NULL if the tenant
+    -- admin revoked it
+    parent_access_code VARCHAR(36) UNIQUE DEFAULT (UUID()),
     -- The eupis_link_id is used to associate the pupil with their EUPIS account
     -- It is NULL if the pupil is not linked to an EUPIS account
     eupis_link_id INT,
@@ -322,6 +331,27 @@ CREATE TABLE pupil_tenant (
     FOREIGN KEY (tenant_id) REFERENCES tenant(id) ON DELETE CASCADE
 );
 
+CREATE TABLE parents (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(50) NOT NULL,
+    last_name VARCHAR(50) NOT NULL,
+    phone VARCHAR(20) UNIQUE,
+    account_id INT NOT NULL,
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+);
+
+-- Links parent accounts to their children, a pupil can have several parents
+-- and a parent can have children in different tenants
+CREATE TABLE parent_pupil (
+    parent_id INT,
+    pupil_id INT,
+    linked_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(parent_id, pupil_id),
+    FOREIGN KEY (parent_id) REFERENCES parents(id) ON DELETE CASCADE,
+    FOREIGN KEY (pupil_id) REFERENCES pupil_global(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_parent_pupil_pupil ON parent_pupil (pupil_id);
+
 -- Pending Accounts Table with UUID verification token
 CREATE TABLE pending_accounts (
     id INT PRIMARY KEY AUTO_INCREMENT,
@@ -362,6 +392,16 @@ CREATE TABLE pending_pupil_global (
     FOREIGN KEY (account_id) REFERENCES pending_accounts(id) ON DELETE CASCADE
 );
 
+-- Pending Parents Table
+CREATE TABLE pending_parents (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    name VARCHAR(50) NOT NULL,
+    last_name VARCHAR(50) NOT NULL,
+    phone VARCHAR(20) UNIQUE,
+    account_id INT NOT NULL,
+    FOREIGN KEY (account_id) REFERENCES pending_accounts(id) ON DELETE CASCADE
+);
+
 CREATE TABLE invite_index (
     id INT PRIMARY KEY AUTO_INCREMENT,
     invite_id INT NOT NULL,
@@ -441,6 +481,232 @@ CREATE TABLE embeddings (
     VECTOR INDEX (embedding) M=6 DISTANCE=cosine
 );
 
+-- Login sessions, one row per issued refresh token family
+-- The refresh token itself is never stored, only its SHA-256 hash
+CREATE TABLE account_sessions (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    account_id INT NOT NULL,
+    login_type ENUM('password', 'parent_access_code', 'oidc') NOT NULL DEFAULT 'password',
+    refresh_token_hash CHAR(64) NOT NULL UNIQUE,
+    -- Hash of the refresh token that was rotated out last, used to detect reuse
+    previous_token_hash CHAR(64),
+    user_agent VARCHAR(255),
+    ip_address VARCHAR(45),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    revoked_at DATETIME,
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_account_sessions_account ON account_sessions (account_id, revoked_at);
+CREATE INDEX idx_account_sessions_previous_hash ON account_sessions (previous_token_hash);
+
+-- Audit of login attempts, used to throttle logins per IP address. The email
+-- is the one that was typed in, account_id is set when it matched an account.
+CREATE TABLE login_attempts (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    email VARCHAR(100),
+    account_id INT,
+    ip_address VARCHAR(45) NOT NULL,
+    -- two_factor are codes checked from the settings of a logged in account
+    login_type ENUM('password', 'parent_access_code', 'oidc', 'two_factor') NOT NULL DEFAULT 'password',
+    success BOOLEAN NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE SET NULL
+);
+CREATE INDEX idx_login_attempts_ip ON login_attempts (ip_address, success, created_at);
+CREATE INDEX idx_login_attempts_account ON login_attempts (account_id, created_at);
+
+-- Consecutive failed logins of an account, removed on a successful login or
+-- when an admin unlocks the account
+CREATE TABLE account_lockouts (
+    account_id INT PRIMARY KEY,
+    failed_count INT NOT NULL DEFAULT 0,
+    last_failed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    locked_until DATETIME,
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+);
+
+-- OpenID Connect providers for single sign-on, one per email domain. Users
+-- with an email of the domain can log in with the provider of the domain.
+CREATE TABLE oidc_providers (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    domain VARCHAR(100) NOT NULL UNIQUE,
+    display_name VARCHAR(100) NOT NULL,
+    issuer_url VARCHAR(255) NOT NULL,
+    client_id VARCHAR(255) NOT NULL,
+    client_secret VARCHAR(255),
+    scopes VARCHAR(255) NOT NULL DEFAULT 'openid email profile',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Pending single sign-on logins. The state is sent to the provider and comes
+-- back with the code, the PKCE verifier and nonce never leave the backend.
+CREATE TABLE oidc_login_states (
+    state_hash CHAR(64) PRIMARY KEY,
+    provider_id INT NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    nonce VARCHAR(128) NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP + INTERVAL 10 MINUTE),
+    FOREIGN KEY (provider_id) REFERENCES oidc_providers(id) ON DELETE CASCADE
+);
+
+-- Accounts linked to a provider subject on their first single sign-on login
+CREATE TABLE account_identities (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    account_id INT NOT NULL,
+    provider_id INT NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(100),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_login_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_account_identities_subject (provider_id, subject),
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE,
+    FOREIGN KEY (provider_id) REFERENCES oidc_providers(id) ON DELETE CASCADE
+);
+
+-- TOTP second factor of an account. The secret is stored when enrollment
+-- starts, encrypted with TWO_FACTOR_SECRET, and enabled once the first code
+-- is confirmed. last_used_step keeps a code from being used twice.
+CREATE TABLE account_totp (
+    account_id INT PRIMARY KEY,
+    secret VARCHAR(255) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    confirmed_at DATETIME,
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+);
+
+-- Single-use recovery codes for the second factor, only the bcrypt hash of
+-- a code is stored
+CREATE TABLE account_recovery_codes (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    account_id INT NOT NULL,
+    code_hash VARCHAR(60) NOT NULL,
+    used_at DATETIME,
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_account_recovery_codes_account ON account_recovery_codes (account_id, used_at);
+
+-- Password reset tokens, only the SHA-256 hash of a token is stored. A token
+-- can be used once and expires after an hour.
+CREATE TABLE password_resets (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    account_id INT NOT NULL,
+    -- Set by the job that sends the reset email, the token itself is never stored
+    token_hash CHAR(64) UNIQUE,
+    request_ip VARCHAR(45),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL DEFAULT (CURRENT_TIMESTAMP + INTERVAL 1 HOUR),
+    used_at DATETIME,
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_password_resets_account ON password_resets (account_id, created_at);
+
+-- In-app inbox, one row per notification delivered to an account
+CREATE TABLE notifications (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    account_id INT NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    link VARCHAR(500),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    read_at DATETIME,
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_notifications_account ON notifications (account_id, read_at, created_at);
+
+-- Per account opt-in for every event and channel, missing rows use the defaults
+-- of the notification package
+CREATE TABLE notification_preferences (
+    account_id INT NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    channel ENUM('inbox', 'email', 'webhook') NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    PRIMARY KEY (account_id, event, channel),
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+);
+
+-- Webhook of a tenant, notifications of the tenant on the webhook channel are
+-- posted to it and signed with the secret
+CREATE TABLE tenant_notification_webhooks (
+    tenant_id INT PRIMARY KEY,
+    url VARCHAR(500) NOT NULL,
+    secret VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (tenant_id) REFERENCES tenant(id) ON DELETE CASCADE
+);
+
+-- Outbox of background jobs. Jobs are inserted together with the data they
+-- belong to and picked up by the workers of the jobs package. Jobs that keep
+-- failing end up dead and stay here until they are retried from the admin API.
+CREATE TABLE jobs (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    job_type VARCHAR(50) NOT NULL,
+    payload TEXT NOT NULL,
+    status ENUM('pending', 'running', 'completed', 'dead') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    run_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_by VARCHAR(100),
+    locked_at DATETIME,
+    last_error TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    completed_at DATETIME
+);
+CREATE INDEX idx_jobs_status_run_at ON jobs (status, run_at);
+
+-- Audit log of every mutating request of a logged in account, across the
+-- workspace and the tenant databases. route is the path template of the
+-- endpoint, the payloads are JSON with secrets redacted. Rows are kept on
+-- purpose when the actor or tenant is deleted.
+CREATE TABLE audit_log (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    tenant_id INT,
+    actor_account_id INT NOT NULL,
+    actor_role VARCHAR(20) NOT NULL,
+    -- Set when a superadmin made the request while viewing as the actor
+    impersonator_account_id INT,
+    ip_address VARCHAR(45),
+    method VARCHAR(10) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    action VARCHAR(100) NOT NULL,
+    entity VARCHAR(50) NOT NULL,
+    entity_id VARCHAR(100),
+    status_code INT NOT NULL,
+    request_data MEDIUMTEXT,
+    before_data MEDIUMTEXT,
+    after_data MEDIUMTEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_audit_log_tenant ON audit_log (tenant_id, created_at);
+CREATE INDEX idx_audit_log_actor ON audit_log (actor_account_id, created_at);
+CREATE INDEX idx_audit_log_entity ON audit_log (entity, entity_id, created_at);
+CREATE INDEX idx_audit_log_impersonator ON audit_log (impersonator_account_id, created_at);
+
+-- Superadmin "view as" sessions. The impersonation token belongs to the
+-- session of the superadmin, so it ends with that session, on expiry or when
+-- it is ended explicitly.
+CREATE TABLE impersonations (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    actor_account_id INT NOT NULL,
+    target_account_id INT NOT NULL,
+    session_id INT NOT NULL,
+    read_only BOOLEAN NOT NULL DEFAULT TRUE,
+    reason VARCHAR(255),
+    ip_address VARCHAR(45),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    ended_at DATETIME,
+    FOREIGN KEY (actor_account_id) REFERENCES accounts(id) ON DELETE CASCADE,
+    FOREIGN KEY (target_account_id) REFERENCES accounts(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_impersonations_actor ON impersonations (actor_account_id, created_at);
+
 -- Event to automatically delete expired pending accounts every hour
 DELIMITER $$
 CREATE EVENT cleanup_expired_pending_accounts
@@ -451,6 +717,57 @@ BEGIN
 END$$
 DELIMITER ;
 
+-- Event to automatically delete expired login sessions every day
+DELIMITER $$
+CREATE EVENT cleanup_expired_account_sessions
+ON SCHEDULE EVERY 1 DAY
+DO
+BEGIN
+    DELETE FROM account_sessions WHERE expires_at < NOW();
+END$$
+DELIMITER ;
+
+-- Event to automatically delete expired password reset tokens every day
+DELIMITER $$
+CREATE EVENT cleanup_expired_password_resets
+ON SCHEDULE EVERY 1 DAY
+DO
+BEGIN
+    DELETE FROM password_resets WHERE expires_at < NOW() - INTERVAL 1 DAY;
+END$$
+DELIMITER ;
+
+-- Event to automatically delete abandoned single sign-on logins every hour
+DELIMITER $$
+CREATE EVENT cleanup_expired_oidc_login_states
+ON SCHEDULE EVERY 1 HOUR
+DO
+BEGIN
+    DELETE FROM oidc_login_states WHERE expires_at < NOW();
+END$$
+DELIMITER ;
+
+-- Event to automatically delete login attempts older than 90 days
+DELIMITER $$
+CREATE EVENT cleanup_old_login_attempts
+ON SCHEDULE EVERY 1 DAY
+DO
+BEGIN
+    DELETE FROM login_attempts WHERE created_at < NOW() - INTERVAL 90 DAY;
+END$$
+DELIMITER ;
+
+-- Event to automatically delete completed jobs after a week
+DELIMITER $$
+CREATE EVENT cleanup_completed_jobs
+ON SCHEDULE EVERY 1 DAY
+DO
+BEGIN
+    DELETE FROM jobs
+    WHERE status = 'completed' AND completed_at < NOW() - INTERVAL 7 DAY;
+END$$
+DELIMITER ;
+
 -- Enable event scheduler if not already enabled
 SET GLOBAL event_scheduler = ON;
 
@@ -519,6 +836,16 @@ SELECT '[LOG] Granting pupil privileges...' AS info;
 GRANT SELECT on ednevnik_workspace.* TO 'pupil'@'localhost' WITH GRANT OPTION;
 
 
+SELECT '[LOG] Dropping user parent if exists...' AS info;
+DROP USER IF EXISTS 'parent'@'localhost';
+
+SELECT '[LOG] Creating user parent...' AS info;
+CREATE USER 'parent'@'localhost';
+
+SELECT '[LOG] Granting parent privileges...' AS info;
+GRANT SELECT on ednevnik_workspace.* TO 'parent'@'localhost' WITH GRANT OPTION;
+
+
 SELECT '[LOG] Dropping user service_reader if exists...' AS info;
 DROP USER IF EXISTS 'service_reader'@'localhost';
 
@@ -537,6 +864,7 @@ GRANT INSERT ON ednevnik_workspace.teacher_tenant TO 'service_reader'@'localhost
 GRANT INSERT, UPDATE ON ednevnik_workspace.accounts TO 'service_reader'@'localhost' WITH GRANT OPTION;
 GRANT UPDATE ON ednevnik_workspace.pupil_global TO 'service_reader'@'localhost' WITH GRANT OPTION;
 GRANT UPDATE ON ednevnik_workspace.teachers TO 'service_reader'@'localhost' WITH GRANT OPTION;
+GRANT INSERT, DELETE ON ednevnik_workspace.parent_pupil TO 'service_reader'@'localhost' WITH GRANT OPTION;
 
 
 FLUSH PRIVILEGES;
@@ -617,6 +945,80 @@ BEGIN
   END IF;
 END$$
 
+CREATE DEFINER='service_reader'@'localhost' TRIGGER bump_membership_after_teacher_tenant_insert
+AFTER INSERT ON teacher_tenant
+FOR EACH ROW
+BEGIN
+  UPDATE accounts a
+  JOIN teachers t ON t.account_id = a.id
+  SET a.membership_version = a.membership_version + 1
+  WHERE t.id = NEW.teacher_id;
+END$$
+
+CREATE DEFINER='service_reader'@'localhost' TRIGGER bump_membership_after_teacher_tenant_delete
+AFTER DELETE ON teacher_tenant
+FOR EACH ROW
+BEGIN
+  UPDATE accounts a
+  JOIN teachers t ON t.account_id = a.id
+  SET a.membership_version = a.membership_version + 1
+  WHERE t.id = OLD.teacher_id;
+END$$
+
+CREATE DEFINER='service_reader'@'localhost' TRIGGER bump_membership_after_pupil_tenant_insert
+AFTER INSERT ON pupil_tenant
+FOR EACH ROW
+BEGIN
+  UPDATE accounts a
+  JOIN pupil_global pg ON pg.account_id = a.id
+  SET a.membership_version = a.membership_version + 1
+  WHERE pg.id = NEW.pupil_id;
+
+  -- Parents see the tenants of their children
+  UPDATE accounts a
+  JOIN parents p ON p.account_id = a.id
+  JOIN parent_pupil pp ON pp.parent_id = p.id
+  SET a.membership_version = a.membership_version + 1
+  WHERE pp.pupil_id = NEW.pupil_id;
+END$$
+
+CREATE DEFINER='service_reader'@'localhost' TRIGGER bump_membership_after_pupil_tenant_delete
+AFTER DELETE ON pupil_tenant
+FOR EACH ROW
+BEGIN
+  UPDATE accounts a
+  JOIN pupil_global pg ON pg.account_id = a.id
+  SET a.membership_version = a.membership_version + 1
+  WHERE pg.id = OLD.pupil_id;
+
+  -- Parents see the tenants of their children
+  UPDATE accounts a
+  JOIN parents p ON p.account_id = a.id
+  JOIN parent_pupil pp ON pp.parent_id = p.id
+  SET a.membership_version = a.membership_version + 1
+  WHERE pp.pupil_id = OLD.pupil_id;
+END$$
+
+CREATE DEFINER='service_reader'@'localhost' TRIGGER bump_membership_after_parent_pupil_insert
+AFTER INSERT ON parent_pupil
+FOR EACH ROW
+BEGIN
+  UPDATE accounts a
+  JOIN parents p ON p.account_id = a.id
+  SET a.membership_version = a.membership_version + 1
+  WHERE p.id = NEW.parent_id;
+END$$
+
+CREATE DEFINER='service_reader'@'localhost' TRIGGER bump_membership_after_parent_pupil_delete
+AFTER DELETE ON parent_pupil
+FOR EACH ROW
+BEGIN
+  UPDATE accounts a
+  JOIN parents p ON p.account_id = a.id
+  SET a.membership_version = a.membership_version + 1
+  WHERE p.id = OLD.parent_id;
+END$$
+
 DELIMITER ;
 SELECT '[LOG] Triggers created. Workspace DB setup complete.' AS info;
 
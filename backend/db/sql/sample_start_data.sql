@@ -325,6 +325,220 @@ CREATE TABLE pupil_attendance (
     FOREIGN KEY (pupil_id) REFERENCES pupils(id) ON DELETE CASCADE,
     FOREIGN KEY (lesson_id) REFERENCES class_lesson(id) ON DELETE CASCADE
 ) WITH SYSTEM VERSIONING;
+
+CREATE TABLE attendance_excuses (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    pupil_id INT NOT NULL,
+    section_id INT NOT NULL,
+    date_from DATE NOT NULL,
+    date_to DATE NOT NULL,
+    reason VARCHAR(1000) NOT NULL,
+    attachment_name VARCHAR(255),
+    attachment_type VARCHAR(100),
+    attachment MEDIUMBLOB,
+    submitted_by_account_id INT NOT NULL,
+    submitted_by_type ENUM('pupil', 'parent') NOT NULL,
+    submitted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    status ENUM('pending', 'approved', 'rejected') NOT NULL DEFAULT 'pending',
+    decided_by_account_id INT,
+    decided_by_signature VARCHAR(128),
+    decided_at TIMESTAMP NULL,
+    decision_note VARCHAR(500),
+    CONSTRAINT check_excuse_date_range CHECK (date_from <= date_to),
+    FOREIGN KEY (pupil_id, section_id) REFERENCES pupils_sections(pupil_id, section_id) ON DELETE CASCADE,
+    FOREIGN KEY (submitted_by_account_id) REFERENCES ednevnik_workspace.accounts(id),
+    FOREIGN KEY (decided_by_account_id) REFERENCES ednevnik_workspace.accounts(id)
+) WITH SYSTEM VERSIONING;
+CREATE INDEX idx_excuse_section_status ON attendance_excuses (section_id, status);
+
+CREATE TABLE attendance_excuse_lessons (
+    excuse_id INT NOT NULL,
+    pupil_id INT NOT NULL,
+    lesson_id INT NOT NULL,
+    previous_status ENUM('present', 'absent', 'unexcused', 'excused') NOT NULL,
+    new_status ENUM('present', 'absent', 'unexcused', 'excused') NOT NULL,
+    PRIMARY KEY (excuse_id, lesson_id),
+    FOREIGN KEY (excuse_id) REFERENCES attendance_excuses(id) ON DELETE CASCADE,
+    FOREIGN KEY (pupil_id, lesson_id) REFERENCES pupil_attendance(pupil_id, lesson_id) ON DELETE CASCADE
+);
+
+CREATE TABLE absence_thresholds (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    measure ENUM('opomena_razrednika', 'ukor_razrednika', 'ukor_odjeljenskog_vijeca', 'ukor_direktora', 'ukor_nastavnickog_vijeca') NOT NULL UNIQUE,
+    unexcused_hours INT NOT NULL,
+    behaviour_cap ENUM('primjerno', 'vrlodobro', 'dobro', 'zadovoljavajuće', 'loše'),
+    CONSTRAINT check_threshold_hours CHECK (unexcused_hours > 0)
+) WITH SYSTEM VERSIONING;
+
+INSERT INTO absence_thresholds (measure, unexcused_hours, behaviour_cap) VALUES
+    ('opomena_razrednika', 8, 'vrlodobro'),
+    ('ukor_razrednika', 13, 'dobro'),
+    ('ukor_odjeljenskog_vijeca', 18, 'dobro'),
+    ('ukor_direktora', 25, 'zadovoljavajuće'),
+    ('ukor_nastavnickog_vijeca', 35, 'loše');
+
+CREATE TABLE pedagogical_measures (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    pupil_id INT NOT NULL,
+    section_id INT NOT NULL,
+    semester_code VARCHAR(10) NOT NULL,
+    measure ENUM('opomena_razrednika', 'ukor_razrednika', 'ukor_odjeljenskog_vijeca', 'ukor_direktora', 'ukor_nastavnickog_vijeca') NOT NULL,
+    unexcused_hours INT NOT NULL,
+    issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_measure_pupil_section_semester UNIQUE (pupil_id, section_id, semester_code, measure),
+    FOREIGN KEY (pupil_id, section_id) REFERENCES pupils_sections(pupil_id, section_id) ON DELETE CASCADE,
+    FOREIGN KEY (semester_code) REFERENCES ednevnik_workspace.semester(semester_code)
+) WITH SYSTEM VERSIONING;
+
+CREATE TABLE grading_schemes (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    subject_code VARCHAR(15),
+    exam_weight DECIMAL(5,2) NOT NULL DEFAULT 1,
+    oral_weight DECIMAL(5,2) NOT NULL DEFAULT 1,
+    written_assignment_weight DECIMAL(5,2) NOT NULL DEFAULT 1,
+    drop_lowest BOOLEAN NOT NULL DEFAULT FALSE,
+    round_up_from DECIMAL(3,2) NOT NULL DEFAULT 0.50,
+    borderline_margin DECIMAL(3,2) NOT NULL DEFAULT 0.10,
+    CONSTRAINT unique_grading_scheme_subject UNIQUE (subject_code),
+    CONSTRAINT check_grading_scheme_weights CHECK (
+        exam_weight >= 0 AND oral_weight >= 0 AND written_assignment_weight >= 0
+        AND exam_weight + oral_weight + written_assignment_weight > 0
+    ),
+    CONSTRAINT check_grading_scheme_rounding CHECK (
+        round_up_from > 0 AND round_up_from < 1
+        AND borderline_margin >= 0 AND borderline_margin < 0.5
+    ),
+    FOREIGN KEY (subject_code) REFERENCES ednevnik_workspace.subjects(subject_code)
+) WITH SYSTEM VERSIONING;
+
+-- The scheme without a subject is the default of the tenant
+INSERT INTO grading_schemes (subject_code) VALUES (NULL);
+
+CREATE TABLE semester_lock_changes (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    semester_code VARCHAR(10) NOT NULL,
+    locked BOOLEAN NOT NULL,
+    reason VARCHAR(500) NOT NULL,
+    changed_by_account_id INT NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_semester_lock_changes_semester (semester_code, id),
+    FOREIGN KEY (semester_code) REFERENCES ednevnik_workspace.semester(semester_code),
+    FOREIGN KEY (changed_by_account_id) REFERENCES ednevnik_workspace.accounts(id)
+);
+
+CREATE TABLE descriptive_assessment_classes (
+    class_code VARCHAR(10) PRIMARY KEY,
+    FOREIGN KEY (class_code) REFERENCES ednevnik_workspace.classes(class_code)
+) WITH SYSTEM VERSIONING;
+
+CREATE TABLE descriptive_assessments (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    pupil_id INT NOT NULL,
+    section_id INT NOT NULL,
+    subject_code VARCHAR(15) NOT NULL,
+    semester_code VARCHAR(10) NOT NULL,
+    achievements TEXT NOT NULL,
+    effort TEXT,
+    recommendations TEXT,
+    teacher_id INT,
+    signature VARCHAR(128),
+    CONSTRAINT unique_descriptive_assessment UNIQUE (pupil_id, section_id, subject_code, semester_code),
+    FOREIGN KEY (pupil_id, section_id) REFERENCES pupils_sections(pupil_id, section_id) ON DELETE CASCADE,
+    FOREIGN KEY (subject_code) REFERENCES ednevnik_workspace.subjects(subject_code),
+    FOREIGN KEY (semester_code) REFERENCES ednevnik_workspace.semester(semester_code),
+    FOREIGN KEY (teacher_id) REFERENCES ednevnik_workspace.teachers(id)
+) WITH SYSTEM VERSIONING;
+
+CREATE TABLE exam_limits (
+    id INT PRIMARY KEY DEFAULT 1,
+    max_per_day INT NOT NULL DEFAULT 1,
+    max_per_week INT NOT NULL DEFAULT 3,
+    CONSTRAINT check_exam_limits_single_row CHECK (id = 1),
+    CONSTRAINT check_exam_limits_caps CHECK (max_per_day > 0 AND max_per_week >= max_per_day)
+);
+
+INSERT INTO exam_limits (id) VALUES (1);
+
+CREATE TABLE planned_exams (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    section_id INT NOT NULL,
+    subject_code VARCHAR(15) NOT NULL,
+    semester_code VARCHAR(10) NOT NULL,
+    exam_date DATE NOT NULL,
+    type ENUM('exam', 'written_assignment') NOT NULL DEFAULT 'written_assignment',
+    description VARCHAR(500),
+    teacher_id INT,
+    signature VARCHAR(128),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_planned_exams_section_date (section_id, exam_date),
+    FOREIGN KEY (section_id) REFERENCES sections(id) ON DELETE CASCADE,
+    FOREIGN KEY (subject_code) REFERENCES ednevnik_workspace.subjects(subject_code),
+    FOREIGN KEY (semester_code) REFERENCES ednevnik_workspace.semester(semester_code),
+    FOREIGN KEY (teacher_id) REFERENCES ednevnik_workspace.teachers(id)
+);
+
+CREATE TABLE remedial_exams (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    section_id INT NOT NULL,
+    subject_code VARCHAR(15) NOT NULL,
+    semester_code VARCHAR(10) NOT NULL,
+    type ENUM('remedial', 'class') NOT NULL DEFAULT 'remedial',
+    exam_date DATE NOT NULL,
+    created_by_account_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_remedial_exams_section_subject (section_id, subject_code),
+    FOREIGN KEY (section_id) REFERENCES sections(id) ON DELETE CASCADE,
+    FOREIGN KEY (subject_code) REFERENCES ednevnik_workspace.subjects(subject_code),
+    FOREIGN KEY (semester_code) REFERENCES ednevnik_workspace.semester(semester_code),
+    FOREIGN KEY (created_by_account_id) REFERENCES ednevnik_workspace.accounts(id)
+);
+
+CREATE TABLE remedial_exam_commission (
+    remedial_exam_id INT NOT NULL,
+    teacher_id INT NOT NULL,
+    role ENUM('chair', 'examiner', 'member') NOT NULL DEFAULT 'member',
+    PRIMARY KEY (remedial_exam_id, teacher_id),
+    FOREIGN KEY (remedial_exam_id) REFERENCES remedial_exams(id) ON DELETE CASCADE,
+    FOREIGN KEY (teacher_id) REFERENCES ednevnik_workspace.teachers(id)
+);
+
+CREATE TABLE remedial_exam_results (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    remedial_exam_id INT NOT NULL,
+    pupil_id INT NOT NULL,
+    previous_grade INT,
+    grade INT,
+    attended BOOLEAN,
+    final_grade_id INT,
+    teacher_id INT,
+    signature VARCHAR(128),
+    recorded_at TIMESTAMP NULL,
+    CONSTRAINT unique_remedial_exam_pupil UNIQUE (remedial_exam_id, pupil_id),
+    CONSTRAINT check_remedial_exam_grade CHECK (grade BETWEEN 1 AND 5),
+    FOREIGN KEY (remedial_exam_id) REFERENCES remedial_exams(id) ON DELETE CASCADE,
+    FOREIGN KEY (pupil_id) REFERENCES pupils(id) ON DELETE CASCADE,
+    FOREIGN KEY (final_grade_id) REFERENCES student_grades(id) ON DELETE SET NULL,
+    FOREIGN KEY (teacher_id) REFERENCES ednevnik_workspace.teachers(id)
+) WITH SYSTEM VERSIONING;
+
+CREATE TABLE gradebook_ledger (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    section_id INT NOT NULL,
+    sequence INT NOT NULL,
+    entity ENUM('grade', 'lesson', 'behaviour') NOT NULL,
+    entity_id INT NOT NULL,
+    pupil_id INT,
+    action ENUM('create', 'update', 'delete', 'seal') NOT NULL,
+    payload_hash CHAR(64) NOT NULL,
+    teacher_id INT,
+    signature CHAR(64) NOT NULL,
+    prev_hash CHAR(64) NOT NULL,
+    entry_hash CHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_gradebook_ledger_sequence UNIQUE (section_id, sequence),
+    INDEX idx_gradebook_ledger_entity (section_id, entity, entity_id),
+    FOREIGN KEY (section_id) REFERENCES sections(id) ON DELETE CASCADE
+);
 SELECT '[LOG] Created tables in tenant database.' AS info;
 
 DELIMITER $$
@@ -421,6 +635,20 @@ GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.time_periods TO 'service_reader'@
 GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.class_lesson TO 'service_reader'@'localhost' WITH GRANT OPTION;
 GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.pupil_attendance TO 'service_reader'@'localhost' WITH GRANT OPTION;
 GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.pupil_behaviour TO 'service_reader'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.attendance_excuses TO 'service_reader'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.attendance_excuse_lessons TO 'service_reader'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.absence_thresholds TO 'service_reader'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.pedagogical_measures TO 'service_reader'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.grading_schemes TO 'service_reader'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT ON ednevnik_tenant_db_tenant_id_1.semester_lock_changes TO 'service_reader'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.descriptive_assessment_classes TO 'service_reader'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.descriptive_assessments TO 'service_reader'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.exam_limits TO 'service_reader'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.planned_exams TO 'service_reader'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.remedial_exams TO 'service_reader'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.remedial_exam_commission TO 'service_reader'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.remedial_exam_results TO 'service_reader'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.gradebook_ledger TO 'service_reader'@'localhost' WITH GRANT OPTION;
 
 -- Grant all privileges to the tenant admin
 GRANT ALL PRIVILEGES ON ednevnik_tenant_db_tenant_id_1.* TO 'tenant_admin'@'localhost' WITH GRANT OPTION;
@@ -438,6 +666,46 @@ GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.time_periods TO 'pupil'@'localhos
 GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.class_lesson TO 'pupil'@'localhost';
 GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.pupil_attendance TO 'pupil'@'localhost';
 GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.pupil_behaviour TO 'pupil'@'localhost';
+GRANT SELECT, INSERT ON ednevnik_tenant_db_tenant_id_1.attendance_excuses TO 'pupil'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.attendance_excuse_lessons TO 'pupil'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.absence_thresholds TO 'pupil'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.pedagogical_measures TO 'pupil'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.grading_schemes TO 'pupil'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.semester_lock_changes TO 'pupil'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.descriptive_assessment_classes TO 'pupil'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.descriptive_assessments TO 'pupil'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.exam_limits TO 'pupil'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.planned_exams TO 'pupil'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.remedial_exams TO 'pupil'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.remedial_exam_commission TO 'pupil'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.remedial_exam_results TO 'pupil'@'localhost';
+
+-- Grant parent privileges, parents see the same data as pupils
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.pupils TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.sections TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.pupils_sections TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.student_grades TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.pupils_sections_invite TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.homeroom_assignments TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.classroom TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.schedule TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.time_periods TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.class_lesson TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.pupil_attendance TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.pupil_behaviour TO 'parent'@'localhost';
+GRANT SELECT, INSERT ON ednevnik_tenant_db_tenant_id_1.attendance_excuses TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.attendance_excuse_lessons TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.absence_thresholds TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.pedagogical_measures TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.grading_schemes TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.semester_lock_changes TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.descriptive_assessment_classes TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.descriptive_assessments TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.exam_limits TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.planned_exams TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.remedial_exams TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.remedial_exam_commission TO 'parent'@'localhost';
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.remedial_exam_results TO 'parent'@'localhost';
 
 -- Grant privileges to teacher@localhost WITH GRANT OPTION
 GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.pupils TO 'teacher'@'localhost' WITH GRANT OPTION;
@@ -456,5 +724,19 @@ GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.time_periods TO 'teacher'@'localh
 GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.class_lesson TO 'teacher'@'localhost' WITH GRANT OPTION;
 GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.pupil_attendance TO 'teacher'@'localhost' WITH GRANT OPTION;
 GRANT SELECT, INSERT, UPDATE, DELETE ON ednevnik_tenant_db_tenant_id_1.pupil_behaviour TO 'teacher'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, UPDATE ON ednevnik_tenant_db_tenant_id_1.attendance_excuses TO 'teacher'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT ON ednevnik_tenant_db_tenant_id_1.attendance_excuse_lessons TO 'teacher'@'localhost' WITH GRANT OPTION;
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.absence_thresholds TO 'teacher'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT ON ednevnik_tenant_db_tenant_id_1.pedagogical_measures TO 'teacher'@'localhost' WITH GRANT OPTION;
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.grading_schemes TO 'teacher'@'localhost' WITH GRANT OPTION;
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.semester_lock_changes TO 'teacher'@'localhost' WITH GRANT OPTION;
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.descriptive_assessment_classes TO 'teacher'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT, UPDATE ON ednevnik_tenant_db_tenant_id_1.descriptive_assessments TO 'teacher'@'localhost' WITH GRANT OPTION;
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.exam_limits TO 'teacher'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT, DELETE ON ednevnik_tenant_db_tenant_id_1.planned_exams TO 'teacher'@'localhost' WITH GRANT OPTION;
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.remedial_exams TO 'teacher'@'localhost' WITH GRANT OPTION;
+GRANT SELECT ON ednevnik_tenant_db_tenant_id_1.remedial_exam_commission TO 'teacher'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, UPDATE ON ednevnik_tenant_db_tenant_id_1.remedial_exam_results TO 'teacher'@'localhost' WITH GRANT OPTION;
+GRANT SELECT, INSERT ON ednevnik_tenant_db_tenant_id_1.gradebook_ledger TO 'teacher'@'localhost' WITH GRANT OPTION;
 
 FLUSH PRIVILEGES;
@@ -0,0 +1,27 @@
+package endpoints
+
+import (
+	"ednevnik-backend/api"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterAuditEndpoints registers the endpoints for querying the audit log
+func RegisterAuditEndpoints(r *mux.Router) {
+	r.HandleFunc("/api/superadmin/audit_log",
+		api.AuthMiddleware(
+			api.GetAuditLogHandler,
+			[]string{"root"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/tenant_admin/audit_log/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetTenantAuditLogHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("GET")
+}
@@ -0,0 +1,91 @@
+package endpoints
+
+import (
+	"strings"
+	"testing"
+
+	"ednevnik-backend/api"
+
+	"github.com/gorilla/mux"
+)
+
+// allEndpoints registers every endpoint group the server registers
+func allEndpoints() *mux.Router {
+	r := mux.NewRouter()
+	RegisterTeacherEndpoints(r)
+	RegisterTenantEndpoints(r)
+	RegisterSectionEndpoints(r)
+	RegisterStaticEndpoints(r)
+	RegisterPupilEndpoints(r)
+	RegisterSemesterEndpoints(r)
+	RegisterVerificationEndpoints(r)
+	RegisterDomainEndpoints(r)
+	RegisterScheduleEndpoints(r)
+	RegisterClassroomEndpoints(r)
+	RegisterLessonEndpoints(r)
+	RegisterGradebookEndpoints(r)
+	RegisterExamEndpoints(r)
+	RegisterRemedialEndpoints(r)
+	RegisterLedgerEndpoints(r)
+	RegisterCertificateEndpoints(r)
+	RegisterCommonEndpoints(r)
+	RegisterParentEndpoints(r)
+	RegisterJobEndpoints(r)
+	RegisterAuditEndpoints(r)
+	RegisterImpersonationEndpoints(r)
+	RegisterOIDCEndpoints(r)
+	return r
+}
+
+func TestEveryRouteHasAnAuditEntity(t *testing.T) {
+	routes := map[string]bool{}
+	err := allEndpoints().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		routes[template] = true
+		if _, ok := api.AuditEntityOf(template); !ok {
+			t.Errorf("route %s has no audit entity", template)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walking the routes failed: %v", err)
+	}
+
+	for _, template := range []string{
+		"/api/tenant_admin/section/{tenant_id}/{section_id}",
+		"/api/teacher/update_grade/{tenant_id}",
+		"/api/teacher/update_behaviour_grade/{tenant_id}",
+		"/api/tenant_admin/gradebook_ledger/seal/{tenant_id}/{section_id}",
+	} {
+		if !routes[template] {
+			t.Errorf("route %s is not registered", template)
+		}
+	}
+}
+
+func TestAuditEntitiesDoNotDependOnKeywordOrder(t *testing.T) {
+	tests := []struct {
+		route  string
+		entity string
+	}{
+		{"/api/tenant_admin/section/{tenant_id}/{section_id}", "section"},
+		{"/api/teacher/update_behaviour_grade/{tenant_id}", "behaviour_grade"},
+		{"/api/teacher/final_grade_proposals/{tenant_id}/{section_id}/{subject_code}/{semester_code}", "grade"},
+		{"/api/teacher/unassign_pupil_from_section/{tenant_id}/{section_id}/{pupil_id}", "pupil"},
+		{"/api/tenant_admin/teacher_section_assignments/{tenant_id}/{teacher_id}", "teacher"},
+		{"/api/tenant_admin/parent_access_code/{tenant_id}/{pupil_id}", "pupil"},
+		{"/api/tenant_admin/gradebook_ledger/seal/{tenant_id}/{section_id}", "gradebook_ledger"},
+	}
+	for _, tt := range tests {
+		name := strings.TrimPrefix(tt.route, "/api/")
+		t.Run(name, func(t *testing.T) {
+			entity, ok := api.AuditEntityOf(tt.route)
+			if !ok || entity != tt.entity {
+				t.Errorf("entity of %s is %q, want %q", tt.route, entity, tt.entity)
+			}
+		})
+	}
+}
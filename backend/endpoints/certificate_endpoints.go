@@ -10,8 +10,35 @@ import (
 func RegisterCertificateEndpoints(r *mux.Router) {
 	r.HandleFunc("/api/pupil/certificate/{tenant_id}/{section_id}/{pupil_id}",
 		api.AuthMiddleware(
-			api.GetCertificateDataHandler,
-			[]string{"root", "tenant_admin", "teacher", "pupil"},
+			api.PolicyMiddleware(
+				api.GetCertificateDataHandler,
+				api.TeacherOfSection,
+				api.PupilEnrolledInSection,
+				api.ParentOfPupil,
+			),
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/pupil/certificate_pdf/{tenant_id}/{section_id}/{pupil_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetCertificatePDFHandler,
+				api.TeacherOfSection,
+				api.PupilEnrolledInSection,
+				api.ParentOfPupil,
+			),
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/teacher/section_certificates/{tenant_id}/{section_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetSectionCertificatesHandler,
+				api.HomeroomTeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
 		),
 	).Methods("GET")
 }
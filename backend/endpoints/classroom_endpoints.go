@@ -10,28 +10,40 @@ import (
 func RegisterClassroomEndpoints(r *mux.Router) {
 	r.HandleFunc("/api/tenant_admin/create_classroom/{tenant_id}",
 		api.AuthMiddleware(
-			api.CreateClassroomHandler,
+			api.PolicyMiddleware(
+				api.CreateClassroomHandler,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("POST")
 
 	r.HandleFunc("/api/tenant_admin/update_classroom/{tenant_id}/{classroom_code}",
 		api.AuthMiddleware(
-			api.UpdateClassroomHandler,
+			api.PolicyMiddleware(
+				api.UpdateClassroomHandler,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("PUT")
 
 	r.HandleFunc("/api/tenant_admin/delete_classroom/{tenant_id}/{classroom_code}",
 		api.AuthMiddleware(
-			api.DeleteClassroomHandler,
+			api.PolicyMiddleware(
+				api.DeleteClassroomHandler,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("DELETE")
 
 	r.HandleFunc("/api/tenant_admin/update_classroom/{tenant_id}/{classroom_code}",
 		api.AuthMiddleware(
-			api.DeleteClassroomHandler,
+			api.PolicyMiddleware(
+				api.DeleteClassroomHandler,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("PUT")
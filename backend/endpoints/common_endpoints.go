@@ -11,7 +11,7 @@ func RegisterCommonEndpoints(r *mux.Router) {
 	r.HandleFunc("/api/common/change_password",
 		api.AuthMiddleware(
 			api.ChangeAccountPasswordHandler,
-			[]string{"teacher", "pupil"},
+			[]string{"teacher", "pupil", "parent"},
 		),
 	).Methods("POST")
 
@@ -21,4 +21,74 @@ func RegisterCommonEndpoints(r *mux.Router) {
 			[]string{"root", "tenant_admin", "teacher", "pupil"},
 		),
 	).Methods("POST")
+
+	r.HandleFunc("/api/common/notifications",
+		api.AuthMiddleware(
+			api.GetNotificationsHandler,
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/common/notifications/{notification_id}/read",
+		api.AuthMiddleware(
+			api.MarkNotificationReadHandler,
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/common/notifications/read_all",
+		api.AuthMiddleware(
+			api.MarkAllNotificationsReadHandler,
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/common/notification_preferences",
+		api.AuthMiddleware(
+			api.GetNotificationPreferencesHandler,
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/common/notification_preferences",
+		api.AuthMiddleware(
+			api.UpdateNotificationPreferencesHandler,
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("PUT")
+
+	r.HandleFunc("/api/common/two_factor",
+		api.AuthMiddleware(
+			api.GetTwoFactorStatusHandler,
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/common/two_factor/setup",
+		api.AuthMiddleware(
+			api.SetupTwoFactorHandler,
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/common/two_factor/confirm",
+		api.AuthMiddleware(
+			api.ConfirmTwoFactorHandler,
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/common/two_factor/recovery_codes",
+		api.AuthMiddleware(
+			api.RegenerateRecoveryCodesHandler,
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/common/two_factor/disable",
+		api.AuthMiddleware(
+			api.DisableTwoFactorHandler,
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("POST")
 }
@@ -0,0 +1,62 @@
+package endpoints
+
+import (
+	"ednevnik-backend/api"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterExamEndpoints function to register endpoints related to the exam
+// calendar of sections
+func RegisterExamEndpoints(r *mux.Router) {
+	r.HandleFunc("/api/teacher/planned_exams/{tenant_id}/{section_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetPlannedExamsHandler,
+				api.TeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/teacher/planned_exams/{tenant_id}/{section_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.CreatePlannedExamHandler,
+				api.TeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/teacher/planned_exams/{tenant_id}/{section_id}/{planned_exam_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.DeletePlannedExamHandler,
+				api.TeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("DELETE")
+
+	r.HandleFunc("/api/pupil/planned_exams/{tenant_id}/{section_id}/{pupil_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetPupilPlannedExamsHandler,
+				api.TeacherOfSection,
+				api.ParentOfPupil,
+			),
+			[]string{"root", "tenant_admin", "teacher", "parent"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/pupil/planned_exams/{tenant_id}/{section_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetPupilPlannedExamsHandler,
+				api.PupilEnrolledInSection,
+			),
+			[]string{"pupil"},
+		),
+	).Methods("GET")
+}
@@ -10,14 +10,91 @@ import (
 func RegisterGradebookEndpoints(r *mux.Router) {
 	r.HandleFunc("/api/teacher/gradebook_metadata/{tenant_id}/{section_id}",
 		api.AuthMiddleware(
-			api.GradebookMetadataHandler,
+			api.PolicyMiddleware(
+				api.GradebookMetadataHandler,
+				api.TeacherOfSection,
+				api.PupilEnrolledInSection,
+			),
 			[]string{"root", "tenant_admin", "teacher", "pupil"},
 		),
 	).Methods("GET")
 
 	r.HandleFunc("/api/teacher/section_grades_for_subject/{tenant_id}/{section_id}/{subject_code}/{semester_code}",
 		api.AuthMiddleware(
-			api.GetSectionGradesForSubjectHandler,
+			api.PolicyMiddleware(
+				api.GetSectionGradesForSubjectHandler,
+				api.TeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/teacher/section_report/{tenant_id}/{section_id}/{semester_code}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetSectionReportHandler,
+				api.HomeroomTeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/teacher/subject_report/{tenant_id}/{section_id}/{subject_code}/{semester_code}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetSubjectReportHandler,
+				api.TeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/teacher/final_grade_proposals/{tenant_id}/{section_id}/{subject_code}/{semester_code}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetFinalGradeProposalsHandler,
+				api.TeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/teacher/final_grade_proposals/{tenant_id}/{section_id}/{subject_code}/{semester_code}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.AcceptFinalGradesHandler,
+				api.TeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/teacher/descriptive_assessments/{tenant_id}/{section_id}/{subject_code}/{semester_code}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetDescriptiveAssessmentsHandler,
+				api.TeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/teacher/descriptive_assessments/{tenant_id}/{section_id}/{subject_code}/{semester_code}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.SaveDescriptiveAssessmentHandler,
+				api.TeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("PUT")
+
+	r.HandleFunc("/api/teacher/descriptive_assessment_history/{tenant_id}/{section_id}/{descriptive_assessment_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetDescriptiveAssessmentHistoryHandler,
+				api.TeacherOfSection,
+			),
 			[]string{"root", "tenant_admin", "teacher"},
 		),
 	).Methods("GET")
@@ -29,6 +106,16 @@ func RegisterGradebookEndpoints(r *mux.Router) {
 		),
 	).Methods("POST")
 
+	r.HandleFunc("/api/teacher/create_grades_bulk/{tenant_id}/{section_id}/{subject_code}/{semester_code}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.CreateGradesBulkHandler,
+				api.TeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("POST")
+
 	r.HandleFunc("/api/teacher/delete_grade/{tenant_id}",
 		api.AuthMiddleware(
 			api.DeleteGradeHandler,
@@ -45,21 +132,53 @@ func RegisterGradebookEndpoints(r *mux.Router) {
 
 	r.HandleFunc("/api/pupil/section_grades_for_pupil/{tenant_id}/{section_id}/{semester_code}",
 		api.AuthMiddleware(
-			api.GetPupilGradesForSectionPupilHandler,
+			api.PolicyMiddleware(
+				api.GetPupilGradesForSectionPupilHandler,
+				api.TeacherOfSection,
+				api.PupilEnrolledInSection,
+			),
 			[]string{"root", "tenant_admin", "teacher", "pupil"},
 		),
 	).Methods("GET")
 
 	r.HandleFunc("/api/pupil/behaviour_grades/{tenant_id}/{section_id}/{pupil_id}",
 		api.AuthMiddleware(
-			api.GetSectionBehaviourGradesForPupilHandler,
-			[]string{"root", "tenant_admin", "teacher"},
+			api.PolicyMiddleware(
+				api.GetSectionBehaviourGradesForPupilHandler,
+				api.TeacherOfSection,
+				api.ParentOfPupil,
+			),
+			[]string{"root", "tenant_admin", "teacher", "parent"},
 		),
 	).Methods("GET")
 
 	r.HandleFunc("/api/pupil/behaviour_grades/{tenant_id}/{section_id}",
 		api.AuthMiddleware(
-			api.GetSectionBehaviourGradesForPupilNoPupilIDHandler,
+			api.PolicyMiddleware(
+				api.GetSectionBehaviourGradesForPupilNoPupilIDHandler,
+				api.PupilEnrolledInSection,
+			),
+			[]string{"pupil"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/pupil/descriptive_assessments/{tenant_id}/{section_id}/{pupil_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetPupilDescriptiveAssessmentsHandler,
+				api.TeacherOfSection,
+				api.ParentOfPupil,
+			),
+			[]string{"root", "tenant_admin", "teacher", "parent"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/pupil/descriptive_assessments/{tenant_id}/{section_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetPupilDescriptiveAssessmentsHandler,
+				api.PupilEnrolledInSection,
+			),
 			[]string{"pupil"},
 		),
 	).Methods("GET")
@@ -80,7 +199,10 @@ func RegisterGradebookEndpoints(r *mux.Router) {
 
 	r.HandleFunc("/api/teacher/complete_gradebook/{tenant_id}/{section_id}",
 		api.AuthMiddleware(
-			api.GetCompleteGradebookDataHandler,
+			api.PolicyMiddleware(
+				api.GetCompleteGradebookDataHandler,
+				api.TeacherOfSection,
+			),
 			[]string{"root", "tenant_admin", "teacher"},
 		),
 	).Methods("GET")
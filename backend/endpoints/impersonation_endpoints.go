@@ -0,0 +1,32 @@
+package endpoints
+
+import (
+	"ednevnik-backend/api"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterImpersonationEndpoints registers the endpoints for viewing the app
+// as another account
+func RegisterImpersonationEndpoints(r *mux.Router) {
+	r.HandleFunc("/api/superadmin/impersonate/{account_id}",
+		api.AuthMiddleware(
+			api.ImpersonateHandler,
+			[]string{"root"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/superadmin/impersonations",
+		api.AuthMiddleware(
+			api.GetImpersonationsHandler,
+			[]string{"root"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/common/impersonation/end",
+		api.AuthMiddleware(
+			api.EndImpersonationHandler,
+			[]string{"tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("POST")
+}
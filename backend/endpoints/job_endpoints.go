@@ -0,0 +1,32 @@
+package endpoints
+
+import (
+	"ednevnik-backend/api"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterJobEndpoints registers the endpoints for inspecting and retrying
+// background jobs
+func RegisterJobEndpoints(r *mux.Router) {
+	r.HandleFunc("/api/superadmin/jobs",
+		api.AuthMiddleware(
+			api.GetJobsHandler,
+			[]string{"root"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/superadmin/jobs/{job_id}",
+		api.AuthMiddleware(
+			api.GetJobHandler,
+			[]string{"root"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/superadmin/jobs/{job_id}/retry",
+		api.AuthMiddleware(
+			api.RetryJobHandler,
+			[]string{"root"},
+		),
+	).Methods("POST")
+}
@@ -0,0 +1,31 @@
+package endpoints
+
+import (
+	"ednevnik-backend/api"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterLedgerEndpoints function to register endpoints related to the
+// signed gradebook ledger
+func RegisterLedgerEndpoints(r *mux.Router) {
+	r.HandleFunc("/api/teacher/gradebook_ledger/verify/{tenant_id}/{section_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.VerifyGradebookLedgerHandler,
+				api.HomeroomTeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/tenant_admin/gradebook_ledger/seal/{tenant_id}/{section_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.SealGradebookLedgerHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("POST")
+}
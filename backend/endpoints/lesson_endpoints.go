@@ -10,7 +10,10 @@ import (
 func RegisterLessonEndpoints(r *mux.Router) {
 	r.HandleFunc("/api/teacher/get_lessons_for_section/{tenant_id}/{section_id}",
 		api.AuthMiddleware(
-			api.GetLessonsForSectionHandler,
+			api.PolicyMiddleware(
+				api.GetLessonsForSectionHandler,
+				api.TeacherOfSection,
+			),
 			[]string{"root", "tenant_admin", "teacher"},
 		),
 	).Methods("GET")
@@ -38,15 +41,23 @@ func RegisterLessonEndpoints(r *mux.Router) {
 
 	r.HandleFunc("/api/teacher/get_absent_attendances_for_section/{tenant_id}/{section_id}",
 		api.AuthMiddleware(
-			api.GetAbsentAttendancesForSectionHandler,
+			api.PolicyMiddleware(
+				api.GetAbsentAttendancesForSectionHandler,
+				api.TeacherOfSection,
+			),
 			[]string{"root", "tenant_admin", "teacher"},
 		),
 	).Methods("GET")
 
 	r.HandleFunc("/api/pupil/get_absent_attendances_for_pupil/{tenant_id}/{section_id}/{pupil_id}",
 		api.AuthMiddleware(
-			api.GetAbsentAttendancesForPupilHandler,
-			[]string{"root", "tenant_admin", "teacher", "pupil"},
+			api.PolicyMiddleware(
+				api.GetAbsentAttendancesForPupilHandler,
+				api.TeacherOfSection,
+				api.PupilEnrolledInSection,
+				api.ParentOfPupil,
+			),
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
 		),
 	).Methods("GET")
 
@@ -56,4 +67,81 @@ func RegisterLessonEndpoints(r *mux.Router) {
 			[]string{"root", "tenant_admin", "teacher"},
 		),
 	).Methods("POST")
+
+	r.HandleFunc("/api/pupil/attendance_excuse/{tenant_id}/{section_id}/{pupil_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.CreateAttendanceExcuseHandler,
+				api.PupilEnrolledInSection,
+				api.ParentOfPupil,
+			),
+			[]string{"pupil", "parent"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/pupil/attendance_excuses/{tenant_id}/{section_id}/{pupil_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetAttendanceExcusesForPupilHandler,
+				api.HomeroomTeacherOfSection,
+				api.PupilEnrolledInSection,
+				api.ParentOfPupil,
+			),
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/pupil/attendance_excuse_attachment/{tenant_id}/{section_id}/{pupil_id}/{excuse_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetAttendanceExcuseAttachmentHandler,
+				api.HomeroomTeacherOfSection,
+				api.PupilEnrolledInSection,
+				api.ParentOfPupil,
+			),
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/teacher/attendance_excuses/{tenant_id}/{section_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetAttendanceExcusesForSectionHandler,
+				api.HomeroomTeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/teacher/decide_attendance_excuses/{tenant_id}/{section_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.DecideAttendanceExcusesHandler,
+				api.HomeroomTeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/teacher/pedagogical_measures/{tenant_id}/{section_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetPedagogicalMeasuresForSectionHandler,
+				api.HomeroomTeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/pupil/pedagogical_measures/{tenant_id}/{section_id}/{pupil_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetPedagogicalMeasuresForPupilHandler,
+				api.HomeroomTeacherOfSection,
+				api.PupilEnrolledInSection,
+				api.ParentOfPupil,
+			),
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("GET")
 }
@@ -0,0 +1,39 @@
+package endpoints
+
+import (
+	"ednevnik-backend/api"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterOIDCEndpoints registers the endpoints for managing single sign-on
+// providers
+func RegisterOIDCEndpoints(r *mux.Router) {
+	r.HandleFunc("/api/superadmin/oidc_providers",
+		api.AuthMiddleware(
+			api.GetOIDCProvidersHandler,
+			[]string{"root"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/superadmin/oidc_providers",
+		api.AuthMiddleware(
+			api.CreateOIDCProviderHandler,
+			[]string{"root"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/superadmin/oidc_providers/{provider_id}",
+		api.AuthMiddleware(
+			api.UpdateOIDCProviderHandler,
+			[]string{"root"},
+		),
+	).Methods("PUT")
+
+	r.HandleFunc("/api/superadmin/oidc_providers/{provider_id}",
+		api.AuthMiddleware(
+			api.DeleteOIDCProviderHandler,
+			[]string{"root"},
+		),
+	).Methods("DELETE")
+}
@@ -0,0 +1,84 @@
+package endpoints
+
+import (
+	"ednevnik-backend/api"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterParentEndpoints registers parent account endpoints and the read
+// only views parents have of their children
+func RegisterParentEndpoints(r *mux.Router) {
+	r.HandleFunc("/api/common/register_parent", api.RegisterParent).Methods("POST")
+
+	r.HandleFunc("/api/parent/children",
+		api.AuthMiddleware(
+			api.GetParentChildrenHandler,
+			[]string{"parent"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/parent/link_pupil",
+		api.AuthMiddleware(
+			api.LinkPupilToParentHandler,
+			[]string{"parent"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/parent/unlink_pupil/{pupil_id}",
+		api.AuthMiddleware(
+			api.UnlinkPupilFromParentHandler,
+			[]string{"parent"},
+		),
+	).Methods("DELETE")
+
+	r.HandleFunc("/api/parent/gradebook_metadata/{tenant_id}/{section_id}/{pupil_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GradebookMetadataHandler,
+				api.ParentOfPupil,
+			),
+			[]string{"parent"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/parent/section_grades/{tenant_id}/{section_id}/{pupil_id}/{semester_code}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetPupilGradesForSectionPupilHandler,
+				api.ParentOfPupil,
+			),
+			[]string{"parent"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/parent/schedule/{tenant_id}/{section_id}/{pupil_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetScheduleForSectionHandler,
+				api.ParentOfPupil,
+			),
+			[]string{"parent"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/tenant_admin/parent_access_code/{tenant_id}/{pupil_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.RegenerateParentAccessCodeHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/tenant_admin/parent_access_code/{tenant_id}/{pupil_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.RevokeParentAccessCodeHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("DELETE")
+}
@@ -10,7 +10,11 @@ import (
 func RegisterPupilEndpoints(r *mux.Router) {
 	r.HandleFunc("/api/teacher/pupils/{tenant_id}/{section_id}",
 		api.AuthMiddleware(
-			api.GetPupilsForSection, []string{"root", "tenant_admin", "teacher"},
+			api.PolicyMiddleware(
+				api.GetPupilsForSection,
+				api.TeacherOfSection,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
 		),
 	).Methods("GET")
 
@@ -46,7 +50,11 @@ func RegisterPupilEndpoints(r *mux.Router) {
 
 	r.HandleFunc("/api/common/pupil_section_invites/{pupil_id}",
 		api.AuthMiddleware(
-			api.GetPupilSectionInvites,
+			api.PolicyMiddleware(
+				api.GetPupilSectionInvites,
+				api.StaffOfPupilTenant,
+				api.PupilSelf,
+			),
 			[]string{"root", "tenant_admin", "teacher", "pupil"},
 		),
 	).Methods("GET")
@@ -60,8 +68,13 @@ func RegisterPupilEndpoints(r *mux.Router) {
 
 	r.HandleFunc("/api/pupil/sections/{pupil_id}/{archived}",
 		api.AuthMiddleware(
-			api.GetSectionsForPupilHandler,
-			[]string{"root", "tenant_admin", "teacher", "pupil"},
+			api.PolicyMiddleware(
+				api.GetSectionsForPupilHandler,
+				api.StaffOfPupilTenant,
+				api.PupilSelf,
+				api.ParentOfPupil,
+			),
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
 		),
 	).Methods("GET")
 
@@ -91,13 +104,23 @@ func RegisterPupilEndpoints(r *mux.Router) {
 
 	r.HandleFunc("/api/pupil/statistics_fields/{pupil_id}",
 		api.AuthMiddleware(
-			api.GetPupilStatisticsFieldsHandler, []string{"root", "tenant_admin", "teacher", "pupil"},
+			api.PolicyMiddleware(
+				api.GetPupilStatisticsFieldsHandler,
+				api.StaffOfPupilTenant,
+				api.PupilSelf,
+			),
+			[]string{"root", "tenant_admin", "teacher", "pupil"},
 		),
 	).Methods("GET")
 
 	r.HandleFunc("/api/pupil/update_statistics_fields/{pupil_id}",
 		api.AuthMiddleware(
-			api.UpdatePupilStatisticsHandler, []string{"root", "tenant_admin", "teacher", "pupil"},
+			api.PolicyMiddleware(
+				api.UpdatePupilStatisticsHandler,
+				api.StaffOfPupilTenant,
+				api.PupilSelf,
+			),
+			[]string{"root", "tenant_admin", "teacher", "pupil"},
 		),
 	).Methods("PUT")
 }
@@ -0,0 +1,78 @@
+package endpoints
+
+import (
+	"ednevnik-backend/api"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterRemedialEndpoints function to register endpoints related to
+// remedial and class exams
+func RegisterRemedialEndpoints(r *mux.Router) {
+	r.HandleFunc("/api/tenant_admin/remedial_candidates/{tenant_id}/{section_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetRemedialCandidatesHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/tenant_admin/remedial_exams/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetRemedialExamsHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/tenant_admin/remedial_exams/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.CreateRemedialExamHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/tenant_admin/remedial_exams/{tenant_id}/{remedial_exam_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.DeleteRemedialExamHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("DELETE")
+
+	r.HandleFunc("/api/teacher/remedial_exams/{tenant_id}",
+		api.AuthMiddleware(
+			api.GetTeacherRemedialExamsHandler,
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/teacher/remedial_exam/{tenant_id}/{remedial_exam_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetRemedialExamHandler,
+				api.RemedialCommissionMember,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/teacher/remedial_exam_results/{tenant_id}/{remedial_exam_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.RecordRemedialExamResultsHandler,
+				api.RemedialCommissionMember,
+			),
+			[]string{"root", "tenant_admin", "teacher"},
+		),
+	).Methods("PUT")
+}
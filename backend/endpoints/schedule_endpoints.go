@@ -10,14 +10,21 @@ import (
 func RegisterScheduleEndpoints(r *mux.Router) {
 	r.HandleFunc("/api/tenant_admin/schedule_create/{tenant_id}/{section_id}",
 		api.AuthMiddleware(
-			api.CreateScheduleHandler,
+			api.PolicyMiddleware(
+				api.CreateScheduleHandler,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("POST")
 
 	r.HandleFunc("/api/pupil/schedule/{tenant_id}/{section_id}",
 		api.AuthMiddleware(
-			api.GetScheduleForSectionHandler,
+			api.PolicyMiddleware(
+				api.GetScheduleForSectionHandler,
+				api.TeacherOfSection,
+				api.PupilEnrolledInSection,
+			),
 			[]string{"root", "tenant_admin", "teacher", "pupil"},
 		),
 	).Methods("GET")
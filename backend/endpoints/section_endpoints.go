@@ -10,14 +10,20 @@ import (
 func RegisterSectionEndpoints(r *mux.Router) {
 	r.HandleFunc("/api/tenant_admin/section_creation_metadata/{tenant_id}",
 		api.AuthMiddleware(
-			api.GetMetadataForSectionCreation,
+			api.PolicyMiddleware(
+				api.GetMetadataForSectionCreation,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("GET")
 
 	r.HandleFunc("/api/tenant_admin/section_create/{tenant_id}",
 		api.AuthMiddleware(
-			api.CreateSection,
+			api.PolicyMiddleware(
+				api.CreateSection,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("POST")
@@ -31,7 +37,10 @@ func RegisterSectionEndpoints(r *mux.Router) {
 
 	r.HandleFunc("/api/tenant_admin/section/{tenant_id}/{section_id}",
 		api.AuthMiddleware(
-			api.DeleteSection,
+			api.PolicyMiddleware(
+				api.DeleteSection,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("DELETE")
@@ -45,7 +54,10 @@ func RegisterSectionEndpoints(r *mux.Router) {
 
 	r.HandleFunc("/api/teacher/send_section_invite/{tenant_id}/{section_id}",
 		api.AuthMiddleware(
-			api.SendPupilSectionInvite,
+			api.PolicyMiddleware(
+				api.SendPupilSectionInvite,
+				api.HomeroomTeacherOfSection,
+			),
 			[]string{"root", "tenant_admin", "teacher"},
 		),
 	).Methods("POST")
@@ -59,22 +71,51 @@ func RegisterSectionEndpoints(r *mux.Router) {
 
 	r.HandleFunc("/api/teacher/unassign_pupil_from_section/{tenant_id}/{section_id}/{pupil_id}",
 		api.AuthMiddleware(
-			api.UnassignPupilFromSection,
+			api.PolicyMiddleware(
+				api.UnassignPupilFromSection,
+				api.HomeroomTeacherOfSection,
+			),
 			[]string{"root", "tenant_admin", "teacher"},
 		),
 	).Methods("DELETE")
 
 	r.HandleFunc("/api/teacher/archive_section/{tenant_id}/{section_id}",
 		api.AuthMiddleware(
-			api.ArchiveSectionHandler,
+			api.PolicyMiddleware(
+				api.ArchiveSectionHandler,
+				api.HomeroomTeacherOfSection,
+			),
 			[]string{"root", "tenant_admin", "teacher"},
 		),
 	).Methods("POST")
 
 	r.HandleFunc("/api/teacher/unenroll_pupil_from_section/{tenant_id}/{section_id}/{pupil_id}",
 		api.AuthMiddleware(
-			api.UnenrollPupilFromSectionHandler,
+			api.PolicyMiddleware(
+				api.UnenrollPupilFromSectionHandler,
+				api.HomeroomTeacherOfSection,
+			),
 			[]string{"root", "tenant_admin", "teacher"},
 		),
 	).Methods("PUT")
+
+	r.HandleFunc("/api/tenant_admin/year_rollover/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.PreviewYearRolloverHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/tenant_admin/year_rollover/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.CommitYearRolloverHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("POST")
 }
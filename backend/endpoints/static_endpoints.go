@@ -19,28 +19,40 @@ func RegisterStaticEndpoints(r *mux.Router) {
 	// Curriculum endpoints
 	r.HandleFunc("/api/tenant_admin/get_curriculums_for_assignment/{tenant_id}",
 		api.AuthMiddleware(
-			api.GetCurriculumsForTenantAssignment,
+			api.PolicyMiddleware(
+				api.GetCurriculumsForTenantAssignment,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("GET")
 
 	r.HandleFunc("/api/superadmin/assign_curriculums_to_tenant/{tenant_id}",
 		api.AuthMiddleware(
-			api.AssignCurriculumsToTenant,
+			api.PolicyMiddleware(
+				api.AssignCurriculumsToTenant,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("POST")
 
 	r.HandleFunc("/api/tenant_admin/get_curriculums_for_tenant/{tenant_id}",
 		api.AuthMiddleware(
-			api.GetTenantCurriculums,
+			api.PolicyMiddleware(
+				api.GetTenantCurriculums,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("GET")
 
 	r.HandleFunc("/api/superadmin/unassign_curriculum_from_tenant/{tenant_id}/{curriculum_code}",
 		api.AuthMiddleware(
-			api.UnassignCurriculumsFromTenant,
+			api.PolicyMiddleware(
+				api.UnassignCurriculumsFromTenant,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("DELETE")
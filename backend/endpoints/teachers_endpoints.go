@@ -47,7 +47,10 @@ func RegisterTeacherEndpoints(r *mux.Router) {
 	// Teacher assignment routes
 	r.HandleFunc("/api/tenant_admin/teachers_per_tenant/{tenant_id}",
 		api.AuthMiddleware(
-			api.ListTeachersPerTenant,
+			api.PolicyMiddleware(
+				api.ListTeachersPerTenant,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("GET")
@@ -58,7 +61,10 @@ func RegisterTeacherEndpoints(r *mux.Router) {
 	// Teacher section assignment routes
 	r.HandleFunc("/api/tenant_admin/teacher_section_assignments/{tenant_id}/{teacher_id}",
 		api.AuthMiddleware(
-			api.HandleTeacherSectionAssignments,
+			api.PolicyMiddleware(
+				api.HandleTeacherSectionAssignments,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("POST")
@@ -72,7 +78,10 @@ func RegisterTeacherEndpoints(r *mux.Router) {
 
 	r.HandleFunc("/api/tenant_admin/teacher_invites/{tenant_id}",
 		api.AuthMiddleware(
-			api.GetTeacherInvitesForTenantHandler,
+			api.PolicyMiddleware(
+				api.GetTeacherInvitesForTenantHandler,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("GET")
@@ -93,7 +102,10 @@ func RegisterTeacherEndpoints(r *mux.Router) {
 
 	r.HandleFunc("/api/tenant_admin/delete_teacher_from_tenant/{tenant_id}/{teacher_id}",
 		api.AuthMiddleware(
-			api.DeleteTeacherForTenantHandler,
+			api.PolicyMiddleware(
+				api.DeleteTeacherForTenantHandler,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("DELETE")
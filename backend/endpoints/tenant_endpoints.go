@@ -46,7 +46,10 @@ func RegisterTenantEndpoints(r *mux.Router) {
 
 	r.HandleFunc("/api/tenant_admin/tenant_semesters/{tenant_id}",
 		api.AuthMiddleware(
-			api.GetTenantSemesters,
+			api.PolicyMiddleware(
+				api.GetTenantSemesters,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("GET")
@@ -60,8 +63,205 @@ func RegisterTenantEndpoints(r *mux.Router) {
 
 	r.HandleFunc("/api/tenant_admin/teacher_invite_data/{tenant_id}",
 		api.AuthMiddleware(
-			api.GetTeacherInviteDataForTenantHandler,
+			api.PolicyMiddleware(
+				api.GetTeacherInviteDataForTenantHandler,
+				api.TenantAdminOfTenant,
+			),
 			[]string{"root", "tenant_admin"},
 		),
 	).Methods("GET")
+
+	r.HandleFunc("/api/tenant_admin/absence_thresholds/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetAbsenceThresholdsHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/tenant_admin/absence_thresholds/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.UpdateAbsenceThresholdsHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("PUT")
+
+	r.HandleFunc("/api/tenant_admin/grading_schemes/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetGradingSchemesHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/tenant_admin/grading_schemes/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.UpdateGradingSchemesHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("PUT")
+
+	r.HandleFunc("/api/tenant_admin/exam_limits/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetExamLimitsHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/tenant_admin/exam_limits/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.UpdateExamLimitsHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("PUT")
+
+	r.HandleFunc("/api/tenant_admin/descriptive_assessment_classes/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetDescriptiveAssessmentClassesHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/tenant_admin/descriptive_assessment_classes/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.UpdateDescriptiveAssessmentClassesHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("PUT")
+
+	r.HandleFunc("/api/tenant_admin/semester_locks/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetSemesterLocksHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/tenant_admin/semester_locks/{tenant_id}/{semester_code}/lock",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.LockSemesterHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/tenant_admin/semester_locks/{tenant_id}/{semester_code}/unlock",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.UnlockSemesterHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/tenant_admin/locked_accounts/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetLockedAccountsHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/tenant_admin/unlock_account/{tenant_id}/{account_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.UnlockAccountHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/superadmin/locked_accounts",
+		api.AuthMiddleware(
+			api.GetAllLockedAccountsHandler,
+			[]string{"root"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/superadmin/unlock_account/{account_id}",
+		api.AuthMiddleware(
+			api.UnlockAnyAccountHandler,
+			[]string{"root"},
+		),
+	).Methods("POST")
+
+	r.HandleFunc("/api/tenant_admin/two_factor_policy/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetTwoFactorPolicyHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/tenant_admin/two_factor_policy/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.UpdateTwoFactorPolicyHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("PUT")
+
+	r.HandleFunc("/api/tenant_admin/notification_webhook/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.GetTenantWebhookHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("GET")
+
+	r.HandleFunc("/api/tenant_admin/notification_webhook/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.UpdateTenantWebhookHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("PUT")
+
+	r.HandleFunc("/api/tenant_admin/notification_webhook/{tenant_id}",
+		api.AuthMiddleware(
+			api.PolicyMiddleware(
+				api.DeleteTenantWebhookHandler,
+				api.TenantAdminOfTenant,
+			),
+			[]string{"root", "tenant_admin"},
+		),
+	).Methods("DELETE")
 }
@@ -9,4 +9,6 @@ import (
 // RegisterVerificationEndpoints TODO: Add description
 func RegisterVerificationEndpoints(r *mux.Router) {
 	r.HandleFunc("/api/common/verify_account", api.VerifyAccount).Methods("POST")
+	r.HandleFunc("/api/common/forgot_password", api.ForgotPasswordHandler).Methods("POST")
+	r.HandleFunc("/api/common/reset_password", api.ResetPasswordHandler).Methods("POST")
 }
@@ -0,0 +1,43 @@
+// Command mock_idp is a local OpenID Connect provider for trying out single
+// sign-on without a real identity provider. Every email typed into its login
+// form is accepted. Register it as a provider with the issuer URL it prints.
+//
+//	go run ./go_scripts/mock_idp
+//
+// MOCK_IDP_ADDR (default :9998), MOCK_IDP_ISSUER (default
+// http://localhost:9998) and MOCK_IDP_CLIENT_ID (default ednevnik) configure
+// it. It must never run in production.
+package main
+
+import (
+	"ednevnik-backend/oidc/oidctest"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+func main() {
+	addr := envOrDefault("MOCK_IDP_ADDR", ":9998")
+
+	idp, err := oidctest.NewProvider(
+		envOrDefault("MOCK_IDP_ISSUER", "http://localhost:9998"),
+		envOrDefault("MOCK_IDP_CLIENT_ID", "ednevnik"),
+	)
+	if err != nil {
+		slog.Error("Error generating signing key", "error", err)
+		os.Exit(1)
+	}
+
+	slog.Info("Mock IdP running", "issuer", idp.Issuer, "client_id", idp.ClientID)
+	if err := http.ListenAndServe(addr, idp.Handler()); err != nil {
+		slog.Error("Mock IdP stopped", "error", err)
+		os.Exit(1)
+	}
+}
+
+func envOrDefault(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
@@ -0,0 +1,87 @@
+package jobs
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ListJobs returns the latest jobs with the given status, newest first. An
+// empty status returns jobs of every status.
+func ListJobs(status string, limit int, db *sql.DB) ([]Job, error) {
+	query := jobSelectQuery + `
+	WHERE (? = '' OR status = ?)
+	ORDER BY id DESC
+	LIMIT ?`
+
+	rows, err := db.Query(query, status, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching jobs: %v", err)
+	}
+	defer rows.Close()
+
+	jobs := []Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning job: %v", err)
+		}
+		jobs = append(jobs, *job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating jobs: %v", err)
+	}
+
+	return jobs, nil
+}
+
+// CountJobs returns the number of jobs for every status
+func CountJobs(db *sql.DB) (map[string]int, error) {
+	counts := map[string]int{
+		StatusPending:   0,
+		StatusRunning:   0,
+		StatusCompleted: 0,
+		StatusDead:      0,
+	}
+
+	rows, err := db.Query(`SELECT status, COUNT(*) FROM jobs GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("error counting jobs: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("error scanning job count: %v", err)
+		}
+		counts[status] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating job counts: %v", err)
+	}
+
+	return counts, nil
+}
+
+// RetryJob puts a dead job back into the queue with fresh attempts
+func RetryJob(id int, db *sql.DB) error {
+	query := `UPDATE jobs
+	SET status = 'pending', attempts = 0, run_at = NOW()
+	WHERE id = ? AND status = 'dead'`
+	res, err := db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("error retrying job: %v", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error retrying job: %v", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("job not found or not dead")
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package jobs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Job statuses
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusDead      = "dead"
+)
+
+// DefaultMaxAttempts is the number of attempts before a job is dead
+const DefaultMaxAttempts = 5
+
+// Job is a row of the jobs outbox table
+type Job struct {
+	ID          int             `json:"id"`
+	Type        string          `json:"job_type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       string          `json:"run_at"`
+	LockedBy    string          `json:"locked_by,omitempty"`
+	LastError   string          `json:"last_error,omitempty"`
+	CreatedAt   string          `json:"created_at"`
+	CompletedAt string          `json:"completed_at,omitempty"`
+}
+
+// Handler runs a job with its JSON payload. Returning an error schedules a
+// retry, unless the error is wrapped with Permanent.
+type Handler func(payload json.RawMessage) error
+
+// permanentError marks errors that retrying can not fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps an error so the job is moved to the dead jobs right away
+// instead of being retried
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent checks if an error was wrapped with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+var (
+	workspaceDB *sql.DB
+	handlers    = map[string]Handler{}
+	handlersMu  sync.RWMutex
+)
+
+// Setup sets the workspace connection of the outbox. It must have full
+// privileges on the jobs table.
+func Setup(db *sql.DB) {
+	workspaceDB = db
+}
+
+// Register sets the handler of a job type. Handlers are registered before
+// the workers are started.
+func Register(jobType string, handler Handler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	handlers[jobType] = handler
+}
+
+// getHandler returns the handler of a job type
+func getHandler(jobType string) (Handler, bool) {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	handler, ok := handlers[jobType]
+	return handler, ok
+}
+
+// Enqueue adds a job to the outbox. Use EnqueueTx when the job belongs to
+// data written in a workspace transaction.
+func Enqueue(jobType string, payload interface{}) (int64, error) {
+	if workspaceDB == nil {
+		return 0, fmt.Errorf("jobs workspace DB is not set")
+	}
+	return insertJob(workspaceDB, jobType, payload)
+}
+
+// EnqueueTx adds a job to the outbox in the given workspace transaction, so
+// the job is only stored if the transaction commits
+func EnqueueTx(tx *sql.Tx, jobType string, payload interface{}) (int64, error) {
+	return insertJob(tx, jobType, payload)
+}
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertJob inserts a pending job with the payload encoded as JSON
+func insertJob(db execer, jobType string, payload interface{}) (int64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("error encoding job payload: %v", err)
+	}
+
+	query := `INSERT INTO jobs (job_type, payload, max_attempts) VALUES (?, ?, ?)`
+	res, err := db.Exec(query, jobType, string(data), DefaultMaxAttempts)
+	if err != nil {
+		return 0, fmt.Errorf("error enqueueing job %s: %v", jobType, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting job ID: %v", err)
+	}
+	return id, nil
+}
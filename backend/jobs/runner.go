@@ -0,0 +1,259 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// pollInterval is how often idle workers look for due jobs
+	pollInterval = 2 * time.Second
+	// leaseTimeout is after how long a running job is considered abandoned,
+	// e.g. because the server restarted while running it
+	leaseTimeout = 15 * time.Minute
+	// baseBackoff is the delay before the first retry, it doubles with every
+	// attempt up to maxBackoff
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 1 * time.Hour
+	// claimBatch is the number of candidates a worker tries to claim
+	claimBatch = 10
+)
+
+// Start runs the reaper and the workers until the context is cancelled.
+// The number of workers is read from JOB_WORKERS and defaults to 2.
+func Start(ctx context.Context) {
+	if workspaceDB == nil {
+		log.Println("jobs workspace DB is not set, background jobs are disabled")
+		return
+	}
+
+	workers, err := strconv.Atoi(os.Getenv("JOB_WORKERS"))
+	if err != nil || workers < 1 {
+		workers = 2
+	}
+
+	hostname, _ := os.Hostname()
+	go reap(ctx)
+	for i := 1; i <= workers; i++ {
+		go work(ctx, fmt.Sprintf("%s:%d:%d", hostname, os.Getpid(), i))
+	}
+}
+
+// reap puts abandoned running jobs back into the queue, once on start and
+// then every lease timeout
+func reap(ctx context.Context) {
+	ticker := time.NewTicker(leaseTimeout)
+	defer ticker.Stop()
+	for {
+		if err := releaseAbandonedJobs(); err != nil {
+			log.Printf("error releasing abandoned jobs: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// work claims and runs due jobs until the context is cancelled
+func work(ctx context.Context, workerID string) {
+	for {
+		job, err := claimJob(workerID)
+		if err != nil {
+			log.Printf("worker %s failed to claim a job: %v", workerID, err)
+		}
+		if job != nil {
+			runJob(*job, workerID)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// claimJob marks the first due pending job as running for the worker. The
+// status check in the update makes sure only one worker gets a job, even
+// with several server instances. It returns nil if no job is due.
+func claimJob(workerID string) (*Job, error) {
+	query := `SELECT id FROM jobs
+	WHERE status = 'pending' AND run_at <= NOW()
+	ORDER BY run_at, id
+	LIMIT ?`
+	rows, err := workspaceDB.Query(query, claimBatch)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching due jobs: %v", err)
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning job ID: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating due jobs: %v", err)
+	}
+
+	claimQuery := `UPDATE jobs
+	SET status = 'running', attempts = attempts + 1, locked_by = ?, locked_at = NOW()
+	WHERE id = ? AND status = 'pending'`
+	for _, id := range ids {
+		res, err := workspaceDB.Exec(claimQuery, workerID, id)
+		if err != nil {
+			return nil, fmt.Errorf("error claiming job %d: %v", id, err)
+		}
+		claimed, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("error claiming job %d: %v", id, err)
+		}
+		if claimed == 1 {
+			return GetJob(id, workspaceDB)
+		}
+	}
+
+	return nil, nil
+}
+
+// runJob runs the handler of a claimed job and records the outcome
+func runJob(job Job, workerID string) {
+	err := callHandler(job)
+	if err == nil {
+		query := `UPDATE jobs
+		SET status = 'completed', completed_at = NOW(), locked_by = NULL,
+		locked_at = NULL, last_error = NULL
+		WHERE id = ? AND locked_by = ?`
+		if _, err := workspaceDB.Exec(query, job.ID, workerID); err != nil {
+			log.Printf("error completing job %d: %v", job.ID, err)
+		}
+		return
+	}
+
+	if IsPermanent(err) || job.Attempts >= job.MaxAttempts {
+		log.Printf("job %d (%s) is dead after %d attempts: %v",
+			job.ID, job.Type, job.Attempts, err)
+		query := `UPDATE jobs
+		SET status = 'dead', last_error = ?, locked_by = NULL, locked_at = NULL
+		WHERE id = ? AND locked_by = ?`
+		if _, err := workspaceDB.Exec(query, err.Error(), job.ID, workerID); err != nil {
+			log.Printf("error marking job %d as dead: %v", job.ID, err)
+		}
+		return
+	}
+
+	delay := backoff(job.Attempts)
+	log.Printf("job %d (%s) failed, retrying in %s: %v", job.ID, job.Type, delay, err)
+	query := `UPDATE jobs
+	SET status = 'pending', last_error = ?, locked_by = NULL, locked_at = NULL,
+	run_at = NOW() + INTERVAL ? SECOND
+	WHERE id = ? AND locked_by = ?`
+	_, dbErr := workspaceDB.Exec(
+		query, err.Error(), int(delay.Seconds()), job.ID, workerID,
+	)
+	if dbErr != nil {
+		log.Printf("error scheduling retry of job %d: %v", job.ID, dbErr)
+	}
+}
+
+// callHandler runs the handler of a job, turning panics into errors so a
+// broken job can not stop the worker
+func callHandler(job Job) (err error) {
+	handler, ok := getHandler(job.Type)
+	if !ok {
+		return Permanent(fmt.Errorf("no handler registered for job type %s", job.Type))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(job.Payload)
+}
+
+// backoff returns the delay before the next attempt, doubling with every
+// attempt
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
+
+// releaseAbandonedJobs puts jobs that have been running longer than the
+// lease timeout back into the queue, or moves them to the dead jobs if they
+// have no attempts left
+func releaseAbandonedJobs() error {
+	query := `UPDATE jobs
+	SET status = IF(attempts >= max_attempts, 'dead', 'pending'),
+	last_error = COALESCE(last_error, 'worker stopped while running the job'),
+	locked_by = NULL, locked_at = NULL
+	WHERE status = 'running' AND locked_at < NOW() - INTERVAL ? SECOND`
+	_, err := workspaceDB.Exec(query, int(leaseTimeout.Seconds()))
+	return err
+}
+
+// GetJob returns a job by its ID
+func GetJob(id int, db *sql.DB) (*Job, error) {
+	query := jobSelectQuery + ` WHERE id = ?`
+	job, err := scanJob(db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("job not found")
+		}
+		return nil, fmt.Errorf("error fetching job: %v", err)
+	}
+	return job, nil
+}
+
+// jobSelectQuery selects the columns read by scanJob
+const jobSelectQuery = `SELECT id, job_type, payload, status, attempts,
+max_attempts, run_at, COALESCE(locked_by, ''), COALESCE(last_error, ''),
+created_at, COALESCE(completed_at, '')
+FROM jobs`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanJob scans a row selected with jobSelectQuery
+func scanJob(row rowScanner) (*Job, error) {
+	var job Job
+	var payload string
+	err := row.Scan(
+		&job.ID,
+		&job.Type,
+		&payload,
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.RunAt,
+		&job.LockedBy,
+		&job.LastError,
+		&job.CreatedAt,
+		&job.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	job.Payload = []byte(payload)
+	return &job, nil
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"ednevnik-backend/api"
 	"ednevnik-backend/endpoints"
+	"ednevnik-backend/jobs"
+	"ednevnik-backend/notification"
+	"ednevnik-backend/tenantfactory"
 	"ednevnik-backend/util"
 	"fmt"
 	"log"
@@ -27,6 +31,18 @@ func main() {
 	}
 
 	api.JwtKey = []byte(os.Getenv("JWT_SECRET"))
+	util.TrustedProxies, err = util.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	util.TwoFactorKey = []byte(os.Getenv("TWO_FACTOR_SECRET"))
+	if len(util.TwoFactorKey) == 0 {
+		log.Fatal("TWO_FACTOR_SECRET must be set")
+	}
+	util.LedgerKey = []byte(os.Getenv("LEDGER_SECRET"))
+	if len(util.LedgerKey) == 0 {
+		log.Fatal("LEDGER_SECRET must be set")
+	}
 
 	workspaceCS := util.BuildDBConnectionString("ednevnik_workspace")
 
@@ -38,17 +54,50 @@ func main() {
 
 	api.DbWorkspace = dbWorkspace
 
+	// Background jobs, handlers are registered before the workers start
+	jobs.Setup(dbWorkspace)
+	notification.Setup(dbWorkspace)
+	tenantfactory.RegisterJobHandlers()
+	jobs.Start(context.Background())
+
 	r := mux.NewRouter()
 
 	r.Use(api.UserWorkspaceDBMiddleware)
+	r.Use(api.AuditMiddleware)
+	r.Use(api.ImpersonationMiddleware)
 
 	// CORS setup
 	corsAllowedOrigins := handlers.AllowedOrigins([]string{os.Getenv("FRONTEND_URL")})
 	corsAllowedMethods := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
 	corsAllowedHeaders := handlers.AllowedHeaders([]string{"Authorization", "Content-Type"})
+	corsExposedHeaders := handlers.ExposedHeaders([]string{"X-Token-Stale"})
 
 	r.HandleFunc("/login", api.Login).Methods("POST")
 	r.HandleFunc("/parent-login", api.ParentLogin).Methods("POST")
+	r.HandleFunc("/login/2fa", api.LoginSecondFactor).Methods("POST")
+	r.HandleFunc("/login/2fa/setup", api.LoginSecondFactorSetup).Methods("POST")
+	r.HandleFunc("/login/2fa/confirm", api.LoginSecondFactorConfirm).Methods("POST")
+	r.HandleFunc("/login/oidc/start", api.OIDCStartHandler).Methods("GET")
+	r.HandleFunc("/login/oidc/callback", api.OIDCCallbackHandler).Methods("POST")
+	r.HandleFunc("/refresh", api.RefreshToken).Methods("POST")
+	r.HandleFunc("/logout",
+		api.AuthMiddleware(
+			api.Logout,
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("POST")
+	r.HandleFunc("/reissue-token",
+		api.AuthMiddleware(
+			api.ReissueToken,
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("POST")
+	r.HandleFunc("/logout-all",
+		api.AuthMiddleware(
+			api.LogoutAllDevices,
+			[]string{"root", "tenant_admin", "teacher", "pupil", "parent"},
+		),
+	).Methods("POST")
 
 	endpoints.RegisterTeacherEndpoints(r)
 	endpoints.RegisterTenantEndpoints(r)
@@ -62,9 +111,17 @@ func main() {
 	endpoints.RegisterClassroomEndpoints(r)
 	endpoints.RegisterLessonEndpoints(r)
 	endpoints.RegisterGradebookEndpoints(r)
+	endpoints.RegisterExamEndpoints(r)
+	endpoints.RegisterRemedialEndpoints(r)
+	endpoints.RegisterLedgerEndpoints(r)
 	endpoints.RegisterCertificateEndpoints(r)
 	endpoints.RegisterCommonEndpoints(r)
+	endpoints.RegisterParentEndpoints(r)
+	endpoints.RegisterJobEndpoints(r)
+	endpoints.RegisterAuditEndpoints(r)
+	endpoints.RegisterImpersonationEndpoints(r)
+	endpoints.RegisterOIDCEndpoints(r)
 
 	fmt.Println("Server started at :8080")
-	log.Fatal(http.ListenAndServe(":8080", handlers.CORS(corsAllowedOrigins, corsAllowedMethods, corsAllowedHeaders)(r)))
+	log.Fatal(http.ListenAndServe(":8080", handlers.CORS(corsAllowedOrigins, corsAllowedMethods, corsAllowedHeaders, corsExposedHeaders)(r)))
 }
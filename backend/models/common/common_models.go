@@ -53,6 +53,14 @@ type Certificate struct {
 	AverageGrade   float64                     `json:"average_grade,omitempty"`
 	GraduateGrade  int                         `json:"graduate_grade,omitempty"`
 	Passed         bool                        `json:"passed"`
+	// Weighted averages of the grades per subject code in the last semester
+	SubjectAverages map[string]float64 `json:"subject_averages,omitempty"`
+	// Descriptive assessments of the last semester, for descriptively
+	// assessed classes
+	DescriptiveAssessments []tenantmodels.DescriptiveAssessment `json:"descriptive_assessments,omitempty"`
+	// Remedial and class exams the pupil took, the final grades already
+	// include their outcome
+	RemedialExams []tenantmodels.RemedialExamResult `json:"remedial_exams,omitempty"`
 	// Just for secondary schools
 	CourseName string `json:"course_name,omitempty"`
 }
@@ -0,0 +1,27 @@
+package tenantmodels
+
+// DescriptiveAssessment is the narrative assessment of a pupil in a subject
+// and semester, given instead of numeric grades in the classes the tenant
+// assesses descriptively
+type DescriptiveAssessment struct {
+	ID              int    `json:"id"`
+	PupilID         int    `json:"pupil_id"`
+	SectionID       int    `json:"section_id"`
+	SubjectCode     string `json:"subject_code"`
+	SemesterCode    string `json:"semester_code"`
+	Achievements    string `json:"achievements"`
+	Effort          string `json:"effort"`
+	Recommendations string `json:"recommendations"`
+	TeacherID       int    `json:"teacher_id,omitempty"`
+	Signature       string `json:"signature"`
+	ValidUntil      string `json:"valid_until,omitempty"`
+	// Optional fields
+	SubjectName string `json:"subject_name,omitempty"`
+}
+
+// DescriptiveAssessmentPupilGroup is a pupil of a section with the
+// descriptive assessment in a subject, when one was given
+type DescriptiveAssessmentPupilGroup struct {
+	Pupil      Pupil                  `json:"pupil"`
+	Assessment *DescriptiveAssessment `json:"assessment,omitempty"`
+}
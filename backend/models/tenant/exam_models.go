@@ -0,0 +1,25 @@
+package tenantmodels
+
+// PlannedExam is a written exam or assignment a teacher booked in the exam
+// calendar of a section
+type PlannedExam struct {
+	ID           int    `json:"id"`
+	SectionID    int    `json:"section_id"`
+	SubjectCode  string `json:"subject_code"`
+	SemesterCode string `json:"semester_code"`
+	ExamDate     string `json:"exam_date"`
+	Type         string `json:"type"`
+	Description  string `json:"description"`
+	TeacherID    int    `json:"teacher_id,omitempty"`
+	Signature    string `json:"signature"`
+	CreatedAt    string `json:"created_at,omitempty"`
+	// Optional fields
+	SubjectName string `json:"subject_name,omitempty"`
+}
+
+// ExamLimits caps how many exams a section can have booked on one day and in
+// one calendar week
+type ExamLimits struct {
+	MaxPerDay  int `json:"max_per_day"`
+	MaxPerWeek int `json:"max_per_week"`
+}
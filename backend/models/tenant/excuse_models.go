@@ -0,0 +1,46 @@
+package tenantmodels
+
+// AttendanceExcuse is a justification for absences submitted by a pupil or
+// one of their parents. Status can be: pending, approved, rejected.
+type AttendanceExcuse struct {
+	ID                   int                      `json:"id"`
+	PupilID              int                      `json:"pupil_id"`
+	Name                 string                   `json:"name,omitempty"`
+	LastName             string                   `json:"last_name,omitempty"`
+	SectionID            int                      `json:"section_id"`
+	DateFrom             string                   `json:"date_from"`
+	DateTo               string                   `json:"date_to"`
+	Reason               string                   `json:"reason"`
+	AttachmentName       string                   `json:"attachment_name,omitempty"`
+	AttachmentType       string                   `json:"attachment_type,omitempty"`
+	Attachment           []byte                   `json:"attachment,omitempty"`
+	SubmittedByAccountID int                      `json:"submitted_by_account_id"`
+	SubmittedByType      string                   `json:"submitted_by_type"`
+	SubmittedAt          string                   `json:"submitted_at"`
+	Status               string                   `json:"status"`
+	DecidedBy            string                   `json:"decided_by,omitempty"`
+	DecidedAt            string                   `json:"decided_at,omitempty"`
+	DecisionNote         string                   `json:"decision_note,omitempty"`
+	Lessons              []AttendanceExcuseLesson `json:"lessons"`
+}
+
+// AttendanceExcuseLesson records an absence covered by an excuse. Before the
+// decision these are the absences in the date range, afterwards the status
+// change that the decision applied.
+type AttendanceExcuseLesson struct {
+	LessonID       int    `json:"lesson_id"`
+	Date           string `json:"date"`
+	PeriodNumber   int    `json:"period_number"`
+	SubjectName    string `json:"subject_name"`
+	PreviousStatus string `json:"previous_status,omitempty"`
+	Status         string `json:"status"`
+}
+
+// AttendanceExcuseDecision is the request body used by the homeroom teacher to
+// approve or reject several excuses at once. Decision can be: approved,
+// rejected.
+type AttendanceExcuseDecision struct {
+	ExcuseIDs []int  `json:"excuse_ids"`
+	Decision  string `json:"decision"`
+	Note      string `json:"note"`
+}
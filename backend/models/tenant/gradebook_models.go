@@ -47,7 +47,7 @@ type BehaviourGrade struct {
 }
 
 // CompleteGradebook contains all gradebook data including pupils, schedules, grades,
-// behaviour grades and lessons
+// behaviour grades, lessons and pedagogical measures
 type CompleteGradebook struct {
 	Pupils          []Pupil                   `json:"pupils"`
 	ScheduleHistory []ScheduleGroupCollection `json:"schedule_history"`
@@ -55,13 +55,16 @@ type CompleteGradebook struct {
 	Subjects        []wpmodels.Subject        `json:"subjects"`
 	Lessons         []LessonWeekGroup         `json:"lessons"`
 	Absences        []WeekAbsenceGroup        `json:"absences"`
+	Measures        []PedagogicalMeasure      `json:"pedagogical_measures"`
+	GradingSchemes  []GradingScheme           `json:"grading_schemes"`
 }
 
 // SubjectGradeGroup represents a group of grades for a specific subject
 type SubjectGradeGroup struct {
-	SubjectName string  `json:"subject_name"`
-	SubjectCode string  `json:"subject_code"`
-	Grades      []Grade `json:"grades"`
+	SubjectName  string  `json:"subject_name"`
+	SubjectCode  string  `json:"subject_code"`
+	AverageGrade float64 `json:"average_grade,omitempty"`
+	Grades       []Grade `json:"grades"`
 }
 
 // SemesterGradeGroup represents a group of grades for a specific semester
@@ -77,3 +80,29 @@ type CompleteGradebookData struct {
 	PupilUnenrolled   bool                 `json:"pupil_unenrolled"`
 	GradesForSemester []SemesterGradeGroup `json:"grades_for_semester"`
 }
+
+// BulkGradeEntry is one grade of a bulk entry for a section, subject and
+// semester
+type BulkGradeEntry struct {
+	PupilID   int    `json:"pupil_id"`
+	Grade     int    `json:"grade"`
+	Type      string `json:"type"`
+	GradeDate string `json:"grade_date"`
+}
+
+// BulkGradeError is the error of an entry of a bulk grade entry, Index is the
+// position of the entry in the request
+type BulkGradeError struct {
+	Index   int    `json:"index"`
+	PupilID int    `json:"pupil_id"`
+	Error   string `json:"error"`
+}
+
+// BulkGradesResult is the outcome of a bulk grade entry. Grades are only saved
+// when no entry has an error, Grades then holds the refreshed grades of the
+// section.
+type BulkGradesResult struct {
+	Created int               `json:"created"`
+	Errors  []BulkGradeError  `json:"errors"`
+	Grades  []GradePupilGroup `json:"grades,omitempty"`
+}
@@ -0,0 +1,65 @@
+package tenantmodels
+
+// GradingScheme sets how the grades of a subject are averaged. Each grade
+// type counts with its weight, a weight of zero leaves the type out. The
+// scheme without a subject code is the default of the tenant.
+//
+// An average rounds up to the next grade from RoundUpFrom (e.g. 3.50 with
+// 0.50), averages within BorderlineMargin of that point are borderline.
+type GradingScheme struct {
+	ID                      int     `json:"id,omitempty"`
+	SubjectCode             string  `json:"subject_code,omitempty"`
+	ExamWeight              float64 `json:"exam_weight"`
+	OralWeight              float64 `json:"oral_weight"`
+	WrittenAssignmentWeight float64 `json:"written_assignment_weight"`
+	DropLowest              bool    `json:"drop_lowest"`
+	RoundUpFrom             float64 `json:"round_up_from"`
+	BorderlineMargin        float64 `json:"borderline_margin"`
+}
+
+// Weight returns the weight of a grade type, final grades are never averaged
+func (s GradingScheme) Weight(gradeType string) float64 {
+	switch gradeType {
+	case "exam":
+		return s.ExamWeight
+	case "oral":
+		return s.OralWeight
+	case "written_assignment":
+		return s.WrittenAssignmentWeight
+	default:
+		return 0
+	}
+}
+
+// FinalGradeProposal is the final grade proposed for a pupil from the regular
+// grades of a subject in a semester. Borderline proposals are decided by the
+// trend of the grades, AlternativeGrade is the other possible grade.
+type FinalGradeProposal struct {
+	Pupil              Pupil   `json:"pupil"`
+	AverageGrade       float64 `json:"average_grade"`
+	GradeCount         int     `json:"grade_count"`
+	Trend              string  `json:"trend,omitempty"`
+	ProposedGrade      int     `json:"proposed_grade,omitempty"`
+	Borderline         bool    `json:"borderline"`
+	AlternativeGrade   int     `json:"alternative_grade,omitempty"`
+	ExistingFinalGrade int     `json:"existing_final_grade,omitempty"`
+}
+
+// AcceptedFinalGrade is a final grade the teacher accepted for a pupil
+type AcceptedFinalGrade struct {
+	PupilID int `json:"pupil_id"`
+	Grade   int `json:"grade"`
+}
+
+// SkippedFinalGrade is an accepted final grade that was not saved
+type SkippedFinalGrade struct {
+	PupilID int    `json:"pupil_id"`
+	Reason  string `json:"reason"`
+}
+
+// AcceptedFinalGradesResult lists the final grades saved in bulk and the ones
+// that were skipped
+type AcceptedFinalGradesResult struct {
+	Created []Grade             `json:"created"`
+	Skipped []SkippedFinalGrade `json:"skipped"`
+}
@@ -0,0 +1,23 @@
+package tenantmodels
+
+// LedgerIssue is a problem found while verifying the gradebook ledger of a
+// section. Sequence is set for issues of the ledger itself, EntityID for
+// issues of a grade, lesson or behaviour row.
+type LedgerIssue struct {
+	Issue    string `json:"issue"`
+	Entity   string `json:"entity,omitempty"`
+	EntityID int    `json:"entity_id,omitempty"`
+	Sequence int    `json:"sequence,omitempty"`
+}
+
+// LedgerVerification is the outcome of verifying the gradebook ledger of a
+// section against its grades, lessons and behaviour grades
+type LedgerVerification struct {
+	SectionID   int           `json:"section_id"`
+	Entries     int           `json:"entries"`
+	CheckedRows int           `json:"checked_rows"`
+	Valid       bool          `json:"valid"`
+	Issues      []LedgerIssue `json:"issues"`
+	// Optional fields
+	Sealed int `json:"sealed,omitempty"`
+}
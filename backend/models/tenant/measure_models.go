@@ -0,0 +1,25 @@
+package tenantmodels
+
+// AbsenceThreshold is the number of unexcused hours in a semester after which
+// a pedagogical measure is issued. BehaviourCap is the best behaviour grade a
+// pupil with the measure can have, empty if the measure does not affect it.
+type AbsenceThreshold struct {
+	ID             int    `json:"id,omitempty"`
+	Measure        string `json:"measure"`
+	UnexcusedHours int    `json:"unexcused_hours"`
+	BehaviourCap   string `json:"behaviour_cap,omitempty"`
+}
+
+// PedagogicalMeasure is a measure (opomena, ukor) issued to a pupil for the
+// unexcused hours accumulated in a semester
+type PedagogicalMeasure struct {
+	ID             int    `json:"id"`
+	PupilID        int    `json:"pupil_id"`
+	Name           string `json:"name,omitempty"`
+	LastName       string `json:"last_name,omitempty"`
+	SectionID      int    `json:"section_id"`
+	SemesterCode   string `json:"semester_code"`
+	Measure        string `json:"measure"`
+	UnexcusedHours int    `json:"unexcused_hours"`
+	IssuedAt       string `json:"issued_at"`
+}
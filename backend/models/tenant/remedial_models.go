@@ -0,0 +1,77 @@
+package tenantmodels
+
+// RemedialCandidate is a pupil that has to take a remedial exam in a subject.
+// Pupils failing the subject take a remedial exam, pupils that could not be
+// graded take a class exam.
+type RemedialCandidate struct {
+	PupilID     int    `json:"pupil_id"`
+	Name        string `json:"name"`
+	LastName    string `json:"last_name"`
+	SubjectCode string `json:"subject_code"`
+	SubjectName string `json:"subject_name"`
+	FinalGrade  int    `json:"final_grade,omitempty"`
+	ExamType    string `json:"exam_type"`
+	// Optional fields
+	PendingExamID int `json:"pending_exam_id,omitempty"`
+}
+
+// RemedialCommissionMember is a teacher on the commission of a remedial exam
+type RemedialCommissionMember struct {
+	TeacherID int    `json:"teacher_id"`
+	Role      string `json:"role"`
+	// Optional fields
+	Name string `json:"name,omitempty"`
+}
+
+// RemedialExamResult is the outcome of a remedial exam for a pupil. The grade
+// is empty until the commission records it.
+type RemedialExamResult struct {
+	ID             int    `json:"id"`
+	RemedialExamID int    `json:"remedial_exam_id"`
+	PupilID        int    `json:"pupil_id"`
+	Name           string `json:"name,omitempty"`
+	LastName       string `json:"last_name,omitempty"`
+	PreviousGrade  int    `json:"previous_grade,omitempty"`
+	Grade          int    `json:"grade,omitempty"`
+	Attended       *bool  `json:"attended,omitempty"`
+	FinalGradeID   int    `json:"final_grade_id,omitempty"`
+	Signature      string `json:"signature,omitempty"`
+	RecordedAt     string `json:"recorded_at,omitempty"`
+	// Optional fields for the certificate
+	SubjectCode string `json:"subject_code,omitempty"`
+	SubjectName string `json:"subject_name,omitempty"`
+	ExamType    string `json:"exam_type,omitempty"`
+	ExamDate    string `json:"exam_date,omitempty"`
+}
+
+// RemedialExam is a remedial or class exam session of a section in a subject
+type RemedialExam struct {
+	ID                 int                        `json:"id"`
+	SectionID          int                        `json:"section_id"`
+	SubjectCode        string                     `json:"subject_code"`
+	SubjectName        string                     `json:"subject_name"`
+	SemesterCode       string                     `json:"semester_code"`
+	Type               string                     `json:"type"`
+	ExamDate           string                     `json:"exam_date"`
+	CreatedByAccountID int                        `json:"created_by_account_id,omitempty"`
+	CreatedAt          string                     `json:"created_at,omitempty"`
+	Commission         []RemedialCommissionMember `json:"commission"`
+	Results            []RemedialExamResult       `json:"results"`
+}
+
+// RemedialExamRequest schedules a remedial exam for pupils of a section
+type RemedialExamRequest struct {
+	SectionID   int                        `json:"section_id"`
+	SubjectCode string                     `json:"subject_code"`
+	Type        string                     `json:"type"`
+	ExamDate    string                     `json:"exam_date"`
+	Commission  []RemedialCommissionMember `json:"commission"`
+	PupilIDs    []int                      `json:"pupil_ids"`
+}
+
+// RemedialResultEntry is the result of a pupil the commission records
+type RemedialResultEntry struct {
+	PupilID  int  `json:"pupil_id"`
+	Attended bool `json:"attended"`
+	Grade    int  `json:"grade,omitempty"`
+}
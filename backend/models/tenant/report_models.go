@@ -0,0 +1,70 @@
+package tenantmodels
+
+// AbsenceTotals counts the absent hours of pupils. Pending hours are
+// absences the homeroom teacher has not excused or left unexcused yet.
+type AbsenceTotals struct {
+	Excused   int `json:"excused"`
+	Unexcused int `json:"unexcused"`
+	Pending   int `json:"pending"`
+}
+
+// SubjectPupilReport is the standing of a pupil in a subject. The subject
+// grade is the final grade when it was given, otherwise the proposed one.
+type SubjectPupilReport struct {
+	PupilID      int     `json:"pupil_id"`
+	Name         string  `json:"name"`
+	LastName     string  `json:"last_name"`
+	GradeCount   int     `json:"grade_count"`
+	AverageGrade float64 `json:"average_grade"`
+	SubjectGrade int     `json:"subject_grade,omitempty"`
+	Final        bool    `json:"final"`
+}
+
+// SubjectReport sums up the grades of a section in a subject and semester.
+// The distribution counts the regular grades by value, passed and failed
+// count pupils by their subject grade.
+type SubjectReport struct {
+	SubjectCode       string      `json:"subject_code"`
+	SubjectName       string      `json:"subject_name"`
+	GradeDistribution map[int]int `json:"grade_distribution"`
+	GradeCount        int         `json:"grade_count"`
+	AverageGrade      float64     `json:"average_grade"`
+	Passed            int         `json:"passed"`
+	Failed            int         `json:"failed"`
+	Ungraded          int         `json:"ungraded"`
+	FinalGrades       int         `json:"final_grades"`
+	// Optional fields
+	Pupils []SubjectPupilReport `json:"pupils,omitempty"`
+}
+
+// PupilReport sums up the semester of a pupil in a section
+type PupilReport struct {
+	PupilID         int           `json:"pupil_id"`
+	Name            string        `json:"name"`
+	LastName        string        `json:"last_name"`
+	AverageGrade    float64       `json:"average_grade"`
+	FailingSubjects []string      `json:"failing_subjects"`
+	Absences        AbsenceTotals `json:"absences"`
+	Behaviour       string        `json:"behaviour,omitempty"`
+}
+
+// FailingGradeGroups lists the pupils by the number of subjects they fail
+type FailingGradeGroups struct {
+	One         []int `json:"one"`
+	Two         []int `json:"two"`
+	ThreeOrMore []int `json:"three_or_more"`
+}
+
+// SectionReport is the success report of a section in a semester
+type SectionReport struct {
+	SectionID             int64              `json:"section_id"`
+	SemesterCode          string             `json:"semester_code"`
+	PupilCount            int                `json:"pupil_count"`
+	AverageGrade          float64            `json:"average_grade"`
+	Passed                int                `json:"passed"`
+	FailingPupils         FailingGradeGroups `json:"failing_pupils"`
+	Absences              AbsenceTotals      `json:"absences"`
+	BehaviourDistribution map[string]int     `json:"behaviour_distribution"`
+	Subjects              []SubjectReport    `json:"subjects"`
+	Pupils                []PupilReport      `json:"pupils"`
+}
@@ -0,0 +1,60 @@
+package tenantmodels
+
+// RolloverRequest starts the rollover of the sections of a school year into
+// the next one
+type RolloverRequest struct {
+	FromYear              string `json:"from_year"`
+	CarryHomeroomTeachers bool   `json:"carry_homeroom_teachers"`
+	CarrySubjectTeachers  bool   `json:"carry_subject_teachers"`
+}
+
+// RolloverPupil is a pupil moved into a section of the next school year.
+// Repeating pupils stay in the class level of the previous year.
+type RolloverPupil struct {
+	PupilID       int    `json:"pupil_id"`
+	Name          string `json:"name"`
+	LastName      string `json:"last_name"`
+	FromSectionID int64  `json:"from_section_id"`
+	Repeating     bool   `json:"repeating"`
+}
+
+// RolloverSubjectTeacher is a subject assignment carried over into a section
+// of the next school year
+type RolloverSubjectTeacher struct {
+	TeacherID   int    `json:"teacher_id"`
+	SubjectCode string `json:"subject_code"`
+}
+
+// RolloverSection is a section of the next school year. Sections that do not
+// exist yet are created when the rollover is committed.
+type RolloverSection struct {
+	SectionID         int64                    `json:"section_id,omitempty"`
+	SectionCode       string                   `json:"section_code"`
+	ClassCode         string                   `json:"class_code"`
+	Year              string                   `json:"year"`
+	CurriculumCode    string                   `json:"curriculum_code"`
+	Exists            bool                     `json:"exists"`
+	FromSectionID     int64                    `json:"from_section_id,omitempty"`
+	HomeroomTeacherID int                      `json:"homeroom_teacher_id,omitempty"`
+	SubjectTeachers   []RolloverSubjectTeacher `json:"subject_teachers"`
+	Pupils            []RolloverPupil          `json:"pupils"`
+}
+
+// RolloverSkip explains why a section or a pupil of it is left out of the
+// rollover
+type RolloverSkip struct {
+	SectionID int64  `json:"section_id"`
+	PupilID   int    `json:"pupil_id,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+// RolloverPlan is the outcome of a school year rollover. A dry run only
+// proposes it, a committed plan carries the IDs of the created sections.
+type RolloverPlan struct {
+	FromYear  string            `json:"from_year"`
+	ToYear    string            `json:"to_year"`
+	DryRun    bool              `json:"dry_run"`
+	Sections  []RolloverSection `json:"sections"`
+	Graduated []RolloverPupil   `json:"graduated"`
+	Skipped   []RolloverSkip    `json:"skipped"`
+}
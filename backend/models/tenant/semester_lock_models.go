@@ -0,0 +1,29 @@
+package tenantmodels
+
+import wpmodels "ednevnik-backend/models/workspace"
+
+// SemesterLockChange is a manual lock or unlock of a semester by the tenant
+// admin
+type SemesterLockChange struct {
+	ID                 int    `json:"id"`
+	SemesterCode       string `json:"semester_code"`
+	Locked             bool   `json:"locked"`
+	Reason             string `json:"reason"`
+	ChangedByAccountID int    `json:"changed_by_account_id"`
+	ChangedAt          string `json:"changed_at"`
+}
+
+// SemesterLock is the lock status of a semester. Grades and attendance can be
+// changed until EditableUntil unless the tenant admin locked or unlocked the
+// semester by hand, LastChange is that change.
+type SemesterLock struct {
+	wpmodels.TenantSemester
+	EditableUntil string              `json:"editable_until"`
+	Locked        bool                `json:"locked"`
+	LastChange    *SemesterLockChange `json:"last_change,omitempty"`
+}
+
+// SemesterLockRequest is the reason given for locking or unlocking a semester
+type SemesterLockRequest struct {
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,43 @@
+package wpmodels
+
+import "encoding/json"
+
+// AuditEntry is a mutating request recorded in the audit log. The payloads
+// are the redacted request body, the state before the change when the handler
+// captured it and the response body.
+type AuditEntry struct {
+	ID             int64  `json:"id"`
+	TenantID       *int   `json:"tenant_id,omitempty"`
+	ActorAccountID int    `json:"actor_account_id"`
+	ActorRole      string `json:"actor_role"`
+	// ImpersonatorAccountID is the superadmin that made the request while
+	// viewing the app as the actor
+	ImpersonatorAccountID *int            `json:"impersonator_account_id,omitempty"`
+	IPAddress             string          `json:"ip_address"`
+	Method                string          `json:"method"`
+	Route                 string          `json:"route"`
+	Action                string          `json:"action"`
+	Entity                string          `json:"entity"`
+	EntityID              string          `json:"entity_id,omitempty"`
+	StatusCode            int             `json:"status_code"`
+	RequestData           json.RawMessage `json:"request_data,omitempty"`
+	BeforeData            json.RawMessage `json:"before_data,omitempty"`
+	AfterData             json.RawMessage `json:"after_data,omitempty"`
+	CreatedAt             string          `json:"created_at"`
+}
+
+// AuditFilter narrows down the audit log. Zero values are ignored, From and
+// To are dates or datetimes in the format of the database.
+type AuditFilter struct {
+	TenantID       int
+	ActorAccountID int
+	// ImpersonatorAccountID keeps only requests made while impersonating
+	ImpersonatorAccountID int
+	Entity                string
+	EntityID              string
+	Action                string
+	From                  string
+	To                    string
+	Limit                 int
+	Offset                int
+}
@@ -17,6 +17,13 @@ type Claims struct {
 	AccountID           int      `json:"account_id"`
 	TenantIDs           []string `json:"tenant_ids"`
 	TenantAdminTenantID int      `json:"tenant_id,omitempty"`
+	SessionID           int      `json:"session_id"`
+	MembershipVersion   int      `json:"membership_version"`
+	// Set on impersonation tokens, the claims above are the ones of the
+	// impersonated account
+	ImpersonationID       int  `json:"impersonation_id,omitempty"`
+	ImpersonatorAccountID int  `json:"impersonator_account_id,omitempty"`
+	ReadOnly              bool `json:"read_only,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -35,3 +42,91 @@ type PasswordChangeRequest struct {
 	NewPassword     string `json:"new_password"`
 	ConfirmPassword string `json:"confirm_password"`
 }
+
+// ForgotPasswordRequest starts a password reset for the account with the
+// email
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// PasswordResetRequest sets a new password with a reset token from the
+// password reset email
+type PasswordResetRequest struct {
+	Token           string `json:"token"`
+	NewPassword     string `json:"new_password"`
+	ConfirmPassword string `json:"confirm_password"`
+}
+
+// TokenResponse is returned by login and refresh endpoints. The access token
+// is short-lived, the refresh token is single use and rotates on every refresh.
+type TokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token,omitempty"`
+	AccessExpiresAt  int64  `json:"access_expires_at"`
+	RefreshExpiresAt int64  `json:"refresh_expires_at,omitempty"`
+	// RecoveryCodes are returned once when two-factor enrollment completes
+	// the login
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// SecondFactorChallenge is returned by login instead of tokens when the
+// account has to verify or enroll a second factor. The MFA token is used
+// for the second login step only.
+type SecondFactorChallenge struct {
+	MFARequired        bool   `json:"mfa_required"`
+	EnrollmentRequired bool   `json:"mfa_enrollment_required"`
+	MFAToken           string `json:"mfa_token"`
+	ExpiresAt          int64  `json:"mfa_expires_at"`
+}
+
+// SecondFactorRequest holds the MFA token from login and a TOTP or
+// recovery code
+type SecondFactorRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+// TwoFactorSetup holds the secret of a started enrollment. The otpauth URL
+// is shown as a QR code.
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// TwoFactorStatus tells whether an account uses and must use a second factor
+type TwoFactorStatus struct {
+	Enabled  bool `json:"enabled"`
+	Required bool `json:"required"`
+}
+
+// TwoFactorPolicy is the two-factor setting of a tenant
+type TwoFactorPolicy struct {
+	RequireTeacher2FA bool `json:"require_teacher_2fa"`
+}
+
+// RefreshRequest holds the refresh token sent to the refresh endpoint
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// AccountSession represents a login session stored in the workspace DB
+type AccountSession struct {
+	ID         int    `json:"id"`
+	AccountID  int    `json:"account_id"`
+	LoginType  string `json:"login_type"`
+	UserAgent  string `json:"user_agent,omitempty"`
+	IPAddress  string `json:"ip_address,omitempty"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at"`
+	ExpiresAt  string `json:"expires_at"`
+}
+
+// LockedAccount is an account that is locked after failed logins
+type LockedAccount struct {
+	AccountID    int    `json:"account_id"`
+	Email        string `json:"email"`
+	AccountType  string `json:"account_type"`
+	FailedCount  int    `json:"failed_count"`
+	LastFailedAt string `json:"last_failed_at"`
+	LockedUntil  string `json:"locked_until"`
+}
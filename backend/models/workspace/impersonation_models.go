@@ -0,0 +1,36 @@
+package wpmodels
+
+// ImpersonationRequest starts viewing the app as another account. Writes
+// are refused unless AllowWrites is set.
+type ImpersonationRequest struct {
+	Reason      string `json:"reason"`
+	AllowWrites bool   `json:"allow_writes"`
+}
+
+// ImpersonationResponse carries the impersonation token. There is no
+// refresh token, a new impersonation is started once it expires.
+type ImpersonationResponse struct {
+	ImpersonationID int    `json:"impersonation_id"`
+	AccessToken     string `json:"access_token"`
+	AccessExpiresAt int64  `json:"access_expires_at"`
+	ReadOnly        bool   `json:"read_only"`
+	AccountID       int    `json:"account_id"`
+	AccountType     string `json:"account_type"`
+	Name            string `json:"name"`
+	LastName        string `json:"last_name"`
+}
+
+// Impersonation is a started "view as" session of a superadmin
+type Impersonation struct {
+	ID              int    `json:"id"`
+	ActorAccountID  int    `json:"actor_account_id"`
+	ActorEmail      string `json:"actor_email"`
+	TargetAccountID int    `json:"target_account_id"`
+	TargetEmail     string `json:"target_email"`
+	ReadOnly        bool   `json:"read_only"`
+	Reason          string `json:"reason,omitempty"`
+	IPAddress       string `json:"ip_address,omitempty"`
+	CreatedAt       string `json:"created_at"`
+	ExpiresAt       string `json:"expires_at"`
+	EndedAt         string `json:"ended_at,omitempty"`
+}
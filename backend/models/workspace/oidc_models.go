@@ -0,0 +1,28 @@
+package wpmodels
+
+// OIDCProvider is an OpenID Connect provider used for single sign-on by the
+// accounts with an email of its domain. The client secret is write only.
+type OIDCProvider struct {
+	ID           int    `json:"id"`
+	Domain       string `json:"domain"`
+	DisplayName  string `json:"display_name"`
+	IssuerURL    string `json:"issuer_url"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	Scopes       string `json:"scopes"`
+	Enabled      bool   `json:"enabled"`
+	CreatedAt    string `json:"created_at,omitempty"`
+}
+
+// OIDCStartResponse tells the frontend where to send the user to log in
+type OIDCStartResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	ProviderName     string `json:"provider_name"`
+}
+
+// OIDCCallbackRequest carries the code and state the provider redirected
+// back with
+type OIDCCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
@@ -0,0 +1,123 @@
+package wpmodels
+
+import (
+	"database/sql"
+	"ednevnik-backend/models/interfaces"
+	"fmt"
+)
+
+// Parent represents a parent account that can be linked to one or more
+// pupils across tenants
+type Parent struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	LastName string `json:"last_name"`
+	Email    string `json:"email"`
+	Password string `json:"password,omitempty"`
+	Phone    string `json:"phone"`
+}
+
+// ParentChild is a pupil linked to a parent account
+type ParentChild struct {
+	PupilID  int    `json:"pupil_id"`
+	Name     string `json:"name"`
+	LastName string `json:"last_name"`
+	LinkedAt string `json:"linked_at"`
+}
+
+// ParentLinkRequest is used to link a pupil to a parent account with the
+// pupil's parent access code
+type ParentLinkRequest struct {
+	ParentAccessCode string `json:"parent_access_code"`
+}
+
+// ParentAccessCodeResponse returns a newly generated parent access code
+type ParentAccessCodeResponse struct {
+	PupilID          int    `json:"pupil_id"`
+	ParentAccessCode string `json:"parent_access_code"`
+}
+
+// ParentAccess is the parent access of a pupil, the code itself is left out
+type ParentAccess struct {
+	PupilID             int   `json:"pupil_id"`
+	HasParentAccessCode bool  `json:"has_parent_access_code"`
+	ParentIDs           []int `json:"parent_ids"`
+}
+
+// GetID returns the parent ID
+func (p Parent) GetID() int {
+	return p.ID
+}
+
+// GetName returns the parent name
+func (p Parent) GetName() string {
+	return p.Name
+}
+
+// GetLastName returns the parent last name
+func (p Parent) GetLastName() string {
+	return p.LastName
+}
+
+// GetEmail returns the parent email
+func (p Parent) GetEmail() string {
+	return p.Email
+}
+
+// GetPhone returns the parent phone number
+func (p Parent) GetPhone() string {
+	return p.Phone
+}
+
+// GetAccountType returns parent, parents have no other account types
+func (p Parent) GetAccountType(workspaceDB *sql.DB) string {
+	return "parent"
+}
+
+// GetTenantIDs returns the tenants of all pupils linked to the parent
+func (p Parent) GetTenantIDs(workspaceDB *sql.DB) ([]string, error) {
+	var tenantIDs []string
+
+	query := `SELECT DISTINCT pt.tenant_id FROM pupil_tenant pt
+	JOIN parent_pupil pp ON pp.pupil_id = pt.pupil_id
+	WHERE pp.parent_id = ?`
+	rows, err := workspaceDB.Query(query, p.GetID())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tenantID string
+		err := rows.Scan(&tenantID)
+		if err != nil {
+			return nil, err
+		}
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	return tenantIDs, nil
+}
+
+// GetPassword returns the hashed password of the parent
+func (p Parent) GetPassword() string {
+	return p.Password
+}
+
+// GetAccountID returns the account ID of the parent
+func (p Parent) GetAccountID(workspaceDB *sql.DB) (int, error) {
+	var accountID int
+	query := "SELECT account_id FROM parents WHERE id = ?"
+	err := workspaceDB.QueryRow(query, p.GetID()).Scan(&accountID)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching account ID: %w", err)
+	}
+	return accountID, nil
+}
+
+// Ensure parent implements user interface
+var _ interfaces.User = (*Parent)(nil)
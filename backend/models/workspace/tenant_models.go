@@ -40,11 +40,12 @@ type Tenant struct {
 
 // TenantSemester TODO: Add description
 type TenantSemester struct {
-	TenantID     int    `json:"tenant_id"`
-	SemesterCode string `json:"semester_code"`
-	StartDate    string `json:"start_date"`
-	EndDate      string `json:"end_date"`
-	NPPCode      string `json:"npp_code"`
+	TenantID      int    `json:"tenant_id"`
+	SemesterCode  string `json:"semester_code"`
+	StartDate     string `json:"start_date"`
+	EndDate       string `json:"end_date"`
+	NPPCode       string `json:"npp_code"`
+	LockGraceDays int    `json:"lock_grace_days"`
 	// Additional fields
 	NPPName      string `json:"npp_name,omitempty"`
 	SemesterName string `json:"semester_name,omitempty"`
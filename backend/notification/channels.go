@@ -0,0 +1,226 @@
+package notification
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Channel names
+const (
+	ChannelInbox   = "inbox"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// channelsFromEnv configures the channels from environment variables.
+// NOTIFICATION_TRANSPORT=local replaces email and webhook with recorders that
+// only log the messages, for local development and tests.
+func channelsFromEnv() []Channel {
+	configured := []Channel{&InboxChannel{}}
+
+	if os.Getenv("NOTIFICATION_TRANSPORT") == "local" {
+		return append(configured,
+			NewRecorderChannel(ChannelEmail),
+			NewRecorderChannel(ChannelWebhook),
+		)
+	}
+
+	return append(configured,
+		SMTPChannelFromEnv(),
+		&WebhookChannel{Client: NewWebhookClient(10 * time.Second)},
+	)
+}
+
+// InboxChannel stores messages in the in-app inbox of the workspace DB
+type InboxChannel struct{}
+
+// Name of the channel
+func (c *InboxChannel) Name() string { return ChannelInbox }
+
+// Send inserts the message into the notifications table
+func (c *InboxChannel) Send(msg Message) error {
+	if workspaceDB == nil {
+		return fmt.Errorf("notification workspace DB is not set")
+	}
+	query := `INSERT INTO notifications (account_id, event, title, body, link)
+	VALUES (?, ?, ?, ?, NULLIF(?, ''))`
+	_, err := workspaceDB.Exec(
+		query, msg.AccountID, msg.Event, msg.Subject, msg.Text, msg.Link,
+	)
+	if err != nil {
+		return fmt.Errorf("error inserting notification: %v", err)
+	}
+	return nil
+}
+
+// SMTPChannel sends messages as HTML email. Without a username no
+// authentication is used, which works with local SMTP stand-ins such as
+// MailHog or Mailpit.
+type SMTPChannel struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPChannelFromEnv configures SMTP from SMTP_* variables, falling back to
+// the Gmail account used before
+func SMTPChannelFromEnv() *SMTPChannel {
+	channel := &SMTPChannel{
+		Host:     envOrDefault("SMTP_HOST", "smtp.gmail.com"),
+		Port:     envOrDefault("SMTP_PORT", "587"),
+		Username: envOrDefault("SMTP_USERNAME", os.Getenv("GMAIL_ADDRESS")),
+		Password: envOrDefault("SMTP_PASSWORD", os.Getenv("GMAIL_APP_PASSWORD")),
+	}
+	channel.From = envOrDefault("SMTP_FROM", channel.Username)
+	return channel
+}
+
+// Name of the channel
+func (c *SMTPChannel) Name() string { return ChannelEmail }
+
+// Send sends the message to the email of the account
+func (c *SMTPChannel) Send(msg Message) error {
+	if c.From == "" {
+		return fmt.Errorf("SMTP_FROM or GMAIL_ADDRESS must be set in environment variables")
+	}
+	if msg.Email == "" {
+		return fmt.Errorf("recipient has no email")
+	}
+
+	var headers strings.Builder
+	headers.WriteString("From: " + c.From + "\r\n")
+	headers.WriteString("To: " + msg.Email + "\r\n")
+	headers.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	headers.WriteString("MIME-version: 1.0\r\n")
+	headers.WriteString("Content-Type: text/html; charset=\"UTF-8\"\r\n\r\n")
+
+	var auth smtp.Auth
+	if c.Username != "" {
+		auth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	}
+
+	return smtp.SendMail(
+		c.Host+":"+c.Port, auth, c.From, []string{msg.Email},
+		[]byte(headers.String()+msg.HTML),
+	)
+}
+
+// WebhookChannel posts messages as JSON to the webhook of the tenant the
+// message belongs to. With a secret the body is signed with HMAC-SHA256 in
+// the X-Ednevnik-Signature header.
+type WebhookChannel struct {
+	Client *http.Client
+}
+
+// Name of the channel
+func (c *WebhookChannel) Name() string { return ChannelWebhook }
+
+// Send posts the message to the webhook of its tenant. Messages without a
+// tenant or of a tenant without a webhook are not sent anywhere, webhooks
+// that are not https are refused.
+func (c *WebhookChannel) Send(msg Message) error {
+	if msg.TenantID == 0 {
+		return nil
+	}
+	webhook, err := deliveries.TenantWebhook(msg.TenantID)
+	if err != nil {
+		return err
+	}
+	if webhook == nil {
+		return nil
+	}
+
+	parsed, err := url.Parse(webhook.URL)
+	if err != nil || parsed.Scheme != "https" {
+		return fmt.Errorf("webhook URL of tenant %d is not an https URL", msg.TenantID)
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if webhook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(webhook.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Ednevnik-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// RecorderChannel keeps messages in memory instead of sending them. It stands
+// in for SMTP and webhooks locally and in tests.
+type RecorderChannel struct {
+	name     string
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewRecorderChannel creates a recorder that replaces the named channel
+func NewRecorderChannel(name string) *RecorderChannel {
+	return &RecorderChannel{name: name}
+}
+
+// Name of the channel
+func (c *RecorderChannel) Name() string { return c.name }
+
+// Send records and logs the message
+func (c *RecorderChannel) Send(msg Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, msg)
+	log.Printf("[%s] %s to %s: %s", c.name, msg.Event, msg.Email, msg.Subject)
+	return nil
+}
+
+// Messages returns the recorded messages
+func (c *RecorderChannel) Messages() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Message(nil), c.messages...)
+}
+
+// Reset removes the recorded messages
+func (c *RecorderChannel) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = nil
+}
+
+// envOrDefault returns the environment variable or the default when unset
+func envOrDefault(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
@@ -0,0 +1,112 @@
+package notification
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// InboxItem is a notification in the in-app inbox
+type InboxItem struct {
+	ID        int    `json:"id"`
+	Event     string `json:"event"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	Link      string `json:"link,omitempty"`
+	CreatedAt string `json:"created_at"`
+	ReadAt    string `json:"read_at,omitempty"`
+}
+
+// GetInbox returns the latest notifications of an account, newest first
+func GetInbox(accountID int, unreadOnly bool, limit int, db *sql.DB) ([]InboxItem, error) {
+	query := `SELECT id, event, title, body, COALESCE(link, ''), created_at,
+	COALESCE(read_at, '')
+	FROM notifications
+	WHERE account_id = ? AND (? = FALSE OR read_at IS NULL)
+	ORDER BY created_at DESC, id DESC
+	LIMIT ?`
+
+	rows, err := db.Query(query, accountID, unreadOnly, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching notifications: %v", err)
+	}
+	defer rows.Close()
+
+	items := []InboxItem{}
+	for rows.Next() {
+		var item InboxItem
+		err := rows.Scan(
+			&item.ID,
+			&item.Event,
+			&item.Title,
+			&item.Body,
+			&item.Link,
+			&item.CreatedAt,
+			&item.ReadAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning notification: %v", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notifications: %v", err)
+	}
+
+	return items, nil
+}
+
+// GetInboxItem returns a notification of an account
+func GetInboxItem(accountID, notificationID int, db *sql.DB) (*InboxItem, error) {
+	query := `SELECT id, event, title, body, COALESCE(link, ''), created_at,
+	COALESCE(read_at, '')
+	FROM notifications
+	WHERE account_id = ? AND id = ?`
+
+	var item InboxItem
+	err := db.QueryRow(query, accountID, notificationID).Scan(
+		&item.ID,
+		&item.Event,
+		&item.Title,
+		&item.Body,
+		&item.Link,
+		&item.CreatedAt,
+		&item.ReadAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// GetUnreadCount returns the number of unread notifications of an account
+func GetUnreadCount(accountID int, db *sql.DB) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM notifications
+	WHERE account_id = ? AND read_at IS NULL`
+	if err := db.QueryRow(query, accountID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting notifications: %v", err)
+	}
+	return count, nil
+}
+
+// MarkRead marks a notification of an account as read. Notifications of
+// other accounts are left untouched.
+func MarkRead(accountID, notificationID int, db *sql.DB) error {
+	query := `UPDATE notifications SET read_at = NOW()
+	WHERE id = ? AND account_id = ? AND read_at IS NULL`
+	if _, err := db.Exec(query, notificationID, accountID); err != nil {
+		return fmt.Errorf("error marking notification as read: %v", err)
+	}
+	return nil
+}
+
+// MarkAllRead marks every notification of an account as read
+func MarkAllRead(accountID int, db *sql.DB) error {
+	query := `UPDATE notifications SET read_at = NOW()
+	WHERE account_id = ? AND read_at IS NULL`
+	if _, err := db.Exec(query, accountID); err != nil {
+		return fmt.Errorf("error marking notifications as read: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,396 @@
+package notification
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"ednevnik-backend/jobs"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// Events that accounts can be notified about
+const (
+	EventGradeCreated               = "grade_created"
+	EventGradeUpdated               = "grade_updated"
+	EventGradeDeleted               = "grade_deleted"
+	EventAbsenceRecorded            = "absence_recorded"
+	EventPupilSectionInvite         = "pupil_section_invite"
+	EventTeacherSectionInvite       = "teacher_section_invite"
+	EventBehaviourGradeChanged      = "behaviour_grade_changed"
+	EventDescriptiveAssessmentSaved = "descriptive_assessment_saved"
+	EventExamPlanned                = "exam_planned"
+	EventRemedialExamRecorded       = "remedial_exam_recorded"
+)
+
+// Events lists every event accounts can set preferences for
+var Events = []string{
+	EventGradeCreated,
+	EventGradeUpdated,
+	EventGradeDeleted,
+	EventAbsenceRecorded,
+	EventPupilSectionInvite,
+	EventTeacherSectionInvite,
+	EventBehaviourGradeChanged,
+	EventDescriptiveAssessmentSaved,
+	EventExamPlanned,
+	EventRemedialExamRecorded,
+}
+
+// Background job types of the notification package
+const (
+	JobNotification       = "notification"
+	JobVerificationEmail  = "verification_email"
+	JobPasswordResetEmail = "password_reset_email"
+)
+
+// notificationJob is the payload of a notification for one account on one
+// channel, so a failing channel is retried without repeating the others
+type notificationJob struct {
+	Event     string            `json:"event"`
+	TenantID  int64             `json:"tenant_id,omitempty"`
+	AccountID int               `json:"account_id"`
+	Channel   string            `json:"channel"`
+	Data      map[string]string `json:"data"`
+}
+
+// VerificationEmailJob is the payload of a verification email for a pending
+// account. The token is read when the job runs.
+type VerificationEmailJob struct {
+	PendingAccountID int    `json:"pending_account_id"`
+	Email            string `json:"email"`
+	Name             string `json:"name"`
+}
+
+// PasswordResetEmailJob is the payload of a password reset email. The token
+// is generated when the job runs, only its hash is stored.
+type PasswordResetEmailJob struct {
+	PasswordResetID int64  `json:"password_reset_id"`
+	Email           string `json:"email"`
+	Name            string `json:"name"`
+}
+
+// Message is a rendered notification for a single account
+type Message struct {
+	TenantID  int64             `json:"tenant_id,omitempty"`
+	AccountID int               `json:"account_id"`
+	Email     string            `json:"email"`
+	Event     string            `json:"event"`
+	Subject   string            `json:"subject"`
+	Text      string            `json:"text"`
+	HTML      string            `json:"-"`
+	Link      string            `json:"link,omitempty"`
+	Data      map[string]string `json:"data,omitempty"`
+}
+
+// Channel delivers messages to accounts. Name is the value stored in
+// notification_preferences.
+type Channel interface {
+	Name() string
+	Send(msg Message) error
+}
+
+var (
+	workspaceDB *sql.DB
+	channels    map[string]Channel
+	channelsMu  sync.RWMutex
+)
+
+// Setup sets the workspace connection used for recipients, preferences and
+// the inbox and registers the job handlers. Channels are configured from
+// environment variables unless UseChannels set them before.
+func Setup(db *sql.DB) {
+	workspaceDB = db
+	configureChannels()
+	jobs.Register(JobNotification, runNotificationJob)
+	jobs.Register(JobVerificationEmail, runVerificationEmailJob)
+	jobs.Register(JobPasswordResetEmail, runPasswordResetEmailJob)
+}
+
+// UseChannels replaces the configured channels, e.g. with recorders in tests
+func UseChannels(newChannels ...Channel) {
+	channelsMu.Lock()
+	defer channelsMu.Unlock()
+	channels = map[string]Channel{}
+	for _, channel := range newChannels {
+		channels[channel.Name()] = channel
+	}
+}
+
+// configureChannels configures the channels from the environment if none
+// are set yet
+func configureChannels() {
+	channelsMu.RLock()
+	configured := channels != nil
+	channelsMu.RUnlock()
+	if !configured {
+		UseChannels(channelsFromEnv()...)
+	}
+}
+
+// getChannel returns a configured channel, configuring them from the
+// environment if Setup was not called
+func getChannel(name string) (Channel, bool) {
+	configureChannels()
+	channelsMu.RLock()
+	defer channelsMu.RUnlock()
+	channel, ok := channels[name]
+	return channel, ok
+}
+
+// Notify queues the event of a tenant for each account, one job for every
+// channel the account opted into. Each job renders the message and delivers
+// it on its channel. Failures to queue are only logged, so notifications
+// never fail the request that caused them.
+func Notify(event string, tenantID int64, accountIDs []int, data map[string]string) {
+	if workspaceDB == nil {
+		return
+	}
+	for _, accountID := range accountIDs {
+		enabled, err := deliveries.EnabledChannels(accountID, event)
+		if err != nil {
+			log.Printf("notification %s for account %d failed: %v", event, accountID, err)
+			continue
+		}
+		for _, channel := range enabled {
+			_, err := jobs.Enqueue(JobNotification, notificationJob{
+				Event:     event,
+				TenantID:  tenantID,
+				AccountID: accountID,
+				Channel:   channel,
+				Data:      data,
+			})
+			if err != nil {
+				log.Printf(
+					"notification %s for account %d on %s failed: %v",
+					event, accountID, channel, err,
+				)
+			}
+		}
+	}
+}
+
+// NotifyPupil notifies a pupil and all parents linked to the pupil
+func NotifyPupil(event string, tenantID int64, pupilID int, data map[string]string) {
+	if workspaceDB == nil {
+		return
+	}
+	accountIDs, err := getPupilRecipients(pupilID)
+	if err != nil {
+		log.Printf("notification %s for pupil %d failed: %v", event, pupilID, err)
+		return
+	}
+	Notify(event, tenantID, accountIDs, data)
+}
+
+// NotifyTeacher notifies a teacher
+func NotifyTeacher(event string, tenantID int64, teacherID int, data map[string]string) {
+	if workspaceDB == nil {
+		return
+	}
+	var accountID int
+	query := `SELECT account_id FROM teachers WHERE id = ?`
+	if err := workspaceDB.QueryRow(query, teacherID).Scan(&accountID); err != nil {
+		log.Printf("notification %s for teacher %d failed: %v", event, teacherID, err)
+		return
+	}
+	Notify(event, tenantID, []int{accountID}, data)
+}
+
+// SendVerificationEmail sends the account verification email. It is always
+// sent by email, regardless of preferences.
+func SendVerificationEmail(email, name, link string) error {
+	msg, err := render("verification", map[string]string{
+		"name": name,
+		"link": link,
+	})
+	if err != nil {
+		return err
+	}
+	msg.Email = email
+	msg.Link = link
+
+	channel, ok := getChannel(ChannelEmail)
+	if !ok {
+		return fmt.Errorf("email channel is not configured")
+	}
+	if err := channel.Send(msg); err != nil {
+		return fmt.Errorf("failed to send verification email: %v", err)
+	}
+	return nil
+}
+
+// SendPasswordResetEmail sends the password reset email. It is always sent
+// by email, regardless of preferences.
+func SendPasswordResetEmail(email, name, link string) error {
+	msg, err := render("password_reset", map[string]string{
+		"name": name,
+		"link": link,
+	})
+	if err != nil {
+		return err
+	}
+	msg.Email = email
+	msg.Link = link
+
+	channel, ok := getChannel(ChannelEmail)
+	if !ok {
+		return fmt.Errorf("email channel is not configured")
+	}
+	if err := channel.Send(msg); err != nil {
+		return fmt.Errorf("failed to send password reset email: %v", err)
+	}
+	return nil
+}
+
+// runNotificationJob delivers a queued notification. A failed delivery is
+// returned so the job is retried and ends up dead if the channel keeps
+// failing.
+func runNotificationJob(payload json.RawMessage) error {
+	var job notificationJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return jobs.Permanent(err)
+	}
+	return deliver(job)
+}
+
+// runVerificationEmailJob sends a queued verification email. Nothing is sent
+// if the pending account was verified or expired in the meantime.
+func runVerificationEmailJob(payload json.RawMessage) error {
+	var job VerificationEmailJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	var token string
+	query := `SELECT verification_token FROM pending_accounts WHERE id = ?`
+	err := workspaceDB.QueryRow(query, job.PendingAccountID).Scan(&token)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get verification token: %v", err)
+	}
+
+	return SendVerificationEmail(
+		job.Email, job.Name, frontendLink("/verify?token="+token),
+	)
+}
+
+// runPasswordResetEmailJob generates the token of a password reset and sends
+// it by email. Every attempt replaces the token, so a link from a failed
+// attempt stops working. Nothing is sent if the reset was used or expired in
+// the meantime.
+func runPasswordResetEmailJob(payload json.RawMessage) error {
+	var job PasswordResetEmailJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Errorf("error generating password reset token: %v", err)
+	}
+	token := hex.EncodeToString(buf)
+	tokenHash := sha256.Sum256([]byte(token))
+
+	query := `UPDATE password_resets SET token_hash = ?
+	WHERE id = ? AND used_at IS NULL AND expires_at > NOW()`
+	result, err := workspaceDB.Exec(
+		query, hex.EncodeToString(tokenHash[:]), job.PasswordResetID,
+	)
+	if err != nil {
+		return fmt.Errorf("error storing password reset token: %v", err)
+	}
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return nil
+	}
+
+	return SendPasswordResetEmail(
+		job.Email, job.Name, frontendLink("/reset_password?token="+token),
+	)
+}
+
+// deliver renders an event for one account and sends it on the channel of
+// the job. Nothing is sent if the account opted out of the channel after the
+// job was queued.
+func deliver(job notificationJob) error {
+	enabled, err := deliveries.EnabledChannels(job.AccountID, job.Event)
+	if err != nil {
+		return err
+	}
+	if !contains(enabled, job.Channel) {
+		return nil
+	}
+
+	channel, ok := getChannel(job.Channel)
+	if !ok {
+		return jobs.Permanent(fmt.Errorf("channel %s is not configured", job.Channel))
+	}
+
+	msg, err := render(job.Event, job.Data)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	msg.TenantID = job.TenantID
+	msg.AccountID = job.AccountID
+	msg.Data = job.Data
+	msg.Link = frontendLink(job.Data["path"])
+
+	msg.Email, err = deliveries.AccountEmail(job.AccountID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error fetching account email: %v", err)
+	}
+
+	if err := channel.Send(msg); err != nil {
+		return fmt.Errorf("notification channel %s failed: %v", job.Channel, err)
+	}
+	return nil
+}
+
+// getPupilRecipients returns the account of a pupil and the accounts of the
+// parents linked to the pupil
+func getPupilRecipients(pupilID int) ([]int, error) {
+	query := `SELECT account_id FROM pupil_global
+	WHERE id = ? AND account_id IS NOT NULL
+	UNION
+	SELECT p.account_id FROM parent_pupil pp
+	JOIN parents p ON p.id = pp.parent_id
+	WHERE pp.pupil_id = ?`
+
+	rows, err := workspaceDB.Query(query, pupilID, pupilID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pupil recipients: %v", err)
+	}
+	defer rows.Close()
+
+	var accountIDs []int
+	for rows.Next() {
+		var accountID int
+		if err := rows.Scan(&accountID); err != nil {
+			return nil, fmt.Errorf("error scanning pupil recipient: %v", err)
+		}
+		accountIDs = append(accountIDs, accountID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pupil recipients: %v", err)
+	}
+
+	return accountIDs, nil
+}
+
+// frontendLink builds an absolute link to a frontend path
+func frontendLink(path string) string {
+	if path == "" {
+		return ""
+	}
+	return os.Getenv("FRONTEND_URL") + path
+}
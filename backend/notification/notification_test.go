@@ -0,0 +1,317 @@
+package notification
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ednevnik-backend/jobs"
+)
+
+// fakeDeliveryStore keeps preferences, emails and tenant webhooks in memory
+type fakeDeliveryStore struct {
+	enabled  map[int][]string
+	emails   map[int]string
+	webhooks map[int64]*TenantWebhook
+	err      error
+}
+
+func (s *fakeDeliveryStore) EnabledChannels(accountID int, event string) ([]string, error) {
+	return s.enabled[accountID], s.err
+}
+
+func (s *fakeDeliveryStore) AccountEmail(accountID int) (string, error) {
+	email, ok := s.emails[accountID]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return email, s.err
+}
+
+func (s *fakeDeliveryStore) TenantWebhook(tenantID int64) (*TenantWebhook, error) {
+	return s.webhooks[tenantID], s.err
+}
+
+// failingChannel fails every send, like an SMTP server that is down
+type failingChannel struct {
+	name string
+}
+
+func (c *failingChannel) Name() string { return c.name }
+
+func (c *failingChannel) Send(msg Message) error {
+	return errors.New("connection refused")
+}
+
+// useFakeDeliveries replaces the store for the duration of a test
+func useFakeDeliveries(t *testing.T, store *fakeDeliveryStore) {
+	t.Helper()
+	previous := deliveries
+	deliveries = store
+	t.Cleanup(func() { deliveries = previous })
+}
+
+func gradeJob(channel string) notificationJob {
+	return notificationJob{
+		Event:     EventGradeCreated,
+		TenantID:  1,
+		AccountID: 7,
+		Channel:   channel,
+		Data: map[string]string{
+			"pupil_name":   "Amra Hodžić",
+			"subject_name": "Matematika",
+			"grade":        "5",
+			"tenant_name":  "OŠ Mehmedalija Mak Dizdar",
+		},
+	}
+}
+
+func TestDeliverSendsOnTheJobChannel(t *testing.T) {
+	useFakeDeliveries(t, &fakeDeliveryStore{
+		enabled: map[int][]string{7: {ChannelInbox, ChannelEmail}},
+		emails:  map[int]string{7: "amra@example.com"},
+	})
+	inbox := NewRecorderChannel(ChannelInbox)
+	email := NewRecorderChannel(ChannelEmail)
+	UseChannels(inbox, email)
+
+	if err := deliver(gradeJob(ChannelEmail)); err != nil {
+		t.Fatalf("deliver returned %v", err)
+	}
+
+	if got := len(inbox.Messages()); got != 0 {
+		t.Errorf("inbox got %d messages, want 0", got)
+	}
+	messages := email.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("email got %d messages, want 1", len(messages))
+	}
+	msg := messages[0]
+	if msg.Email != "amra@example.com" || msg.AccountID != 7 || msg.TenantID != 1 {
+		t.Errorf("unexpected recipient %+v", msg)
+	}
+	if msg.Subject != "Nova ocjena iz predmeta Matematika" {
+		t.Errorf("unexpected subject %q", msg.Subject)
+	}
+}
+
+func TestDeliverReturnsChannelError(t *testing.T) {
+	useFakeDeliveries(t, &fakeDeliveryStore{
+		enabled: map[int][]string{7: {ChannelInbox, ChannelEmail}},
+		emails:  map[int]string{7: "amra@example.com"},
+	})
+	inbox := NewRecorderChannel(ChannelInbox)
+	UseChannels(inbox, &failingChannel{name: ChannelEmail})
+
+	err := deliver(gradeJob(ChannelEmail))
+	if err == nil {
+		t.Fatal("deliver swallowed the channel error")
+	}
+	if jobs.IsPermanent(err) {
+		t.Errorf("a failed send must be retried, got permanent error %v", err)
+	}
+
+	if err := deliver(gradeJob(ChannelInbox)); err != nil {
+		t.Errorf("inbox delivery failed with %v", err)
+	}
+	if got := len(inbox.Messages()); got != 1 {
+		t.Errorf("inbox got %d messages, want 1", got)
+	}
+}
+
+func TestDeliverSkipsChannelsTheAccountOptedOutOf(t *testing.T) {
+	useFakeDeliveries(t, &fakeDeliveryStore{
+		enabled: map[int][]string{7: {ChannelInbox}},
+		emails:  map[int]string{7: "amra@example.com"},
+	})
+	email := NewRecorderChannel(ChannelEmail)
+	UseChannels(NewRecorderChannel(ChannelInbox), email)
+
+	if err := deliver(gradeJob(ChannelEmail)); err != nil {
+		t.Fatalf("deliver returned %v", err)
+	}
+	if got := len(email.Messages()); got != 0 {
+		t.Errorf("email got %d messages, want 0", got)
+	}
+}
+
+func TestDeliverWithoutConfiguredChannelIsPermanent(t *testing.T) {
+	useFakeDeliveries(t, &fakeDeliveryStore{
+		enabled: map[int][]string{7: {ChannelWebhook}},
+		emails:  map[int]string{7: "amra@example.com"},
+	})
+	UseChannels(NewRecorderChannel(ChannelInbox))
+
+	err := deliver(gradeJob(ChannelWebhook))
+	if !jobs.IsPermanent(err) {
+		t.Errorf("deliver returned %v, want a permanent error", err)
+	}
+}
+
+func TestDeliverToDeletedAccountSucceeds(t *testing.T) {
+	useFakeDeliveries(t, &fakeDeliveryStore{
+		enabled: map[int][]string{7: {ChannelInbox}},
+	})
+	inbox := NewRecorderChannel(ChannelInbox)
+	UseChannels(inbox)
+
+	if err := deliver(gradeJob(ChannelInbox)); err != nil {
+		t.Fatalf("deliver returned %v", err)
+	}
+	if got := len(inbox.Messages()); got != 0 {
+		t.Errorf("inbox got %d messages, want 0", got)
+	}
+}
+
+func TestWebhookPostsToTheTenantWebhook(t *testing.T) {
+	var received []Message
+	var signatures []string
+	tenantOne := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var msg Message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Errorf("invalid webhook body: %v", err)
+		}
+		mac := hmac.New(sha256.New, []byte("tenant-one-secret"))
+		mac.Write(body)
+		signatures = append(signatures, hex.EncodeToString(mac.Sum(nil)))
+		if r.Header.Get("X-Ednevnik-Signature") != signatures[len(signatures)-1] {
+			t.Error("webhook body is not signed with the tenant secret")
+		}
+		received = append(received, msg)
+	}))
+	defer tenantOne.Close()
+
+	useFakeDeliveries(t, &fakeDeliveryStore{
+		webhooks: map[int64]*TenantWebhook{
+			1: {TenantID: 1, URL: tenantOne.URL, Secret: "tenant-one-secret"},
+		},
+	})
+	channel := &WebhookChannel{Client: tenantOne.Client()}
+
+	tests := []struct {
+		name     string
+		tenantID int64
+		want     int
+	}{
+		{"tenant with webhook", 1, 1},
+		{"tenant without webhook", 2, 1},
+		{"message without tenant", 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := channel.Send(Message{TenantID: tt.tenantID, AccountID: 7, Event: EventGradeCreated})
+			if err != nil {
+				t.Fatalf("send returned %v", err)
+			}
+			if len(received) != tt.want {
+				t.Errorf("webhook received %d messages, want %d", len(received), tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookReturnsErrorStatus(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	useFakeDeliveries(t, &fakeDeliveryStore{
+		webhooks: map[int64]*TenantWebhook{1: {TenantID: 1, URL: server.URL}},
+	})
+	channel := &WebhookChannel{Client: server.Client()}
+
+	if err := channel.Send(Message{TenantID: 1, AccountID: 7}); err == nil {
+		t.Error("send ignored the error status of the webhook")
+	}
+}
+
+func TestWebhookDoesNotFollowRedirects(t *testing.T) {
+	internalHit := false
+	internal := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internalHit = true
+	}))
+	defer internal.Close()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	useFakeDeliveries(t, &fakeDeliveryStore{
+		webhooks: map[int64]*TenantWebhook{1: {TenantID: 1, URL: server.URL}},
+	})
+	client := server.Client()
+	client.CheckRedirect = refuseRedirects
+	channel := &WebhookChannel{Client: client}
+
+	if err := channel.Send(Message{TenantID: 1, AccountID: 7}); err == nil {
+		t.Error("send accepted a redirect")
+	}
+	if internalHit {
+		t.Error("redirect was followed")
+	}
+}
+
+func TestWebhookRefusesPlainHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("webhook was posted over http")
+	}))
+	defer server.Close()
+
+	useFakeDeliveries(t, &fakeDeliveryStore{
+		webhooks: map[int64]*TenantWebhook{1: {TenantID: 1, URL: server.URL}},
+	})
+	channel := &WebhookChannel{Client: server.Client()}
+
+	if err := channel.Send(Message{TenantID: 1, AccountID: 7}); err == nil {
+		t.Error("send accepted an http webhook")
+	}
+}
+
+func TestWebhookClientRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("webhook client connected to a loopback address")
+	}))
+	defer server.Close()
+
+	res, err := NewWebhookClient(time.Second).Post(server.URL, "application/json", nil)
+	if err == nil {
+		res.Body.Close()
+		t.Error("webhook client posted to a loopback address")
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://8.8.8.8/hook", true},
+		{"http://8.8.8.8/hook", false},
+		{"/hook", false},
+		{"https://127.0.0.1/hook", false},
+		{"https://[::1]/hook", false},
+		{"https://10.1.2.3/hook", false},
+		{"https://172.16.0.1/hook", false},
+		{"https://192.168.1.1/hook", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+		{"https://0.0.0.0/hook", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := ValidateWebhookURL(tt.url)
+			if (err == nil) != tt.valid {
+				t.Errorf("ValidateWebhookURL(%q) = %v, want valid %v", tt.url, err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package notification
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Preference tells whether an account receives an event on a channel
+type Preference struct {
+	Event   string `json:"event"`
+	Channel string `json:"channel"`
+	Enabled bool   `json:"enabled"`
+}
+
+// PreferenceChannels lists the channels accounts can opt into
+var PreferenceChannels = []string{ChannelInbox, ChannelEmail, ChannelWebhook}
+
+// defaultEnabled is used when an account has no preference for a channel.
+// Only the inbox is enabled by default, other channels are opt-in.
+func defaultEnabled(channel string) bool {
+	return channel == ChannelInbox
+}
+
+// GetPreferences returns the preference of an account for every event and
+// channel, filling in the defaults for the ones that were never set
+func GetPreferences(accountID int, db *sql.DB) ([]Preference, error) {
+	stored, err := getStoredPreferences(accountID, db)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := []Preference{}
+	for _, event := range Events {
+		for _, channel := range PreferenceChannels {
+			enabled, ok := stored[event+":"+channel]
+			if !ok {
+				enabled = defaultEnabled(channel)
+			}
+			preferences = append(preferences, Preference{
+				Event:   event,
+				Channel: channel,
+				Enabled: enabled,
+			})
+		}
+	}
+	return preferences, nil
+}
+
+// SetPreferences stores the preferences of an account
+func SetPreferences(accountID int, preferences []Preference, db *sql.DB) (err error) {
+	for _, preference := range preferences {
+		if !contains(Events, preference.Event) {
+			return fmt.Errorf("unknown event: %s", preference.Event)
+		}
+		if !contains(PreferenceChannels, preference.Channel) {
+			return fmt.Errorf("unknown channel: %s", preference.Channel)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	query := `INSERT INTO notification_preferences (account_id, event, channel, enabled)
+	VALUES (?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE enabled = VALUES(enabled)`
+	for _, preference := range preferences {
+		_, err = tx.Exec(
+			query, accountID, preference.Event, preference.Channel, preference.Enabled,
+		)
+		if err != nil {
+			return fmt.Errorf("error saving notification preference: %v", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+	return nil
+}
+
+// enabledChannels returns the channels an account receives an event on
+func enabledChannels(accountID int, event string) ([]string, error) {
+	stored, err := getStoredPreferences(accountID, workspaceDB)
+	if err != nil {
+		return nil, err
+	}
+
+	var enabled []string
+	for _, channel := range PreferenceChannels {
+		on, ok := stored[event+":"+channel]
+		if !ok {
+			on = defaultEnabled(channel)
+		}
+		if on {
+			enabled = append(enabled, channel)
+		}
+	}
+	return enabled, nil
+}
+
+// getStoredPreferences returns the stored preferences of an account keyed by
+// event:channel
+func getStoredPreferences(accountID int, db *sql.DB) (map[string]bool, error) {
+	query := `SELECT event, channel, enabled FROM notification_preferences
+	WHERE account_id = ?`
+	rows, err := db.Query(query, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching notification preferences: %v", err)
+	}
+	defer rows.Close()
+
+	stored := map[string]bool{}
+	for rows.Next() {
+		var event, channel string
+		var enabled bool
+		if err := rows.Scan(&event, &channel, &enabled); err != nil {
+			return nil, fmt.Errorf("error scanning notification preference: %v", err)
+		}
+		stored[event+":"+channel] = enabled
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification preferences: %v", err)
+	}
+
+	return stored, nil
+}
+
+// contains checks if a slice contains a value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*
+var templateFS embed.FS
+
+var (
+	eventTemplates = texttemplate.Must(
+		texttemplate.New("events").Option("missingkey=zero").
+			ParseFS(templateFS, "templates/events.tmpl"),
+	)
+	emailTemplate = htmltemplate.Must(
+		htmltemplate.ParseFS(templateFS, "templates/email.html"),
+	)
+)
+
+// emailData fills the sections of the email layout
+type emailData struct {
+	Title      string
+	Heading    string
+	Subheading string
+	Greeting   string
+	Body       string
+	Link       string
+	ButtonText string
+	Note       string
+}
+
+// render executes the subject and text templates of an event and wraps the
+// text in the email layout
+func render(event string, data map[string]string) (Message, error) {
+	msg := Message{Event: event}
+
+	subject, err := executeText(event+"_subject", data)
+	if err != nil {
+		return msg, err
+	}
+	text, err := executeText(event+"_text", data)
+	if err != nil {
+		return msg, err
+	}
+	msg.Subject = subject
+	msg.Text = text
+
+	layout := emailData{
+		Title:      subject,
+		Heading:    subject,
+		Body:       text,
+		Link:       frontendLink(data["path"]),
+		ButtonText: "Otvori eDnevnik",
+	}
+	if event == "verification" {
+		layout.Heading = "Dobrodošli!"
+		layout.Subheading = "Samo jedan korak do završetka registracije"
+		layout.Greeting = fmt.Sprintf("Zdravo %s! 👋", data["name"])
+		layout.Body = "Hvala vam što ste se registrovali na platformu eDnevnik. " +
+			"Da biste završili registraciju, potrebno je da potvrdite svoju email adresu."
+		layout.Link = data["link"]
+		layout.ButtonText = "Potvrdite Email"
+		layout.Note = "Ovaj link za potvrdu će biti aktivan 24 sata."
+	}
+	if event == "password_reset" {
+		layout.Heading = "Promjena lozinke"
+		layout.Greeting = fmt.Sprintf("Zdravo %s!", data["name"])
+		layout.Body = "Zatražena je promjena lozinke za vaš eDnevnik nalog. " +
+			"Ako niste vi zatražili promjenu, zanemarite ovaj email."
+		layout.Link = data["link"]
+		layout.ButtonText = "Promijenite lozinku"
+		layout.Note = "Ovaj link se može iskoristiti samo jednom i bit će aktivan 1 sat."
+	}
+
+	var html bytes.Buffer
+	if err := emailTemplate.Execute(&html, layout); err != nil {
+		return msg, fmt.Errorf("error rendering email for %s: %v", event, err)
+	}
+	msg.HTML = html.String()
+
+	return msg, nil
+}
+
+// executeText executes a named template of events.tmpl
+func executeText(name string, data map[string]string) (string, error) {
+	if eventTemplates.Lookup(name) == nil {
+		return "", fmt.Errorf("unknown notification template: %s", name)
+	}
+	var out bytes.Buffer
+	if err := eventTemplates.ExecuteTemplate(&out, name, data); err != nil {
+		return "", fmt.Errorf("error rendering %s: %v", name, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
@@ -0,0 +1,156 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// TenantWebhook is the URL notifications of a tenant are posted to. The
+// secret is never returned, HasSecret tells whether one is set.
+type TenantWebhook struct {
+	TenantID  int64  `json:"tenant_id"`
+	URL       string `json:"url"`
+	Secret    string `json:"-"`
+	HasSecret bool   `json:"has_secret"`
+}
+
+// GetTenantWebhook returns the webhook of a tenant, or nil if the tenant has
+// none
+func GetTenantWebhook(tenantID int64, db *sql.DB) (*TenantWebhook, error) {
+	webhook := TenantWebhook{TenantID: tenantID}
+	query := `SELECT url, secret FROM tenant_notification_webhooks
+	WHERE tenant_id = ?`
+	err := db.QueryRow(query, tenantID).Scan(&webhook.URL, &webhook.Secret)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching tenant webhook: %v", err)
+	}
+	webhook.HasSecret = webhook.Secret != ""
+	return &webhook, nil
+}
+
+// SetTenantWebhook stores the webhook of a tenant after validating its URL.
+// An empty secret keeps the secret that is already stored.
+func SetTenantWebhook(tenantID int64, webhookURL, secret string, db *sql.DB) error {
+	if err := ValidateWebhookURL(webhookURL); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO tenant_notification_webhooks (tenant_id, url, secret)
+	VALUES (?, ?, ?)
+	ON DUPLICATE KEY UPDATE url = VALUES(url),
+	secret = IF(VALUES(secret) = '', secret, VALUES(secret))`
+	if _, err := db.Exec(query, tenantID, webhookURL, secret); err != nil {
+		return fmt.Errorf("error saving tenant webhook: %v", err)
+	}
+	return nil
+}
+
+// ValidateWebhookURL checks that a webhook URL is an absolute https URL whose
+// host only resolves to public addresses, so tenants cannot make the server
+// post to itself or to the internal network
+func ValidateWebhookURL(webhookURL string) error {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Hostname() == "" {
+		return fmt.Errorf("webhook URL must be an absolute https URL")
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(context.Background(), "ip", parsed.Hostname())
+	if err != nil {
+		return fmt.Errorf("webhook host %s cannot be resolved", parsed.Hostname())
+	}
+	for _, ip := range ips {
+		if !isPublicIP(ip) {
+			return fmt.Errorf("webhook host %s resolves to a non-public address", parsed.Hostname())
+		}
+	}
+	return nil
+}
+
+// isPublicIP reports whether an address is outside the loopback, private,
+// link-local, multicast and unspecified ranges
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// checkWebhookDial refuses connections to non-public addresses. It runs on
+// the resolved address of every dial, so a host that resolved to a public
+// address when the webhook was saved cannot be pointed inside later.
+func checkWebhookDial(network, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("webhook address %s is not public", host)
+	}
+	return nil
+}
+
+// refuseRedirects makes a client return redirects instead of following them,
+// a redirect could point a webhook at an internal address
+func refuseRedirects(req *http.Request, via []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// NewWebhookClient returns the client webhooks are posted with. It only
+// connects to public addresses and does not follow redirects.
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: checkWebhookDial}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:       timeout,
+		Transport:     transport,
+		CheckRedirect: refuseRedirects,
+	}
+}
+
+// DeleteTenantWebhook removes the webhook of a tenant
+func DeleteTenantWebhook(tenantID int64, db *sql.DB) error {
+	query := `DELETE FROM tenant_notification_webhooks WHERE tenant_id = ?`
+	if _, err := db.Exec(query, tenantID); err != nil {
+		return fmt.Errorf("error deleting tenant webhook: %v", err)
+	}
+	return nil
+}
+
+// deliveryStore reads what a delivery needs from the workspace DB. Tests
+// replace it to deliver without a database.
+type deliveryStore interface {
+	EnabledChannels(accountID int, event string) ([]string, error)
+	AccountEmail(accountID int) (string, error)
+	TenantWebhook(tenantID int64) (*TenantWebhook, error)
+}
+
+var deliveries deliveryStore = databaseDeliveryStore{}
+
+// databaseDeliveryStore reads from the workspace connection of Setup
+type databaseDeliveryStore struct{}
+
+func (databaseDeliveryStore) EnabledChannels(accountID int, event string) ([]string, error) {
+	return enabledChannels(accountID, event)
+}
+
+func (databaseDeliveryStore) AccountEmail(accountID int) (string, error) {
+	var email string
+	query := `SELECT email FROM accounts WHERE id = ?`
+	err := workspaceDB.QueryRow(query, accountID).Scan(&email)
+	return email, err
+}
+
+func (databaseDeliveryStore) TenantWebhook(tenantID int64) (*TenantWebhook, error) {
+	return GetTenantWebhook(tenantID, workspaceDB)
+}
@@ -0,0 +1,148 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IDTokenClaims are the claims of a verified ID token
+type IDTokenClaims struct {
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Name          string `json:"name"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+	jwt.RegisteredClaims
+}
+
+// HasVerifiedEmail reports whether the provider vouches for the email. A
+// token without the email_verified claim does not.
+func (c *IDTokenClaims) HasVerifiedEmail() bool {
+	return c.Email != "" && c.EmailVerified != nil && *c.EmailVerified
+}
+
+// jwk is an RSA key of a JSON Web Key Set
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type cachedKeys struct {
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of
+// an ID token and returns its claims
+func (c *Client) VerifyIDToken(
+	metadata *Metadata, config Config, rawIDToken, nonce string,
+) (*IDTokenClaims, error) {
+	claims := &IDTokenClaims{}
+	_, err := jwt.ParseWithClaims(
+		rawIDToken,
+		claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return c.publicKey(metadata.JWKSURI, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %v", err)
+	}
+
+	if claims.Nonce == "" || claims.Nonce != nonce {
+		return nil, fmt.Errorf("invalid id_token: nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("invalid id_token: missing subject")
+	}
+	claims.Email = strings.ToLower(strings.TrimSpace(claims.Email))
+
+	return claims, nil
+}
+
+// publicKey returns the key with the ID from the key set. The key set is
+// fetched again when the key is unknown, providers rotate their keys.
+func (c *Client) publicKey(jwksURI, kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	cached, ok := c.keys[jwksURI]
+	c.mu.Unlock()
+
+	if ok && time.Since(cached.fetchedAt) < metadataTTL {
+		if key := findKey(cached.keys, kid); key != nil {
+			return key, nil
+		}
+	}
+
+	keys, err := c.fetchKeys(jwksURI)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.keys[jwksURI] = cachedKeys{keys: keys, fetchedAt: time.Now()}
+	c.mu.Unlock()
+
+	if key := findKey(keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// findKey looks up a key by ID. Tokens without a key ID can only be matched
+// when the set has a single key.
+func findKey(keys map[string]*rsa.PublicKey, kid string) *rsa.PublicKey {
+	if key, ok := keys[kid]; ok {
+		return key
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// fetchKeys downloads the RSA signing keys of a key set
+func (c *Client) fetchKeys(jwksURI string) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := c.getJSON(jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("error fetching signing keys: %v", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			continue
+		}
+		keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,195 @@
+// Package oidc is a minimal OpenID Connect relying party. It supports the
+// authorization code flow with PKCE and verifies RS256 signed ID tokens
+// against the keys published by the provider.
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metadataTTL is how long discovery documents and keys are cached
+const metadataTTL = time.Hour
+
+// Config of a provider as registered with it
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Metadata is the part of the discovery document the flow needs
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// TokenResponse is the response of the token endpoint
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Client talks to providers and caches their metadata and keys
+type Client struct {
+	HTTPClient *http.Client
+
+	mu       sync.Mutex
+	metadata map[string]cachedMetadata
+	keys     map[string]cachedKeys
+}
+
+type cachedMetadata struct {
+	metadata  *Metadata
+	fetchedAt time.Time
+}
+
+// NewClient creates a client with a timeout on every request
+func NewClient() *Client {
+	return &Client{
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		metadata:   map[string]cachedMetadata{},
+		keys:       map[string]cachedKeys{},
+	}
+}
+
+// Discover fetches the discovery document of an issuer
+func (c *Client) Discover(issuer string) (*Metadata, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+
+	c.mu.Lock()
+	cached, ok := c.metadata[issuer]
+	c.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < metadataTTL {
+		return cached.metadata, nil
+	}
+
+	var metadata Metadata
+	if err := c.getJSON(issuer+"/.well-known/openid-configuration", &metadata); err != nil {
+		return nil, fmt.Errorf("error fetching discovery document: %v", err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery document is for issuer %s", metadata.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" ||
+		metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document is missing endpoints")
+	}
+
+	c.mu.Lock()
+	c.metadata[issuer] = cachedMetadata{metadata: &metadata, fetchedAt: time.Now()}
+	c.mu.Unlock()
+	return &metadata, nil
+}
+
+// AuthCodeURL returns the URL the user is sent to for logging in with the
+// provider. The challenge is the S256 PKCE challenge of the code verifier.
+func AuthCodeURL(metadata *Metadata, config Config, state, nonce, challenge string) string {
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", config.ClientID)
+	values.Set("redirect_uri", config.RedirectURL)
+	values.Set("scope", strings.Join(scopes, " "))
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+	values.Set("code_challenge", challenge)
+	values.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + values.Encode()
+}
+
+// Exchange trades an authorization code and its PKCE verifier for tokens
+func (c *Client) Exchange(
+	metadata *Metadata, config Config, code, verifier string,
+) (*TokenResponse, error) {
+	values := url.Values{}
+	values.Set("grant_type", "authorization_code")
+	values.Set("code", code)
+	values.Set("redirect_uri", config.RedirectURL)
+	values.Set("client_id", config.ClientID)
+	values.Set("code_verifier", verifier)
+	if config.ClientSecret != "" {
+		values.Set("client_secret", config.ClientSecret)
+	}
+
+	res, err := c.HTTPClient.PostForm(metadata.TokenEndpoint, values)
+	if err != nil {
+		return nil, fmt.Errorf("error calling token endpoint: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint responded with status %d", res.StatusCode)
+	}
+
+	var tokens TokenResponse
+	if err := json.NewDecoder(res.Body).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("error decoding token response: %v", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+	return &tokens, nil
+}
+
+// GeneratePKCE returns a new code verifier and its S256 challenge
+func GeneratePKCE() (verifier, challenge string, err error) {
+	verifier, err = RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	return verifier, S256Challenge(verifier), nil
+}
+
+// S256Challenge returns the PKCE challenge of a code verifier
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// RandomString returns 32 random bytes, URL safe encoded. It is used for
+// states, nonces and code verifiers.
+func RandomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// getJSON fetches a URL and decodes the JSON response
+func (c *Client) getJSON(url string, target interface{}) error {
+	res, err := c.HTTPClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with status %d", url, res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(target)
+}
@@ -0,0 +1,204 @@
+package oidc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"ednevnik-backend/oidc/oidctest"
+)
+
+const testClientID = "ednevnik"
+
+// startProvider serves a mock provider for the duration of a test
+func startProvider(t *testing.T) (*oidctest.Provider, Config) {
+	t.Helper()
+	idp, err := oidctest.NewProvider("", testClientID)
+	if err != nil {
+		t.Fatalf("creating provider failed: %v", err)
+	}
+	server := httptest.NewServer(idp.Handler())
+	t.Cleanup(server.Close)
+	idp.Issuer = server.URL
+
+	return idp, Config{
+		Issuer:      server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "https://ednevnik.test/login/oidc/callback",
+	}
+}
+
+// login posts an email to the login form behind the authorization URL and
+// returns the query the provider redirects back with
+func login(t *testing.T, authorizationURL, email string) url.Values {
+	t.Helper()
+	authURL, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatalf("invalid authorization URL: %v", err)
+	}
+	form := authURL.Query()
+	form.Set("email", email)
+	authURL.RawQuery = ""
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := client.PostForm(authURL.String(), form)
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("login responded with status %d", res.StatusCode)
+	}
+
+	redirect, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect: %v", err)
+	}
+	return redirect.Query()
+}
+
+// startLogin discovers the provider and logs in, like the start and callback
+// handlers do
+func startLogin(
+	t *testing.T, client *Client, config Config, email string,
+) (*Metadata, url.Values, string, string) {
+	t.Helper()
+	metadata, err := client.Discover(config.Issuer)
+	if err != nil {
+		t.Fatalf("discovery failed: %v", err)
+	}
+	state, _ := RandomString()
+	nonce, _ := RandomString()
+	verifier, challenge, err := GeneratePKCE()
+	if err != nil {
+		t.Fatalf("generating PKCE failed: %v", err)
+	}
+
+	callback := login(t, AuthCodeURL(metadata, config, state, nonce, challenge), email)
+	if callback.Get("state") != state {
+		t.Fatalf("provider returned state %q, want %q", callback.Get("state"), state)
+	}
+	return metadata, callback, verifier, nonce
+}
+
+func TestLoginWithPKCE(t *testing.T) {
+	_, config := startProvider(t)
+	client := NewClient()
+
+	metadata, callback, verifier, nonce := startLogin(t, client, config, "Amra@Skola.ba")
+
+	tokens, err := client.Exchange(metadata, config, callback.Get("code"), verifier)
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	claims, err := client.VerifyIDToken(metadata, config, tokens.IDToken, nonce)
+	if err != nil {
+		t.Fatalf("verifying the id_token failed: %v", err)
+	}
+	if claims.Subject != "mock|amra@skola.ba" || claims.Email != "amra@skola.ba" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if !claims.HasVerifiedEmail() {
+		t.Error("verified email is not accepted")
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	_, config := startProvider(t)
+	client := NewClient()
+
+	metadata, callback, _, _ := startLogin(t, client, config, "amra@skola.ba")
+
+	otherVerifier, _, _ := GeneratePKCE()
+	if _, err := client.Exchange(metadata, config, callback.Get("code"), otherVerifier); err == nil {
+		t.Error("code was exchanged with the verifier of another login")
+	}
+}
+
+func TestExchangeRejectsReusedCode(t *testing.T) {
+	_, config := startProvider(t)
+	client := NewClient()
+
+	metadata, callback, verifier, _ := startLogin(t, client, config, "amra@skola.ba")
+
+	if _, err := client.Exchange(metadata, config, callback.Get("code"), verifier); err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	if _, err := client.Exchange(metadata, config, callback.Get("code"), verifier); err == nil {
+		t.Error("code was exchanged twice")
+	}
+}
+
+func TestVerifyIDTokenRejectsWrongNonce(t *testing.T) {
+	_, config := startProvider(t)
+	client := NewClient()
+
+	metadata, callback, verifier, _ := startLogin(t, client, config, "amra@skola.ba")
+
+	tokens, err := client.Exchange(metadata, config, callback.Get("code"), verifier)
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	otherNonce, _ := RandomString()
+	if _, err := client.VerifyIDToken(metadata, config, tokens.IDToken, otherNonce); err == nil {
+		t.Error("id_token of another login was accepted")
+	}
+	if _, err := client.VerifyIDToken(metadata, config, tokens.IDToken, ""); err == nil {
+		t.Error("id_token was accepted without a nonce")
+	}
+}
+
+func TestVerifyIDTokenRejectsOtherClient(t *testing.T) {
+	_, config := startProvider(t)
+	client := NewClient()
+
+	metadata, callback, verifier, nonce := startLogin(t, client, config, "amra@skola.ba")
+
+	tokens, err := client.Exchange(metadata, config, callback.Get("code"), verifier)
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	otherClient := config
+	otherClient.ClientID = "other"
+	if _, err := client.VerifyIDToken(metadata, otherClient, tokens.IDToken, nonce); err == nil {
+		t.Error("id_token for another client was accepted")
+	}
+}
+
+func TestHasVerifiedEmail(t *testing.T) {
+	verified, unverified := true, false
+	tests := []struct {
+		name          string
+		emailVerified *bool
+		want          bool
+	}{
+		{"verified", &verified, true},
+		{"not verified", &unverified, false},
+		{"claim missing", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp, config := startProvider(t)
+			idp.EmailVerified = tt.emailVerified
+			client := NewClient()
+
+			metadata, callback, verifier, nonce := startLogin(t, client, config, "amra@skola.ba")
+			tokens, err := client.Exchange(metadata, config, callback.Get("code"), verifier)
+			if err != nil {
+				t.Fatalf("exchange failed: %v", err)
+			}
+			claims, err := client.VerifyIDToken(metadata, config, tokens.IDToken, nonce)
+			if err != nil {
+				t.Fatalf("verifying the id_token failed: %v", err)
+			}
+			if got := claims.HasVerifiedEmail(); got != tt.want {
+				t.Errorf("HasVerifiedEmail() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
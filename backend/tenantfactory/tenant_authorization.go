@@ -0,0 +1,82 @@
+package tenantfactory
+
+import (
+	"ednevnik-backend/util"
+	"fmt"
+)
+
+// IsTeacherAssignedToSection checks whether a teacher teaches in a section or
+// is its homeroom teacher
+func (t *ConfigurableTenant) IsTeacherAssignedToSection(
+	teacherID, sectionID int,
+) (bool, error) {
+	query := `SELECT EXISTS (
+		SELECT 1 FROM teachers_sections
+		WHERE teacher_id = ? AND section_id = ?
+	) OR EXISTS (
+		SELECT 1 FROM homeroom_assignments
+		WHERE teacher_id = ? AND section_id = ?
+	)`
+	var assigned bool
+	err := t.UserTenantDB.QueryRow(
+		query, teacherID, sectionID, teacherID, sectionID,
+	).Scan(&assigned)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to check teacher %d for section %d: %w", teacherID, sectionID, err,
+		)
+	}
+	return assigned, nil
+}
+
+// IsHomeroomTeacherOfSection checks whether a teacher is the homeroom teacher
+// of a section
+func (t *ConfigurableTenant) IsHomeroomTeacherOfSection(
+	teacherID, sectionID int,
+) (bool, error) {
+	query := `SELECT EXISTS (
+		SELECT 1 FROM homeroom_assignments
+		WHERE teacher_id = ? AND section_id = ?
+	)`
+	var homeroom bool
+	err := t.UserTenantDB.QueryRow(query, teacherID, sectionID).Scan(&homeroom)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to check homeroom teacher %d for section %d: %w",
+			teacherID, sectionID, err,
+		)
+	}
+	return homeroom, nil
+}
+
+// IsPupilEnrolledInSection checks whether a pupil belongs to a section. Pupils
+// that were unenrolled keep read access to the section they attended.
+func (t *ConfigurableTenant) IsPupilEnrolledInSection(
+	pupilID, sectionID int,
+) (bool, error) {
+	query := `SELECT EXISTS (
+		SELECT 1 FROM pupils_sections
+		WHERE pupil_id = ? AND section_id = ?
+	)`
+	var enrolled bool
+	err := t.UserTenantDB.QueryRow(query, pupilID, sectionID).Scan(&enrolled)
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to check pupil %d for section %d: %w", pupilID, sectionID, err,
+		)
+	}
+	return enrolled, nil
+}
+
+// checkPupilInSection rejects rows for pupils that are not enrolled in the
+// section they are written to
+func (t *ConfigurableTenant) checkPupilInSection(pupilID, sectionID int) error {
+	enrolled, err := t.IsPupilEnrolledInSection(pupilID, sectionID)
+	if err != nil {
+		return err
+	}
+	if !enrolled {
+		return util.ErrPupilNotInSection
+	}
+	return nil
+}
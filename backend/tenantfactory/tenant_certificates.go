@@ -1,8 +1,11 @@
 package tenantfactory
 
 import (
+	"database/sql"
+	"ednevnik-backend/certificate"
 	commonmodels "ednevnik-backend/models/common"
 	"ednevnik-backend/util"
+	"fmt"
 )
 
 // GetCertificateData retrieves the certificate data for a pupil in a section
@@ -21,3 +24,64 @@ func (t *ConfigurableTenant) GetCertificateData(
 	}
 	return certificate, nil
 }
+
+// GetCertificatePDF renders the certificate of a pupil in a section as PDF
+// and returns it with its file name
+func (t *ConfigurableTenant) GetCertificatePDF(
+	sectionID, pupilID int,
+) ([]byte, string, error) {
+	data, err := t.GetCertificateData(sectionID, pupilID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	pdf, err := certificate.Render(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return pdf, certificate.FileName(data), nil
+}
+
+// GetSectionCertificatesZip renders the certificates of all pupils enrolled
+// in a section into a ZIP file. Pupils whose certificate data is incomplete
+// are listed in a note inside the archive instead.
+func (t *ConfigurableTenant) GetSectionCertificatesZip(sectionID int) ([]byte, error) {
+	section, err := util.GetSectionByID(int64(sectionID), t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+	if section.ID == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	pupils, err := util.GetPupilsForSection(
+		fmt.Sprintf("%d", sectionID), false, t.UserTenantDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []certificate.File
+	var notes []string
+	for _, pupil := range pupils {
+		data, err := t.GetCertificateData(sectionID, pupil.ID)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf(
+				"%s %s: svjedočanstvo nije izrađeno (%v)",
+				pupil.LastName, pupil.Name, err,
+			))
+			continue
+		}
+
+		pdf, err := certificate.Render(data)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, certificate.File{
+			Name: certificate.FileName(data),
+			Data: pdf,
+		})
+	}
+
+	return certificate.Archive(files, notes)
+}
@@ -120,6 +120,13 @@ func (t *ConfigurableTenant) GrantTenantDBPrivileges() error {
 		))
 	}
 
+	// Parents get the same read only access as pupils
+	for _, p := range util.GetPupilTablePrivileges() {
+		queries = append(queries, fmt.Sprintf(
+			"GRANT %s ON %s.%s TO '%s'@'localhost';", p.Actions, tenantDBName, p.Table, "parent",
+		))
+	}
+
 	for _, q := range queries {
 		if _, err := t.UserWorkspaceDB.Exec(q); err != nil {
 			return fmt.Errorf("error executing query '%s': %v", q, err)
@@ -156,6 +163,13 @@ func (t *ConfigurableTenant) RevokeTenantDBPrivileges() error {
 		))
 	}
 
+	// Revoke parent privileges
+	for _, p := range util.GetPupilTablePrivileges() {
+		queries = append(queries, fmt.Sprintf(
+			"REVOKE %s ON %s.%s FROM '%s'@'localhost';", p.Actions, tenantDBName, p.Table, "parent",
+		))
+	}
+
 	for _, q := range queries {
 		if _, err := t.UserWorkspaceDB.Exec(q); err != nil {
 			return fmt.Errorf("error executing query '%s': %v", q, err)
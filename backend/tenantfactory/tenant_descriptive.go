@@ -0,0 +1,129 @@
+package tenantfactory
+
+import (
+	"database/sql"
+	tenantmodels "ednevnik-backend/models/tenant"
+	"ednevnik-backend/notification"
+	"ednevnik-backend/util"
+	"fmt"
+)
+
+// GetDescriptiveAssessmentClasses retrieves the classes the tenant assesses
+// descriptively.
+func (t *ConfigurableTenant) GetDescriptiveAssessmentClasses() ([]string, error) {
+	return util.GetDescriptiveAssessmentClasses(t.UserTenantDB)
+}
+
+// SetDescriptiveAssessmentClasses replaces the classes the tenant assesses
+// descriptively. Only primary schools assess descriptively.
+func (t *ConfigurableTenant) SetDescriptiveAssessmentClasses(
+	classCodes []string,
+) ([]string, error) {
+	if t.TenantData.TenantType != "primary" && len(classCodes) > 0 {
+		return nil, fmt.Errorf("opisno ocjenjivanje je dostupno samo osnovnim školama")
+	}
+
+	err := util.SetDescriptiveAssessmentClasses(classCodes, t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+	return util.GetDescriptiveAssessmentClasses(t.UserTenantDB)
+}
+
+// GetDescriptiveAssessmentsForSectionSubject retrieves the descriptive
+// assessments of all pupils in a section for a subject and semester.
+func (t *ConfigurableTenant) GetDescriptiveAssessmentsForSectionSubject(
+	sectionID int, semesterCode, subjectCode string,
+) ([]tenantmodels.DescriptiveAssessmentPupilGroup, error) {
+	return util.GetDescriptiveAssessmentsForSectionSubject(
+		sectionID, semesterCode, subjectCode, t.UserTenantDB,
+	)
+}
+
+// GetDescriptiveAssessmentsForPupil retrieves the descriptive assessments of
+// a pupil in a section.
+func (t *ConfigurableTenant) GetDescriptiveAssessmentsForPupil(
+	pupilID, sectionID int,
+) ([]tenantmodels.DescriptiveAssessment, error) {
+	return util.GetDescriptiveAssessmentsForPupil(pupilID, sectionID, t.UserTenantDB)
+}
+
+// SaveDescriptiveAssessment creates or updates the descriptive assessment of a
+// pupil and notifies the pupil.
+func (t *ConfigurableTenant) SaveDescriptiveAssessment(
+	assessment tenantmodels.DescriptiveAssessment, teacherID int,
+) (*tenantmodels.DescriptiveAssessment, error) {
+	descriptive, err := t.isDescriptiveSection(assessment.SectionID)
+	if err != nil {
+		return nil, err
+	}
+	if !descriptive {
+		return nil, fmt.Errorf("odjeljenje se ne ocjenjuje opisno")
+	}
+
+	err = t.checkSemesterWritable(assessment.SectionID, assessment.SemesterCode, "")
+	if err != nil {
+		return nil, err
+	}
+
+	teacherForSignature, err := util.GetTeacherByID(
+		fmt.Sprintf("%d", teacherID), t.UserWorkspaceDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+	signature := teacherForSignature.Name + " " + teacherForSignature.LastName
+
+	assessment.TeacherID = teacherID
+	savedAssessment, err := util.SaveDescriptiveAssessment(
+		assessment, signature, t.UserTenantDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	t.notifyPupil(
+		notification.EventDescriptiveAssessmentSaved, assessment.PupilID,
+		assessment.SubjectCode, nil,
+	)
+
+	return savedAssessment, nil
+}
+
+// GetDescriptiveAssessmentHistory retrieves the earlier versions of a
+// descriptive assessment in a section.
+func (t *ConfigurableTenant) GetDescriptiveAssessmentHistory(
+	sectionID, assessmentID int,
+) ([]tenantmodels.DescriptiveAssessment, error) {
+	assessment, err := util.GetDescriptiveAssessmentByID(assessmentID, t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+	if assessment.SectionID != sectionID {
+		return nil, sql.ErrNoRows
+	}
+
+	return util.GetDescriptiveAssessmentHistory(assessmentID, t.UserTenantDB)
+}
+
+// isDescriptiveSection checks if a section of a primary school is assessed
+// descriptively
+func (t *ConfigurableTenant) isDescriptiveSection(sectionID int) (bool, error) {
+	if t.TenantData.TenantType != "primary" {
+		return false, nil
+	}
+	return util.IsDescriptiveSection(sectionID, t.UserTenantDB)
+}
+
+// checkNumericGradesAllowed returns an error for sections that are assessed
+// descriptively
+func (t *ConfigurableTenant) checkNumericGradesAllowed(sectionID int) error {
+	descriptive, err := t.isDescriptiveSection(sectionID)
+	if err != nil {
+		return err
+	}
+	if descriptive {
+		return util.ErrDescriptiveSection
+	}
+	return nil
+}
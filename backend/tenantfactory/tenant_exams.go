@@ -0,0 +1,125 @@
+package tenantfactory
+
+import (
+	"database/sql"
+	tenantmodels "ednevnik-backend/models/tenant"
+	"ednevnik-backend/notification"
+	"ednevnik-backend/util"
+	"fmt"
+	"time"
+)
+
+// GetExamLimits retrieves the exam limits of the tenant.
+func (t *ConfigurableTenant) GetExamLimits() (*tenantmodels.ExamLimits, error) {
+	return util.GetExamLimits(t.UserTenantDB)
+}
+
+// SetExamLimits replaces the exam limits of the tenant.
+func (t *ConfigurableTenant) SetExamLimits(
+	limits tenantmodels.ExamLimits,
+) (*tenantmodels.ExamLimits, error) {
+	err := util.SetExamLimits(limits, t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+	return util.GetExamLimits(t.UserTenantDB)
+}
+
+// GetPlannedExamsForSection retrieves the exams booked in a section between
+// two dates.
+func (t *ConfigurableTenant) GetPlannedExamsForSection(
+	sectionID int, dateFrom, dateTo string,
+) ([]tenantmodels.PlannedExam, error) {
+	return util.GetPlannedExamsForSection(
+		sectionID, dateFrom, dateTo, t.UserTenantDB,
+	)
+}
+
+// GetUpcomingPlannedExams retrieves the exams of a section from today on.
+func (t *ConfigurableTenant) GetUpcomingPlannedExams(
+	sectionID int,
+) ([]tenantmodels.PlannedExam, error) {
+	return util.GetPlannedExamsForSection(
+		sectionID, time.Now().Format("2006-01-02"), "", t.UserTenantDB,
+	)
+}
+
+// CreatePlannedExam books an exam in the calendar of a section and notifies
+// the pupils. The subject must be on the section schedule that day and the
+// exam limits of the tenant must not be exceeded.
+func (t *ConfigurableTenant) CreatePlannedExam(
+	exam tenantmodels.PlannedExam, teacherID int,
+) (*tenantmodels.PlannedExam, error) {
+	semesterCode, err := util.CheckPlannedExamDate(
+		t.UserWorkspaceDB, t.UserTenantDB, fmt.Sprintf("%d", t.TenantData.ID),
+		exam.SectionID, exam.ExamDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := t.GetScheduleForSection(fmt.Sprintf("%d", exam.SectionID))
+	if err != nil {
+		return nil, err
+	}
+	if !util.SubjectScheduledOn(schedule, exam.SubjectCode, exam.ExamDate) {
+		return nil, fmt.Errorf(
+			"predmet %s nije na rasporedu odjeljenja na dan %s",
+			exam.SubjectCode, exam.ExamDate,
+		)
+	}
+
+	teacherForSignature, err := util.GetTeacherByID(
+		fmt.Sprintf("%d", teacherID), t.UserWorkspaceDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+	signature := teacherForSignature.Name + " " + teacherForSignature.LastName
+
+	exam.SemesterCode = semesterCode
+	exam.TeacherID = teacherID
+	createdExam, err := util.CreatePlannedExam(exam, signature, t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+
+	pupils, err := util.GetPupilsForSection(
+		fmt.Sprintf("%d", exam.SectionID), false, t.UserTenantDB,
+	)
+	if err == nil {
+		for _, pupil := range pupils {
+			t.notifyPupil(
+				notification.EventExamPlanned, pupil.ID, createdExam.SubjectCode,
+				map[string]string{"exam_date": createdExam.ExamDate},
+			)
+		}
+	}
+
+	return createdExam, nil
+}
+
+// DeletePlannedExam cancels an exam of a section that has not taken place.
+// Teachers can only cancel exams they booked or exams of their homeroom
+// section, teacher ID 0 cancels any exam.
+func (t *ConfigurableTenant) DeletePlannedExam(sectionID, examID, teacherID int) error {
+	exam, err := util.GetPlannedExamByID(examID, t.UserTenantDB)
+	if err != nil {
+		return err
+	}
+	if exam.SectionID != sectionID {
+		return sql.ErrNoRows
+	}
+	if exam.ExamDate < time.Now().Format("2006-01-02") {
+		return fmt.Errorf("provjera koja je održana ne može se otkazati")
+	}
+
+	return util.DeletePlannedExam(examID, sectionID, teacherID, t.UserTenantDB)
+}
+
+// GetPlannedExamByID retrieves a booked exam.
+func (t *ConfigurableTenant) GetPlannedExamByID(
+	examID int,
+) (*tenantmodels.PlannedExam, error) {
+	return util.GetPlannedExamByID(examID, t.UserTenantDB)
+}
@@ -0,0 +1,76 @@
+package tenantfactory
+
+import (
+	"database/sql"
+	tenantmodels "ednevnik-backend/models/tenant"
+	wpmodels "ednevnik-backend/models/workspace"
+	"ednevnik-backend/util"
+)
+
+// CreateAttendanceExcuse stores an excuse for the absences of a pupil in a
+// section, submitted by the pupil or one of their parents.
+func (t *ConfigurableTenant) CreateAttendanceExcuse(
+	excuse tenantmodels.AttendanceExcuse, claims *wpmodels.Claims,
+) (*tenantmodels.AttendanceExcuse, error) {
+	excuse.SubmittedByAccountID = claims.AccountID
+	excuse.SubmittedByType = claims.AccountType
+	return util.CreateAttendanceExcuse(excuse, t.UserTenantDB)
+}
+
+// GetAttendanceExcusesForPupil retrieves all excuses of a pupil in a section
+// from the tenant's database.
+func (t *ConfigurableTenant) GetAttendanceExcusesForPupil(
+	pupilID, sectionID int,
+) ([]tenantmodels.AttendanceExcuse, error) {
+	return util.GetAttendanceExcusesForPupil(pupilID, sectionID, t.UserTenantDB)
+}
+
+// GetAttendanceExcusesForSection retrieves the excuses of a section with the
+// given status, or all of them when the status is empty.
+func (t *ConfigurableTenant) GetAttendanceExcusesForSection(
+	sectionID int, status string,
+) ([]tenantmodels.AttendanceExcuse, error) {
+	return util.GetAttendanceExcusesForSection(sectionID, status, t.UserTenantDB)
+}
+
+// GetAttendanceExcuseAttachment retrieves the attachment of an excuse.
+func (t *ConfigurableTenant) GetAttendanceExcuseAttachment(
+	excuseID, pupilID, sectionID int,
+) (*tenantmodels.AttendanceExcuse, error) {
+	return util.GetAttendanceExcuseAttachment(
+		excuseID, pupilID, sectionID, t.UserTenantDB,
+	)
+}
+
+// DecideAttendanceExcuses approves or rejects pending excuses of a section and
+// updates the covered attendance records. The decision is signed with the name
+// of the logged in user.
+func (t *ConfigurableTenant) DecideAttendanceExcuses(
+	sectionID int,
+	decision tenantmodels.AttendanceExcuseDecision,
+	claims *wpmodels.Claims,
+) error {
+	for _, excuseID := range decision.ExcuseIDs {
+		excuse, err := util.GetAttendanceExcuseByID(excuseID, t.UserTenantDB)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = t.checkSemesterRangeWritable(sectionID, excuse.DateFrom, excuse.DateTo)
+		if err != nil {
+			return err
+		}
+	}
+
+	semesters, err := t.absenceSemesters(sectionID)
+	if err != nil {
+		return err
+	}
+
+	signature := claims.Name + " " + claims.LastName
+	return util.DecideAttendanceExcuses(
+		sectionID, decision, claims.AccountID, signature, semesters, t.UserTenantDB,
+	)
+}
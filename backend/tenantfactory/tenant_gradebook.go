@@ -3,7 +3,9 @@ package tenantfactory
 import (
 	commonmodels "ednevnik-backend/models/common"
 	tenantmodels "ednevnik-backend/models/tenant"
+	"ednevnik-backend/notification"
 	"ednevnik-backend/util"
+	"errors"
 	"fmt"
 )
 
@@ -30,6 +32,17 @@ func (t *ConfigurableTenant) GetSectionGradesForSubject(
 func (t *ConfigurableTenant) CreateGrade(
 	grade *tenantmodels.Grade,
 ) (*tenantmodels.GradePupilGroup, error) {
+	if err := t.checkPupilInSection(grade.PupilID, grade.SectionID); err != nil {
+		return nil, err
+	}
+	if err := t.checkNumericGradesAllowed(grade.SectionID); err != nil {
+		return nil, err
+	}
+	err := t.checkSemesterWritable(grade.SectionID, grade.SemesterCode, grade.GradeDate)
+	if err != nil {
+		return nil, err
+	}
+
 	createdGrade, err := util.CreateGrade(
 		grade,
 		t.UserTenantDB,
@@ -39,13 +52,95 @@ func (t *ConfigurableTenant) CreateGrade(
 		return nil, err
 	}
 
+	t.notifyPupil(
+		notification.EventGradeCreated, grade.PupilID, grade.SubjectCode,
+		map[string]string{"grade": fmt.Sprintf("%d", grade.Grade)},
+	)
+
 	return createdGrade, nil
 }
 
+// CreateGradesBulk saves the grades of many pupils of a section at once. All
+// entries are validated first and nothing is saved when one of them fails.
+func (t *ConfigurableTenant) CreateGradesBulk(
+	sectionID int,
+	semesterCode,
+	subjectCode string,
+	teacherID int,
+	entries []tenantmodels.BulkGradeEntry,
+) (*tenantmodels.BulkGradesResult, error) {
+	if err := t.checkNumericGradesAllowed(sectionID); err != nil {
+		return nil, err
+	}
+	if err := t.checkSemesterWritable(sectionID, semesterCode, ""); err != nil {
+		return nil, err
+	}
+
+	rowErrors, err := util.ValidateBulkGrades(sectionID, entries, t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+
+	dateErrors := map[string]error{}
+	for i, entry := range entries {
+		dateErr, checked := dateErrors[entry.GradeDate]
+		if !checked {
+			dateErr = t.checkSemesterWritable(sectionID, semesterCode, entry.GradeDate)
+			if dateErr != nil && !errors.Is(dateErr, util.ErrOutsideSemester) {
+				return nil, dateErr
+			}
+			dateErrors[entry.GradeDate] = dateErr
+		}
+		if dateErr != nil {
+			rowErrors = append(rowErrors, tenantmodels.BulkGradeError{
+				Index: i, PupilID: entry.PupilID, Error: dateErr.Error(),
+			})
+		}
+	}
+	if len(rowErrors) > 0 {
+		util.SortBulkGradeErrors(rowErrors)
+		return &tenantmodels.BulkGradesResult{Errors: rowErrors}, nil
+	}
+
+	result, err := util.CreateGradesBulk(
+		sectionID,
+		semesterCode,
+		subjectCode,
+		teacherID,
+		entries,
+		t.UserTenantDB,
+		t.UserWorkspaceDB,
+	)
+	if err != nil || len(result.Errors) > 0 {
+		return result, err
+	}
+
+	for _, entry := range entries {
+		t.notifyPupil(
+			notification.EventGradeCreated, entry.PupilID, subjectCode,
+			map[string]string{"grade": fmt.Sprintf("%d", entry.Grade)},
+		)
+	}
+
+	return result, nil
+}
+
 // DeleteGrade removes a grade from the database and returns the updated grades for the pupil.
 func (t *ConfigurableTenant) DeleteGrade(
 	grade *tenantmodels.Grade, teacherID int,
 ) (*tenantmodels.GradePupilGroup, error) {
+	storedGrade, err := util.GetGradeByID(grade.ID, t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+	keepStoredGradeOwner(grade, storedGrade)
+	err = t.checkSemesterWritable(
+		storedGrade.SectionID, storedGrade.SemesterCode, storedGrade.GradeDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	teacherForSignature, err := util.GetTeacherByID(
 		fmt.Sprintf("%d", teacherID), t.UserWorkspaceDB,
 	)
@@ -57,19 +152,38 @@ func (t *ConfigurableTenant) DeleteGrade(
 	gradesAfterDeletion, err := util.DeleteGrade(
 		grade,
 		signature,
+		teacherID,
 		t.UserTenantDB,
 		t.UserWorkspaceDB,
 	)
 	if err != nil {
 		return nil, err
 	}
+
+	t.notifyPupil(
+		notification.EventGradeDeleted, grade.PupilID, grade.SubjectCode, nil,
+	)
+
 	return gradesAfterDeletion, err
 }
 
-// UpdateGrade updates an existing grade
+// UpdateGrade updates an existing grade. The grade stays in its semester, so
+// the new date must fall within it.
 func (t *ConfigurableTenant) UpdateGrade(
 	grade *tenantmodels.Grade,
 ) (*tenantmodels.GradePupilGroup, error) {
+	storedGrade, err := util.GetGradeByID(grade.ID, t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+	keepStoredGradeOwner(grade, storedGrade)
+	err = t.checkSemesterWritable(
+		storedGrade.SectionID, storedGrade.SemesterCode, grade.GradeDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	createdGrade, err := util.EditGrade(
 		grade,
 		t.UserTenantDB,
@@ -79,9 +193,24 @@ func (t *ConfigurableTenant) UpdateGrade(
 		return nil, err
 	}
 
+	t.notifyPupil(
+		notification.EventGradeUpdated, grade.PupilID, grade.SubjectCode,
+		map[string]string{"grade": fmt.Sprintf("%d", grade.Grade)},
+	)
+
 	return createdGrade, nil
 }
 
+// keepStoredGradeOwner replaces the pupil, section, subject and semester of a
+// grade from the request with the ones of the stored grade, a grade can not
+// be moved to another pupil or section
+func keepStoredGradeOwner(grade, storedGrade *tenantmodels.Grade) {
+	grade.PupilID = storedGrade.PupilID
+	grade.SectionID = storedGrade.SectionID
+	grade.SubjectCode = storedGrade.SubjectCode
+	grade.SemesterCode = storedGrade.SemesterCode
+}
+
 // GetPupilGradesForSectionPupil returns subject grades for a pupil in a section
 func (t *ConfigurableTenant) GetPupilGradesForSectionPupil(
 	sectionID,
@@ -115,6 +244,18 @@ func (t *ConfigurableTenant) GetSectionBehaviourGradesForPupil(
 	return behaviourGrades, nil
 }
 
+// GetGradeByID retrieves the current version of a grade by its ID.
+func (t *ConfigurableTenant) GetGradeByID(gradeID int) (*tenantmodels.Grade, error) {
+	return util.GetGradeByID(gradeID, t.UserTenantDB)
+}
+
+// GetBehaviourGradeByID retrieves the current version of a behaviour grade by its ID.
+func (t *ConfigurableTenant) GetBehaviourGradeByID(
+	behaviourGradeID int,
+) (*tenantmodels.BehaviourGrade, error) {
+	return util.GetBehaviourGradeByID(behaviourGradeID, t.UserTenantDB)
+}
+
 // GetGradeEditHistory retrieves the edit history of a specific grade by its ID.
 func (t *ConfigurableTenant) GetGradeEditHistory(gradeID int) ([]tenantmodels.Grade, error) {
 	grades, err := util.GetGradeEditHistoryHelper(
@@ -213,5 +354,20 @@ func (t *ConfigurableTenant) GetCompleteGradebookData(
 	}
 	completeGradebook.Absences = absences
 
+	measures, err := util.GetPedagogicalMeasuresForSection(
+		sectionID,
+		t.UserTenantDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+	completeGradebook.Measures = measures
+
+	gradingSchemes, err := util.GetGradingSchemes(t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+	completeGradebook.GradingSchemes = gradingSchemes
+
 	return &completeGradebook, nil
 }
@@ -0,0 +1,74 @@
+package tenantfactory
+
+import (
+	tenantmodels "ednevnik-backend/models/tenant"
+	"ednevnik-backend/notification"
+	"ednevnik-backend/util"
+	"fmt"
+)
+
+// GetGradingSchemes retrieves the grading schemes of the tenant.
+func (t *ConfigurableTenant) GetGradingSchemes() ([]tenantmodels.GradingScheme, error) {
+	return util.GetGradingSchemes(t.UserTenantDB)
+}
+
+// SetGradingSchemes replaces the grading schemes of the tenant.
+func (t *ConfigurableTenant) SetGradingSchemes(
+	schemes []tenantmodels.GradingScheme,
+) ([]tenantmodels.GradingScheme, error) {
+	err := util.SetGradingSchemes(schemes, t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+	return util.GetGradingSchemes(t.UserTenantDB)
+}
+
+// GetFinalGradeProposals proposes final grades for the pupils of a section.
+func (t *ConfigurableTenant) GetFinalGradeProposals(
+	sectionID int,
+	semesterCode,
+	subjectCode string,
+) ([]tenantmodels.FinalGradeProposal, error) {
+	return util.GetFinalGradeProposals(
+		sectionID, semesterCode, subjectCode, t.UserTenantDB,
+	)
+}
+
+// AcceptFinalGrades saves accepted final grades in bulk and notifies the
+// pupils that received one.
+func (t *ConfigurableTenant) AcceptFinalGrades(
+	sectionID int,
+	semesterCode,
+	subjectCode string,
+	teacherID int,
+	accepted []tenantmodels.AcceptedFinalGrade,
+) (*tenantmodels.AcceptedFinalGradesResult, error) {
+	if err := t.checkNumericGradesAllowed(sectionID); err != nil {
+		return nil, err
+	}
+	if err := t.checkSemesterWritable(sectionID, semesterCode, ""); err != nil {
+		return nil, err
+	}
+
+	result, err := util.AcceptFinalGrades(
+		sectionID,
+		semesterCode,
+		subjectCode,
+		teacherID,
+		accepted,
+		t.UserTenantDB,
+		t.UserWorkspaceDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, grade := range result.Created {
+		t.notifyPupil(
+			notification.EventGradeCreated, grade.PupilID, grade.SubjectCode,
+			map[string]string{"grade": fmt.Sprintf("%d", grade.Grade)},
+		)
+	}
+
+	return result, nil
+}
@@ -5,6 +5,7 @@ import (
 	commonmodels "ednevnik-backend/models/common"
 	tenantmodels "ednevnik-backend/models/tenant"
 	wpmodels "ednevnik-backend/models/workspace"
+	"ednevnik-backend/notification"
 	"ednevnik-backend/util"
 	"fmt"
 )
@@ -23,6 +24,14 @@ func (t *ConfigurableTenant) SendPupilSectionInvite(
 		)
 	}
 
+	t.notifyPupil(
+		notification.EventPupilSectionInvite, pupilID, "",
+		map[string]string{
+			"section_name": newInvite.SectionName,
+			"path":         "/pupil_invites",
+		},
+	)
+
 	return newInvite, nil
 }
 
@@ -200,6 +209,16 @@ func (t *ConfigurableTenant) HandleTeacherSectionAssignments(
 		)
 	}
 
+	for _, assignment := range assignmentRequest {
+		if len(assignment.PendingSubjects) > 0 || assignment.PendingHomeroom {
+			t.notifyTeacher(
+				notification.EventTeacherSectionInvite, teacherID,
+				map[string]string{"path": "/teacher_invites"},
+			)
+			break
+		}
+	}
+
 	// Get the count of teacher section records
 	var teacherSectionCount int
 	teacherSectionsCountQuery := `SELECT COUNT(*) FROM teachers_sections
@@ -0,0 +1,105 @@
+package tenantfactory
+
+import (
+	"ednevnik-backend/jobs"
+	"ednevnik-backend/util"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Background job types of tenants
+const (
+	JobArchiveSection        = "archive_section"
+	JobArchiveRemedialResult = "archive_remedial_result"
+)
+
+// archiveSectionJob is the payload of a section archiving job
+type archiveSectionJob struct {
+	TenantID  int64 `json:"tenant_id"`
+	SectionID int   `json:"section_id"`
+}
+
+// archiveRemedialResultJob is the payload of a job that archives the final
+// grades of a pupil after a remedial exam
+type archiveRemedialResultJob struct {
+	TenantID  int64 `json:"tenant_id"`
+	SectionID int   `json:"section_id"`
+	PupilID   int   `json:"pupil_id"`
+}
+
+// RegisterJobHandlers registers the handlers of tenant background jobs
+func RegisterJobHandlers() {
+	jobs.Register(JobArchiveSection, runArchiveSectionJob)
+	jobs.Register(JobArchiveRemedialResult, runArchiveRemedialResultJob)
+}
+
+// runArchiveSectionJob archives a section queued by ArchiveSection. It runs
+// with the root DB user because it is not tied to a request.
+func runArchiveSectionJob(payload json.RawMessage) error {
+	var job archiveSectionJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	tenantInstance, err := AccountID(strconv.FormatInt(job.TenantID, 10), "root")
+	if err != nil {
+		return err
+	}
+	t, ok := tenantInstance.(*ConfigurableTenant)
+	if !ok {
+		return jobs.Permanent(fmt.Errorf("unsupported tenant implementation"))
+	}
+
+	section, err := util.GetSectionByID(int64(job.SectionID), t.UserTenantDB)
+	if err != nil {
+		return err
+	}
+	if section.Archived {
+		return nil
+	}
+
+	// Grades may have changed since the job was queued, in which case
+	// retrying will not help
+	_, _, err = util.ValidateSectionArchivable(
+		section, t.UserTenantDB, t.UserWorkspaceDB,
+	)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+
+	return util.ArchiveSectionHelper(
+		job.SectionID,
+		&t.TenantData,
+		&t.Config,
+		t.UserTenantDB,
+		t.UserWorkspaceDB,
+	)
+}
+
+// runArchiveRemedialResultJob copies the final grades of a pupil of an
+// archived section to the workspace once the pupil passed a remedial exam
+func runArchiveRemedialResultJob(payload json.RawMessage) error {
+	var job archiveRemedialResultJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	tenantInstance, err := AccountID(strconv.FormatInt(job.TenantID, 10), "root")
+	if err != nil {
+		return err
+	}
+	t, ok := tenantInstance.(*ConfigurableTenant)
+	if !ok {
+		return jobs.Permanent(fmt.Errorf("unsupported tenant implementation"))
+	}
+
+	return util.ArchiveRemedialPupilHelper(
+		job.SectionID,
+		job.PupilID,
+		&t.TenantData,
+		&t.Config,
+		t.UserTenantDB,
+		t.UserWorkspaceDB,
+	)
+}
@@ -0,0 +1,39 @@
+package tenantfactory
+
+import (
+	tenantmodels "ednevnik-backend/models/tenant"
+	"ednevnik-backend/util"
+)
+
+// VerifyGradebookLedger checks the grades, lessons and behaviour grades of a
+// section against its signed ledger.
+func (t *ConfigurableTenant) VerifyGradebookLedger(
+	sectionID int,
+) (*tenantmodels.LedgerVerification, error) {
+	if _, err := util.GetSectionByID(int64(sectionID), t.UserTenantDB); err != nil {
+		return nil, err
+	}
+	return util.VerifySectionLedger(sectionID, t.UserTenantDB)
+}
+
+// SealGradebookLedger signs the rows a section had before the ledger existed
+// and verifies the section afterwards. A section is sealed only once.
+func (t *ConfigurableTenant) SealGradebookLedger(
+	sectionID, teacherID int,
+) (*tenantmodels.LedgerVerification, error) {
+	if _, err := util.GetSectionByID(int64(sectionID), t.UserTenantDB); err != nil {
+		return nil, err
+	}
+
+	sealed, err := util.SealSectionLedger(sectionID, teacherID, t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+
+	verification, err := util.VerifySectionLedger(sectionID, t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+	verification.Sealed = sealed
+	return verification, nil
+}
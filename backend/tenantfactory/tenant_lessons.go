@@ -3,6 +3,7 @@ package tenantfactory
 import (
 	tenantmodels "ednevnik-backend/models/tenant"
 	wpmodels "ednevnik-backend/models/workspace"
+	"ednevnik-backend/notification"
 	"ednevnik-backend/util"
 	"fmt"
 )
@@ -27,6 +28,13 @@ func (t *ConfigurableTenant) GetLessonsForSection(
 func (t *ConfigurableTenant) CreateSectionLesson(
 	requestData tenantmodels.LessonData, teacherID int,
 ) (*tenantmodels.LessonData, error) {
+	err := t.checkSemesterWritable(
+		requestData.LessonData.SectionID, "", requestData.LessonData.Date,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	teacherForSignature, err := util.GetTeacherByID(
 		fmt.Sprintf("%d", teacherID),
 		t.UserWorkspaceDB,
@@ -37,12 +45,19 @@ func (t *ConfigurableTenant) CreateSectionLesson(
 
 	signature := teacherForSignature.Name + " " + teacherForSignature.LastName
 
+	semesters, err := t.absenceSemesters(requestData.LessonData.SectionID)
+	if err != nil {
+		return nil, err
+	}
+
 	newLesson, err := util.CreateLesson(
-		requestData, t.UserTenantDB, signature,
+		requestData, t.UserTenantDB, signature, teacherID, semesters,
 	)
 	if err != nil {
 		return nil, err
 	}
+
+	t.notifyAbsences(newLesson, nil)
 	return newLesson, nil
 }
 
@@ -61,22 +76,59 @@ func (t *ConfigurableTenant) UpdateLesson(
 
 	signature := teacherForSignature.Name + " " + teacherForSignature.LastName
 
+	oldLesson, err := util.GetLessonByID(lessonID, t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+	err = t.checkSemesterWritable(
+		oldLesson.LessonData.SectionID, "", oldLesson.LessonData.Date,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if requestData.LessonData.Date != "" {
+		err = t.checkSemesterWritable(
+			oldLesson.LessonData.SectionID, "", requestData.LessonData.Date,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	semesters, err := t.absenceSemesters(requestData.LessonData.SectionID)
+	if err != nil {
+		return nil, err
+	}
+
 	updatedLesson, err := util.UpdateLesson(
-		lessonID, requestData, t.UserTenantDB, signature,
+		lessonID, requestData, t.UserTenantDB, signature, teacherID, semesters,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return updatedLesson, err
+
+	t.notifyAbsences(updatedLesson, oldLesson)
+	return updatedLesson, nil
 }
 
 // DeleteLesson removes a lesson identified by lessonID from the tenant's database.
 // Returns an error if the deletion operation fails.
 func (t *ConfigurableTenant) DeleteLesson(
-	lessonID int,
+	lessonID, teacherID int,
 ) error {
-	err := util.DeleteLesson(
-		lessonID, t.UserTenantDB,
+	lesson, err := util.GetLessonByID(lessonID, t.UserTenantDB)
+	if err != nil {
+		return err
+	}
+	err = t.checkSemesterWritable(
+		lesson.LessonData.SectionID, "", lesson.LessonData.Date,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = util.DeleteLesson(
+		lessonID, t.UserTenantDB, teacherID,
 	)
 	return err
 }
@@ -121,12 +173,56 @@ func (t *ConfigurableTenant) GetAbsentAttendancesForPupil(
 }
 
 // HandleAttendanceAction processes attendance actions such as marking a pupil's
-// absence as excused or unexcused.
+// absence as excused or unexcused, and issues pedagogical measures the pupil
+// earned with it.
 func (t *ConfigurableTenant) HandleAttendanceAction(
 	action tenantmodels.AttendanceAction,
 ) error {
-	err := util.HandleAttendanceActionHelper(
-		action, t.UserTenantDB,
+	lesson, err := util.GetLessonByID(action.LessonID, t.UserTenantDB)
+	if err != nil {
+		return err
+	}
+	err = t.checkSemesterWritable(
+		lesson.LessonData.SectionID, "", lesson.LessonData.Date,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	semesters, err := t.absenceSemesters(lesson.LessonData.SectionID)
+	if err != nil {
+		return err
+	}
+
+	return util.HandleAttendanceActionHelper(
+		action, lesson.LessonData.SectionID, semesters, t.UserTenantDB,
+	)
+}
+
+// notifyAbsences notifies pupils that are absent from a lesson, skipping the
+// ones that were already absent before the lesson was updated
+func (t *ConfigurableTenant) notifyAbsences(
+	lesson, previous *tenantmodels.LessonData,
+) {
+	wasAbsent := map[int]bool{}
+	if previous != nil {
+		for _, attendance := range previous.PupilAttendanceData {
+			wasAbsent[attendance.PupilID] = attendance.Status != "present"
+		}
+	}
+
+	for _, attendance := range lesson.PupilAttendanceData {
+		if attendance.Status == "present" || wasAbsent[attendance.PupilID] {
+			continue
+		}
+		t.notifyPupil(
+			notification.EventAbsenceRecorded,
+			attendance.PupilID,
+			lesson.LessonData.SubjectCode,
+			map[string]string{
+				"date":          lesson.LessonData.Date,
+				"period_number": fmt.Sprintf("%d", lesson.LessonData.PeriodNumber),
+			},
+		)
+	}
 }
@@ -0,0 +1,52 @@
+package tenantfactory
+
+import (
+	tenantmodels "ednevnik-backend/models/tenant"
+	wpmodels "ednevnik-backend/models/workspace"
+	"ednevnik-backend/util"
+	"fmt"
+)
+
+// GetAbsenceThresholds retrieves the absence thresholds of the tenant.
+func (t *ConfigurableTenant) GetAbsenceThresholds() ([]tenantmodels.AbsenceThreshold, error) {
+	return util.GetAbsenceThresholds(t.UserTenantDB)
+}
+
+// SetAbsenceThresholds replaces the absence thresholds of the tenant.
+func (t *ConfigurableTenant) SetAbsenceThresholds(
+	thresholds []tenantmodels.AbsenceThreshold,
+) ([]tenantmodels.AbsenceThreshold, error) {
+	err := util.SetAbsenceThresholds(thresholds, t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+	return util.GetAbsenceThresholds(t.UserTenantDB)
+}
+
+// GetPedagogicalMeasuresForSection retrieves the pedagogical measures issued
+// to pupils of a section.
+func (t *ConfigurableTenant) GetPedagogicalMeasuresForSection(
+	sectionID int,
+) ([]tenantmodels.PedagogicalMeasure, error) {
+	return util.GetPedagogicalMeasuresForSection(sectionID, t.UserTenantDB)
+}
+
+// GetPedagogicalMeasuresForPupil retrieves the pedagogical measures issued to
+// a pupil in a section.
+func (t *ConfigurableTenant) GetPedagogicalMeasuresForPupil(
+	pupilID, sectionID int,
+) ([]tenantmodels.PedagogicalMeasure, error) {
+	return util.GetPedagogicalMeasuresForPupil(pupilID, sectionID, t.UserTenantDB)
+}
+
+// absenceSemesters returns the semesters of a section the absence thresholds
+// are evaluated in, when attendance of the section changes
+func (t *ConfigurableTenant) absenceSemesters(
+	sectionID int,
+) ([]wpmodels.TenantSemester, error) {
+	semesters, err := t.GetSemestersForSection(fmt.Sprintf("%d", sectionID))
+	if err != nil {
+		return nil, fmt.Errorf("error getting semesters for absence thresholds: %v", err)
+	}
+	return semesters, nil
+}
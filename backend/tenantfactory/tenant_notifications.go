@@ -0,0 +1,49 @@
+package tenantfactory
+
+import (
+	"ednevnik-backend/notification"
+)
+
+// notifyPupil notifies a pupil of the tenant and their parents. The tenant,
+// pupil and subject names are added to the template data. Lookup failures
+// only leave the names empty, notifications never fail the request.
+func (t *ConfigurableTenant) notifyPupil(
+	event string, pupilID int, subjectCode string, data map[string]string,
+) {
+	if data == nil {
+		data = map[string]string{}
+	}
+	data["tenant_name"] = t.TenantData.TenantName
+	if _, ok := data["path"]; !ok {
+		data["path"] = "/pupil_home"
+	}
+
+	var name, lastName string
+	query := `SELECT name, last_name FROM pupils WHERE id = ?`
+	if err := t.UserTenantDB.QueryRow(query, pupilID).Scan(&name, &lastName); err == nil {
+		data["pupil_name"] = name + " " + lastName
+	}
+
+	if subjectCode != "" {
+		data["subject_name"] = subjectCode
+		var subjectName string
+		query := `SELECT subject_name FROM ednevnik_workspace.subjects
+		WHERE subject_code = ?`
+		if err := t.UserWorkspaceDB.QueryRow(query, subjectCode).Scan(&subjectName); err == nil {
+			data["subject_name"] = subjectName
+		}
+	}
+
+	notification.NotifyPupil(event, t.TenantData.ID, pupilID, data)
+}
+
+// notifyTeacher notifies a teacher about an event of the tenant
+func (t *ConfigurableTenant) notifyTeacher(
+	event string, teacherID int, data map[string]string,
+) {
+	if data == nil {
+		data = map[string]string{}
+	}
+	data["tenant_name"] = t.TenantData.TenantName
+	notification.NotifyTeacher(event, t.TenantData.ID, teacherID, data)
+}
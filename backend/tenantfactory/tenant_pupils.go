@@ -3,6 +3,7 @@ package tenantfactory
 import (
 	commonmodels "ednevnik-backend/models/common"
 	tenantmodels "ednevnik-backend/models/tenant"
+	"ednevnik-backend/notification"
 	"ednevnik-backend/util"
 	"fmt"
 )
@@ -124,6 +125,24 @@ func (t *ConfigurableTenant) DeletePupilFromTenant(pupilID string) error {
 func (t *ConfigurableTenant) UpdatePupilBehaviourGrade(
 	behaviourGradesToUpdate tenantmodels.BehaviourGrade, teacherID int,
 ) (*tenantmodels.BehaviourGrade, error) {
+	// The stored grade decides which pupil, section and semester are updated
+	storedBehaviourGrade, err := util.GetBehaviourGradeByID(
+		behaviourGradesToUpdate.ID, t.UserTenantDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+	behaviourGradesToUpdate.PupilID = storedBehaviourGrade.PupilID
+	behaviourGradesToUpdate.SectionID = storedBehaviourGrade.SectionID
+	behaviourGradesToUpdate.SemesterCode = storedBehaviourGrade.SemesterCode
+
+	err = t.checkSemesterWritable(
+		behaviourGradesToUpdate.SectionID, behaviourGradesToUpdate.SemesterCode, "",
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	teacherForSignature, err := util.GetTeacherByID(
 		fmt.Sprintf("%d", teacherID), t.UserWorkspaceDB,
 	)
@@ -135,6 +154,7 @@ func (t *ConfigurableTenant) UpdatePupilBehaviourGrade(
 
 	behaviourGrade, err := util.UpdatePupilBehaviourGradeHelper(
 		signature,
+		teacherID,
 		behaviourGradesToUpdate,
 		t.UserTenantDB,
 	)
@@ -142,5 +162,10 @@ func (t *ConfigurableTenant) UpdatePupilBehaviourGrade(
 		return nil, err
 	}
 
+	t.notifyPupil(
+		notification.EventBehaviourGradeChanged, behaviourGradesToUpdate.PupilID, "",
+		map[string]string{"behaviour": behaviourGradesToUpdate.Behaviour},
+	)
+
 	return behaviourGrade, nil
 }
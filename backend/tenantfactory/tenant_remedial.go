@@ -0,0 +1,126 @@
+package tenantfactory
+
+import (
+	"ednevnik-backend/jobs"
+	tenantmodels "ednevnik-backend/models/tenant"
+	"ednevnik-backend/notification"
+	"ednevnik-backend/util"
+	"fmt"
+	"log"
+)
+
+// GetRemedialCandidates retrieves the pupils of a section that have to take a
+// remedial or class exam.
+func (t *ConfigurableTenant) GetRemedialCandidates(
+	sectionID int,
+) ([]tenantmodels.RemedialCandidate, error) {
+	return util.GetRemedialCandidates(
+		sectionID, t.Config.MaxSemesterCode, t.UserTenantDB, t.UserWorkspaceDB,
+	)
+}
+
+// CreateRemedialExam schedules a remedial exam with its commission.
+func (t *ConfigurableTenant) CreateRemedialExam(
+	request tenantmodels.RemedialExamRequest, accountID int,
+) (*tenantmodels.RemedialExam, error) {
+	return util.CreateRemedialExam(
+		request, t.Config.MaxSemesterCode, t.TenantData.ID, accountID,
+		t.UserTenantDB, t.UserWorkspaceDB,
+	)
+}
+
+// GetRemedialExams retrieves the remedial exams of the tenant, optionally of
+// one section.
+func (t *ConfigurableTenant) GetRemedialExams(
+	sectionID int,
+) ([]tenantmodels.RemedialExam, error) {
+	return util.GetRemedialExams(sectionID, 0, t.UserTenantDB)
+}
+
+// GetRemedialExamsForTeacher retrieves the remedial exams a teacher is on the
+// commission of.
+func (t *ConfigurableTenant) GetRemedialExamsForTeacher(
+	teacherID int,
+) ([]tenantmodels.RemedialExam, error) {
+	return util.GetRemedialExams(0, teacherID, t.UserTenantDB)
+}
+
+// GetRemedialExamByID retrieves a remedial exam with its commission and
+// pupils.
+func (t *ConfigurableTenant) GetRemedialExamByID(
+	examID int,
+) (*tenantmodels.RemedialExam, error) {
+	return util.GetRemedialExamByID(examID, t.UserTenantDB)
+}
+
+// DeleteRemedialExam cancels a remedial exam without recorded results.
+func (t *ConfigurableTenant) DeleteRemedialExam(examID int) error {
+	if _, err := util.GetRemedialExamByID(examID, t.UserTenantDB); err != nil {
+		return err
+	}
+	return util.DeleteRemedialExam(examID, t.UserTenantDB)
+}
+
+// RecordRemedialExamResults records the results of a remedial exam and
+// notifies the pupils. Pupils of archived sections that passed get their
+// final grades copied to the workspace in the background.
+func (t *ConfigurableTenant) RecordRemedialExamResults(
+	examID, teacherID int, entries []tenantmodels.RemedialResultEntry,
+) (*tenantmodels.RemedialExam, error) {
+	exam, err := util.RecordRemedialExamResults(
+		examID, teacherID, entries, t.UserTenantDB, t.UserWorkspaceDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	section, err := util.GetSectionByID(int64(exam.SectionID), t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := make(map[int]bool, len(entries))
+	for _, entry := range entries {
+		recorded[entry.PupilID] = true
+	}
+
+	for _, result := range exam.Results {
+		if !recorded[result.PupilID] {
+			continue
+		}
+
+		outcome := "nije pristupio/la ispitu"
+		if result.Attended != nil && *result.Attended {
+			outcome = fmt.Sprintf("ocjena %d", result.Grade)
+		}
+		t.notifyPupil(
+			notification.EventRemedialExamRecorded, result.PupilID,
+			exam.SubjectCode,
+			map[string]string{"exam_date": exam.ExamDate, "result": outcome},
+		)
+
+		if section.Archived && result.Grade >= 2 {
+			_, err := jobs.Enqueue(JobArchiveRemedialResult, archiveRemedialResultJob{
+				TenantID:  t.TenantData.ID,
+				SectionID: exam.SectionID,
+				PupilID:   result.PupilID,
+			})
+			if err != nil {
+				log.Printf(
+					"Failed to queue archiving of remedial result for pupil %d: %v",
+					result.PupilID, err,
+				)
+			}
+		}
+	}
+
+	return exam, nil
+}
+
+// IsRemedialCommissionMember checks if a teacher is on the commission of a
+// remedial exam.
+func (t *ConfigurableTenant) IsRemedialCommissionMember(
+	examID, teacherID int,
+) (bool, error) {
+	return util.IsRemedialCommissionMember(examID, teacherID, t.UserTenantDB)
+}
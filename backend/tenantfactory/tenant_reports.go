@@ -0,0 +1,27 @@
+package tenantfactory
+
+import (
+	tenantmodels "ednevnik-backend/models/tenant"
+	"ednevnik-backend/util"
+	"fmt"
+)
+
+// GetSectionReport builds the success report of a section in a semester.
+func (t *ConfigurableTenant) GetSectionReport(
+	sectionID int, semesterCode string,
+) (*tenantmodels.SectionReport, error) {
+	return util.GetSectionReport(
+		t.UserWorkspaceDB, t.UserTenantDB, fmt.Sprintf("%d", t.TenantData.ID),
+		sectionID, semesterCode,
+	)
+}
+
+// GetSubjectReport builds the report of a section in a subject and semester.
+func (t *ConfigurableTenant) GetSubjectReport(
+	sectionID int, subjectCode, semesterCode string,
+) (*tenantmodels.SubjectReport, error) {
+	return util.GetSubjectReport(
+		t.UserWorkspaceDB, t.UserTenantDB, fmt.Sprintf("%d", t.TenantData.ID),
+		sectionID, subjectCode, semesterCode,
+	)
+}
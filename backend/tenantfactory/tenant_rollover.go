@@ -0,0 +1,35 @@
+package tenantfactory
+
+import (
+	tenantmodels "ednevnik-backend/models/tenant"
+	"ednevnik-backend/util"
+)
+
+// PreviewYearRollover proposes the sections of the next school year without
+// changing anything.
+func (t *ConfigurableTenant) PreviewYearRollover(
+	request tenantmodels.RolloverRequest,
+) (*tenantmodels.RolloverPlan, error) {
+	return util.BuildRolloverPlan(
+		request, t.TenantData.ID, &t.Config, t.UserTenantDB, t.UserWorkspaceDB,
+	)
+}
+
+// CommitYearRollover rebuilds the rollover plan and applies it, so the
+// committed plan reflects the grades at the time of the commit.
+func (t *ConfigurableTenant) CommitYearRollover(
+	request tenantmodels.RolloverRequest,
+) (*tenantmodels.RolloverPlan, error) {
+	plan, err := util.BuildRolloverPlan(
+		request, t.TenantData.ID, &t.Config, t.UserTenantDB, t.UserWorkspaceDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := util.ApplyRolloverPlan(plan, t.TenantData.ID, t.UserTenantDB); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
@@ -1,10 +1,12 @@
 package tenantfactory
 
 import (
+	"ednevnik-backend/jobs"
 	tenantmodels "ednevnik-backend/models/tenant"
 	wpmodels "ednevnik-backend/models/workspace"
 	"ednevnik-backend/util"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -64,6 +66,15 @@ func (t *ConfigurableTenant) GetSectionsForPupil(
 	return sections, nil
 }
 
+// GetSectionByID retrieves a section of the tenant by its ID
+func (t *ConfigurableTenant) GetSectionByID(sectionID string) (tenantmodels.Section, error) {
+	id, err := strconv.ParseInt(sectionID, 10, 64)
+	if err != nil {
+		return tenantmodels.Section{}, fmt.Errorf("invalid section ID: %v", err)
+	}
+	return util.GetSectionByID(id, t.UserTenantDB)
+}
+
 // DeleteTenantSection TODO: Add description
 func (t *ConfigurableTenant) DeleteTenantSection(sectionID string) error {
 	// Get teacher in section for cleanup
@@ -312,16 +323,29 @@ func (t *ConfigurableTenant) GetPupilCountForSection(sectionID int) (int, error)
 	return count, nil
 }
 
-// ArchiveSection uses the ArchiveSectionHelper util function to archive a
-// section
+// ArchiveSection checks that a section can be archived and queues a job that
+// copies the final grades to the workspace and archives it in the background
 func (t *ConfigurableTenant) ArchiveSection(sectionID int) error {
-	return util.ArchiveSectionHelper(
-		sectionID,
-		&t.TenantData,
-		&t.Config,
-		t.UserTenantDB,
-		t.UserWorkspaceDB,
+	section, err := util.GetSectionByID(int64(sectionID), t.UserTenantDB)
+	if err != nil {
+		return err
+	}
+	if section.Archived {
+		return nil
+	}
+
+	_, _, err = util.ValidateSectionArchivable(
+		section, t.UserTenantDB, t.UserWorkspaceDB,
 	)
+	if err != nil {
+		return err
+	}
+
+	_, err = jobs.Enqueue(JobArchiveSection, archiveSectionJob{
+		TenantID:  t.TenantData.ID,
+		SectionID: sectionID,
+	})
+	return err
 }
 
 // UnenrollPupilFromSection unenrolls a pupil from a section by setting
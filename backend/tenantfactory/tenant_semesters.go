@@ -1,6 +1,7 @@
 package tenantfactory
 
 import (
+	tenantmodels "ednevnik-backend/models/tenant"
 	wpmodels "ednevnik-backend/models/workspace"
 	"ednevnik-backend/util"
 	"fmt"
@@ -8,12 +9,12 @@ import (
 
 // UpdateTenantSemesterDates TODO: Add description
 func (t *ConfigurableTenant) UpdateTenantSemesterDates(
-	semesterCode, startDate, endDate, nppCode string,
+	semesterCode, startDate, endDate, nppCode string, lockGraceDays *int,
 ) (wpmodels.TenantSemester, error) {
 	updatedSemester, err := util.UpdateTenantSemesterDates(
 		t.UserWorkspaceDB,
 		fmt.Sprintf("%d", t.TenantData.ID),
-		semesterCode, startDate, endDate, nppCode,
+		semesterCode, startDate, endDate, nppCode, lockGraceDays,
 	)
 	if err != nil {
 		return wpmodels.TenantSemester{}, fmt.Errorf(
@@ -51,3 +52,44 @@ func (t *ConfigurableTenant) GetSemestersForSection(
 	}
 	return sectionSemesters, nil
 }
+
+// GetSemesterLocks returns the lock status of all semesters of the tenant
+func (t *ConfigurableTenant) GetSemesterLocks() ([]tenantmodels.SemesterLock, error) {
+	return util.GetSemesterLocks(
+		t.UserWorkspaceDB, t.UserTenantDB, fmt.Sprintf("%d", t.TenantData.ID),
+	)
+}
+
+// SetSemesterLock locks or unlocks a semester by hand and returns the lock
+// status of all semesters
+func (t *ConfigurableTenant) SetSemesterLock(
+	semesterCode string, locked bool, reason string, accountID int,
+) ([]tenantmodels.SemesterLock, error) {
+	err := util.SetSemesterLock(semesterCode, locked, reason, accountID, t.UserTenantDB)
+	if err != nil {
+		return nil, err
+	}
+	return t.GetSemesterLocks()
+}
+
+// checkSemesterWritable returns an error when grades or attendance of a
+// section can not be changed for the semester and date
+func (t *ConfigurableTenant) checkSemesterWritable(
+	sectionID int, semesterCode, date string,
+) error {
+	return util.CheckSemesterWritable(
+		t.UserWorkspaceDB, t.UserTenantDB, fmt.Sprintf("%d", t.TenantData.ID),
+		sectionID, semesterCode, date,
+	)
+}
+
+// checkSemesterRangeWritable returns an error when a semester of the section
+// overlapping the dates is locked
+func (t *ConfigurableTenant) checkSemesterRangeWritable(
+	sectionID int, dateFrom, dateTo string,
+) error {
+	return util.CheckSemesterRangeWritable(
+		t.UserWorkspaceDB, t.UserTenantDB, fmt.Sprintf("%d", t.TenantData.ID),
+		sectionID, dateFrom, dateTo,
+	)
+}
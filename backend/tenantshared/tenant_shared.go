@@ -31,13 +31,14 @@ type ITenant interface {
 	GrantTenantDBPrivileges() error
 	RevokeTenantDBPrivileges() error
 	GetSectionsForTenant(archived int) ([]tenantmodels.Section, error)
+	GetSectionByID(sectionID string) (tenantmodels.Section, error)
 	DeleteTenantSection(tenantID string) error
 	UpdateTenantSection(newSection tenantmodels.Section, sectionID string) (tenantmodels.Section, error)
 	CreateTenantSection(newSection tenantmodels.SectionCreate) (tenantmodels.Section, error)
 	GetPupilsForSection(sectionID string, includeUnenrolled bool) (*commonmodels.GetSectionPupilsResponse, error)
 	DeletePupilFromSection(pupilID, sectionID string) error
 	UpdatePupil(oldPupil tenantmodels.Pupil, newPupil tenantmodels.Pupil) error
-	UpdateTenantSemesterDates(semesterCode, startDate, endDate, nppCode string) (wpmodels.TenantSemester, error)
+	UpdateTenantSemesterDates(semesterCode, startDate, endDate, nppCode string, lockGraceDays *int) (wpmodels.TenantSemester, error)
 	GetSemestersForTenant() ([]wpmodels.TenantSemester, error)
 	SendPupilSectionInvite(pupilID int, sectionID, tenantID string) (*tenantmodels.PupilSectionInvite, error)
 	GetPupilSectionInvite(inviteID int) (*tenantmodels.PupilSectionInvite, error)
@@ -68,25 +69,75 @@ type ITenant interface {
 	GetLessonsForSection(sectionID int, claims *wpmodels.Claims) ([]tenantmodels.LessonData, error)
 	CreateSectionLesson(requestData tenantmodels.LessonData, teacherID int) (*tenantmodels.LessonData, error)
 	UpdateLesson(lessonID int, requestData tenantmodels.LessonData, teacherID int) (*tenantmodels.LessonData, error)
-	DeleteLesson(lessonID int) error
+	DeleteLesson(lessonID, teacherID int) error
 	GetSubjectsForSection(sectionID int, claims *wpmodels.Claims) ([]wpmodels.Subject, error)
 	GetLessonByID(lessonID int) (*tenantmodels.LessonData, error)
 	GetAbsentAttendancesForSection(sectionID int) ([]tenantmodels.PupilAttendance, error)
 	GetAbsentAttendancesForPupil(pupilID, sectionID int) ([]tenantmodels.PupilAttendance, error)
 	HandleAttendanceAction(action tenantmodels.AttendanceAction) error
+	CreateAttendanceExcuse(excuse tenantmodels.AttendanceExcuse, claims *wpmodels.Claims) (*tenantmodels.AttendanceExcuse, error)
+	GetAttendanceExcusesForPupil(pupilID, sectionID int) ([]tenantmodels.AttendanceExcuse, error)
+	GetAttendanceExcusesForSection(sectionID int, status string) ([]tenantmodels.AttendanceExcuse, error)
+	GetAttendanceExcuseAttachment(excuseID, pupilID, sectionID int) (*tenantmodels.AttendanceExcuse, error)
+	DecideAttendanceExcuses(sectionID int, decision tenantmodels.AttendanceExcuseDecision, claims *wpmodels.Claims) error
+	GetAbsenceThresholds() ([]tenantmodels.AbsenceThreshold, error)
+	SetAbsenceThresholds(thresholds []tenantmodels.AbsenceThreshold) ([]tenantmodels.AbsenceThreshold, error)
+	GetGradingSchemes() ([]tenantmodels.GradingScheme, error)
+	SetGradingSchemes(schemes []tenantmodels.GradingScheme) ([]tenantmodels.GradingScheme, error)
+	GetFinalGradeProposals(sectionID int, semesterCode, subjectCode string) ([]tenantmodels.FinalGradeProposal, error)
+	AcceptFinalGrades(sectionID int, semesterCode, subjectCode string, teacherID int, accepted []tenantmodels.AcceptedFinalGrade) (*tenantmodels.AcceptedFinalGradesResult, error)
+	GetDescriptiveAssessmentClasses() ([]string, error)
+	SetDescriptiveAssessmentClasses(classCodes []string) ([]string, error)
+	GetDescriptiveAssessmentsForSectionSubject(sectionID int, semesterCode, subjectCode string) ([]tenantmodels.DescriptiveAssessmentPupilGroup, error)
+	GetDescriptiveAssessmentsForPupil(pupilID, sectionID int) ([]tenantmodels.DescriptiveAssessment, error)
+	SaveDescriptiveAssessment(assessment tenantmodels.DescriptiveAssessment, teacherID int) (*tenantmodels.DescriptiveAssessment, error)
+	GetDescriptiveAssessmentHistory(sectionID, assessmentID int) ([]tenantmodels.DescriptiveAssessment, error)
+	GetExamLimits() (*tenantmodels.ExamLimits, error)
+	SetExamLimits(limits tenantmodels.ExamLimits) (*tenantmodels.ExamLimits, error)
+	GetPlannedExamsForSection(sectionID int, dateFrom, dateTo string) ([]tenantmodels.PlannedExam, error)
+	GetUpcomingPlannedExams(sectionID int) ([]tenantmodels.PlannedExam, error)
+	GetPlannedExamByID(examID int) (*tenantmodels.PlannedExam, error)
+	CreatePlannedExam(exam tenantmodels.PlannedExam, teacherID int) (*tenantmodels.PlannedExam, error)
+	DeletePlannedExam(sectionID, examID, teacherID int) error
+	GetSectionReport(sectionID int, semesterCode string) (*tenantmodels.SectionReport, error)
+	GetSubjectReport(sectionID int, subjectCode, semesterCode string) (*tenantmodels.SubjectReport, error)
+	GetRemedialCandidates(sectionID int) ([]tenantmodels.RemedialCandidate, error)
+	CreateRemedialExam(request tenantmodels.RemedialExamRequest, accountID int) (*tenantmodels.RemedialExam, error)
+	GetRemedialExams(sectionID int) ([]tenantmodels.RemedialExam, error)
+	GetRemedialExamsForTeacher(teacherID int) ([]tenantmodels.RemedialExam, error)
+	GetRemedialExamByID(examID int) (*tenantmodels.RemedialExam, error)
+	DeleteRemedialExam(examID int) error
+	RecordRemedialExamResults(examID, teacherID int, entries []tenantmodels.RemedialResultEntry) (*tenantmodels.RemedialExam, error)
+	IsRemedialCommissionMember(examID, teacherID int) (bool, error)
+	PreviewYearRollover(request tenantmodels.RolloverRequest) (*tenantmodels.RolloverPlan, error)
+	CommitYearRollover(request tenantmodels.RolloverRequest) (*tenantmodels.RolloverPlan, error)
+	VerifyGradebookLedger(sectionID int) (*tenantmodels.LedgerVerification, error)
+	SealGradebookLedger(sectionID, teacherID int) (*tenantmodels.LedgerVerification, error)
+	GetPedagogicalMeasuresForSection(sectionID int) ([]tenantmodels.PedagogicalMeasure, error)
+	GetPedagogicalMeasuresForPupil(pupilID, sectionID int) ([]tenantmodels.PedagogicalMeasure, error)
 	GetPupilCountForSection(sectionID int) (int, error)
 	GetSectionGradesForSubject(sectionID int, semesterCode, subjectCode string) ([]tenantmodels.GradePupilGroup, error)
 	CreateGrade(grade *tenantmodels.Grade) (*tenantmodels.GradePupilGroup, error)
+	CreateGradesBulk(sectionID int, semesterCode, subjectCode string, teacherID int, entries []tenantmodels.BulkGradeEntry) (*tenantmodels.BulkGradesResult, error)
 	DeleteGrade(grade *tenantmodels.Grade, teacherID int) (*tenantmodels.GradePupilGroup, error)
 	UpdateGrade(grade *tenantmodels.Grade) (*tenantmodels.GradePupilGroup, error)
 	GetPupilGradesForSectionPupil(sectionID, pupilID int, semesterCode string) ([]commonmodels.GradeSubjectGroup, error)
 	UpdatePupilBehaviourGrade(behaviourGradesToUpdate tenantmodels.BehaviourGrade, teacherID int) (*tenantmodels.BehaviourGrade, error)
 	GetSemestersForSection(sectionID string) ([]wpmodels.TenantSemester, error)
+	GetSemesterLocks() ([]tenantmodels.SemesterLock, error)
+	SetSemesterLock(semesterCode string, locked bool, reason string, accountID int) ([]tenantmodels.SemesterLock, error)
 	GetSectionBehaviourGradesForPupil(pupilID, sectionID int) ([]tenantmodels.BehaviourGrade, error)
 	ArchiveSection(sectionID int) error
 	GetCertificateData(sectionID, pupilID int) (*commonmodels.Certificate, error)
+	GetCertificatePDF(sectionID, pupilID int) ([]byte, string, error)
+	GetSectionCertificatesZip(sectionID int) ([]byte, error)
+	GetGradeByID(gradeID int) (*tenantmodels.Grade, error)
+	GetBehaviourGradeByID(behaviourGradeID int) (*tenantmodels.BehaviourGrade, error)
 	GetGradeEditHistory(gradeID int) ([]tenantmodels.Grade, error)
 	GetBehaviourGradeHistory(behaviourGradeID int) ([]tenantmodels.BehaviourGrade, error)
 	GetCompleteGradebookData(sectionID int) (*tenantmodels.CompleteGradebook, error)
 	UnenrollPupilFromSection(pupilID, sectionID int) error
+	IsTeacherAssignedToSection(teacherID, sectionID int) (bool, error)
+	IsHomeroomTeacherOfSection(teacherID, sectionID int) (bool, error)
+	IsPupilEnrolledInSection(pupilID, sectionID int) (bool, error)
 }
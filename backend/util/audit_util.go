@@ -0,0 +1,228 @@
+package util
+
+import (
+	"database/sql"
+	wpmodels "ednevnik-backend/models/workspace"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// AuditPageSize is the default and maximum number of audit entries returned
+// by one query
+const AuditPageSize = 200
+
+// auditRedactedKeys are JSON keys whose values never end up in the audit log
+var auditRedactedKeys = map[string]bool{
+	"password":           true,
+	"current_password":   true,
+	"new_password":       true,
+	"confirm_password":   true,
+	"teacher_password":   true,
+	"token":              true,
+	"access_token":       true,
+	"refresh_token":      true,
+	"mfa_token":          true,
+	"secret":             true,
+	"client_secret":      true,
+	"otpauth_url":        true,
+	"recovery_codes":     true,
+	"parent_access_code": true,
+	"access_code":        true,
+	"attachment":         true,
+}
+
+// RedactAuditPayload returns a JSON payload with the values of secret keys
+// replaced, at any depth. Payloads that are not JSON are not stored.
+func RedactAuditPayload(payload []byte) []byte {
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return nil
+	}
+	redacted, err := json.Marshal(redactAuditValue(value))
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+// redactAuditValue walks decoded JSON and redacts secret keys
+func redactAuditValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			if auditRedactedKeys[strings.ToLower(key)] {
+				v[key] = "[redacted]"
+				continue
+			}
+			v[key] = redactAuditValue(nested)
+		}
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = redactAuditValue(nested)
+		}
+	}
+	return value
+}
+
+// InsertAuditEntry stores an entry in the audit log
+func InsertAuditEntry(entry wpmodels.AuditEntry, workspaceDB *sql.DB) error {
+	query := `INSERT INTO audit_log (tenant_id, actor_account_id, actor_role,
+	impersonator_account_id, ip_address, method, route, action, entity,
+	entity_id, status_code, request_data, before_data, after_data)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?)`
+	_, err := workspaceDB.Exec(
+		query,
+		entry.TenantID,
+		entry.ActorAccountID,
+		entry.ActorRole,
+		entry.ImpersonatorAccountID,
+		entry.IPAddress,
+		entry.Method,
+		entry.Route,
+		entry.Action,
+		entry.Entity,
+		entry.EntityID,
+		entry.StatusCode,
+		nullableJSON(entry.RequestData),
+		nullableJSON(entry.BeforeData),
+		nullableJSON(entry.AfterData),
+	)
+	if err != nil {
+		return fmt.Errorf("error inserting audit entry: %v", err)
+	}
+	return nil
+}
+
+// GetAuditEntries returns the audit entries matching the filter, newest first
+func GetAuditEntries(
+	filter wpmodels.AuditFilter, workspaceDB *sql.DB,
+) ([]wpmodels.AuditEntry, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.TenantID != 0 {
+		conditions = append(conditions, "tenant_id = ?")
+		args = append(args, filter.TenantID)
+	}
+	if filter.ActorAccountID != 0 {
+		conditions = append(conditions, "actor_account_id = ?")
+		args = append(args, filter.ActorAccountID)
+	}
+	if filter.ImpersonatorAccountID != 0 {
+		conditions = append(conditions, "impersonator_account_id = ?")
+		args = append(args, filter.ImpersonatorAccountID)
+	}
+	if filter.Entity != "" {
+		conditions = append(conditions, "entity = ?")
+		args = append(args, filter.Entity)
+	}
+	if filter.EntityID != "" {
+		conditions = append(conditions, "entity_id = ?")
+		args = append(args, filter.EntityID)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.From != "" {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		// A date without time includes the whole day
+		if len(filter.To) == len("2006-01-02") {
+			conditions = append(conditions, "created_at < ? + INTERVAL 1 DAY")
+		} else {
+			conditions = append(conditions, "created_at <= ?")
+		}
+		args = append(args, filter.To)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > AuditPageSize {
+		limit = AuditPageSize
+	}
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := `SELECT id, tenant_id, actor_account_id, actor_role,
+	impersonator_account_id, COALESCE(ip_address, ''), method, route, action, entity,
+	COALESCE(entity_id, ''), status_code, request_data, before_data,
+	after_data, created_at
+	FROM audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := workspaceDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching audit log: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []wpmodels.AuditEntry{}
+	for rows.Next() {
+		var entry wpmodels.AuditEntry
+		var tenantID, impersonatorAccountID sql.NullInt64
+		var requestData, beforeData, afterData sql.NullString
+		err := rows.Scan(
+			&entry.ID,
+			&tenantID,
+			&entry.ActorAccountID,
+			&entry.ActorRole,
+			&impersonatorAccountID,
+			&entry.IPAddress,
+			&entry.Method,
+			&entry.Route,
+			&entry.Action,
+			&entry.Entity,
+			&entry.EntityID,
+			&entry.StatusCode,
+			&requestData,
+			&beforeData,
+			&afterData,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning audit entry: %v", err)
+		}
+		if tenantID.Valid {
+			id := int(tenantID.Int64)
+			entry.TenantID = &id
+		}
+		if impersonatorAccountID.Valid {
+			id := int(impersonatorAccountID.Int64)
+			entry.ImpersonatorAccountID = &id
+		}
+		entry.RequestData = rawJSON(requestData)
+		entry.BeforeData = rawJSON(beforeData)
+		entry.AfterData = rawJSON(afterData)
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit log: %v", err)
+	}
+
+	return entries, nil
+}
+
+// nullableJSON stores empty payloads as NULL
+func nullableJSON(payload json.RawMessage) interface{} {
+	if len(payload) == 0 {
+		return nil
+	}
+	return string(payload)
+}
+
+// rawJSON turns a stored payload back into JSON
+func rawJSON(payload sql.NullString) json.RawMessage {
+	if !payload.Valid || payload.String == "" {
+		return nil
+	}
+	return json.RawMessage(payload.String)
+}
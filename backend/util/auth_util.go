@@ -3,7 +3,9 @@ package util
 import (
 	"database/sql"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
 
 	"ednevnik-backend/constants"
 	"ednevnik-backend/models/interfaces"
@@ -44,7 +46,26 @@ func GetClaimsFromContext(r *http.Request) (*wpmodels.Claims, bool) {
 	return claims, ok && claims != nil
 }
 
-// GetUserByEmail retrieves a user by email, checking teachers first, then pupils
+// ClaimsHaveTenantAccess reports whether the claims grant access to a tenant.
+// Root can access every tenant, a tenant admin the tenant they administer and
+// everyone else only the tenants they are members of.
+func ClaimsHaveTenantAccess(claims *wpmodels.Claims, tenantID string) bool {
+	if claims.AccountType == "root" {
+		return true
+	}
+	if claims.AccountType == "tenant_admin" &&
+		fmt.Sprintf("%d", claims.TenantAdminTenantID) == tenantID {
+		return true
+	}
+	for _, id := range claims.TenantIDs {
+		if id == tenantID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUserByEmail retrieves a user by email, checking teachers first, then pupils and parents
 func GetUserByEmail(email string, workspaceDB *sql.DB) (interfaces.User, error) {
 	// Try to get user as teacher first
 	if user, err := GetTeacherByEmail(workspaceDB, email); err == nil && user != nil {
@@ -56,9 +77,108 @@ func GetUserByEmail(email string, workspaceDB *sql.DB) (interfaces.User, error)
 		return user, nil
 	}
 
+	// Try to get user as parent
+	if user, err := GetParentByEmail(email, workspaceDB); err == nil && user != nil {
+		return user, nil
+	}
+
 	return nil, sql.ErrNoRows
 }
 
+// GetUserByAccountID retrieves a user by account ID, checking teachers first,
+// then pupils and parents. Used when claims have to be rebuilt without a password login.
+func GetUserByAccountID(accountID int, workspaceDB *sql.DB) (interfaces.User, error) {
+	var email string
+	err := workspaceDB.QueryRow(
+		"SELECT email FROM accounts WHERE id = ?", accountID,
+	).Scan(&email)
+	if err != nil {
+		return nil, err
+	}
+	return GetUserByEmail(email, workspaceDB)
+}
+
+// TrustedProxies are the networks of the reverse proxies in front of the
+// backend. X-Forwarded-For is only honoured on requests coming from them.
+var TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses a comma separated list of IP addresses and
+// CIDR ranges of trusted proxies
+func ParseTrustedProxies(value string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			ip := net.ParseIP(part)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy address: %s", part)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy range: %s", part)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// isTrustedProxy reports whether an address belongs to a trusted proxy
+func isTrustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRequestIP returns the client IP address. X-Forwarded-For is only read
+// when the request comes from a trusted proxy, and then the last address
+// that is not a trusted proxy is taken, since the addresses before it can
+// be set by the client.
+func GetRequestIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if !isTrustedProxy(remote) {
+		return remote
+	}
+
+	var forwarded []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, address := range strings.Split(header, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				forwarded = append(forwarded, address)
+			}
+		}
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		if !isTrustedProxy(forwarded[i]) {
+			return forwarded[i]
+		}
+	}
+	if len(forwarded) > 0 {
+		return forwarded[0]
+	}
+	return remote
+}
+
 // ChangeAccountPassword changes the password for a specific account.
 func ChangeAccountPassword(
 	accountID int,
@@ -0,0 +1,152 @@
+package util
+
+import (
+	"database/sql"
+	tenantmodels "ednevnik-backend/models/tenant"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// gradeTypes are the types a grade can be entered with
+var gradeTypes = map[string]bool{
+	"exam":               true,
+	"oral":               true,
+	"written_assignment": true,
+	"final":              true,
+}
+
+// ValidateBulkGrades checks the entries of a bulk grade entry against the
+// pupils of the section and returns the errors per entry. Entries without a
+// date are dated today.
+func ValidateBulkGrades(
+	sectionID int, entries []tenantmodels.BulkGradeEntry, tenantDB *sql.DB,
+) ([]tenantmodels.BulkGradeError, error) {
+	pupils, err := GetPupilsForSection(
+		fmt.Sprintf("%d", sectionID), false, tenantDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+	activePupils := make(map[int]bool, len(pupils))
+	for _, pupil := range pupils {
+		activePupils[pupil.ID] = true
+	}
+
+	today := time.Now().Format(semesterDateLayout)
+	rowErrors := []tenantmodels.BulkGradeError{}
+	for i := range entries {
+		entry := &entries[i]
+		if entry.GradeDate == "" {
+			entry.GradeDate = today
+		}
+
+		var message string
+		switch {
+		case !activePupils[entry.PupilID]:
+			message = "učenik nije upisan u odjeljenje"
+		case entry.Grade > 5 || entry.Grade < 1:
+			message = "ocjena mora biti između 1 i 5"
+		case !gradeTypes[entry.Type]:
+			message = fmt.Sprintf("nepoznata vrsta ocjene %s", entry.Type)
+		default:
+			continue
+		}
+		rowErrors = append(rowErrors, tenantmodels.BulkGradeError{
+			Index: i, PupilID: entry.PupilID, Error: message,
+		})
+	}
+
+	return rowErrors, nil
+}
+
+// SortBulkGradeErrors orders the errors of a bulk grade entry by entry
+func SortBulkGradeErrors(rowErrors []tenantmodels.BulkGradeError) {
+	sort.SliceStable(rowErrors, func(i, j int) bool {
+		return rowErrors[i].Index < rowErrors[j].Index
+	})
+}
+
+// CreateGradesBulk inserts the grades of a bulk grade entry in one
+// transaction. When an entry is rejected by the database nothing is saved and
+// the errors are returned, otherwise the refreshed grades of the section.
+func CreateGradesBulk(
+	sectionID int,
+	semesterCode,
+	subjectCode string,
+	teacherID int,
+	entries []tenantmodels.BulkGradeEntry,
+	tenantDB *sql.DB,
+	workspaceDB *sql.DB,
+) (result *tenantmodels.BulkGradesResult, err error) {
+	teacherForSignature, err := GetTeacherByID(
+		fmt.Sprintf("%d", teacherID), workspaceDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+	signature := teacherForSignature.Name + " " + teacherForSignature.LastName
+
+	tx, err := tenantDB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	query := `INSERT INTO student_grades (pupil_id, section_id, subject_code,
+	grade, grade_date, type, teacher_id, semester_code, signature) VALUES
+	(?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result = &tenantmodels.BulkGradesResult{
+		Errors: []tenantmodels.BulkGradeError{},
+	}
+	for i, entry := range entries {
+		res, execErr := tx.Exec(
+			query, entry.PupilID, sectionID, subjectCode, entry.Grade,
+			entry.GradeDate, entry.Type, teacherID, semesterCode, signature,
+		)
+		if execErr == nil {
+			var gradeID int64
+			gradeID, err = res.LastInsertId()
+			if err != nil {
+				return nil, err
+			}
+			err = AppendLedgerEntry(
+				tx, LedgerEntityGrade, int(gradeID), LedgerActionCreate, teacherID,
+			)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !IsDuplicateFinalGradeError(execErr) {
+			return nil, execErr
+		}
+		result.Errors = append(result.Errors, tenantmodels.BulkGradeError{
+			Index: i, PupilID: entry.PupilID, Error: "zaključna ocjena već postoji",
+		})
+	}
+
+	if len(result.Errors) > 0 {
+		_ = tx.Rollback()
+		return result, nil
+	}
+
+	result.Grades, err = GetPupilGradesForSectionSubject(
+		sectionID, semesterCode, subjectCode, tx,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	result.Created = len(entries)
+
+	return result, nil
+}
@@ -66,6 +66,25 @@ func GetCertificateData(
 
 	averageFinalGrade := CalculateAverageFinalGrade(finalGrades)
 
+	subjectAverages, err := getCertificateSubjectAverages(
+		pupilID, sectionID, tenantDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptiveAssessments, err := getCertificateDescriptiveAssessments(
+		pupilID, sectionID, tenantDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	remedialExams, err := GetRemedialResultsForPupil(pupilID, sectionID, tenantDB)
+	if err != nil {
+		return nil, err
+	}
+
 	behaviourQuery := `SELECT b.id, b.pupil_id, b.section_id, b.behaviour,
 	b.semester_code FROM pupil_behaviour b
 	JOIN ednevnik_workspace.semester sem ON b.semester_code = sem.semester_code
@@ -101,7 +120,71 @@ func GetCertificateData(
 		AverageGrade:   averageFinalGrade,
 		GraduateGrade:  graduateGrade,
 		Passed:         passed,
+
+		SubjectAverages:        subjectAverages,
+		DescriptiveAssessments: descriptiveAssessments,
+		RemedialExams:          remedialExams,
 	}
 
 	return certificate, nil
 }
+
+// getCertificateSubjectAverages averages the grades of a pupil in each
+// subject of the last semester with the grading schemes of the tenant
+func getCertificateSubjectAverages(
+	pupilID, sectionID int, tenantDB *sql.DB,
+) (map[string]float64, error) {
+	gradesQuery := `SELECT sg.subject_code, sg.grade, sg.type
+	FROM student_grades sg
+	JOIN ednevnik_workspace.semester sem ON sg.semester_code = sem.semester_code
+	WHERE sg.pupil_id = ? AND sg.section_id = ? AND sg.type != 'final'
+	AND sem.progress_level = (SELECT MAX(progress_level) FROM ednevnik_workspace.semester)`
+
+	rows, err := tenantDB.Query(gradesQuery, pupilID, sectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	gradesBySubject := map[string][]tenantmodels.Grade{}
+	for rows.Next() {
+		var grade tenantmodels.Grade
+		if err := rows.Scan(&grade.SubjectCode, &grade.Grade, &grade.Type); err != nil {
+			return nil, err
+		}
+		gradesBySubject[grade.SubjectCode] = append(gradesBySubject[grade.SubjectCode], grade)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	schemes, err := GetGradingSchemes(tenantDB)
+	if err != nil {
+		return nil, err
+	}
+
+	subjectAverages := make(map[string]float64, len(gradesBySubject))
+	for subjectCode, grades := range gradesBySubject {
+		subjectAverages[subjectCode] = CalculateAverageGrade(
+			grades, GradingSchemeForSubject(schemes, subjectCode),
+		)
+	}
+
+	return subjectAverages, nil
+}
+
+// getCertificateDescriptiveAssessments returns the descriptive assessments of
+// a pupil in the last semester
+func getCertificateDescriptiveAssessments(
+	pupilID, sectionID int, tenantDB *sql.DB,
+) ([]tenantmodels.DescriptiveAssessment, error) {
+	query := descriptiveAssessmentSelect + `
+	FROM descriptive_assessments da
+	JOIN ednevnik_workspace.subjects s ON s.subject_code = da.subject_code
+	JOIN ednevnik_workspace.semester sem ON sem.semester_code = da.semester_code
+	WHERE da.pupil_id = ? AND da.section_id = ?
+	AND sem.progress_level = (SELECT MAX(progress_level) FROM ednevnik_workspace.semester)
+	ORDER BY s.subject_name`
+
+	return queryDescriptiveAssessments(tenantDB, query, pupilID, sectionID)
+}
@@ -0,0 +1,322 @@
+package util
+
+import (
+	"database/sql"
+	"ednevnik-backend/models/interfaces"
+	tenantmodels "ednevnik-backend/models/tenant"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDescriptiveSection is returned when numeric grades are given in a
+// section that is assessed descriptively
+var ErrDescriptiveSection = errors.New("odjeljenje se ocjenjuje opisno")
+
+const descriptiveAssessmentSelect = `SELECT da.id, da.pupil_id, da.section_id,
+	da.subject_code, da.semester_code, da.achievements, COALESCE(da.effort, ''),
+	COALESCE(da.recommendations, ''), COALESCE(da.teacher_id, 0),
+	COALESCE(da.signature, ''), s.subject_name`
+
+// GetDescriptiveAssessmentClasses returns the classes the tenant assesses
+// descriptively
+func GetDescriptiveAssessmentClasses(
+	tenantDB interfaces.DatabaseQuerier,
+) ([]string, error) {
+	rows, err := tenantDB.Query(
+		`SELECT class_code FROM descriptive_assessment_classes ORDER BY class_code`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying descriptive assessment classes: %v", err)
+	}
+	defer rows.Close()
+
+	classCodes := []string{}
+	for rows.Next() {
+		var classCode string
+		if err := rows.Scan(&classCode); err != nil {
+			return nil, fmt.Errorf("error scanning descriptive assessment class: %v", err)
+		}
+		classCodes = append(classCodes, classCode)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating descriptive assessment classes: %v", err)
+	}
+
+	return classCodes, nil
+}
+
+// SetDescriptiveAssessmentClasses replaces the classes the tenant assesses
+// descriptively
+func SetDescriptiveAssessmentClasses(
+	classCodes []string, tenantDB *sql.DB,
+) (err error) {
+	seen := map[string]bool{}
+	for _, classCode := range classCodes {
+		if classCode == "" {
+			return fmt.Errorf("razred je obavezan")
+		}
+		if seen[classCode] {
+			return fmt.Errorf("razred %s je naveden više puta", classCode)
+		}
+		seen[classCode] = true
+	}
+
+	tx, err := tenantDB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting tenantDB transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec(`DELETE FROM descriptive_assessment_classes`); err != nil {
+		return fmt.Errorf("error deleting descriptive assessment classes: %v", err)
+	}
+
+	for _, classCode := range classCodes {
+		_, err = tx.Exec(
+			`INSERT INTO descriptive_assessment_classes (class_code) VALUES (?)`,
+			classCode,
+		)
+		if err != nil {
+			return fmt.Errorf("error inserting descriptive assessment class %s: %v", classCode, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+// IsDescriptiveSection checks if the class of a section is assessed
+// descriptively
+func IsDescriptiveSection(
+	sectionID int, tenantDB interfaces.DatabaseQuerier,
+) (bool, error) {
+	query := `SELECT EXISTS(
+		SELECT 1 FROM sections s
+		JOIN descriptive_assessment_classes dac ON dac.class_code = s.class_code
+		WHERE s.id = ?
+	)`
+
+	var descriptive bool
+	if err := tenantDB.QueryRow(query, sectionID).Scan(&descriptive); err != nil {
+		return false, err
+	}
+	return descriptive, nil
+}
+
+// GetDescriptiveAssessmentsForSectionSubject returns the pupils of a section
+// with their descriptive assessment in a subject and semester
+func GetDescriptiveAssessmentsForSectionSubject(
+	sectionID int,
+	semesterCode,
+	subjectCode string,
+	tenantDB interfaces.DatabaseQuerier,
+) ([]tenantmodels.DescriptiveAssessmentPupilGroup, error) {
+	pupils, err := GetPupilsForSection(
+		fmt.Sprintf("%d", sectionID), true, tenantDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	query := descriptiveAssessmentSelect + `
+	FROM descriptive_assessments da
+	JOIN ednevnik_workspace.subjects s ON s.subject_code = da.subject_code
+	WHERE da.section_id = ? AND da.semester_code = ? AND da.subject_code = ?`
+
+	assessments, err := queryDescriptiveAssessments(
+		tenantDB, query, sectionID, semesterCode, subjectCode,
+	)
+	if err != nil {
+		return nil, err
+	}
+	byPupil := make(map[int]tenantmodels.DescriptiveAssessment, len(assessments))
+	for _, assessment := range assessments {
+		byPupil[assessment.PupilID] = assessment
+	}
+
+	groups := []tenantmodels.DescriptiveAssessmentPupilGroup{}
+	for _, pupil := range pupils {
+		group := tenantmodels.DescriptiveAssessmentPupilGroup{Pupil: pupil}
+		if assessment, ok := byPupil[pupil.ID]; ok {
+			group.Assessment = &assessment
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// GetDescriptiveAssessmentsForPupil returns all descriptive assessments of a
+// pupil in a section
+func GetDescriptiveAssessmentsForPupil(
+	pupilID, sectionID int, tenantDB interfaces.DatabaseQuerier,
+) ([]tenantmodels.DescriptiveAssessment, error) {
+	query := descriptiveAssessmentSelect + `
+	FROM descriptive_assessments da
+	JOIN ednevnik_workspace.subjects s ON s.subject_code = da.subject_code
+	JOIN ednevnik_workspace.semester sem ON sem.semester_code = da.semester_code
+	WHERE da.pupil_id = ? AND da.section_id = ?
+	ORDER BY sem.progress_level, s.subject_name`
+
+	return queryDescriptiveAssessments(tenantDB, query, pupilID, sectionID)
+}
+
+// GetDescriptiveAssessmentByID retrieves the current version of a descriptive
+// assessment
+func GetDescriptiveAssessmentByID(
+	assessmentID int, tenantDB interfaces.DatabaseQuerier,
+) (*tenantmodels.DescriptiveAssessment, error) {
+	query := descriptiveAssessmentSelect + `
+	FROM descriptive_assessments da
+	JOIN ednevnik_workspace.subjects s ON s.subject_code = da.subject_code
+	WHERE da.id = ?`
+
+	assessments, err := queryDescriptiveAssessments(tenantDB, query, assessmentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(assessments) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &assessments[0], nil
+}
+
+// GetDescriptiveAssessmentHistory returns the earlier versions of a
+// descriptive assessment, oldest first
+func GetDescriptiveAssessmentHistory(
+	assessmentID int, tenantDB interfaces.DatabaseQuerier,
+) ([]tenantmodels.DescriptiveAssessment, error) {
+	query := `SELECT hist.id, hist.pupil_id, hist.section_id, hist.subject_code,
+	hist.semester_code, hist.achievements, COALESCE(hist.effort, ''),
+	COALESCE(hist.recommendations, ''), COALESCE(hist.teacher_id, 0),
+	COALESCE(hist.signature, ''), s.subject_name, hist.ROW_END
+	FROM descriptive_assessments FOR SYSTEM_TIME ALL hist
+	JOIN ednevnik_workspace.subjects s ON s.subject_code = hist.subject_code
+	WHERE hist.id = ?
+	AND YEAR(hist.ROW_END) < 2038
+	ORDER BY hist.ROW_START ASC`
+
+	rows, err := tenantDB.Query(query, assessmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []tenantmodels.DescriptiveAssessment{}
+	for rows.Next() {
+		var assessment tenantmodels.DescriptiveAssessment
+		err := rows.Scan(
+			&assessment.ID, &assessment.PupilID, &assessment.SectionID,
+			&assessment.SubjectCode, &assessment.SemesterCode,
+			&assessment.Achievements, &assessment.Effort,
+			&assessment.Recommendations, &assessment.TeacherID,
+			&assessment.Signature, &assessment.SubjectName,
+			&assessment.ValidUntil,
+		)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, assessment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// SaveDescriptiveAssessment creates or updates the descriptive assessment of
+// a pupil in a subject and semester. Every update keeps the earlier version
+// in the history of the assessment.
+func SaveDescriptiveAssessment(
+	assessment tenantmodels.DescriptiveAssessment,
+	signature string,
+	tenantDB *sql.DB,
+) (*tenantmodels.DescriptiveAssessment, error) {
+	assessment.Achievements = strings.TrimSpace(assessment.Achievements)
+	assessment.Effort = strings.TrimSpace(assessment.Effort)
+	assessment.Recommendations = strings.TrimSpace(assessment.Recommendations)
+	if assessment.Achievements == "" {
+		return nil, fmt.Errorf("opis postignuća je obavezan")
+	}
+	if assessment.SubjectCode == "" || assessment.SemesterCode == "" {
+		return nil, fmt.Errorf("predmet i polugodište su obavezni")
+	}
+
+	query := `INSERT INTO descriptive_assessments (pupil_id, section_id,
+	subject_code, semester_code, achievements, effort, recommendations,
+	teacher_id, signature) VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?)
+	ON DUPLICATE KEY UPDATE achievements = VALUES(achievements),
+	effort = VALUES(effort), recommendations = VALUES(recommendations),
+	teacher_id = VALUES(teacher_id), signature = VALUES(signature)`
+
+	_, err := tenantDB.Exec(
+		query,
+		assessment.PupilID,
+		assessment.SectionID,
+		assessment.SubjectCode,
+		assessment.SemesterCode,
+		assessment.Achievements,
+		assessment.Effort,
+		assessment.Recommendations,
+		assessment.TeacherID,
+		signature,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error saving descriptive assessment: %v", err)
+	}
+
+	var assessmentID int
+	err = tenantDB.QueryRow(
+		`SELECT id FROM descriptive_assessments
+		WHERE pupil_id = ? AND section_id = ? AND subject_code = ? AND semester_code = ?`,
+		assessment.PupilID, assessment.SectionID, assessment.SubjectCode,
+		assessment.SemesterCode,
+	).Scan(&assessmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetDescriptiveAssessmentByID(assessmentID, tenantDB)
+}
+
+func queryDescriptiveAssessments(
+	tenantDB interfaces.DatabaseQuerier, query string, args ...interface{},
+) ([]tenantmodels.DescriptiveAssessment, error) {
+	rows, err := tenantDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying descriptive assessments: %v", err)
+	}
+	defer rows.Close()
+
+	assessments := []tenantmodels.DescriptiveAssessment{}
+	for rows.Next() {
+		var assessment tenantmodels.DescriptiveAssessment
+		err := rows.Scan(
+			&assessment.ID, &assessment.PupilID, &assessment.SectionID,
+			&assessment.SubjectCode, &assessment.SemesterCode,
+			&assessment.Achievements, &assessment.Effort,
+			&assessment.Recommendations, &assessment.TeacherID,
+			&assessment.Signature, &assessment.SubjectName,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning descriptive assessment: %v", err)
+		}
+		assessments = append(assessments, assessment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating descriptive assessments: %v", err)
+	}
+
+	return assessments, nil
+}
@@ -0,0 +1,353 @@
+package util
+
+import (
+	"database/sql"
+	"ednevnik-backend/models/interfaces"
+	tenantmodels "ednevnik-backend/models/tenant"
+	wpmodels "ednevnik-backend/models/workspace"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrExamLimitExceeded is returned when booking an exam would put more exams
+// on a day or in a week of a section than the tenant allows
+var ErrExamLimitExceeded = errors.New("prekoračen je dozvoljeni broj pismenih provjera")
+
+// ErrNotExamOwner is returned when a teacher cancels an exam booked by
+// another teacher in a section they are not the homeroom teacher of
+var ErrNotExamOwner = errors.New("provjeru može otkazati samo nastavnik koji ju je zakazao ili razrednik")
+
+// DefaultExamLimits are used when the tenant has not set limits of its own
+var DefaultExamLimits = tenantmodels.ExamLimits{
+	MaxPerDay:  1,
+	MaxPerWeek: 3,
+}
+
+// plannedExamTypes are the grade types an exam can be booked for
+var plannedExamTypes = map[string]bool{
+	"exam":               true,
+	"written_assignment": true,
+}
+
+const plannedExamSelect = `SELECT pe.id, pe.section_id, pe.subject_code,
+	pe.semester_code, pe.exam_date, pe.type, COALESCE(pe.description, ''),
+	COALESCE(pe.teacher_id, 0), COALESCE(pe.signature, ''), pe.created_at,
+	s.subject_name
+	FROM planned_exams pe
+	JOIN ednevnik_workspace.subjects s ON s.subject_code = pe.subject_code`
+
+// GetExamLimits returns the exam limits of the tenant
+func GetExamLimits(
+	tenantDB interfaces.DatabaseQuerier,
+) (*tenantmodels.ExamLimits, error) {
+	query := `SELECT max_per_day, max_per_week FROM exam_limits WHERE id = 1`
+
+	limits := DefaultExamLimits
+	err := tenantDB.QueryRow(query).Scan(&limits.MaxPerDay, &limits.MaxPerWeek)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("error querying exam limits: %v", err)
+	}
+
+	return &limits, nil
+}
+
+// SetExamLimits replaces the exam limits of the tenant
+func SetExamLimits(limits tenantmodels.ExamLimits, tenantDB *sql.DB) error {
+	if limits.MaxPerDay < 1 {
+		return fmt.Errorf("dozvoljeni broj provjera u danu mora biti barem 1")
+	}
+	if limits.MaxPerWeek < limits.MaxPerDay {
+		return fmt.Errorf("dozvoljeni broj provjera u sedmici ne može biti manji od broja u danu")
+	}
+
+	query := `INSERT INTO exam_limits (id, max_per_day, max_per_week)
+	VALUES (1, ?, ?)
+	ON DUPLICATE KEY UPDATE max_per_day = VALUES(max_per_day),
+	max_per_week = VALUES(max_per_week)`
+
+	_, err := tenantDB.Exec(query, limits.MaxPerDay, limits.MaxPerWeek)
+	if err != nil {
+		return fmt.Errorf("error saving exam limits: %v", err)
+	}
+
+	return nil
+}
+
+// GetPlannedExamsForSection returns the exams booked in a section between two
+// dates, an empty date leaves that side of the range open
+func GetPlannedExamsForSection(
+	sectionID int, dateFrom, dateTo string, tenantDB interfaces.DatabaseQuerier,
+) ([]tenantmodels.PlannedExam, error) {
+	query := plannedExamSelect + `
+	WHERE pe.section_id = ?
+	AND (? = '' OR pe.exam_date >= ?)
+	AND (? = '' OR pe.exam_date <= ?)
+	ORDER BY pe.exam_date, s.subject_name`
+
+	return queryPlannedExams(
+		tenantDB, query, sectionID, dateFrom, dateFrom, dateTo, dateTo,
+	)
+}
+
+// GetPlannedExamByID retrieves a booked exam
+func GetPlannedExamByID(
+	examID int, tenantDB interfaces.DatabaseQuerier,
+) (*tenantmodels.PlannedExam, error) {
+	exams, err := queryPlannedExams(
+		tenantDB, plannedExamSelect+` WHERE pe.id = ?`, examID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(exams) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &exams[0], nil
+}
+
+// CheckPlannedExamDate returns the semester of a section an exam can be booked
+// on a date in. The date can not be in the past, must fall within a semester
+// of the section and that semester must not be locked.
+func CheckPlannedExamDate(
+	workspaceDB *sql.DB,
+	tenantDB interfaces.DatabaseQuerier,
+	tenantID string,
+	sectionID int,
+	date string,
+) (string, error) {
+	date = normalizeDate(date)
+	if _, err := time.Parse(semesterDateLayout, date); err != nil {
+		return "", fmt.Errorf("%w: neispravan datum %s", ErrOutsideSemester, date)
+	}
+
+	today := time.Now().Format(semesterDateLayout)
+	if date < today {
+		return "", fmt.Errorf("provjera se ne može zakazati za datum koji je prošao")
+	}
+
+	semesters, err := GetSemestersForSectionHelper(
+		workspaceDB, tenantDB, tenantID, strconv.Itoa(sectionID),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	var semester *wpmodels.TenantSemester
+	for i := range semesters {
+		if dateInSemester(date, semesters[i]) {
+			semester = &semesters[i]
+			break
+		}
+	}
+	if semester == nil {
+		return "", fmt.Errorf("%w: %s", ErrOutsideSemester, date)
+	}
+
+	changes, err := GetLatestSemesterLockChanges(tenantDB)
+	if err != nil {
+		return "", err
+	}
+	if err := checkSemesterUnlocked(*semester, changes, today); err != nil {
+		return "", err
+	}
+
+	return semester.SemesterCode, nil
+}
+
+// SubjectScheduledOn checks if a subject has a lesson in the section schedule
+// on the weekday of a date
+func SubjectScheduledOn(
+	schedule tenantmodels.ScheduleGroupCollection, subjectCode, date string,
+) bool {
+	examDate, err := time.Parse(semesterDateLayout, normalizeDate(date))
+	if err != nil {
+		return false
+	}
+	weekday, ok := weekdayConvertToBosnianMap[examDate.Weekday().String()]
+	if !ok {
+		return false
+	}
+
+	for _, group := range schedule {
+		for _, lesson := range group.Schedules {
+			if lesson.SubjectCode == subjectCode && lesson.Weekday == weekday {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CreatePlannedExam books an exam in the calendar of a section. The exams of
+// the week are locked while they are counted, so two teachers booking at the
+// same time can not exceed the limits of the tenant together.
+func CreatePlannedExam(
+	exam tenantmodels.PlannedExam, signature string, tenantDB *sql.DB,
+) (createdExam *tenantmodels.PlannedExam, err error) {
+	exam.Description = strings.TrimSpace(exam.Description)
+	if exam.Type == "" {
+		exam.Type = "written_assignment"
+	}
+	if !plannedExamTypes[exam.Type] {
+		return nil, fmt.Errorf("nepoznata vrsta provjere %s", exam.Type)
+	}
+	if exam.SubjectCode == "" {
+		return nil, fmt.Errorf("predmet je obavezan")
+	}
+	if len(exam.Description) > 500 {
+		return nil, fmt.Errorf("opis provjere može imati najviše 500 znakova")
+	}
+
+	exam.ExamDate = normalizeDate(exam.ExamDate)
+	examDate, err := time.Parse(semesterDateLayout, exam.ExamDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: neispravan datum %s", ErrOutsideSemester, exam.ExamDate)
+	}
+	weekStart, weekEnd := examWeek(examDate)
+
+	tx, err := tenantDB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting tenantDB transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	limits, err := GetExamLimits(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(
+		`SELECT exam_date FROM planned_exams
+		WHERE section_id = ? AND exam_date BETWEEN ? AND ?
+		FOR UPDATE`,
+		exam.SectionID, weekStart, weekEnd,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying planned exams: %v", err)
+	}
+	var dayCount, weekCount int
+	for rows.Next() {
+		var bookedDate string
+		if err = rows.Scan(&bookedDate); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning planned exam: %v", err)
+		}
+		weekCount++
+		if normalizeDate(bookedDate) == exam.ExamDate {
+			dayCount++
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating planned exams: %v", err)
+	}
+
+	if dayCount >= limits.MaxPerDay {
+		return nil, fmt.Errorf(
+			"%w: odjeljenje već ima %d provjera na dan %s (dozvoljeno %d)",
+			ErrExamLimitExceeded, dayCount, exam.ExamDate, limits.MaxPerDay,
+		)
+	}
+	if weekCount >= limits.MaxPerWeek {
+		return nil, fmt.Errorf(
+			"%w: odjeljenje već ima %d provjera u sedmici od %s do %s (dozvoljeno %d)",
+			ErrExamLimitExceeded, weekCount, weekStart, weekEnd, limits.MaxPerWeek,
+		)
+	}
+
+	result, err := tx.Exec(
+		`INSERT INTO planned_exams (section_id, subject_code, semester_code,
+		exam_date, type, description, teacher_id, signature)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?)`,
+		exam.SectionID, exam.SubjectCode, exam.SemesterCode, exam.ExamDate,
+		exam.Type, exam.Description, exam.TeacherID, signature,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting planned exam: %v", err)
+	}
+	examID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	createdExam, err = GetPlannedExamByID(int(examID), tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return createdExam, nil
+}
+
+// DeletePlannedExam removes a booked exam of a section from the calendar. A
+// teacher can only remove exams they booked or exams of a section they are
+// the homeroom teacher of, teacher ID 0 removes any exam of the section.
+func DeletePlannedExam(examID, sectionID, teacherID int, tenantDB *sql.DB) error {
+	query := `DELETE FROM planned_exams
+	WHERE id = ? AND section_id = ?
+	AND (? = 0 OR teacher_id = ? OR EXISTS (
+		SELECT 1 FROM homeroom_assignments ha
+		WHERE ha.section_id = planned_exams.section_id AND ha.teacher_id = ?
+	))`
+	res, err := tenantDB.Exec(query, examID, sectionID, teacherID, teacherID, teacherID)
+	if err != nil {
+		return fmt.Errorf("error deleting planned exam: %v", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error deleting planned exam: %v", err)
+	}
+	if affected == 0 {
+		return ErrNotExamOwner
+	}
+	return nil
+}
+
+// examWeek returns the Monday and Sunday of the week of a date
+func examWeek(date time.Time) (string, string) {
+	offset := (int(date.Weekday()) + 6) % 7
+	monday := date.AddDate(0, 0, -offset)
+	return monday.Format(semesterDateLayout),
+		monday.AddDate(0, 0, 6).Format(semesterDateLayout)
+}
+
+func queryPlannedExams(
+	tenantDB interfaces.DatabaseQuerier, query string, args ...interface{},
+) ([]tenantmodels.PlannedExam, error) {
+	rows, err := tenantDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying planned exams: %v", err)
+	}
+	defer rows.Close()
+
+	exams := []tenantmodels.PlannedExam{}
+	for rows.Next() {
+		var exam tenantmodels.PlannedExam
+		err := rows.Scan(
+			&exam.ID, &exam.SectionID, &exam.SubjectCode, &exam.SemesterCode,
+			&exam.ExamDate, &exam.Type, &exam.Description, &exam.TeacherID,
+			&exam.Signature, &exam.CreatedAt, &exam.SubjectName,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning planned exam: %v", err)
+		}
+		exam.ExamDate = normalizeDate(exam.ExamDate)
+		exams = append(exams, exam)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating planned exams: %v", err)
+	}
+
+	return exams, nil
+}
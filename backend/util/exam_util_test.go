@@ -0,0 +1,101 @@
+package util
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// plannedExamRow is an exam in the fake planned_exams table
+type plannedExamRow struct {
+	sectionID int64
+	teacherID int64
+}
+
+// fakeExamDriver deletes planned exams from memory the way the DELETE of
+// DeletePlannedExam does, it understands no other statement
+type fakeExamDriver struct {
+	exams     map[int64]plannedExamRow
+	homerooms map[int64]int64
+}
+
+func (d *fakeExamDriver) Open(name string) (driver.Conn, error) { return fakeExamConn{d}, nil }
+
+func (d *fakeExamDriver) Connect(ctx context.Context) (driver.Conn, error) {
+	return fakeExamConn{d}, nil
+}
+
+func (d *fakeExamDriver) Driver() driver.Driver { return d }
+
+type fakeExamConn struct{ driver *fakeExamDriver }
+
+func (c fakeExamConn) Prepare(query string) (driver.Stmt, error) {
+	if !strings.HasPrefix(query, "DELETE FROM planned_exams") {
+		return nil, errors.New("unexpected query: " + query)
+	}
+	return fakeExamStmt{c.driver}, nil
+}
+
+func (c fakeExamConn) Close() error { return nil }
+
+func (c fakeExamConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type fakeExamStmt struct{ driver *fakeExamDriver }
+
+func (s fakeExamStmt) Close() error  { return nil }
+func (s fakeExamStmt) NumInput() int { return 5 }
+
+// Exec receives the exam ID, the section ID and the teacher ID three times
+func (s fakeExamStmt) Exec(args []driver.Value) (driver.Result, error) {
+	examID, sectionID, teacherID := args[0].(int64), args[1].(int64), args[2].(int64)
+	exam, ok := s.driver.exams[examID]
+	if !ok || exam.sectionID != sectionID {
+		return driver.RowsAffected(0), nil
+	}
+	if teacherID != 0 && exam.teacherID != teacherID &&
+		s.driver.homerooms[exam.sectionID] != teacherID {
+		return driver.RowsAffected(0), nil
+	}
+	delete(s.driver.exams, examID)
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeExamStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+// Exam 1 of section 1 is booked by teacher 10, teacher 30 is the homeroom
+// teacher of section 1 and teacher 20 only teaches in it
+func TestDeletePlannedExamOnlyByOwnerOrHomeroomTeacher(t *testing.T) {
+	tests := []struct {
+		name      string
+		teacherID int
+		wantErr   error
+	}{
+		{"teacher who booked the exam", 10, nil},
+		{"homeroom teacher", 30, nil},
+		{"other teacher of the section", 20, ErrNotExamOwner},
+		{"tenant admin", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeExamDriver{
+				exams:     map[int64]plannedExamRow{1: {sectionID: 1, teacherID: 10}},
+				homerooms: map[int64]int64{1: 30},
+			}
+			db := sql.OpenDB(fake)
+			defer db.Close()
+
+			err := DeletePlannedExam(1, 1, tt.teacherID, db)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeletePlannedExam returned %v, want %v", err, tt.wantErr)
+			}
+			if _, kept := fake.exams[1]; kept != (tt.wantErr != nil) {
+				t.Errorf("exam kept = %v, want %v", kept, tt.wantErr != nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package util
+
+import (
+	"database/sql"
+	tenantmodels "ednevnik-backend/models/tenant"
+	wpmodels "ednevnik-backend/models/workspace"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxExcuseAttachmentSize is the largest attachment accepted with an excuse
+const MaxExcuseAttachmentSize = 5 << 20
+
+// CreateAttendanceExcuse validates and stores a new excuse for the absences of
+// a pupil in a section. The date range has to cover at least one absence that
+// is not excused yet.
+func CreateAttendanceExcuse(
+	excuse tenantmodels.AttendanceExcuse, tenantDB *sql.DB,
+) (*tenantmodels.AttendanceExcuse, error) {
+	dateFrom, err := time.Parse("2006-01-02", excuse.DateFrom)
+	if err != nil {
+		return nil, fmt.Errorf("datum početka nije validan")
+	}
+	dateTo, err := time.Parse("2006-01-02", excuse.DateTo)
+	if err != nil {
+		return nil, fmt.Errorf("datum završetka nije validan")
+	}
+	if dateTo.Before(dateFrom) {
+		return nil, fmt.Errorf("datum završetka je prije datuma početka")
+	}
+	if strings.TrimSpace(excuse.Reason) == "" {
+		return nil, fmt.Errorf("razlog opravdanja je obavezan")
+	}
+	if len(excuse.Attachment) > MaxExcuseAttachmentSize {
+		return nil, fmt.Errorf("prilog je veći od 5 MB")
+	}
+	if len(excuse.Attachment) > 0 && excuse.AttachmentName == "" {
+		return nil, fmt.Errorf("naziv priloga je obavezan")
+	}
+
+	absences, err := getExcusableAbsences(
+		excuse.PupilID, excuse.SectionID, excuse.DateFrom, excuse.DateTo,
+		[]string{"absent", "unexcused"}, tenantDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(absences) == 0 {
+		return nil, fmt.Errorf("u odabranom periodu nema neopravdanih izostanaka")
+	}
+
+	query := `INSERT INTO attendance_excuses (pupil_id, section_id, date_from,
+	date_to, reason, attachment_name, attachment_type, attachment,
+	submitted_by_account_id, submitted_by_type)
+	VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?)`
+
+	var attachment interface{}
+	if len(excuse.Attachment) > 0 {
+		attachment = excuse.Attachment
+	}
+
+	res, err := tenantDB.Exec(
+		query,
+		excuse.PupilID,
+		excuse.SectionID,
+		excuse.DateFrom,
+		excuse.DateTo,
+		excuse.Reason,
+		excuse.AttachmentName,
+		excuse.AttachmentType,
+		attachment,
+		excuse.SubmittedByAccountID,
+		excuse.SubmittedByType,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting attendance excuse: %v", err)
+	}
+
+	excuseID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return GetAttendanceExcuseByID(int(excuseID), tenantDB)
+}
+
+// GetAttendanceExcuseByID returns an excuse together with the absences it
+// covers, without the attachment
+func GetAttendanceExcuseByID(
+	excuseID int, tenantDB *sql.DB,
+) (*tenantmodels.AttendanceExcuse, error) {
+	query := excuseSelectQuery + ` WHERE ae.id = ?`
+	excuses, err := queryAttendanceExcuses(tenantDB, query, excuseID)
+	if err != nil {
+		return nil, err
+	}
+	if len(excuses) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &excuses[0], nil
+}
+
+// GetAttendanceExcusesForPupil returns all excuses submitted for a pupil in a
+// section, newest first
+func GetAttendanceExcusesForPupil(
+	pupilID, sectionID int, tenantDB *sql.DB,
+) ([]tenantmodels.AttendanceExcuse, error) {
+	query := excuseSelectQuery + ` WHERE ae.pupil_id = ? AND ae.section_id = ?
+	ORDER BY ae.submitted_at DESC, ae.id DESC`
+	return queryAttendanceExcuses(tenantDB, query, pupilID, sectionID)
+}
+
+// GetAttendanceExcusesForSection returns the excuses of all pupils in a
+// section. An empty status returns excuses regardless of their status.
+func GetAttendanceExcusesForSection(
+	sectionID int, status string, tenantDB *sql.DB,
+) ([]tenantmodels.AttendanceExcuse, error) {
+	query := excuseSelectQuery + ` WHERE ae.section_id = ?
+	AND (? = '' OR ae.status = ?)
+	ORDER BY ae.submitted_at DESC, ae.id DESC`
+	return queryAttendanceExcuses(tenantDB, query, sectionID, status, status)
+}
+
+// GetAttendanceExcuseAttachment returns the attachment of an excuse that
+// belongs to the pupil and section, or sql.ErrNoRows if there is none
+func GetAttendanceExcuseAttachment(
+	excuseID, pupilID, sectionID int, tenantDB *sql.DB,
+) (*tenantmodels.AttendanceExcuse, error) {
+	var excuse tenantmodels.AttendanceExcuse
+	var attachmentType sql.NullString
+	query := `SELECT id, attachment_name, attachment_type, attachment
+	FROM attendance_excuses
+	WHERE id = ? AND pupil_id = ? AND section_id = ? AND attachment IS NOT NULL`
+
+	err := tenantDB.QueryRow(query, excuseID, pupilID, sectionID).Scan(
+		&excuse.ID,
+		&excuse.AttachmentName,
+		&attachmentType,
+		&excuse.Attachment,
+	)
+	if err != nil {
+		return nil, err
+	}
+	excuse.AttachmentType = attachmentType.String
+
+	return &excuse, nil
+}
+
+// DecideAttendanceExcuses approves or rejects pending excuses of a section in
+// a single transaction. Approving marks the covered absences as excused,
+// rejecting marks absences that were not reviewed yet as unexcused. Every
+// status change is recorded in attendance_excuse_lessons, and the excuse keeps
+// the account and signature of whoever decided. The pedagogical measures
+// earned with rejected excuses are issued in the same transaction.
+func DecideAttendanceExcuses(
+	sectionID int,
+	decision tenantmodels.AttendanceExcuseDecision,
+	accountID int,
+	signature string,
+	semesters []wpmodels.TenantSemester,
+	tenantDB *sql.DB,
+) (err error) {
+	var newStatus string
+	var fromStatuses []string
+	switch decision.Decision {
+	case "approved":
+		newStatus = "excused"
+		fromStatuses = []string{"absent", "unexcused"}
+	case "rejected":
+		newStatus = "unexcused"
+		fromStatuses = []string{"absent"}
+	default:
+		return fmt.Errorf("invalid decision: %s", decision.Decision)
+	}
+	if len(decision.ExcuseIDs) == 0 {
+		return fmt.Errorf("no excuses selected")
+	}
+
+	tx, err := tenantDB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting tenantDB transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	statusPlaceholders := strings.TrimSuffix(
+		strings.Repeat("?, ", len(fromStatuses)), ", ",
+	)
+
+	lockQuery := `SELECT pupil_id, date_from, date_to FROM attendance_excuses
+	WHERE id = ? AND section_id = ? AND status = 'pending'
+	FOR UPDATE`
+
+	auditQuery := fmt.Sprintf(`INSERT INTO attendance_excuse_lessons
+	(excuse_id, pupil_id, lesson_id, previous_status, new_status)
+	SELECT ?, pa.pupil_id, pa.lesson_id, pa.status, ?
+	FROM pupil_attendance pa
+	JOIN class_lesson cl ON pa.lesson_id = cl.id
+	WHERE pa.pupil_id = ? AND cl.section_id = ?
+	AND cl.date BETWEEN ? AND ?
+	AND pa.status IN (%s)`, statusPlaceholders)
+
+	attendanceQuery := `UPDATE pupil_attendance pa
+	JOIN attendance_excuse_lessons ael
+		ON ael.pupil_id = pa.pupil_id AND ael.lesson_id = pa.lesson_id
+	SET pa.status = ael.new_status
+	WHERE ael.excuse_id = ?`
+
+	excuseQuery := `UPDATE attendance_excuses
+	SET status = ?, decided_by_account_id = ?, decided_by_signature = ?,
+	decided_at = NOW(), decision_note = NULLIF(?, '')
+	WHERE id = ?`
+
+	for _, excuseID := range decision.ExcuseIDs {
+		var pupilID int
+		var dateFrom, dateTo string
+		err = tx.QueryRow(lockQuery, excuseID, sectionID).Scan(
+			&pupilID, &dateFrom, &dateTo,
+		)
+		if err == sql.ErrNoRows {
+			err = fmt.Errorf("opravdanje %d ne postoji ili je već obrađeno", excuseID)
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("error locking attendance excuse: %v", err)
+		}
+
+		args := []interface{}{
+			excuseID, newStatus, pupilID, sectionID, dateFrom, dateTo,
+		}
+		for _, status := range fromStatuses {
+			args = append(args, status)
+		}
+		if _, err = tx.Exec(auditQuery, args...); err != nil {
+			return fmt.Errorf("error recording excused lessons: %v", err)
+		}
+
+		if _, err = tx.Exec(attendanceQuery, excuseID); err != nil {
+			return fmt.Errorf("error updating attendance: %v", err)
+		}
+
+		_, err = tx.Exec(
+			excuseQuery, decision.Decision, accountID, signature, decision.Note, excuseID,
+		)
+		if err != nil {
+			return fmt.Errorf("error updating attendance excuse: %v", err)
+		}
+	}
+
+	if err = EvaluateAbsenceThresholds(tx, sectionID, nil, semesters); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+const excuseSelectQuery = `SELECT ae.id, ae.pupil_id, p.name, p.last_name,
+	ae.section_id, ae.date_from, ae.date_to, ae.reason,
+	COALESCE(ae.attachment_name, ''), COALESCE(ae.attachment_type, ''),
+	ae.submitted_by_account_id, ae.submitted_by_type, ae.submitted_at, ae.status,
+	COALESCE(ae.decided_by_signature, ''), COALESCE(ae.decided_at, ''),
+	COALESCE(ae.decision_note, '')
+	FROM attendance_excuses ae
+	JOIN pupils p ON ae.pupil_id = p.id`
+
+// queryAttendanceExcuses runs a query built on excuseSelectQuery and loads the
+// lessons of every excuse
+func queryAttendanceExcuses(
+	tenantDB *sql.DB, query string, args ...interface{},
+) ([]tenantmodels.AttendanceExcuse, error) {
+	rows, err := tenantDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying attendance excuses: %v", err)
+	}
+	defer rows.Close()
+
+	excuses := []tenantmodels.AttendanceExcuse{}
+	for rows.Next() {
+		var excuse tenantmodels.AttendanceExcuse
+		err := rows.Scan(
+			&excuse.ID,
+			&excuse.PupilID,
+			&excuse.Name,
+			&excuse.LastName,
+			&excuse.SectionID,
+			&excuse.DateFrom,
+			&excuse.DateTo,
+			&excuse.Reason,
+			&excuse.AttachmentName,
+			&excuse.AttachmentType,
+			&excuse.SubmittedByAccountID,
+			&excuse.SubmittedByType,
+			&excuse.SubmittedAt,
+			&excuse.Status,
+			&excuse.DecidedBy,
+			&excuse.DecidedAt,
+			&excuse.DecisionNote,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning attendance excuse: %v", err)
+		}
+		excuses = append(excuses, excuse)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attendance excuses: %v", err)
+	}
+	rows.Close()
+
+	for i := range excuses {
+		excuses[i].Lessons, err = getAttendanceExcuseLessons(&excuses[i], tenantDB)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return excuses, nil
+}
+
+// getAttendanceExcuseLessons returns the absences covered by a pending excuse,
+// or the status changes applied by the decision once it is decided
+func getAttendanceExcuseLessons(
+	excuse *tenantmodels.AttendanceExcuse, tenantDB *sql.DB,
+) ([]tenantmodels.AttendanceExcuseLesson, error) {
+	if excuse.Status == "pending" {
+		return getExcusableAbsences(
+			excuse.PupilID, excuse.SectionID, excuse.DateFrom, excuse.DateTo,
+			[]string{"absent", "unexcused"}, tenantDB,
+		)
+	}
+
+	query := `SELECT ael.lesson_id, cl.date, cl.period_number, s.subject_name,
+	ael.previous_status, ael.new_status
+	FROM attendance_excuse_lessons ael
+	JOIN class_lesson cl ON ael.lesson_id = cl.id
+	JOIN ednevnik_workspace.subjects s ON cl.subject_code = s.subject_code
+	WHERE ael.excuse_id = ?
+	ORDER BY cl.date, cl.period_number`
+
+	rows, err := tenantDB.Query(query, excuse.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying excused lessons: %v", err)
+	}
+	defer rows.Close()
+
+	lessons := []tenantmodels.AttendanceExcuseLesson{}
+	for rows.Next() {
+		var lesson tenantmodels.AttendanceExcuseLesson
+		err := rows.Scan(
+			&lesson.LessonID,
+			&lesson.Date,
+			&lesson.PeriodNumber,
+			&lesson.SubjectName,
+			&lesson.PreviousStatus,
+			&lesson.Status,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning excused lesson: %v", err)
+		}
+		lessons = append(lessons, lesson)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating excused lessons: %v", err)
+	}
+
+	return lessons, nil
+}
+
+// getExcusableAbsences returns the absences of a pupil in a section within a
+// date range that have one of the given statuses
+func getExcusableAbsences(
+	pupilID, sectionID int,
+	dateFrom, dateTo string,
+	statuses []string,
+	tenantDB *sql.DB,
+) ([]tenantmodels.AttendanceExcuseLesson, error) {
+	query := fmt.Sprintf(`SELECT pa.lesson_id, cl.date, cl.period_number,
+	s.subject_name, pa.status
+	FROM pupil_attendance pa
+	JOIN class_lesson cl ON pa.lesson_id = cl.id
+	JOIN ednevnik_workspace.subjects s ON cl.subject_code = s.subject_code
+	WHERE pa.pupil_id = ? AND cl.section_id = ?
+	AND cl.date BETWEEN ? AND ?
+	AND pa.status IN (%s)
+	ORDER BY cl.date, cl.period_number`,
+		strings.TrimSuffix(strings.Repeat("?, ", len(statuses)), ", "),
+	)
+
+	args := []interface{}{pupilID, sectionID, dateFrom, dateTo}
+	for _, status := range statuses {
+		args = append(args, status)
+	}
+
+	rows, err := tenantDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying absences for excuse: %v", err)
+	}
+	defer rows.Close()
+
+	lessons := []tenantmodels.AttendanceExcuseLesson{}
+	for rows.Next() {
+		var lesson tenantmodels.AttendanceExcuseLesson
+		err := rows.Scan(
+			&lesson.LessonID,
+			&lesson.Date,
+			&lesson.PeriodNumber,
+			&lesson.SubjectName,
+			&lesson.Status,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning absence for excuse: %v", err)
+		}
+		lessons = append(lessons, lesson)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating absences for excuse: %v", err)
+	}
+
+	return lessons, nil
+}
@@ -0,0 +1,274 @@
+package util
+
+import (
+	"database/sql"
+	tenantmodels "ednevnik-backend/models/tenant"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Trends of the grades of a pupil within a semester
+const (
+	GradeTrendRising  = "rising"
+	GradeTrendFalling = "falling"
+	GradeTrendStable  = "stable"
+)
+
+const (
+	// trendMinGrades is the number of grades needed to tell a trend
+	trendMinGrades = 3
+	// trendThreshold is how much the later half of the grades has to differ
+	// from the earlier half to count as a trend
+	trendThreshold = 0.5
+)
+
+// IsDuplicateFinalGradeError checks if the prevent_multiple_final_grades
+// triggers refused a second final grade
+func IsDuplicateFinalGradeError(err error) bool {
+	return strings.Contains(err.Error(), "A final grade already exists")
+}
+
+// ProposeFinalGrade proposes a final grade from the regular grades of a pupil
+// in a subject. The average is rounded with the rounding point of the scheme.
+// Averages close to the rounding point are borderline and follow the trend
+// of the grades, a stable trend keeps the rounded grade.
+func ProposeFinalGrade(
+	grades []tenantmodels.Grade,
+	scheme tenantmodels.GradingScheme,
+) tenantmodels.FinalGradeProposal {
+	var proposal tenantmodels.FinalGradeProposal
+
+	var counted []tenantmodels.Grade
+	for _, grade := range grades {
+		if grade.Type == "final" || grade.IsDeleted || scheme.Weight(grade.Type) <= 0 {
+			continue
+		}
+		counted = append(counted, grade)
+	}
+	proposal.GradeCount = len(counted)
+	if len(counted) == 0 {
+		return proposal
+	}
+
+	proposal.AverageGrade = CalculateAverageGrade(counted, scheme)
+	proposal.Trend = gradeTrend(counted, scheme)
+
+	lower := int(math.Floor(proposal.AverageGrade))
+	upper := min(lower+1, 5)
+	fraction := proposal.AverageGrade - float64(lower)
+
+	proposal.ProposedGrade = lower
+	if fraction >= scheme.RoundUpFrom {
+		proposal.ProposedGrade = upper
+	}
+
+	distance := math.Round(math.Abs(fraction-scheme.RoundUpFrom) * 100)
+	if lower == upper || scheme.BorderlineMargin <= 0 ||
+		distance > math.Round(scheme.BorderlineMargin*100) {
+		return proposal
+	}
+
+	proposal.Borderline = true
+	switch proposal.Trend {
+	case GradeTrendRising:
+		proposal.ProposedGrade = upper
+	case GradeTrendFalling:
+		proposal.ProposedGrade = lower
+	}
+	proposal.AlternativeGrade = lower
+	if proposal.ProposedGrade == lower {
+		proposal.AlternativeGrade = upper
+	}
+
+	return proposal
+}
+
+// gradeTrend compares the average of the later half of the grades with the
+// earlier half
+func gradeTrend(
+	grades []tenantmodels.Grade, scheme tenantmodels.GradingScheme,
+) string {
+	if len(grades) < trendMinGrades {
+		return GradeTrendStable
+	}
+
+	sorted := make([]tenantmodels.Grade, len(grades))
+	copy(sorted, grades)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].GradeDate < sorted[j].GradeDate
+	})
+
+	// Dropping the lowest grade would skew the halves
+	scheme.DropLowest = false
+	half := len(sorted) / 2
+	difference := CalculateAverageGrade(sorted[half:], scheme) -
+		CalculateAverageGrade(sorted[:half], scheme)
+
+	switch {
+	case difference >= trendThreshold:
+		return GradeTrendRising
+	case difference <= -trendThreshold:
+		return GradeTrendFalling
+	default:
+		return GradeTrendStable
+	}
+}
+
+// GetFinalGradeProposals proposes a final grade for every active pupil of a
+// section in a subject and semester
+func GetFinalGradeProposals(
+	sectionID int,
+	semesterCode,
+	subjectCode string,
+	tenantDB *sql.DB,
+) ([]tenantmodels.FinalGradeProposal, error) {
+	pupils, err := GetPupilsForSection(
+		fmt.Sprintf("%d", sectionID), false, tenantDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	grades, err := GetGradesForSectionSubject(
+		sectionID, semesterCode, subjectCode, tenantDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	schemes, err := GetGradingSchemes(tenantDB)
+	if err != nil {
+		return nil, err
+	}
+	scheme := GradingSchemeForSubject(schemes, subjectCode)
+
+	proposals := make([]tenantmodels.FinalGradeProposal, 0, len(pupils))
+	for _, pupil := range pupils {
+		pupilGrades := findGradesForPupil(pupil.ID, grades)
+
+		proposal := ProposeFinalGrade(pupilGrades, scheme)
+		proposal.Pupil = pupil
+		for _, grade := range pupilGrades {
+			if grade.Type == "final" && !grade.IsDeleted {
+				proposal.ExistingFinalGrade = grade.Grade
+			}
+		}
+		proposals = append(proposals, proposal)
+	}
+
+	return proposals, nil
+}
+
+// AcceptFinalGrades saves the final grades a teacher accepted for pupils of a
+// section. Pupils that already have a final grade are skipped, the
+// prevent_multiple_final_grades_insert trigger decides that.
+func AcceptFinalGrades(
+	sectionID int,
+	semesterCode,
+	subjectCode string,
+	teacherID int,
+	accepted []tenantmodels.AcceptedFinalGrade,
+	tenantDB *sql.DB,
+	workspaceDB *sql.DB,
+) (result *tenantmodels.AcceptedFinalGradesResult, err error) {
+	for _, finalGrade := range accepted {
+		if finalGrade.Grade > 5 || finalGrade.Grade < 1 {
+			return nil, fmt.Errorf("ocjena mora biti između 1 i 5")
+		}
+	}
+
+	pupils, err := GetPupilsForSection(
+		fmt.Sprintf("%d", sectionID), false, tenantDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+	activePupils := make(map[int]bool, len(pupils))
+	for _, pupil := range pupils {
+		activePupils[pupil.ID] = true
+	}
+
+	teacherForSignature, err := GetTeacherByID(
+		fmt.Sprintf("%d", teacherID), workspaceDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+	signature := teacherForSignature.Name + " " + teacherForSignature.LastName
+
+	tx, err := tenantDB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	query := `INSERT INTO student_grades (pupil_id, section_id, subject_code,
+	grade, grade_date, type, teacher_id, semester_code, signature) VALUES
+	(?, ?, ?, ?, ?, 'final', ?, ?, ?)`
+	gradeDate := time.Now().Format("2006-01-02")
+
+	result = &tenantmodels.AcceptedFinalGradesResult{
+		Created: []tenantmodels.Grade{},
+		Skipped: []tenantmodels.SkippedFinalGrade{},
+	}
+	for _, finalGrade := range accepted {
+		if !activePupils[finalGrade.PupilID] {
+			result.Skipped = append(result.Skipped, tenantmodels.SkippedFinalGrade{
+				PupilID: finalGrade.PupilID,
+				Reason:  "učenik nije upisan u odjeljenje",
+			})
+			continue
+		}
+
+		res, execErr := tx.Exec(
+			query, finalGrade.PupilID, sectionID, subjectCode, finalGrade.Grade,
+			gradeDate, teacherID, semesterCode, signature,
+		)
+		if execErr != nil {
+			if IsDuplicateFinalGradeError(execErr) {
+				result.Skipped = append(result.Skipped, tenantmodels.SkippedFinalGrade{
+					PupilID: finalGrade.PupilID,
+					Reason:  "zaključna ocjena već postoji",
+				})
+				continue
+			}
+			return nil, execErr
+		}
+
+		gradeID, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		err = AppendLedgerEntry(
+			tx, LedgerEntityGrade, int(gradeID), LedgerActionCreate, teacherID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result.Created = append(result.Created, tenantmodels.Grade{
+			ID:           int(gradeID),
+			PupilID:      finalGrade.PupilID,
+			SectionID:    sectionID,
+			SubjectCode:  subjectCode,
+			Grade:        finalGrade.Grade,
+			GradeDate:    gradeDate,
+			TeacherID:    teacherID,
+			Type:         "final",
+			Signature:    signature,
+			SemesterCode: semesterCode,
+		})
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
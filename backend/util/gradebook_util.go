@@ -6,34 +6,53 @@ import (
 	interfaces "ednevnik-backend/models/interfaces"
 	tenantmodels "ednevnik-backend/models/tenant"
 	wpmodels "ednevnik-backend/models/workspace"
+	"errors"
 	"fmt"
 	"math"
 )
 
-// CalculateAverageGrade calculates the average grade from a slice of grades
-// Returns the result rounded to exactly 2 decimal places
-func CalculateAverageGrade(grades []tenantmodels.Grade) float64 {
-	if len(grades) == 0 {
-		return 0.0
-	}
+// ErrPupilNotInSection is returned when a grade is written for a pupil that
+// is not enrolled in the section
+var ErrPupilNotInSection = errors.New("učenik nije upisan u odjeljenje")
 
-	var total float64
-	var count int
+// CalculateAverageGrade calculates the weighted average grade from a slice of
+// grades using the grading scheme of their subject. With DropLowest the lowest
+// counted grade is left out when there is more than one.
+// Returns the result rounded to exactly 2 decimal places
+func CalculateAverageGrade(
+	grades []tenantmodels.Grade,
+	scheme tenantmodels.GradingScheme,
+) float64 {
+	var counted []tenantmodels.Grade
+	lowest := -1
 
 	for _, grade := range grades {
-		if grade.Type == "final" || grade.IsDeleted {
+		if grade.Type == "final" || grade.IsDeleted || scheme.Weight(grade.Type) <= 0 {
 			continue
 		}
-		total += float64(grade.Grade)
-		count++
+		if lowest == -1 || grade.Grade < counted[lowest].Grade {
+			lowest = len(counted)
+		}
+		counted = append(counted, grade)
 	}
 
-	// If no non-final grades found, return 0
-	if count == 0 {
+	if scheme.DropLowest && len(counted) > 1 {
+		counted = append(counted[:lowest], counted[lowest+1:]...)
+	}
+
+	var total, weights float64
+	for _, grade := range counted {
+		weight := scheme.Weight(grade.Type)
+		total += weight * float64(grade.Grade)
+		weights += weight
+	}
+
+	// If no counted grades found, return 0
+	if weights == 0 {
 		return 0.0
 	}
 
-	average := total / float64(count)
+	average := total / weights
 
 	// Round to 2 decimal places
 	return math.Round(average*100) / 100
@@ -273,13 +292,19 @@ func GetPupilGradesForSectionPupilHelper(
 		return nil, err
 	}
 
+	schemes, err := GetGradingSchemes(tenantDB)
+	if err != nil {
+		return nil, err
+	}
+
 	var gradeSubjectGroups []commonmodels.GradeSubjectGroup
 	for _, subject := range subjects {
 		subjectGrades := findGradesForSubject(subject.SubjectCode, grades)
+		scheme := GradingSchemeForSubject(schemes, subject.SubjectCode)
 		gradeSubjectGroups = append(gradeSubjectGroups, commonmodels.GradeSubjectGroup{
 			Subject:      subject,
 			Grades:       subjectGrades,
-			AverageGrade: CalculateAverageGrade(subjectGrades),
+			AverageGrade: CalculateAverageGrade(subjectGrades, scheme),
 		})
 	}
 
@@ -338,13 +363,19 @@ func GetPupilGradesForSectionSubject(
 		return nil, err
 	}
 
+	schemes, err := GetGradingSchemes(tenantDB)
+	if err != nil {
+		return nil, err
+	}
+	scheme := GradingSchemeForSubject(schemes, subjectCode)
+
 	var gradePupilsGroup []tenantmodels.GradePupilGroup
 	for _, pupil := range pupils {
 		pupilGrades := findGradesForPupil(pupil.ID, grades)
 		gradePupilsGroup = append(gradePupilsGroup, tenantmodels.GradePupilGroup{
 			Pupil:        pupil,
 			Grades:       pupilGrades,
-			AverageGrade: CalculateAverageGrade(pupilGrades),
+			AverageGrade: CalculateAverageGrade(pupilGrades, scheme),
 		})
 	}
 
@@ -447,10 +478,16 @@ func GetGradesForSectionSubjectPupil(
 		return nil, err
 	}
 
+	schemes, err := GetGradingSchemes(tenantDB)
+	if err != nil {
+		return nil, err
+	}
+	scheme := GradingSchemeForSubject(schemes, subjectCode)
+
 	pupilGradeGroup := tenantmodels.GradePupilGroup{
 		Pupil:        *pupil,
 		Grades:       grades,
-		AverageGrade: CalculateAverageGrade(grades),
+		AverageGrade: CalculateAverageGrade(grades, scheme),
 	}
 
 	return &pupilGradeGroup, nil
@@ -493,7 +530,7 @@ func CreateGrade(
 
 	signature := teacherForSignature.Name + " " + teacherForSignature.LastName
 
-	_, err = tx.Exec(query, grade.PupilID, grade.SectionID, grade.SubjectCode,
+	res, err := tx.Exec(query, grade.PupilID, grade.SectionID, grade.SubjectCode,
 		grade.Grade, grade.GradeDate, grade.Type, grade.TeacherID, grade.SemesterCode,
 		signature,
 	)
@@ -501,6 +538,17 @@ func CreateGrade(
 		return nil, err
 	}
 
+	gradeID, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	err = AppendLedgerEntry(
+		tx, LedgerEntityGrade, int(gradeID), LedgerActionCreate, grade.TeacherID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	createdGrade, err := GetGradesForSectionSubjectPupil(
 		grade.SectionID, grade.SubjectCode, grade.PupilID, grade.SemesterCode, tx, workspaceDB,
 	)
@@ -517,9 +565,11 @@ func CreateGrade(
 }
 
 // DeleteGrade removes a grade from the database and returns the updated grades for the pupil.
+// The deletion is signed by the teacher in the gradebook ledger.
 func DeleteGrade(
 	grade *tenantmodels.Grade,
 	signature string,
+	teacherID int,
 	tenantDB *sql.DB,
 	workspaceDB *sql.DB,
 ) (*tenantmodels.GradePupilGroup, error) {
@@ -541,6 +591,13 @@ func DeleteGrade(
 		return nil, err
 	}
 
+	err = AppendLedgerEntry(
+		tx, LedgerEntityGrade, grade.ID, LedgerActionDelete, teacherID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `DELETE FROM student_grades WHERE id = ?`
 	_, err = tx.Exec(query, grade.ID)
 	if err != nil {
@@ -604,6 +661,13 @@ func EditGrade(
 		return nil, err
 	}
 
+	err = AppendLedgerEntry(
+		tx, LedgerEntityGrade, grade.ID, LedgerActionUpdate, grade.TeacherID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	updatedGradeData, err := GetGradesForSectionSubjectPupil(
 		grade.SectionID, grade.SubjectCode, grade.PupilID, grade.SemesterCode, tx, workspaceDB,
 	)
@@ -777,6 +841,49 @@ func GetAllFinalGradesForSection(
 	return grades, nil
 }
 
+// GetGradeByID retrieves the current version of a grade
+func GetGradeByID(
+	gradeID int,
+	tenantDB interfaces.DatabaseQuerier,
+) (*tenantmodels.Grade, error) {
+	query := `SELECT id, pupil_id, section_id, subject_code, grade, grade_date,
+	COALESCE(type, ''), COALESCE(teacher_id, 0), COALESCE(semester_code, ''),
+	COALESCE(signature, '')
+	FROM student_grades WHERE id = ?`
+
+	var grade tenantmodels.Grade
+	err := tenantDB.QueryRow(query, gradeID).Scan(
+		&grade.ID, &grade.PupilID, &grade.SectionID, &grade.SubjectCode,
+		&grade.Grade, &grade.GradeDate, &grade.Type, &grade.TeacherID,
+		&grade.SemesterCode, &grade.Signature,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &grade, nil
+}
+
+// GetBehaviourGradeByID retrieves the current version of a behaviour grade
+func GetBehaviourGradeByID(
+	behaviourGradeID int,
+	tenantDB interfaces.DatabaseQuerier,
+) (*tenantmodels.BehaviourGrade, error) {
+	query := `SELECT id, pupil_id, section_id, behaviour,
+	COALESCE(semester_code, ''), COALESCE(signature, '')
+	FROM pupil_behaviour WHERE id = ?`
+
+	var behaviourGrade tenantmodels.BehaviourGrade
+	err := tenantDB.QueryRow(query, behaviourGradeID).Scan(
+		&behaviourGrade.ID, &behaviourGrade.PupilID, &behaviourGrade.SectionID,
+		&behaviourGrade.Behaviour, &behaviourGrade.SemesterCode,
+		&behaviourGrade.Signature,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &behaviourGrade, nil
+}
+
 // GetGradeEditHistoryHelper retrieves the edit history for a specific grade ID,
 // excluding the current version. Returns historical versions ordered by
 // modification time (oldest first).
@@ -895,6 +1002,11 @@ func GetGradeDataForCompleteGradebook(
 		return nil, err
 	}
 
+	schemes, err := GetGradingSchemes(tenantDB)
+	if err != nil {
+		return nil, err
+	}
+
 	completeGradebookData := make([]tenantmodels.CompleteGradebookData, 0, len(pupils))
 
 	for _, pupil := range pupils {
@@ -920,6 +1032,9 @@ func GetGradeDataForCompleteGradebook(
 				)
 				if grades, exists := gradesByPupilSemesterSubject[gradeKey]; exists {
 					subjectGradesForPupil.Grades = grades
+					subjectGradesForPupil.AverageGrade = CalculateAverageGrade(
+						grades, GradingSchemeForSubject(schemes, subject.SubjectCode),
+					)
 				}
 
 				semesterGradesForPupil.SubjectGrades = append(
@@ -0,0 +1,153 @@
+package util
+
+import (
+	"database/sql"
+	interfaces "ednevnik-backend/models/interfaces"
+	tenantmodels "ednevnik-backend/models/tenant"
+	"fmt"
+)
+
+// DefaultGradingScheme is a plain mean of all grades. It is used when the
+// tenant has no default scheme of its own.
+var DefaultGradingScheme = tenantmodels.GradingScheme{
+	ExamWeight:              1,
+	OralWeight:              1,
+	WrittenAssignmentWeight: 1,
+	RoundUpFrom:             0.5,
+	BorderlineMargin:        0.1,
+}
+
+// GetGradingSchemes returns the grading schemes of the tenant, the default
+// scheme first
+func GetGradingSchemes(
+	tenantDB interfaces.DatabaseQuerier,
+) ([]tenantmodels.GradingScheme, error) {
+	query := `SELECT id, COALESCE(subject_code, ''), exam_weight, oral_weight,
+	written_assignment_weight, drop_lowest, round_up_from, borderline_margin
+	FROM grading_schemes
+	ORDER BY subject_code IS NOT NULL, subject_code`
+
+	rows, err := tenantDB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying grading schemes: %v", err)
+	}
+	defer rows.Close()
+
+	schemes := []tenantmodels.GradingScheme{}
+	for rows.Next() {
+		var scheme tenantmodels.GradingScheme
+		err := rows.Scan(
+			&scheme.ID,
+			&scheme.SubjectCode,
+			&scheme.ExamWeight,
+			&scheme.OralWeight,
+			&scheme.WrittenAssignmentWeight,
+			&scheme.DropLowest,
+			&scheme.RoundUpFrom,
+			&scheme.BorderlineMargin,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning grading scheme: %v", err)
+		}
+		schemes = append(schemes, scheme)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating grading schemes: %v", err)
+	}
+
+	return schemes, nil
+}
+
+// SetGradingSchemes replaces all grading schemes of the tenant. A scheme
+// without a subject code becomes the default of the tenant.
+func SetGradingSchemes(
+	schemes []tenantmodels.GradingScheme, tenantDB *sql.DB,
+) (err error) {
+	subjects := map[string]bool{}
+	for i := range schemes {
+		// Without a rounding point the average rounds at .5
+		if schemes[i].RoundUpFrom == 0 {
+			schemes[i].RoundUpFrom = DefaultGradingScheme.RoundUpFrom
+		}
+	}
+	for _, scheme := range schemes {
+		if subjects[scheme.SubjectCode] {
+			if scheme.SubjectCode == "" {
+				return fmt.Errorf("može postojati samo jedna zadana shema ocjenjivanja")
+			}
+			return fmt.Errorf("predmet %s ima više shema ocjenjivanja", scheme.SubjectCode)
+		}
+		subjects[scheme.SubjectCode] = true
+
+		if scheme.ExamWeight < 0 || scheme.OralWeight < 0 ||
+			scheme.WrittenAssignmentWeight < 0 {
+			return fmt.Errorf("težine ocjena ne mogu biti negativne")
+		}
+		if scheme.ExamWeight+scheme.OralWeight+scheme.WrittenAssignmentWeight == 0 {
+			return fmt.Errorf("barem jedna vrsta ocjena mora imati težinu veću od nule")
+		}
+		if scheme.RoundUpFrom <= 0 || scheme.RoundUpFrom >= 1 {
+			return fmt.Errorf("zaokruživanje mora biti između 0 i 1")
+		}
+		if scheme.BorderlineMargin < 0 || scheme.BorderlineMargin >= 0.5 {
+			return fmt.Errorf("granica za granične slučajeve mora biti između 0 i 0.5")
+		}
+	}
+
+	tx, err := tenantDB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting tenantDB transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec(`DELETE FROM grading_schemes`); err != nil {
+		return fmt.Errorf("error deleting grading schemes: %v", err)
+	}
+
+	insertQuery := `INSERT INTO grading_schemes (subject_code, exam_weight,
+	oral_weight, written_assignment_weight, drop_lowest, round_up_from,
+	borderline_margin) VALUES (NULLIF(?, ''), ?, ?, ?, ?, ?, ?)`
+	for _, scheme := range schemes {
+		_, err = tx.Exec(
+			insertQuery,
+			scheme.SubjectCode,
+			scheme.ExamWeight,
+			scheme.OralWeight,
+			scheme.WrittenAssignmentWeight,
+			scheme.DropLowest,
+			scheme.RoundUpFrom,
+			scheme.BorderlineMargin,
+		)
+		if err != nil {
+			return fmt.Errorf("error inserting grading scheme %s: %v", scheme.SubjectCode, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+// GradingSchemeForSubject picks the scheme of a subject, falling back to the
+// default scheme of the tenant and then to a plain mean
+func GradingSchemeForSubject(
+	schemes []tenantmodels.GradingScheme, subjectCode string,
+) tenantmodels.GradingScheme {
+	scheme := DefaultGradingScheme
+	for _, s := range schemes {
+		if s.SubjectCode == subjectCode {
+			return s
+		}
+		if s.SubjectCode == "" {
+			scheme = s
+		}
+	}
+	return scheme
+}
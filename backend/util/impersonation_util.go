@@ -0,0 +1,114 @@
+package util
+
+import (
+	"database/sql"
+	wpmodels "ednevnik-backend/models/workspace"
+	"fmt"
+	"time"
+)
+
+// CreateImpersonation records a superadmin starting to view the app as
+// another account and returns the impersonation ID
+func CreateImpersonation(
+	actorAccountID, targetAccountID, sessionID int,
+	readOnly bool,
+	reason, ipAddress string,
+	ttl time.Duration,
+	workspaceDB *sql.DB,
+) (int, error) {
+	query := `INSERT INTO impersonations (actor_account_id, target_account_id,
+	session_id, read_only, reason, ip_address, expires_at)
+	VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, NOW() + INTERVAL ? SECOND)`
+	result, err := workspaceDB.Exec(
+		query, actorAccountID, targetAccountID, sessionID, readOnly, reason,
+		ipAddress, int(ttl.Seconds()),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error creating impersonation: %v", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error creating impersonation: %v", err)
+	}
+	return int(id), nil
+}
+
+// GetImpersonationStatus checks that an impersonation was neither ended nor
+// expired and that the session of the superadmin is still active. It returns
+// the membership version of the impersonated account.
+func GetImpersonationStatus(
+	impersonationID int, workspaceDB *sql.DB,
+) (active bool, membershipVersion int, err error) {
+	query := `SELECT (i.ended_at IS NULL AND i.expires_at > NOW()
+	AND s.revoked_at IS NULL AND s.expires_at > NOW()),
+	a.membership_version
+	FROM impersonations i
+	JOIN account_sessions s ON s.id = i.session_id
+	JOIN accounts a ON a.id = i.target_account_id
+	WHERE i.id = ?`
+	err = workspaceDB.QueryRow(query, impersonationID).Scan(&active, &membershipVersion)
+	if err == sql.ErrNoRows {
+		return false, 0, nil
+	}
+	if err != nil {
+		return false, 0, fmt.Errorf("error checking impersonation: %v", err)
+	}
+	return active, membershipVersion, nil
+}
+
+// EndImpersonation ends an impersonation before it expires
+func EndImpersonation(impersonationID int, workspaceDB *sql.DB) error {
+	query := `UPDATE impersonations SET ended_at = NOW()
+	WHERE id = ? AND ended_at IS NULL`
+	if _, err := workspaceDB.Exec(query, impersonationID); err != nil {
+		return fmt.Errorf("error ending impersonation: %v", err)
+	}
+	return nil
+}
+
+// GetImpersonations returns the latest impersonations, newest first
+func GetImpersonations(limit int, workspaceDB *sql.DB) ([]wpmodels.Impersonation, error) {
+	query := `SELECT i.id, i.actor_account_id, actor.email, i.target_account_id,
+	target.email, i.read_only, COALESCE(i.reason, ''),
+	COALESCE(i.ip_address, ''), i.created_at, i.expires_at,
+	COALESCE(i.ended_at, '')
+	FROM impersonations i
+	JOIN accounts actor ON actor.id = i.actor_account_id
+	JOIN accounts target ON target.id = i.target_account_id
+	ORDER BY i.created_at DESC, i.id DESC
+	LIMIT ?`
+
+	rows, err := workspaceDB.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching impersonations: %v", err)
+	}
+	defer rows.Close()
+
+	impersonations := []wpmodels.Impersonation{}
+	for rows.Next() {
+		var impersonation wpmodels.Impersonation
+		err := rows.Scan(
+			&impersonation.ID,
+			&impersonation.ActorAccountID,
+			&impersonation.ActorEmail,
+			&impersonation.TargetAccountID,
+			&impersonation.TargetEmail,
+			&impersonation.ReadOnly,
+			&impersonation.Reason,
+			&impersonation.IPAddress,
+			&impersonation.CreatedAt,
+			&impersonation.ExpiresAt,
+			&impersonation.EndedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning impersonation: %v", err)
+		}
+		impersonations = append(impersonations, impersonation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating impersonations: %v", err)
+	}
+
+	return impersonations, nil
+}
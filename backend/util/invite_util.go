@@ -358,11 +358,29 @@ func AcceptPupilSectionInvite(
 	SELECT pupil_id, section_id FROM pupils_sections_invite WHERE id = ?
 	ON DUPLICATE KEY UPDATE is_active = 1`
 
-	_, err = tenantTx.Exec(insertPupilSection, inviteID)
+	res, err := tenantTx.Exec(insertPupilSection, inviteID)
 	if err != nil {
 		return fmt.Errorf("error inserting pupil section from invite: %v", err)
 	}
 
+	// The behaviour grades created for a new enrollment are signed by the
+	// system, a reactivated enrollment keeps its grades
+	if inserted, _ := res.RowsAffected(); inserted == 1 {
+		var sectionID int
+		err = tenantTx.QueryRow(
+			`SELECT section_id FROM pupils_sections_invite WHERE id = ?`, inviteID,
+		).Scan(&sectionID)
+		if err != nil {
+			return fmt.Errorf("error reading section of invite: %v", err)
+		}
+		err = AppendBehaviourLedgerEntries(
+			tenantTx, pupilID, sectionID, "", LedgerActionCreate, 0,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Insert into pupil tenant
 	insertQuery := `INSERT IGNORE INTO pupil_tenant (pupil_id, tenant_id)
 	VALUES (?, ?)`
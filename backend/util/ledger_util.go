@@ -0,0 +1,548 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	interfaces "ednevnik-backend/models/interfaces"
+	tenantmodels "ednevnik-backend/models/tenant"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Entities and actions recorded in the gradebook ledger
+const (
+	LedgerEntityGrade     = "grade"
+	LedgerEntityLesson    = "lesson"
+	LedgerEntityBehaviour = "behaviour"
+
+	LedgerActionCreate = "create"
+	LedgerActionUpdate = "update"
+	LedgerActionDelete = "delete"
+	LedgerActionSeal   = "seal"
+)
+
+// Issues reported when verifying the gradebook ledger
+const (
+	LedgerIssueBrokenChain      = "broken_chain"
+	LedgerIssueInvalidEntry     = "invalid_entry"
+	LedgerIssueInvalidSignature = "invalid_signature"
+	LedgerIssueModified         = "modified"
+	LedgerIssueDeleted          = "deleted"
+	LedgerIssueRestored         = "restored"
+	LedgerIssueUnsigned         = "unsigned"
+)
+
+// ErrLedgerAlreadySealed is returned when sealing a section whose ledger
+// already has entries
+var ErrLedgerAlreadySealed = errors.New("dnevnik odjeljenja je već zapečaćen")
+
+// LedgerKey is the server secret the gradebook ledger is signed with. The
+// database users never see it, so rows changed by direct SQL can not be
+// signed again.
+var LedgerKey []byte
+
+// ledgerGenesisHash is the previous hash of the first entry of a section
+var ledgerGenesisHash = strings.Repeat("0", 64)
+
+// ledgerEntities lists the signed tables in the order they are verified. The
+// first columns are always the ID and the section, followed by the pupil for
+// entities that belong to a pupil. Every listed column is part of the signed
+// payload. The teacher of a grade is left out because removing a teacher
+// from the tenant clears it.
+var ledgerEntities = []struct {
+	entity   string
+	hasPupil bool
+	query    string
+}{
+	{
+		LedgerEntityGrade, true,
+		`SELECT id, section_id, pupil_id, subject_code, type, grade, grade_date,
+		semester_code, signature FROM student_grades`,
+	},
+	{
+		LedgerEntityLesson, false,
+		`SELECT id, section_id, subject_code, date, period_number, description,
+		signature FROM class_lesson`,
+	},
+	{
+		LedgerEntityBehaviour, true,
+		`SELECT id, section_id, pupil_id, semester_code, behaviour, signature
+		FROM pupil_behaviour`,
+	},
+}
+
+// ledgerRow is the signed state of a grade, lesson or behaviour row
+type ledgerRow struct {
+	ID          int
+	SectionID   int
+	PupilID     int
+	PayloadHash string
+}
+
+// ledgerEntry is an entry of the gradebook ledger of a section
+type ledgerEntry struct {
+	SectionID   int
+	Sequence    int
+	Entity      string
+	EntityID    int
+	PupilID     int
+	Action      string
+	PayloadHash string
+	TeacherID   int
+	Signature   string
+	PrevHash    string
+	EntryHash   string
+}
+
+// ledgerEntityKey identifies a signed row within a section
+type ledgerEntityKey struct {
+	entity string
+	id     int
+}
+
+// AppendLedgerEntry signs the current state of a grade, lesson or behaviour
+// row and appends it to the ledger of its section. It has to run in the
+// transaction that changes the row, after inserts and updates and before
+// deletes. Rows without a section are not recorded. Teacher 0 signs changes
+// made by the system.
+func AppendLedgerEntry(
+	tx *sql.Tx, entity string, entityID int, action string, teacherID int,
+) error {
+	row, err := getLedgerRow(entity, entityID, tx)
+	if err != nil {
+		return err
+	}
+	if row.SectionID == 0 {
+		return nil
+	}
+	return appendLedgerEntry(tx, row.SectionID, entity, *row, action, teacherID)
+}
+
+// AppendBehaviourLedgerEntries appends the behaviour grades of a pupil in a
+// section to the ledger, of every semester when no semester is given
+func AppendBehaviourLedgerEntries(
+	tx *sql.Tx,
+	pupilID, sectionID int,
+	semesterCode, action string,
+	teacherID int,
+) error {
+	query := `SELECT id FROM pupil_behaviour WHERE pupil_id = ? AND section_id = ?`
+	args := []any{pupilID, sectionID}
+	if semesterCode != "" {
+		query += ` AND semester_code = ?`
+		args = append(args, semesterCode)
+	}
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("error querying behaviour grades: %v", err)
+	}
+	var behaviourIDs []int
+	for rows.Next() {
+		var behaviourID int
+		if err := rows.Scan(&behaviourID); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning behaviour grade: %v", err)
+		}
+		behaviourIDs = append(behaviourIDs, behaviourID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating behaviour grades: %v", err)
+	}
+
+	for _, behaviourID := range behaviourIDs {
+		err = AppendLedgerEntry(tx, LedgerEntityBehaviour, behaviourID, action, teacherID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifySectionLedger walks the ledger of a section and compares it with the
+// grades, lessons and behaviour grades of the section. It reports broken
+// links and forged entries of the ledger itself, and rows that were changed,
+// deleted or inserted without going through the ledger. Rows of pupils that
+// left the section are removed by the database, so they are not reported as
+// deleted.
+func VerifySectionLedger(
+	sectionID int, tenantDB interfaces.DatabaseQuerier,
+) (*tenantmodels.LedgerVerification, error) {
+	entries, err := getLedgerEntries(sectionID, tenantDB)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &tenantmodels.LedgerVerification{
+		SectionID: sectionID,
+		Entries:   len(entries),
+		Issues:    []tenantmodels.LedgerIssue{},
+	}
+
+	prevHash := ledgerGenesisHash
+	latest := map[ledgerEntityKey]ledgerEntry{}
+	for i, entry := range entries {
+		if entry.Sequence != i+1 || entry.PrevHash != prevHash {
+			verification.Issues = append(verification.Issues, tenantmodels.LedgerIssue{
+				Issue: LedgerIssueBrokenChain, Sequence: entry.Sequence,
+			})
+		}
+		if !hmac.Equal([]byte(entry.EntryHash), []byte(hashLedgerEntry(entry))) {
+			verification.Issues = append(verification.Issues, tenantmodels.LedgerIssue{
+				Issue: LedgerIssueInvalidEntry, Sequence: entry.Sequence,
+			})
+		}
+		if !hmac.Equal([]byte(entry.Signature), []byte(signLedgerEntry(entry))) {
+			verification.Issues = append(verification.Issues, tenantmodels.LedgerIssue{
+				Issue: LedgerIssueInvalidSignature, Sequence: entry.Sequence,
+			})
+		}
+		prevHash = entry.EntryHash
+		latest[ledgerEntityKey{entry.Entity, entry.EntityID}] = entry
+	}
+
+	enrolled, err := getLedgerEnrolledPupils(sectionID, tenantDB)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, spec := range ledgerEntities {
+		rows, err := getLedgerRows(spec.entity, sectionID, tenantDB)
+		if err != nil {
+			return nil, err
+		}
+		verification.CheckedRows += len(rows)
+
+		present := make(map[int]bool, len(rows))
+		for _, row := range rows {
+			present[row.ID] = true
+
+			issue := ""
+			entry, ok := latest[ledgerEntityKey{spec.entity, row.ID}]
+			switch {
+			case !ok:
+				issue = LedgerIssueUnsigned
+			case entry.Action == LedgerActionDelete:
+				issue = LedgerIssueRestored
+			case entry.PayloadHash != row.PayloadHash:
+				issue = LedgerIssueModified
+			}
+			if issue != "" {
+				verification.Issues = append(verification.Issues, tenantmodels.LedgerIssue{
+					Issue: issue, Entity: spec.entity, EntityID: row.ID,
+				})
+			}
+		}
+
+		var deleted []int
+		for key, entry := range latest {
+			if key.entity != spec.entity || present[key.id] ||
+				entry.Action == LedgerActionDelete {
+				continue
+			}
+			if spec.hasPupil && !enrolled[entry.PupilID] {
+				continue
+			}
+			deleted = append(deleted, key.id)
+		}
+		sort.Ints(deleted)
+		for _, entityID := range deleted {
+			verification.Issues = append(verification.Issues, tenantmodels.LedgerIssue{
+				Issue: LedgerIssueDeleted, Entity: spec.entity, EntityID: entityID,
+			})
+		}
+	}
+
+	verification.Valid = len(verification.Issues) == 0
+	return verification, nil
+}
+
+// SealSectionLedger signs the grades, lessons and behaviour grades of a
+// section that were written before the ledger existed. It is a one-time
+// backfill: once the ledger of a section has an entry, every later change
+// is recorded by the API and rows changed outside of it must stay visible,
+// so sealing fails with ErrLedgerAlreadySealed. Returns the number of sealed
+// rows.
+func SealSectionLedger(
+	sectionID, teacherID int, tenantDB *sql.DB,
+) (sealed int, err error) {
+	tx, err := tenantDB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error starting tenantDB transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// Locks the ledger of the section, so writes to it wait for the seal
+	var recorded int
+	err = tx.QueryRow(
+		`SELECT COUNT(*) FROM gradebook_ledger WHERE section_id = ? FOR UPDATE`,
+		sectionID,
+	).Scan(&recorded)
+	if err != nil {
+		return 0, fmt.Errorf("error querying gradebook ledger: %v", err)
+	}
+	if recorded > 0 {
+		return 0, ErrLedgerAlreadySealed
+	}
+
+	for _, spec := range ledgerEntities {
+		var rows []ledgerRow
+		rows, err = getLedgerRows(spec.entity, sectionID, tx)
+		if err != nil {
+			return 0, err
+		}
+		for _, row := range rows {
+			err = appendLedgerEntry(
+				tx, sectionID, spec.entity, row, LedgerActionSeal, teacherID,
+			)
+			if err != nil {
+				return 0, err
+			}
+			sealed++
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %v", err)
+	}
+	return sealed, nil
+}
+
+// appendLedgerEntry chains a signed row to the ledger of a section. The last
+// entry is locked, so concurrent writes to the section wait for each other.
+func appendLedgerEntry(
+	tx *sql.Tx,
+	sectionID int,
+	entity string,
+	row ledgerRow,
+	action string,
+	teacherID int,
+) error {
+	entry := ledgerEntry{
+		SectionID:   sectionID,
+		Entity:      entity,
+		EntityID:    row.ID,
+		PupilID:     row.PupilID,
+		Action:      action,
+		PayloadHash: row.PayloadHash,
+		TeacherID:   teacherID,
+		PrevHash:    ledgerGenesisHash,
+	}
+
+	err := tx.QueryRow(
+		`SELECT sequence, entry_hash FROM gradebook_ledger WHERE section_id = ?
+		ORDER BY sequence DESC LIMIT 1 FOR UPDATE`,
+		sectionID,
+	).Scan(&entry.Sequence, &entry.PrevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("error querying gradebook ledger: %v", err)
+	}
+	entry.Sequence++
+	entry.Signature = signLedgerEntry(entry)
+	entry.EntryHash = hashLedgerEntry(entry)
+
+	_, err = tx.Exec(
+		`INSERT INTO gradebook_ledger (section_id, sequence, entity, entity_id,
+		pupil_id, action, payload_hash, teacher_id, signature, prev_hash,
+		entry_hash) VALUES (?, ?, ?, ?, NULLIF(?, 0), ?, ?, NULLIF(?, 0), ?, ?, ?)`,
+		entry.SectionID, entry.Sequence, entry.Entity, entry.EntityID,
+		entry.PupilID, entry.Action, entry.PayloadHash, entry.TeacherID,
+		entry.Signature, entry.PrevHash, entry.EntryHash,
+	)
+	if err != nil {
+		return fmt.Errorf("error appending to gradebook ledger: %v", err)
+	}
+	return nil
+}
+
+// getLedgerEntries returns the ledger of a section in order
+func getLedgerEntries(
+	sectionID int, tenantDB interfaces.DatabaseQuerier,
+) ([]ledgerEntry, error) {
+	rows, err := tenantDB.Query(
+		`SELECT section_id, sequence, entity, entity_id, COALESCE(pupil_id, 0),
+		action, payload_hash, COALESCE(teacher_id, 0), signature, prev_hash,
+		entry_hash FROM gradebook_ledger WHERE section_id = ?
+		ORDER BY sequence`,
+		sectionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying gradebook ledger: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []ledgerEntry
+	for rows.Next() {
+		var entry ledgerEntry
+		err := rows.Scan(
+			&entry.SectionID, &entry.Sequence, &entry.Entity, &entry.EntityID,
+			&entry.PupilID, &entry.Action, &entry.PayloadHash, &entry.TeacherID,
+			&entry.Signature, &entry.PrevHash, &entry.EntryHash,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning gradebook ledger: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating gradebook ledger: %v", err)
+	}
+	return entries, nil
+}
+
+// getLedgerRow reads the signed state of a row
+func getLedgerRow(
+	entity string, entityID int, tenantDB interfaces.DatabaseQuerier,
+) (*ledgerRow, error) {
+	rows, err := queryLedgerRows(entity, " WHERE id = ?", entityID, tenantDB)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &rows[0], nil
+}
+
+// getLedgerRows reads the signed state of the rows of a section
+func getLedgerRows(
+	entity string, sectionID int, tenantDB interfaces.DatabaseQuerier,
+) ([]ledgerRow, error) {
+	return queryLedgerRows(entity, " WHERE section_id = ?", sectionID, tenantDB)
+}
+
+// queryLedgerRows reads rows of a signed table and hashes their columns
+func queryLedgerRows(
+	entity, where string, arg int, tenantDB interfaces.DatabaseQuerier,
+) ([]ledgerRow, error) {
+	query := ""
+	hasPupil := false
+	for _, spec := range ledgerEntities {
+		if spec.entity == entity {
+			query, hasPupil = spec.query, spec.hasPupil
+		}
+	}
+	if query == "" {
+		return nil, fmt.Errorf("unknown ledger entity %s", entity)
+	}
+
+	rows, err := tenantDB.Query(query+where+" ORDER BY id", arg)
+	if err != nil {
+		return nil, fmt.Errorf("error querying %s rows: %v", entity, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []ledgerRow
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		targets := make([]any, len(columns))
+		for i := range values {
+			targets[i] = &values[i]
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return nil, fmt.Errorf("error scanning %s row: %v", entity, err)
+		}
+
+		row := ledgerRow{PayloadHash: hashLedgerPayload(values)}
+		row.ID, _ = strconv.Atoi(values[0].String)
+		row.SectionID, _ = strconv.Atoi(values[1].String)
+		if hasPupil {
+			row.PupilID, _ = strconv.Atoi(values[2].String)
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating %s rows: %v", entity, err)
+	}
+	return result, nil
+}
+
+// getLedgerEnrolledPupils returns the pupils that are in a section
+func getLedgerEnrolledPupils(
+	sectionID int, tenantDB interfaces.DatabaseQuerier,
+) (map[int]bool, error) {
+	rows, err := tenantDB.Query(
+		`SELECT pupil_id FROM pupils_sections WHERE section_id = ?`, sectionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying section pupils: %v", err)
+	}
+	defer rows.Close()
+
+	enrolled := map[int]bool{}
+	for rows.Next() {
+		var pupilID int
+		if err := rows.Scan(&pupilID); err != nil {
+			return nil, fmt.Errorf("error scanning section pupil: %v", err)
+		}
+		enrolled[pupilID] = true
+	}
+	return enrolled, rows.Err()
+}
+
+// hashLedgerPayload hashes the columns of a row. Every value is prefixed with
+// its length so no two rows share an encoding, NULL is written as "-".
+func hashLedgerPayload(values []sql.NullString) string {
+	var payload strings.Builder
+	for _, value := range values {
+		if !value.Valid {
+			payload.WriteString("-|")
+			continue
+		}
+		fmt.Fprintf(&payload, "%d:%s|", len(value.String), value.String)
+	}
+	sum := sha256.Sum256([]byte(payload.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// ledgerTeacherKey derives the signing key of a teacher from the server
+// secret, teacher 0 is the system
+func ledgerTeacherKey(teacherID int) []byte {
+	return ledgerMAC(LedgerKey, fmt.Sprintf("teacher:%d", teacherID))
+}
+
+// signLedgerEntry signs the payload of an entry with the key of its teacher
+func signLedgerEntry(entry ledgerEntry) string {
+	return hex.EncodeToString(ledgerMAC(
+		ledgerTeacherKey(entry.TeacherID),
+		fmt.Sprintf(
+			"%s|%d|%s|%s", entry.Entity, entry.EntityID, entry.Action,
+			entry.PayloadHash,
+		),
+	))
+}
+
+// hashLedgerEntry links an entry to the previous one of its section
+func hashLedgerEntry(entry ledgerEntry) string {
+	return hex.EncodeToString(ledgerMAC(
+		LedgerKey,
+		fmt.Sprintf(
+			"%s|%d|%d|%s|%d|%d|%s|%s|%d|%s",
+			entry.PrevHash, entry.SectionID, entry.Sequence, entry.Entity,
+			entry.EntityID, entry.PupilID, entry.Action, entry.PayloadHash,
+			entry.TeacherID, entry.Signature,
+		),
+	))
+}
+
+// ledgerMAC returns the HMAC-SHA256 of a message
+func ledgerMAC(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
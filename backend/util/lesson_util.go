@@ -18,11 +18,15 @@ import (
 // Parameters:
 //   - requestData: Contains lesson details and attendance data for all pupils
 //   - tenantDB: Database connection for the specific tenant
+//   - teacherID: The teacher that signs the lesson in the gradebook ledger
+//   - semesters: Semesters of the section, to issue pedagogical measures for
+//     the recorded absences
 //
 // Returns:
 //   - error: nil on success, or error describing what went wrong
 func CreateLesson(
 	requestData tenantmodels.LessonData, tenantDB *sql.DB, signature string,
+	teacherID int, semesters []wpmodels.TenantSemester,
 ) (newLesson *tenantmodels.LessonData, err error) {
 	tx, err := tenantDB.Begin()
 	if err != nil {
@@ -56,6 +60,13 @@ func CreateLesson(
 		return nil, err
 	}
 
+	err = AppendLedgerEntry(
+		tx, LedgerEntityLesson, int(lessonID), LedgerActionCreate, teacherID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	attendanceInsertQuery := `INSERT INTO pupil_attendance (pupil_id,
 	lesson_id, status) VALUES (?, ?, ?)`
 
@@ -71,6 +82,14 @@ func CreateLesson(
 		}
 	}
 
+	err = EvaluateAbsenceThresholds(
+		tx, requestData.LessonData.SectionID,
+		attendancePupilIDs(requestData.PupilAttendanceData), semesters,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return nil, err
@@ -93,10 +112,16 @@ func CreateLesson(
 // added or removed from the lesson. If any operation fails, the entire transaction is
 // rolled back to maintain data integrity.
 //
+// A lesson moved to another section is recorded as deleted in the gradebook
+// ledger of its old section and as created in the ledger of the new one.
+//
 // Parameters:
 //   - lessonID: The ID of the lesson to update
 //   - requestData: Contains updated lesson details and attendance data for all pupils
 //   - tenantDB: Database connection for the specific tenant
+//   - teacherID: The teacher that signs the lesson in the gradebook ledger
+//   - semesters: Semesters of the section of the lesson, to issue pedagogical
+//     measures for the recorded absences
 //
 // Returns:
 //   - error: nil on success, or error describing what went wrong
@@ -105,6 +130,8 @@ func UpdateLesson(
 	requestData tenantmodels.LessonData,
 	tenantDB *sql.DB,
 	signature string,
+	teacherID int,
+	semesters []wpmodels.TenantSemester,
 ) (updatedLesson *tenantmodels.LessonData, err error) {
 	tx, err := tenantDB.Begin()
 	if err != nil {
@@ -117,6 +144,25 @@ func UpdateLesson(
 		}
 	}()
 
+	var oldSectionID sql.NullInt64
+	err = tx.QueryRow(
+		`SELECT section_id FROM class_lesson WHERE id = ? FOR UPDATE`, lessonID,
+	).Scan(&oldSectionID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying lesson: %v", err)
+	}
+
+	ledgerAction := LedgerActionUpdate
+	if int(oldSectionID.Int64) != requestData.LessonData.SectionID {
+		err = AppendLedgerEntry(
+			tx, LedgerEntityLesson, lessonID, LedgerActionDelete, teacherID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		ledgerAction = LedgerActionCreate
+	}
+
 	// Update lesson data
 	lessonUpdateQuery := `UPDATE class_lesson SET description = ?, date = ?,
 		period_number = ?, section_id = ?, subject_code = ?, signature = ? WHERE id = ?`
@@ -135,6 +181,11 @@ func UpdateLesson(
 		return nil, fmt.Errorf("error updating lesson: %v", err)
 	}
 
+	err = AppendLedgerEntry(tx, LedgerEntityLesson, lessonID, ledgerAction, teacherID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Delete existing attendance records for this lesson
 	deleteAttendanceQuery := `DELETE FROM pupil_attendance WHERE lesson_id = ?`
 	_, err = tx.Exec(deleteAttendanceQuery, lessonID)
@@ -159,6 +210,14 @@ func UpdateLesson(
 		}
 	}
 
+	err = EvaluateAbsenceThresholds(
+		tx, requestData.LessonData.SectionID,
+		attendancePupilIDs(requestData.PupilAttendanceData), semesters,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return nil, err
@@ -181,19 +240,39 @@ func UpdateLesson(
 // Parameters:
 //   - lessonID: The ID of the lesson to delete
 //   - tenantDB: Database connection for the specific tenant
+//   - teacherID: The teacher that signs the deletion in the gradebook ledger
 //
 // Returns:
 //   - error: nil on success, or error describing what went wrong
 func DeleteLesson(
 	lessonID int,
 	tenantDB *sql.DB,
-) error {
+	teacherID int,
+) (err error) {
+	tx, err := tenantDB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting tenantDB transaction: %v", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = AppendLedgerEntry(
+		tx, LedgerEntityLesson, lessonID, LedgerActionDelete, teacherID,
+	)
+	if err != nil {
+		return err
+	}
+
 	deleteLessonQuery := `DELETE FROM class_lesson WHERE id = ?`
-	_, err := tenantDB.Exec(deleteLessonQuery, lessonID)
+	_, err = tx.Exec(deleteLessonQuery, lessonID)
 	if err != nil {
 		return err
 	}
-	return nil
+	return tx.Commit()
 }
 
 // GetLessonsForSection retrieves all lessons for a specific section along with
@@ -476,13 +555,44 @@ func GetAbsentAttendancesForPupilHelper(
 	return attendances, nil
 }
 
-// HandleAttendanceActionHelper processes attendance actions
+// HandleAttendanceActionHelper processes attendance actions and issues the
+// pedagogical measures the pupil earned with them in the same transaction
 func HandleAttendanceActionHelper(
-	action tenantmodels.AttendanceAction, tenantDB *sql.DB,
-) error {
+	action tenantmodels.AttendanceAction,
+	sectionID int,
+	semesters []wpmodels.TenantSemester,
+	tenantDB *sql.DB,
+) (err error) {
+	tx, err := tenantDB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting tenantDB transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
 	query := `UPDATE pupil_attendance SET status = ? WHERE pupil_id = ? AND lesson_id = ?`
-	_, err := tenantDB.Exec(query, action.Type, action.PupilID, action.LessonID)
-	return err
+	_, err = tx.Exec(query, action.Type, action.PupilID, action.LessonID)
+	if err != nil {
+		return err
+	}
+
+	err = EvaluateAbsenceThresholds(tx, sectionID, []int{action.PupilID}, semesters)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// attendancePupilIDs returns the IDs of the pupils in the attendance data
+func attendancePupilIDs(attendances []tenantmodels.PupilAttendance) []int {
+	pupilIDs := make([]int, 0, len(attendances))
+	for _, attendance := range attendances {
+		pupilIDs = append(pupilIDs, attendance.PupilID)
+	}
+	return pupilIDs
 }
 
 // WeekCountOfLessonsForSection returns the total number
@@ -0,0 +1,262 @@
+package util
+
+import (
+	"database/sql"
+	wpmodels "ednevnik-backend/models/workspace"
+	"fmt"
+	"time"
+)
+
+// Login throttling. Every failed login after the free attempts doubles the
+// time the account or IP address has to wait, up to the lockout duration.
+// After MaxFailedLogins consecutive failures an account is locked for the
+// whole lockout duration or until an admin unlocks it.
+const (
+	LoginFreeAttempts    = 3
+	MaxFailedLogins      = 10
+	LoginBackoffBase     = 2 * time.Second
+	LoginLockoutDuration = 15 * time.Minute
+	// IPFreeAttempts is the number of failed logins from one IP address in
+	// the lockout window before the IP address is throttled
+	IPFreeAttempts = 20
+)
+
+// loginDelay returns how long to wait after the given number of failures
+func loginDelay(failures, freeAttempts int) time.Duration {
+	if failures <= freeAttempts {
+		return 0
+	}
+	delay := LoginBackoffBase
+	for i := freeAttempts + 1; i < failures; i++ {
+		delay *= 2
+		if delay >= LoginLockoutDuration {
+			return LoginLockoutDuration
+		}
+	}
+	return delay
+}
+
+// GetLoginIPDelay returns how long an IP address has to wait before the next
+// login attempt, based on its failed attempts in the lockout window
+func GetLoginIPDelay(ipAddress string, workspaceDB *sql.DB) (time.Duration, error) {
+	var failures int
+	var secondsSinceLast sql.NullInt64
+	query := `SELECT COUNT(*), TIMESTAMPDIFF(SECOND, MAX(created_at), NOW())
+	FROM login_attempts
+	WHERE ip_address = ? AND success = FALSE
+	AND created_at > NOW() - INTERVAL ? SECOND`
+	err := workspaceDB.QueryRow(
+		query, ipAddress, int(LoginLockoutDuration.Seconds()),
+	).Scan(&failures, &secondsSinceLast)
+	if err != nil {
+		return 0, fmt.Errorf("error checking login attempts: %v", err)
+	}
+
+	delay := loginDelay(failures, IPFreeAttempts)
+	if delay == 0 || !secondsSinceLast.Valid {
+		return 0, nil
+	}
+	remaining := delay - time.Duration(secondsSinceLast.Int64)*time.Second
+	if remaining < 0 {
+		return 0, nil
+	}
+	return remaining, nil
+}
+
+// GetAccountLockoutDelay returns how long an account has to wait before the
+// next login attempt
+func GetAccountLockoutDelay(accountID int, workspaceDB *sql.DB) (time.Duration, error) {
+	var remaining int64
+	query := `SELECT GREATEST(TIMESTAMPDIFF(SECOND, NOW(), locked_until), 0)
+	FROM account_lockouts
+	WHERE account_id = ? AND locked_until IS NOT NULL`
+	err := workspaceDB.QueryRow(query, accountID).Scan(&remaining)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error checking account lockout: %v", err)
+	}
+	return time.Duration(remaining) * time.Second, nil
+}
+
+// RecordFailedLogin stores a failed login attempt and, if the email matched
+// an account, extends the lockout of the account. accountID is 0 for
+// unknown emails and parent access codes.
+func RecordFailedLogin(
+	email string, accountID int, ipAddress, loginType string, workspaceDB *sql.DB,
+) error {
+	if err := insertLoginAttempt(
+		email, accountID, ipAddress, loginType, false, workspaceDB,
+	); err != nil {
+		return err
+	}
+	if accountID == 0 {
+		return nil
+	}
+
+	upsertQuery := `INSERT INTO account_lockouts (account_id, failed_count, last_failed_at)
+	VALUES (?, 1, NOW())
+	ON DUPLICATE KEY UPDATE failed_count = failed_count + 1, last_failed_at = NOW()`
+	if _, err := workspaceDB.Exec(upsertQuery, accountID); err != nil {
+		return fmt.Errorf("error updating account lockout: %v", err)
+	}
+
+	var failures int
+	countQuery := `SELECT failed_count FROM account_lockouts WHERE account_id = ?`
+	if err := workspaceDB.QueryRow(countQuery, accountID).Scan(&failures); err != nil {
+		return fmt.Errorf("error reading account lockout: %v", err)
+	}
+
+	delay := loginDelay(failures, LoginFreeAttempts)
+	if failures >= MaxFailedLogins {
+		delay = LoginLockoutDuration
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	lockQuery := `UPDATE account_lockouts
+	SET locked_until = NOW() + INTERVAL ? SECOND
+	WHERE account_id = ?`
+	if _, err := workspaceDB.Exec(lockQuery, int(delay.Seconds()), accountID); err != nil {
+		return fmt.Errorf("error locking account: %v", err)
+	}
+	return nil
+}
+
+// RecordSuccessfulLogin stores a successful login attempt and clears the
+// failed logins of the account
+func RecordSuccessfulLogin(
+	email string, accountID int, ipAddress, loginType string, workspaceDB *sql.DB,
+) error {
+	if err := insertLoginAttempt(
+		email, accountID, ipAddress, loginType, true, workspaceDB,
+	); err != nil {
+		return err
+	}
+	return UnlockAccount(accountID, workspaceDB)
+}
+
+// UnlockAccount clears the failed logins and lockout of an account
+func UnlockAccount(accountID int, workspaceDB *sql.DB) error {
+	query := `DELETE FROM account_lockouts WHERE account_id = ?`
+	if _, err := workspaceDB.Exec(query, accountID); err != nil {
+		return fmt.Errorf("error unlocking account: %v", err)
+	}
+	return nil
+}
+
+// GetLockedAccounts returns accounts with failed logins that are still
+// locked. With a tenant ID only teachers, pupils and parents of pupils of
+// the tenant are returned.
+func GetLockedAccounts(tenantID int, workspaceDB *sql.DB) ([]wpmodels.LockedAccount, error) {
+	query := `SELECT a.id, a.email, a.account_type, l.failed_count,
+	l.last_failed_at, l.locked_until
+	FROM account_lockouts l
+	JOIN accounts a ON a.id = l.account_id
+	WHERE l.locked_until > NOW()
+	AND (? = 0 OR a.id IN (` + tenantAccountsQuery + `))
+	ORDER BY l.locked_until DESC`
+
+	rows, err := workspaceDB.Query(query, tenantID, tenantID, tenantID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching locked accounts: %v", err)
+	}
+	defer rows.Close()
+
+	accounts := []wpmodels.LockedAccount{}
+	for rows.Next() {
+		var account wpmodels.LockedAccount
+		if err := rows.Scan(
+			&account.AccountID,
+			&account.Email,
+			&account.AccountType,
+			&account.FailedCount,
+			&account.LastFailedAt,
+			&account.LockedUntil,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning locked account: %v", err)
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating locked accounts: %v", err)
+	}
+
+	return accounts, nil
+}
+
+// GetAccountLockout returns the failed logins and lockout of an account, or
+// nil if the account has none
+func GetAccountLockout(accountID int, workspaceDB *sql.DB) (*wpmodels.LockedAccount, error) {
+	var account wpmodels.LockedAccount
+	query := `SELECT a.id, a.email, a.account_type, l.failed_count,
+	l.last_failed_at, COALESCE(l.locked_until, '')
+	FROM account_lockouts l
+	JOIN accounts a ON a.id = l.account_id
+	WHERE l.account_id = ?`
+	err := workspaceDB.QueryRow(query, accountID).Scan(
+		&account.AccountID,
+		&account.Email,
+		&account.AccountType,
+		&account.FailedCount,
+		&account.LastFailedAt,
+		&account.LockedUntil,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching account lockout: %v", err)
+	}
+	return &account, nil
+}
+
+// AccountBelongsToTenant checks if an account is a teacher or pupil of a
+// tenant, or a parent of a pupil of the tenant
+func AccountBelongsToTenant(accountID, tenantID int, workspaceDB *sql.DB) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM accounts
+	WHERE id = ? AND id IN (` + tenantAccountsQuery + `))`
+	err := workspaceDB.QueryRow(
+		query, accountID, tenantID, tenantID, tenantID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking account tenant: %v", err)
+	}
+	return exists, nil
+}
+
+// tenantAccountsQuery selects the account IDs of the teachers, pupils and
+// parents of a tenant. It takes the tenant ID three times.
+const tenantAccountsQuery = `SELECT t.account_id FROM teachers t
+	JOIN teacher_tenant tt ON tt.teacher_id = t.id WHERE tt.tenant_id = ?
+	UNION
+	SELECT pg.account_id FROM pupil_global pg
+	JOIN pupil_tenant pt ON pt.pupil_id = pg.id WHERE pt.tenant_id = ?
+	UNION
+	SELECT p.account_id FROM parents p
+	JOIN parent_pupil pp ON pp.parent_id = p.id
+	JOIN pupil_tenant pt ON pt.pupil_id = pp.pupil_id WHERE pt.tenant_id = ?`
+
+// insertLoginAttempt stores a login attempt for the audit and IP throttling
+func insertLoginAttempt(
+	email string, accountID int, ipAddress, loginType string, success bool,
+	workspaceDB *sql.DB,
+) error {
+	if len(email) > 100 {
+		email = email[:100]
+	}
+	query := `INSERT INTO login_attempts (email, account_id, ip_address,
+	login_type, success)
+	VALUES (NULLIF(?, ''), NULLIF(?, 0), ?, ?, ?)`
+	_, err := workspaceDB.Exec(
+		query, email, accountID, ipAddress, loginType, success,
+	)
+	if err != nil {
+		return fmt.Errorf("error recording login attempt: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package util
+
+import (
+	"database/sql"
+	interfaces "ednevnik-backend/models/interfaces"
+	tenantmodels "ednevnik-backend/models/tenant"
+	wpmodels "ednevnik-backend/models/workspace"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GetAbsenceThresholds returns the absence thresholds of the tenant ordered
+// from the lowest number of unexcused hours
+func GetAbsenceThresholds(
+	tenantDB interfaces.DatabaseQuerier,
+) ([]tenantmodels.AbsenceThreshold, error) {
+	query := `SELECT id, measure, unexcused_hours, COALESCE(behaviour_cap, '')
+	FROM absence_thresholds
+	ORDER BY unexcused_hours`
+
+	rows, err := tenantDB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying absence thresholds: %v", err)
+	}
+	defer rows.Close()
+
+	thresholds := []tenantmodels.AbsenceThreshold{}
+	for rows.Next() {
+		var threshold tenantmodels.AbsenceThreshold
+		err := rows.Scan(
+			&threshold.ID,
+			&threshold.Measure,
+			&threshold.UnexcusedHours,
+			&threshold.BehaviourCap,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning absence threshold: %v", err)
+		}
+		thresholds = append(thresholds, threshold)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating absence thresholds: %v", err)
+	}
+
+	return thresholds, nil
+}
+
+// SetAbsenceThresholds replaces all absence thresholds of the tenant. Measures
+// that were already issued are kept.
+func SetAbsenceThresholds(
+	thresholds []tenantmodels.AbsenceThreshold, tenantDB *sql.DB,
+) (err error) {
+	for _, threshold := range thresholds {
+		if threshold.UnexcusedHours <= 0 {
+			return fmt.Errorf("broj sati za mjeru %s mora biti veći od nule", threshold.Measure)
+		}
+	}
+
+	tx, err := tenantDB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting tenantDB transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec(`DELETE FROM absence_thresholds`); err != nil {
+		return fmt.Errorf("error deleting absence thresholds: %v", err)
+	}
+
+	insertQuery := `INSERT INTO absence_thresholds (measure, unexcused_hours,
+	behaviour_cap) VALUES (?, ?, NULLIF(?, ''))`
+	for _, threshold := range thresholds {
+		_, err = tx.Exec(
+			insertQuery,
+			threshold.Measure,
+			threshold.UnexcusedHours,
+			threshold.BehaviourCap,
+		)
+		if err != nil {
+			return fmt.Errorf("error inserting absence threshold %s: %v", threshold.Measure, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+// GetPedagogicalMeasuresForSection returns the measures issued to pupils of a
+// section
+func GetPedagogicalMeasuresForSection(
+	sectionID int, tenantDB *sql.DB,
+) ([]tenantmodels.PedagogicalMeasure, error) {
+	query := measureSelectQuery + ` WHERE pm.section_id = ?
+	ORDER BY p.last_name, p.name, pm.semester_code, pm.unexcused_hours`
+	return queryPedagogicalMeasures(tenantDB, query, sectionID)
+}
+
+// GetPedagogicalMeasuresForPupil returns the measures issued to a pupil in a
+// section
+func GetPedagogicalMeasuresForPupil(
+	pupilID, sectionID int, tenantDB *sql.DB,
+) ([]tenantmodels.PedagogicalMeasure, error) {
+	query := measureSelectQuery + ` WHERE pm.pupil_id = ? AND pm.section_id = ?
+	ORDER BY pm.semester_code, pm.unexcused_hours`
+	return queryPedagogicalMeasures(tenantDB, query, pupilID, sectionID)
+}
+
+// unlockedSemesters returns the semesters that are not locked on a day
+func unlockedSemesters(
+	semesters []wpmodels.TenantSemester,
+	changes map[string]tenantmodels.SemesterLockChange,
+	today string,
+) []wpmodels.TenantSemester {
+	unlocked := []wpmodels.TenantSemester{}
+	for _, semester := range semesters {
+		if !SemesterLockStatus(semester, changes, today).Locked {
+			unlocked = append(unlocked, semester)
+		}
+	}
+	return unlocked
+}
+
+// EvaluateAbsenceThresholds issues the pedagogical measures earned by pupils
+// of a section with their unexcused hours in each semester. When no pupils are
+// given every pupil of the section is evaluated, locked semesters are left as
+// they are. Issued measures are never withdrawn automatically. A new measure
+// lowers the behaviour grade of the semester to the cap of its threshold, if
+// the grade is better than the cap.
+// It runs in the transaction that changes the attendance, so the attendance
+// is only saved together with the measures it earned.
+func EvaluateAbsenceThresholds(
+	tx *sql.Tx,
+	sectionID int,
+	pupilIDs []int,
+	semesters []wpmodels.TenantSemester,
+) (err error) {
+	thresholds, err := GetAbsenceThresholds(tx)
+	if err != nil {
+		return err
+	}
+	if len(thresholds) == 0 {
+		return nil
+	}
+
+	changes, err := GetLatestSemesterLockChanges(tx)
+	if err != nil {
+		return err
+	}
+	semesters = unlockedSemesters(semesters, changes, time.Now().Format(semesterDateLayout))
+
+	measureQuery := `INSERT INTO pedagogical_measures (pupil_id, section_id,
+	semester_code, measure, unexcused_hours) VALUES (?, ?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE id = id`
+
+	// Behaviour grades are ordered from best to worst in the ENUM
+	behaviourQuery := `UPDATE pupil_behaviour SET behaviour = ?
+	WHERE pupil_id = ? AND section_id = ? AND semester_code = ?
+	AND behaviour + 0 < FIELD(?, 'primjerno', 'vrlodobro', 'dobro', 'zadovoljavajuće', 'loše')`
+
+	for _, semester := range semesters {
+		var hours map[int]int
+		hours, err = countUnexcusedHours(tx, sectionID, pupilIDs, semester)
+		if err != nil {
+			return err
+		}
+
+		for pupilID, unexcusedHours := range hours {
+			for _, threshold := range thresholds {
+				if unexcusedHours < threshold.UnexcusedHours {
+					break
+				}
+
+				var res sql.Result
+				res, err = tx.Exec(
+					measureQuery,
+					pupilID,
+					sectionID,
+					semester.SemesterCode,
+					threshold.Measure,
+					unexcusedHours,
+				)
+				if err != nil {
+					return fmt.Errorf("error issuing pedagogical measure: %v", err)
+				}
+
+				// Duplicates report 0 affected rows, so the behaviour grade is
+				// only capped when the measure is issued
+				affected, _ := res.RowsAffected()
+				if affected != 1 || threshold.BehaviourCap == "" {
+					continue
+				}
+
+				res, err = tx.Exec(
+					behaviourQuery,
+					threshold.BehaviourCap,
+					pupilID,
+					sectionID,
+					semester.SemesterCode,
+					threshold.BehaviourCap,
+				)
+				if err != nil {
+					return fmt.Errorf("error updating behaviour grade: %v", err)
+				}
+
+				// Capped grades are signed by the system
+				if affected, _ = res.RowsAffected(); affected == 0 {
+					continue
+				}
+				err = AppendBehaviourLedgerEntries(
+					tx, pupilID, sectionID, semester.SemesterCode,
+					LedgerActionUpdate, 0,
+				)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// countUnexcusedHours returns the number of unexcused hours in a semester for
+// the enrolled pupils of a section that have any
+func countUnexcusedHours(
+	tx *sql.Tx,
+	sectionID int,
+	pupilIDs []int,
+	semester wpmodels.TenantSemester,
+) (map[int]int, error) {
+	query := `SELECT pa.pupil_id, COUNT(*)
+	FROM pupil_attendance pa
+	JOIN class_lesson cl ON pa.lesson_id = cl.id
+	JOIN pupils_sections ps
+		ON ps.pupil_id = pa.pupil_id AND ps.section_id = cl.section_id
+	WHERE cl.section_id = ? AND pa.status = 'unexcused'
+	AND cl.date BETWEEN ? AND ?`
+	args := []interface{}{sectionID, semester.StartDate, semester.EndDate}
+
+	if len(pupilIDs) > 0 {
+		query += fmt.Sprintf(
+			" AND pa.pupil_id IN (%s)",
+			strings.TrimSuffix(strings.Repeat("?, ", len(pupilIDs)), ", "),
+		)
+		for _, pupilID := range pupilIDs {
+			args = append(args, pupilID)
+		}
+	}
+	query += " GROUP BY pa.pupil_id"
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error counting unexcused hours: %v", err)
+	}
+	defer rows.Close()
+
+	hours := map[int]int{}
+	for rows.Next() {
+		var pupilID, count int
+		if err := rows.Scan(&pupilID, &count); err != nil {
+			return nil, fmt.Errorf("error scanning unexcused hours: %v", err)
+		}
+		hours[pupilID] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating unexcused hours: %v", err)
+	}
+
+	return hours, nil
+}
+
+const measureSelectQuery = `SELECT pm.id, pm.pupil_id, p.name, p.last_name,
+	pm.section_id, pm.semester_code, pm.measure, pm.unexcused_hours, pm.issued_at
+	FROM pedagogical_measures pm
+	JOIN pupils p ON pm.pupil_id = p.id`
+
+// queryPedagogicalMeasures runs a query built on measureSelectQuery
+func queryPedagogicalMeasures(
+	tenantDB *sql.DB, query string, args ...interface{},
+) ([]tenantmodels.PedagogicalMeasure, error) {
+	rows, err := tenantDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying pedagogical measures: %v", err)
+	}
+	defer rows.Close()
+
+	measures := []tenantmodels.PedagogicalMeasure{}
+	for rows.Next() {
+		var measure tenantmodels.PedagogicalMeasure
+		err := rows.Scan(
+			&measure.ID,
+			&measure.PupilID,
+			&measure.Name,
+			&measure.LastName,
+			&measure.SectionID,
+			&measure.SemesterCode,
+			&measure.Measure,
+			&measure.UnexcusedHours,
+			&measure.IssuedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning pedagogical measure: %v", err)
+		}
+		measures = append(measures, measure)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pedagogical measures: %v", err)
+	}
+
+	return measures, nil
+}
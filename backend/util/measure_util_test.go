@@ -0,0 +1,45 @@
+package util
+
+import (
+	"testing"
+
+	tenantmodels "ednevnik-backend/models/tenant"
+	wpmodels "ednevnik-backend/models/workspace"
+)
+
+func TestAbsenceThresholdsSkipLockedSemesters(t *testing.T) {
+	semesters := []wpmodels.TenantSemester{
+		{SemesterCode: "I", EndDate: "2026-01-31", LockGraceDays: 14},
+		{SemesterCode: "II", EndDate: "2026-06-15", LockGraceDays: 14},
+	}
+	tests := []struct {
+		name    string
+		changes map[string]tenantmodels.SemesterLockChange
+		want    []string
+	}{
+		{"grace period of the first semester is over", nil, []string{"II"}},
+		{
+			"first semester unlocked by the admin",
+			map[string]tenantmodels.SemesterLockChange{"I": {Locked: false}},
+			[]string{"I", "II"},
+		},
+		{
+			"second semester locked by the admin",
+			map[string]tenantmodels.SemesterLockChange{"II": {Locked: true}},
+			[]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unlocked := unlockedSemesters(semesters, tt.changes, "2026-03-01")
+			if len(unlocked) != len(tt.want) {
+				t.Fatalf("unlocked %+v, want %v", unlocked, tt.want)
+			}
+			for i, semester := range unlocked {
+				if semester.SemesterCode != tt.want[i] {
+					t.Errorf("unlocked %+v, want %v", unlocked, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package util
+
+import (
+	"database/sql"
+	wpmodels "ednevnik-backend/models/workspace"
+	"ednevnik-backend/oidc"
+	"fmt"
+	"strings"
+)
+
+// oidcProviderColumns are the columns scanned by scanOIDCProvider
+const oidcProviderColumns = `id, domain, display_name, issuer_url, client_id,
+	COALESCE(client_secret, ''), scopes, enabled, created_at`
+
+// GetOIDCProviders returns all single sign-on providers without their secrets
+func GetOIDCProviders(workspaceDB *sql.DB) ([]wpmodels.OIDCProvider, error) {
+	query := `SELECT ` + oidcProviderColumns + ` FROM oidc_providers ORDER BY domain`
+	rows, err := workspaceDB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching OIDC providers: %v", err)
+	}
+	defer rows.Close()
+
+	providers := []wpmodels.OIDCProvider{}
+	for rows.Next() {
+		provider, err := scanOIDCProvider(rows)
+		if err != nil {
+			return nil, err
+		}
+		provider.ClientSecret = ""
+		providers = append(providers, *provider)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating OIDC providers: %v", err)
+	}
+	return providers, nil
+}
+
+// GetOIDCProviderByID returns a provider including its secret
+func GetOIDCProviderByID(providerID int, workspaceDB *sql.DB) (*wpmodels.OIDCProvider, error) {
+	query := `SELECT ` + oidcProviderColumns + ` FROM oidc_providers WHERE id = ?`
+	return scanOIDCProvider(workspaceDB.QueryRow(query, providerID))
+}
+
+// GetOIDCProviderForEmail returns the enabled provider of the email domain.
+// Subdomains use the provider of their parent domain unless they have their
+// own.
+func GetOIDCProviderForEmail(email string, workspaceDB *sql.DB) (*wpmodels.OIDCProvider, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return nil, sql.ErrNoRows
+	}
+	domain := email[at+1:]
+
+	query := `SELECT ` + oidcProviderColumns + ` FROM oidc_providers
+	WHERE enabled = TRUE AND (domain = ? OR ? LIKE CONCAT('%.', domain))
+	ORDER BY LENGTH(domain) DESC
+	LIMIT 1`
+	return scanOIDCProvider(workspaceDB.QueryRow(query, domain, domain))
+}
+
+// EmailMatchesOIDCDomain reports whether an email belongs to the domain of a
+// provider or one of its subdomains
+func EmailMatchesOIDCDomain(email, domain string) bool {
+	email = strings.ToLower(email)
+	domain = strings.ToLower(domain)
+	return strings.HasSuffix(email, "@"+domain) || strings.HasSuffix(email, "."+domain)
+}
+
+// CreateOIDCProvider stores a new provider and returns its ID
+func CreateOIDCProvider(provider wpmodels.OIDCProvider, workspaceDB *sql.DB) (int, error) {
+	if err := ValidateOIDCProvider(&provider); err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO oidc_providers (domain, display_name, issuer_url,
+	client_id, client_secret, scopes, enabled)
+	VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, ?)`
+	result, err := workspaceDB.Exec(
+		query, provider.Domain, provider.DisplayName, provider.IssuerURL,
+		provider.ClientID, provider.ClientSecret, provider.Scopes, provider.Enabled,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error creating OIDC provider: %v", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error creating OIDC provider: %v", err)
+	}
+	return int(id), nil
+}
+
+// UpdateOIDCProvider updates a provider. An empty client secret keeps the
+// stored one.
+func UpdateOIDCProvider(
+	providerID int, provider wpmodels.OIDCProvider, workspaceDB *sql.DB,
+) error {
+	if err := ValidateOIDCProvider(&provider); err != nil {
+		return err
+	}
+
+	query := `UPDATE oidc_providers SET domain = ?, display_name = ?,
+	issuer_url = ?, client_id = ?,
+	client_secret = COALESCE(NULLIF(?, ''), client_secret),
+	scopes = ?, enabled = ?
+	WHERE id = ?`
+	result, err := workspaceDB.Exec(
+		query, provider.Domain, provider.DisplayName, provider.IssuerURL,
+		provider.ClientID, provider.ClientSecret, provider.Scopes,
+		provider.Enabled, providerID,
+	)
+	if err != nil {
+		return fmt.Errorf("error updating OIDC provider: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		if _, err := GetOIDCProviderByID(providerID, workspaceDB); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteOIDCProvider removes a provider together with its linked identities
+func DeleteOIDCProvider(providerID int, workspaceDB *sql.DB) error {
+	query := `DELETE FROM oidc_providers WHERE id = ?`
+	if _, err := workspaceDB.Exec(query, providerID); err != nil {
+		return fmt.Errorf("error deleting OIDC provider: %v", err)
+	}
+	return nil
+}
+
+// OIDCConfig returns the relying party configuration of a provider
+func OIDCConfig(provider *wpmodels.OIDCProvider, redirectURL string) oidc.Config {
+	return oidc.Config{
+		Issuer:       provider.IssuerURL,
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       strings.Fields(provider.Scopes),
+	}
+}
+
+// CreateOIDCLoginState stores a pending single sign-on login, only the hash of
+// the state is stored
+func CreateOIDCLoginState(
+	state string, providerID int, codeVerifier, nonce string, workspaceDB *sql.DB,
+) error {
+	query := `INSERT INTO oidc_login_states (state_hash, provider_id,
+	code_verifier, nonce)
+	VALUES (?, ?, ?, ?)`
+	_, err := workspaceDB.Exec(query, HashToken(state), providerID, codeVerifier, nonce)
+	if err != nil {
+		return fmt.Errorf("error storing login state: %v", err)
+	}
+	return nil
+}
+
+// ConsumeOIDCLoginState returns and removes a pending login, so every state
+// can be used once. Unknown and expired states return sql.ErrNoRows.
+func ConsumeOIDCLoginState(
+	state string, workspaceDB *sql.DB,
+) (providerID int, codeVerifier, nonce string, err error) {
+	tx, err := workspaceDB.Begin()
+	if err != nil {
+		return 0, "", "", fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	stateHash := HashToken(state)
+	query := `SELECT provider_id, code_verifier, nonce FROM oidc_login_states
+	WHERE state_hash = ? AND expires_at > NOW()
+	FOR UPDATE`
+	err = tx.QueryRow(query, stateHash).Scan(&providerID, &codeVerifier, &nonce)
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	_, err = tx.Exec(`DELETE FROM oidc_login_states WHERE state_hash = ?`, stateHash)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("error removing login state: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, "", "", fmt.Errorf("error committing transaction: %v", err)
+	}
+	return providerID, codeVerifier, nonce, nil
+}
+
+// GetAccountIDForIdentity returns the account linked to a provider subject
+func GetAccountIDForIdentity(providerID int, subject string, workspaceDB *sql.DB) (int, error) {
+	var accountID int
+	query := `SELECT account_id FROM account_identities
+	WHERE provider_id = ? AND subject = ?`
+	err := workspaceDB.QueryRow(query, providerID, subject).Scan(&accountID)
+	return accountID, err
+}
+
+// LinkAccountIdentity links a provider subject to an account, or updates the
+// last login of an existing link
+func LinkAccountIdentity(
+	accountID, providerID int, subject, email string, workspaceDB *sql.DB,
+) error {
+	query := `INSERT INTO account_identities (account_id, provider_id, subject, email)
+	VALUES (?, ?, ?, ?)
+	ON DUPLICATE KEY UPDATE email = VALUES(email), last_login_at = NOW()`
+	if _, err := workspaceDB.Exec(query, accountID, providerID, subject, email); err != nil {
+		return fmt.Errorf("error linking identity: %v", err)
+	}
+	return nil
+}
+
+// ValidateOIDCProvider checks the required fields and normalizes the domain,
+// issuer and scopes
+func ValidateOIDCProvider(provider *wpmodels.OIDCProvider) error {
+	provider.Domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(provider.Domain), "@"))
+	provider.IssuerURL = strings.TrimSuffix(strings.TrimSpace(provider.IssuerURL), "/")
+	if provider.Domain == "" || provider.DisplayName == "" ||
+		provider.IssuerURL == "" || provider.ClientID == "" {
+		return fmt.Errorf("domain, display_name, issuer_url and client_id are required")
+	}
+	if !strings.HasPrefix(provider.IssuerURL, "https://") &&
+		!strings.HasPrefix(provider.IssuerURL, "http://localhost") &&
+		!strings.HasPrefix(provider.IssuerURL, "http://127.0.0.1") {
+		return fmt.Errorf("issuer_url must use https")
+	}
+	if provider.Scopes == "" {
+		provider.Scopes = "openid email profile"
+	}
+	if !strings.Contains(" "+provider.Scopes+" ", " openid ") {
+		provider.Scopes = "openid " + provider.Scopes
+	}
+	return nil
+}
+
+// scanOIDCProvider scans a row selected with oidcProviderColumns
+func scanOIDCProvider(row rowScanner) (*wpmodels.OIDCProvider, error) {
+	var provider wpmodels.OIDCProvider
+	err := row.Scan(
+		&provider.ID,
+		&provider.Domain,
+		&provider.DisplayName,
+		&provider.IssuerURL,
+		&provider.ClientID,
+		&provider.ClientSecret,
+		&provider.Scopes,
+		&provider.Enabled,
+		&provider.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &provider, nil
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
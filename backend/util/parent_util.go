@@ -0,0 +1,302 @@
+package util
+
+import (
+	"database/sql"
+	wpmodels "ednevnik-backend/models/workspace"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// RegisterParent creates a pending parent account and sends the verification
+// email. Unlike staff and pupils, parents may use any email domain.
+func RegisterParent(parent wpmodels.Parent, workspaceDB *sql.DB) error {
+	var err error
+	if err = ValidateIdentifier(parent.Email); err != nil {
+		return fmt.Errorf("ovaj email nije validan")
+	}
+
+	exists, err := AccountWithEmailExists(parent.Email, workspaceDB)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("korisnik sa ovim emailom već postoji")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(parent.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	parent.Password = string(hash)
+
+	tx, err := workspaceDB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	accountQuery := `INSERT INTO pending_accounts (email, password, account_type)
+	VALUES (?, ?, 'parent')`
+	res, err := tx.Exec(accountQuery, parent.Email, parent.Password)
+	if err != nil {
+		if IsDuplicateEmailError(err) {
+			return fmt.Errorf(
+				"neverifikovani korisnik sa ovim emailom već postoji - provjerite email za aktivaciju",
+			)
+		}
+		return err
+	}
+
+	accountID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	parentQuery := `INSERT INTO pending_parents (name, last_name, phone, account_id)
+	VALUES (?, ?, NULLIF(?, ''), ?)`
+	_, err = tx.Exec(parentQuery, parent.Name, parent.LastName, parent.Phone, accountID)
+	if err != nil {
+		if IsDuplicatePhoneError(err) {
+			return fmt.Errorf(
+				"neverifikovani korisnik sa ovim brojem telefona već postoji - provjerite email za aktivaciju",
+			)
+		}
+		return err
+	}
+
+	err = EnqueueVerificationEmail(
+		tx,
+		accountID,
+		parent.Email,
+		fmt.Sprintf("%s %s", parent.Name, parent.LastName),
+	)
+	if err != nil {
+		return fmt.Errorf("error queueing verification email: %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+// GetParentByEmail returns the parent with the given email or nil if there
+// is no such parent
+func GetParentByEmail(email string, workspaceDB *sql.DB) (*wpmodels.Parent, error) {
+	var parent wpmodels.Parent
+	var phone sql.NullString
+	query := `SELECT p.id, p.name, p.last_name, a.email, a.password, p.phone
+	FROM parents p
+	JOIN accounts a ON p.account_id = a.id
+	WHERE a.email = ? AND a.account_type = 'parent'`
+
+	err := workspaceDB.QueryRow(query, email).Scan(
+		&parent.ID,
+		&parent.Name,
+		&parent.LastName,
+		&parent.Email,
+		&parent.Password,
+		&phone,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	parent.Phone = phone.String
+
+	return &parent, nil
+}
+
+// GetChildrenForParent returns all pupils linked to a parent
+func GetChildrenForParent(
+	parentID int, workspaceDB *sql.DB,
+) ([]wpmodels.ParentChild, error) {
+	query := `SELECT pg.id, pg.name, pg.last_name, pp.linked_at
+	FROM parent_pupil pp
+	JOIN pupil_global pg ON pg.id = pp.pupil_id
+	WHERE pp.parent_id = ?
+	ORDER BY pg.last_name, pg.name`
+
+	rows, err := workspaceDB.Query(query, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching children: %v", err)
+	}
+	defer rows.Close()
+
+	children := []wpmodels.ParentChild{}
+	for rows.Next() {
+		var child wpmodels.ParentChild
+		err := rows.Scan(&child.PupilID, &child.Name, &child.LastName, &child.LinkedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning child: %v", err)
+		}
+		children = append(children, child)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	return children, nil
+}
+
+// LinkPupilToParent links the pupil owning the access code to a parent
+func LinkPupilToParent(
+	parentID int, parentAccessCode string, workspaceDB *sql.DB,
+) (*wpmodels.ParentChild, error) {
+	var child wpmodels.ParentChild
+	query := `SELECT id, name, last_name FROM pupil_global
+	WHERE parent_access_code = ?`
+	err := workspaceDB.QueryRow(query, parentAccessCode).Scan(
+		&child.PupilID, &child.Name, &child.LastName,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("pristupni kod nije validan")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pupil: %v", err)
+	}
+
+	insertQuery := `INSERT IGNORE INTO parent_pupil (parent_id, pupil_id)
+	VALUES (?, ?)`
+	if _, err := workspaceDB.Exec(insertQuery, parentID, child.PupilID); err != nil {
+		return nil, fmt.Errorf("error linking pupil: %v", err)
+	}
+
+	return &child, nil
+}
+
+// UnlinkPupilFromParent removes the link between a parent and a pupil
+func UnlinkPupilFromParent(parentID, pupilID int, workspaceDB *sql.DB) error {
+	query := `DELETE FROM parent_pupil WHERE parent_id = ? AND pupil_id = ?`
+	if _, err := workspaceDB.Exec(query, parentID, pupilID); err != nil {
+		return fmt.Errorf("error unlinking pupil: %v", err)
+	}
+	return nil
+}
+
+// IsParentOfPupil checks whether a pupil is linked to a parent
+func IsParentOfPupil(parentID, pupilID int, workspaceDB *sql.DB) (bool, error) {
+	var linked bool
+	query := `SELECT EXISTS(SELECT 1 FROM parent_pupil
+	WHERE parent_id = ? AND pupil_id = ?)`
+	err := workspaceDB.QueryRow(query, parentID, pupilID).Scan(&linked)
+	if err != nil {
+		return false, fmt.Errorf("error checking parent link: %v", err)
+	}
+	return linked, nil
+}
+
+// GetParentAccess returns whether a pupil of the tenant has a parent access
+// code and which parents are linked to the pupil
+func GetParentAccess(
+	pupilID int, tenantID string, workspaceDB *sql.DB,
+) (*wpmodels.ParentAccess, error) {
+	access := wpmodels.ParentAccess{PupilID: pupilID, ParentIDs: []int{}}
+	query := `SELECT pg.parent_access_code IS NOT NULL FROM pupil_global pg
+	JOIN pupil_tenant pt ON pt.pupil_id = pg.id
+	WHERE pg.id = ? AND pt.tenant_id = ?`
+	err := workspaceDB.QueryRow(query, pupilID, tenantID).Scan(&access.HasParentAccessCode)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := workspaceDB.Query(
+		`SELECT parent_id FROM parent_pupil WHERE pupil_id = ?`, pupilID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching parents: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var parentID int
+		if err := rows.Scan(&parentID); err != nil {
+			return nil, fmt.Errorf("error scanning parent: %v", err)
+		}
+		access.ParentIDs = append(access.ParentIDs, parentID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	return &access, nil
+}
+
+// RegenerateParentAccessCode replaces the parent access code of a pupil that
+// belongs to the tenant. Parents that are already linked stay linked.
+func RegenerateParentAccessCode(
+	pupilID int, tenantID string, workspaceDB *sql.DB,
+) (string, error) {
+	query := `UPDATE pupil_global pg
+	JOIN pupil_tenant pt ON pt.pupil_id = pg.id
+	SET pg.parent_access_code = UUID()
+	WHERE pg.id = ? AND pt.tenant_id = ?`
+	res, err := workspaceDB.Exec(query, pupilID, tenantID)
+	if err != nil {
+		return "", fmt.Errorf("error regenerating access code: %v", err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return "", sql.ErrNoRows
+	}
+
+	var code string
+	selectQuery := `SELECT parent_access_code FROM pupil_global WHERE id = ?`
+	if err := workspaceDB.QueryRow(selectQuery, pupilID).Scan(&code); err != nil {
+		return "", fmt.Errorf("error fetching access code: %v", err)
+	}
+	return code, nil
+}
+
+// RevokeParentAccessCode removes the parent access code of a pupil that
+// belongs to the tenant and unlinks all parents, since the links may have
+// been made with a leaked code.
+func RevokeParentAccessCode(
+	pupilID int, tenantID string, workspaceDB *sql.DB,
+) error {
+	tx, err := workspaceDB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE pupil_global pg
+	JOIN pupil_tenant pt ON pt.pupil_id = pg.id
+	SET pg.parent_access_code = NULL
+	WHERE pg.id = ? AND pt.tenant_id = ?`
+	if _, err := tx.Exec(query, pupilID, tenantID); err != nil {
+		return fmt.Errorf("error revoking access code: %v", err)
+	}
+
+	// RowsAffected is 0 for a code that was already revoked, so membership is
+	// checked separately
+	var inTenant bool
+	checkQuery := `SELECT EXISTS(SELECT 1 FROM pupil_tenant
+	WHERE pupil_id = ? AND tenant_id = ?)`
+	if err := tx.QueryRow(checkQuery, pupilID, tenantID).Scan(&inTenant); err != nil {
+		return fmt.Errorf("error checking pupil tenant: %v", err)
+	}
+	if !inTenant {
+		return sql.ErrNoRows
+	}
+
+	deleteQuery := `DELETE FROM parent_pupil WHERE pupil_id = ?`
+	if _, err := tx.Exec(deleteQuery, pupilID); err != nil {
+		return fmt.Errorf("error unlinking parents: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+	return nil
+}
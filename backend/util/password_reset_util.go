@@ -0,0 +1,165 @@
+package util
+
+import (
+	"database/sql"
+	"ednevnik-backend/jobs"
+	"ednevnik-backend/notification"
+	"fmt"
+	"log"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// PasswordResetLimit is the number of reset emails an account can request
+	// in an hour
+	PasswordResetLimit = 3
+)
+
+// RequestPasswordReset creates a single-use password reset for the account
+// with the email and queues the email with the reset link. Unknown emails and requests
+// over the limit are ignored without an error, so the response does not
+// reveal which emails have an account.
+func RequestPasswordReset(email, requestIP string, workspaceDB *sql.DB) (err error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return fmt.Errorf("email je obavezan")
+	}
+
+	var accountID int
+	accountQuery := `SELECT id FROM accounts WHERE email = ?`
+	err = workspaceDB.QueryRow(accountQuery, email).Scan(&accountID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error fetching account: %v", err)
+	}
+
+	// Accounts without a teacher, pupil or parent profile are greeted by email
+	name := email
+	if user, err := GetUserByAccountID(accountID, workspaceDB); err == nil {
+		name = fmt.Sprintf("%s %s", user.GetName(), user.GetLastName())
+	}
+
+	var recentResets int
+	countQuery := `SELECT COUNT(*) FROM password_resets
+	WHERE account_id = ? AND created_at > NOW() - INTERVAL 1 HOUR`
+	if err := workspaceDB.QueryRow(countQuery, accountID).Scan(&recentResets); err != nil {
+		return fmt.Errorf("error counting password resets: %v", err)
+	}
+	if recentResets >= PasswordResetLimit {
+		log.Printf("password reset limit reached for account %d", accountID)
+		return nil
+	}
+
+	tx, err := workspaceDB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// The token is generated by the job, so it never ends up in the outbox
+	insertQuery := `INSERT INTO password_resets (account_id, request_ip)
+	VALUES (?, ?)`
+	result, err := tx.Exec(insertQuery, accountID, requestIP)
+	if err != nil {
+		return fmt.Errorf("error creating password reset: %v", err)
+	}
+	resetID, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error creating password reset: %v", err)
+	}
+
+	_, err = jobs.EnqueueTx(tx, notification.JobPasswordResetEmail,
+		notification.PasswordResetEmailJob{
+			PasswordResetID: resetID,
+			Email:           email,
+			Name:            name,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token. The token and every
+// other open token of the account are used up and the account is unlocked. It returns the account ID so
+// the caller can revoke the sessions of the account.
+func ResetPassword(
+	token, newPassword, confirmPassword string, workspaceDB *sql.DB,
+) (accountID int, err error) {
+	if newPassword == "" {
+		return 0, fmt.Errorf("nova lozinka je obavezna")
+	}
+	if newPassword != confirmPassword {
+		return 0, fmt.Errorf("nove lozinke se ne podudaraju. Molimo pokušajte ponovo")
+	}
+
+	tx, err := workspaceDB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	query := `SELECT account_id FROM password_resets
+	WHERE token_hash = ? AND used_at IS NULL AND expires_at > NOW()
+	FOR UPDATE`
+	err = tx.QueryRow(query, HashToken(token)).Scan(&accountID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("link za promjenu lozinke nije validan ili je istekao")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error fetching password reset: %v", err)
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword(
+		[]byte(newPassword), bcrypt.DefaultCost,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE accounts SET password = ? WHERE id = ?`,
+		string(hashedPassword), accountID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error updating password: %v", err)
+	}
+
+	_, err = tx.Exec(
+		`UPDATE password_resets SET used_at = NOW()
+		WHERE account_id = ? AND used_at IS NULL`,
+		accountID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error using password reset: %v", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM account_lockouts WHERE account_id = ?`, accountID)
+	if err != nil {
+		return 0, fmt.Errorf("error unlocking account: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return accountID, nil
+}
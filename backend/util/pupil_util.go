@@ -6,7 +6,6 @@ import (
 	tenantmodels "ednevnik-backend/models/tenant"
 	wpmodels "ednevnik-backend/models/workspace"
 	"fmt"
-	"os"
 	"strings"
 
 	"golang.org/x/crypto/bcrypt"
@@ -393,24 +392,21 @@ func RegisterPupil(
 		return err
 	}
 
-	err = tx.Commit()
+	err = EnqueueVerificationEmail(
+		tx,
+		accountID,
+		pupil.Email,
+		fmt.Sprintf("%s %s", pupil.Name, pupil.LastName),
+	)
 	if err != nil {
-		return fmt.Errorf("error committing transaction: %v", err)
+		return fmt.Errorf("error queueing verification email: %v", err)
 	}
 
-	token, err := GetPendingAccountVerificationToken(int(accountID), workspaceDB)
+	err = tx.Commit()
 	if err != nil {
-		return fmt.Errorf("error getting verification token: %v", err)
+		return fmt.Errorf("error committing transaction: %v", err)
 	}
 
-	go func() {
-		_ = SendVerificationEmail(
-			pupil.Email,
-			fmt.Sprintf("%s %s", pupil.Name, pupil.LastName),
-			fmt.Sprintf("%s/verify?token=%s", os.Getenv("FRONTEND_URL"), token),
-		)
-	}()
-
 	return nil
 }
 
@@ -498,7 +494,8 @@ func GetGlobalPupilByID(
 
 	query := `SELECT p.id, p.name, p.last_name, p.gender, p.address,
 	p.guardian_name, p.phone_number, p.guardian_number, p.date_of_birth,
-	p.religion, a.password, a.email, p.place_of_birth, p.parent_access_code
+	p.religion, a.password, a.email, p.place_of_birth,
+	COALESCE(p.parent_access_code, '')
 	FROM pupil_global p
 	JOIN accounts a ON p.account_id = a.id
 	WHERE p.id = ?`
@@ -587,6 +584,19 @@ func GetPupilTablePrivileges() []struct {
 		{"class_lesson", "SELECT"},
 		{"pupil_attendance", "SELECT"},
 		{"pupil_behaviour", "SELECT"},
+		{"attendance_excuses", "SELECT, INSERT"},
+		{"attendance_excuse_lessons", "SELECT"},
+		{"absence_thresholds", "SELECT"},
+		{"pedagogical_measures", "SELECT"},
+		{"grading_schemes", "SELECT"},
+		{"semester_lock_changes", "SELECT"},
+		{"descriptive_assessment_classes", "SELECT"},
+		{"descriptive_assessments", "SELECT"},
+		{"exam_limits", "SELECT"},
+		{"planned_exams", "SELECT"},
+		{"remedial_exams", "SELECT"},
+		{"remedial_exam_commission", "SELECT"},
+		{"remedial_exam_results", "SELECT"},
 	}
 }
 
@@ -785,9 +795,10 @@ func GetTenantsForPupil(
 }
 
 // UpdatePupilBehaviourGradeHelper updates a pupil behaviour grade for a pupil in a
-// specific section
+// specific section, signed by the teacher in the gradebook ledger
 func UpdatePupilBehaviourGradeHelper(
 	signature string,
+	teacherID int,
 	behaviourGrade tenantmodels.BehaviourGrade,
 	tenantDB *sql.DB,
 ) (updatedBehaviourGrade *tenantmodels.BehaviourGrade, err error) {
@@ -818,6 +829,14 @@ func UpdatePupilBehaviourGradeHelper(
 		return nil, err
 	}
 
+	err = AppendBehaviourLedgerEntries(
+		tx, behaviourGrade.PupilID, behaviourGrade.SectionID,
+		behaviourGrade.SemesterCode, LedgerActionUpdate, teacherID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return nil, err
@@ -0,0 +1,725 @@
+package util
+
+import (
+	"database/sql"
+	"ednevnik-backend/models/interfaces"
+	tenantmodels "ednevnik-backend/models/tenant"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Types of remedial exams. Pupils failing a subject take a remedial exam,
+// pupils without a final grade in a subject take a class exam.
+const (
+	RemedialExamTypeRemedial = "remedial"
+	RemedialExamTypeClass    = "class"
+)
+
+// remedialCommissionRoles are the roles a teacher can have on a commission
+var remedialCommissionRoles = map[string]bool{
+	"chair":    true,
+	"examiner": true,
+	"member":   true,
+}
+
+// remedialCommissionMinSize is the number of teachers a commission needs
+const remedialCommissionMinSize = 3
+
+const remedialExamSelect = `SELECT re.id, re.section_id, re.subject_code,
+	s.subject_name, re.semester_code, re.type, re.exam_date,
+	re.created_by_account_id, re.created_at
+	FROM remedial_exams re
+	JOIN ednevnik_workspace.subjects s ON s.subject_code = re.subject_code`
+
+// GetRemedialCandidates returns the active pupils of a section that have to
+// take a remedial or class exam in the last semester. Pupils already booked
+// for an exam that has no result yet carry the ID of that exam.
+func GetRemedialCandidates(
+	sectionID int,
+	semesterCode string,
+	tenantDB interfaces.DatabaseQuerier,
+	workspaceDB interfaces.DatabaseQuerier,
+) ([]tenantmodels.RemedialCandidate, error) {
+	section, err := GetSectionByID(int64(sectionID), tenantDB)
+	if err != nil {
+		return nil, err
+	}
+	if section.ID == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	descriptive, err := IsDescriptiveSection(sectionID, tenantDB)
+	if err != nil {
+		return nil, err
+	}
+	if descriptive {
+		return nil, ErrDescriptiveSection
+	}
+
+	subjects, err := GetAllSubjectsForCurriculumCode(
+		section.CurriculumCode, workspaceDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	pupils, err := GetPupilsForSection(strconv.Itoa(sectionID), false, tenantDB)
+	if err != nil {
+		return nil, err
+	}
+
+	grades, err := GetAllFinalGradesForSection(sectionID, tenantDB)
+	if err != nil {
+		return nil, err
+	}
+	finalGrades := make(map[int]map[string]int)
+	for _, grade := range grades {
+		if grade.SemesterCode != semesterCode {
+			continue
+		}
+		if finalGrades[grade.PupilID] == nil {
+			finalGrades[grade.PupilID] = make(map[string]int)
+		}
+		finalGrades[grade.PupilID][grade.SubjectCode] = grade.Grade
+	}
+
+	pendingExams, err := getPendingRemedialExams(sectionID, tenantDB)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []tenantmodels.RemedialCandidate{}
+	for _, pupil := range pupils {
+		for _, subject := range subjects {
+			candidate := tenantmodels.RemedialCandidate{
+				PupilID:     pupil.ID,
+				Name:        pupil.Name,
+				LastName:    pupil.LastName,
+				SubjectCode: subject.SubjectCode,
+				SubjectName: subject.SubjectName,
+			}
+
+			finalGrade, graded := finalGrades[pupil.ID][subject.SubjectCode]
+			switch {
+			case !graded:
+				candidate.ExamType = RemedialExamTypeClass
+			case finalGrade < 2:
+				candidate.ExamType = RemedialExamTypeRemedial
+				candidate.FinalGrade = finalGrade
+			default:
+				continue
+			}
+
+			candidate.PendingExamID = pendingExams[pupil.ID][subject.SubjectCode]
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	return candidates, nil
+}
+
+// validateRemedialCommission checks that a commission has enough distinct
+// teachers with exactly one chair and one examiner
+func validateRemedialCommission(
+	commission []tenantmodels.RemedialCommissionMember,
+) error {
+	teachers := make(map[int]bool, len(commission))
+	roles := make(map[string]int)
+	for _, member := range commission {
+		if member.TeacherID <= 0 {
+			return fmt.Errorf("nastavnik u komisiji nije ispravan")
+		}
+		if teachers[member.TeacherID] {
+			return fmt.Errorf("nastavnik %d je više puta naveden u komisiji", member.TeacherID)
+		}
+		teachers[member.TeacherID] = true
+
+		if !remedialCommissionRoles[member.Role] {
+			return fmt.Errorf("nepoznata uloga u komisiji %s", member.Role)
+		}
+		roles[member.Role]++
+	}
+
+	if len(teachers) < remedialCommissionMinSize {
+		return fmt.Errorf(
+			"komisija mora imati najmanje %d nastavnika", remedialCommissionMinSize,
+		)
+	}
+	if roles["chair"] != 1 {
+		return fmt.Errorf("komisija mora imati tačno jednog predsjednika")
+	}
+	if roles["examiner"] != 1 {
+		return fmt.Errorf("komisija mora imati tačno jednog ispitivača")
+	}
+
+	return nil
+}
+
+// CreateRemedialExam schedules a remedial or class exam for pupils of a
+// section in a subject. Every pupil must be a candidate for that type of exam
+// and must not be booked for another exam in the subject without a result.
+func CreateRemedialExam(
+	request tenantmodels.RemedialExamRequest,
+	semesterCode string,
+	tenantID int64,
+	accountID int,
+	tenantDB *sql.DB,
+	workspaceDB *sql.DB,
+) (createdExam *tenantmodels.RemedialExam, err error) {
+	if request.Type == "" {
+		request.Type = RemedialExamTypeRemedial
+	}
+	if request.Type != RemedialExamTypeRemedial && request.Type != RemedialExamTypeClass {
+		return nil, fmt.Errorf("nepoznata vrsta ispita %s", request.Type)
+	}
+	if request.SubjectCode == "" {
+		return nil, fmt.Errorf("predmet je obavezan")
+	}
+	request.ExamDate = normalizeDate(request.ExamDate)
+	if _, err := time.Parse(semesterDateLayout, request.ExamDate); err != nil {
+		return nil, fmt.Errorf("neispravan datum ispita %s", request.ExamDate)
+	}
+	if len(request.PupilIDs) == 0 {
+		return nil, fmt.Errorf("ispit mora imati barem jednog učenika")
+	}
+
+	if err := validateRemedialCommission(request.Commission); err != nil {
+		return nil, err
+	}
+	for _, member := range request.Commission {
+		var inTenant bool
+		err := workspaceDB.QueryRow(
+			`SELECT EXISTS(SELECT 1 FROM teacher_tenant
+			WHERE teacher_id = ? AND tenant_id = ?)`,
+			member.TeacherID, tenantID,
+		).Scan(&inTenant)
+		if err != nil {
+			return nil, fmt.Errorf("error checking commission teacher: %v", err)
+		}
+		if !inTenant {
+			return nil, fmt.Errorf("nastavnik %d ne radi u školi", member.TeacherID)
+		}
+	}
+
+	tx, err := tenantDB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting tenantDB transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	candidates, err := GetRemedialCandidates(
+		request.SectionID, semesterCode, tx, workspaceDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+	candidatesByPupil := make(map[int]tenantmodels.RemedialCandidate)
+	for _, candidate := range candidates {
+		if candidate.SubjectCode == request.SubjectCode {
+			candidatesByPupil[candidate.PupilID] = candidate
+		}
+	}
+
+	booked := make(map[int]bool, len(request.PupilIDs))
+	for _, pupilID := range request.PupilIDs {
+		candidate, ok := candidatesByPupil[pupilID]
+		if !ok || candidate.ExamType != request.Type {
+			return nil, fmt.Errorf(
+				"učenik %d ne polaže ovu vrstu ispita iz predmeta %s",
+				pupilID, request.SubjectCode,
+			)
+		}
+		if candidate.PendingExamID != 0 || booked[pupilID] {
+			return nil, fmt.Errorf(
+				"učenik %d je već prijavljen na ispit iz predmeta %s",
+				pupilID, request.SubjectCode,
+			)
+		}
+		booked[pupilID] = true
+	}
+
+	result, err := tx.Exec(
+		`INSERT INTO remedial_exams (section_id, subject_code, semester_code,
+		type, exam_date, created_by_account_id) VALUES (?, ?, ?, ?, ?, ?)`,
+		request.SectionID, request.SubjectCode, semesterCode, request.Type,
+		request.ExamDate, accountID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting remedial exam: %v", err)
+	}
+	examID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, member := range request.Commission {
+		_, err = tx.Exec(
+			`INSERT INTO remedial_exam_commission (remedial_exam_id, teacher_id,
+			role) VALUES (?, ?, ?)`,
+			examID, member.TeacherID, member.Role,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error inserting remedial exam commission: %v", err)
+		}
+	}
+
+	for _, pupilID := range request.PupilIDs {
+		_, err = tx.Exec(
+			`INSERT INTO remedial_exam_results (remedial_exam_id, pupil_id,
+			previous_grade) VALUES (?, ?, NULLIF(?, 0))`,
+			examID, pupilID, candidatesByPupil[pupilID].FinalGrade,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error inserting remedial exam pupil: %v", err)
+		}
+	}
+
+	createdExam, err = GetRemedialExamByID(int(examID), tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return createdExam, nil
+}
+
+// GetRemedialExams returns the remedial exams of the tenant with their
+// commission and pupils. A section ID or teacher ID of 0 does not filter.
+func GetRemedialExams(
+	sectionID, teacherID int, tenantDB interfaces.DatabaseQuerier,
+) ([]tenantmodels.RemedialExam, error) {
+	query := remedialExamSelect + `
+	WHERE (? = 0 OR re.section_id = ?)
+	AND (? = 0 OR EXISTS(
+		SELECT 1 FROM remedial_exam_commission rc
+		WHERE rc.remedial_exam_id = re.id AND rc.teacher_id = ?
+	))
+	ORDER BY re.exam_date DESC, s.subject_name`
+
+	exams, err := queryRemedialExams(
+		tenantDB, query, sectionID, sectionID, teacherID, teacherID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range exams {
+		if err := loadRemedialExamDetails(&exams[i], tenantDB); err != nil {
+			return nil, err
+		}
+	}
+
+	return exams, nil
+}
+
+// GetRemedialExamByID retrieves a remedial exam with its commission and
+// pupils
+func GetRemedialExamByID(
+	examID int, tenantDB interfaces.DatabaseQuerier,
+) (*tenantmodels.RemedialExam, error) {
+	exams, err := queryRemedialExams(
+		tenantDB, remedialExamSelect+` WHERE re.id = ?`, examID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(exams) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	exam := exams[0]
+	if err := loadRemedialExamDetails(&exam, tenantDB); err != nil {
+		return nil, err
+	}
+	return &exam, nil
+}
+
+// IsRemedialCommissionMember checks if a teacher is on the commission of a
+// remedial exam
+func IsRemedialCommissionMember(
+	examID, teacherID int, tenantDB interfaces.DatabaseQuerier,
+) (bool, error) {
+	query := `SELECT EXISTS(
+		SELECT 1 FROM remedial_exam_commission
+		WHERE remedial_exam_id = ? AND teacher_id = ?
+	)`
+
+	var member bool
+	if err := tenantDB.QueryRow(query, examID, teacherID).Scan(&member); err != nil {
+		return false, err
+	}
+	return member, nil
+}
+
+// DeleteRemedialExam cancels a remedial exam. Exams with recorded results
+// are part of the grade history and can not be cancelled.
+func DeleteRemedialExam(examID int, tenantDB *sql.DB) error {
+	var recorded int
+	err := tenantDB.QueryRow(
+		`SELECT COUNT(*) FROM remedial_exam_results
+		WHERE remedial_exam_id = ? AND recorded_at IS NOT NULL`,
+		examID,
+	).Scan(&recorded)
+	if err != nil {
+		return fmt.Errorf("error querying remedial exam results: %v", err)
+	}
+	if recorded > 0 {
+		return fmt.Errorf("ispit sa upisanim rezultatima ne može se otkazati")
+	}
+
+	_, err = tenantDB.Exec(`DELETE FROM remedial_exams WHERE id = ?`, examID)
+	if err != nil {
+		return fmt.Errorf("error deleting remedial exam: %v", err)
+	}
+	return nil
+}
+
+// RecordRemedialExamResults records the results of pupils on a remedial exam.
+// A passing remedial exam replaces the failing final grade and a class exam
+// gives the final grade the pupil did not have. The final grade keeps its
+// history through system versioning of student_grades. The semester is locked
+// by the time remedial exams take place, so the lock is not checked here.
+func RecordRemedialExamResults(
+	examID,
+	teacherID int,
+	entries []tenantmodels.RemedialResultEntry,
+	tenantDB *sql.DB,
+	workspaceDB *sql.DB,
+) (recordedExam *tenantmodels.RemedialExam, err error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("nema rezultata za upis")
+	}
+	for _, entry := range entries {
+		if entry.Attended && (entry.Grade < 1 || entry.Grade > 5) {
+			return nil, fmt.Errorf("ocjena mora biti između 1 i 5")
+		}
+	}
+
+	teacherForSignature, err := GetTeacherByID(
+		fmt.Sprintf("%d", teacherID), workspaceDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+	signature := teacherForSignature.Name + " " + teacherForSignature.LastName
+
+	tx, err := tenantDB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting tenantDB transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	exam, err := GetRemedialExamByID(examID, tx)
+	if err != nil {
+		return nil, err
+	}
+	if exam.ExamDate > time.Now().Format(semesterDateLayout) {
+		return nil, fmt.Errorf("rezultati se ne mogu upisati prije dana ispita")
+	}
+
+	for _, entry := range entries {
+		var resultID int
+		var recordedAt sql.NullString
+		err = tx.QueryRow(
+			`SELECT id, recorded_at FROM remedial_exam_results
+			WHERE remedial_exam_id = ? AND pupil_id = ? FOR UPDATE`,
+			examID, entry.PupilID,
+		).Scan(&resultID, &recordedAt)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("učenik %d nije prijavljen na ispit", entry.PupilID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error querying remedial exam result: %v", err)
+		}
+		if recordedAt.Valid {
+			return nil, fmt.Errorf("rezultat učenika %d je već upisan", entry.PupilID)
+		}
+
+		var finalGradeID, previousGrade int
+		err = tx.QueryRow(
+			`SELECT id, grade FROM student_grades
+			WHERE pupil_id = ? AND section_id = ? AND subject_code = ?
+			AND semester_code = ? AND type = 'final' FOR UPDATE`,
+			entry.PupilID, exam.SectionID, exam.SubjectCode, exam.SemesterCode,
+		).Scan(&finalGradeID, &previousGrade)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("error querying final grade: %v", err)
+		}
+		err = nil
+
+		switch {
+		case exam.Type == RemedialExamTypeClass && finalGradeID != 0:
+			return nil, fmt.Errorf(
+				"učenik %d već ima zaključnu ocjenu iz predmeta", entry.PupilID,
+			)
+		case exam.Type == RemedialExamTypeRemedial && finalGradeID == 0:
+			return nil, fmt.Errorf(
+				"učenik %d nema zaključnu ocjenu iz predmeta", entry.PupilID,
+			)
+		case entry.Attended && exam.Type == RemedialExamTypeClass:
+			var res sql.Result
+			res, err = tx.Exec(
+				`INSERT INTO student_grades (pupil_id, section_id, subject_code,
+				grade, grade_date, type, teacher_id, semester_code, signature)
+				VALUES (?, ?, ?, ?, ?, 'final', ?, ?, ?)`,
+				entry.PupilID, exam.SectionID, exam.SubjectCode, entry.Grade,
+				exam.ExamDate, teacherID, exam.SemesterCode, signature,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("error inserting final grade: %v", err)
+			}
+			var gradeID int64
+			gradeID, err = res.LastInsertId()
+			if err != nil {
+				return nil, err
+			}
+			finalGradeID = int(gradeID)
+			err = AppendLedgerEntry(
+				tx, LedgerEntityGrade, finalGradeID, LedgerActionCreate, teacherID,
+			)
+			if err != nil {
+				return nil, err
+			}
+		case entry.Attended && entry.Grade >= 2:
+			_, err = tx.Exec(
+				`UPDATE student_grades SET grade = ?, grade_date = ?,
+				teacher_id = ?, signature = ? WHERE id = ?`,
+				entry.Grade, exam.ExamDate, teacherID, signature, finalGradeID,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("error updating final grade: %v", err)
+			}
+			err = AppendLedgerEntry(
+				tx, LedgerEntityGrade, finalGradeID, LedgerActionUpdate, teacherID,
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		grade := entry.Grade
+		if !entry.Attended {
+			grade = 0
+		}
+		_, err = tx.Exec(
+			`UPDATE remedial_exam_results SET previous_grade = NULLIF(?, 0),
+			grade = NULLIF(?, 0), attended = ?, final_grade_id = NULLIF(?, 0),
+			teacher_id = ?, signature = ?, recorded_at = NOW()
+			WHERE id = ?`,
+			previousGrade, grade, entry.Attended, finalGradeID, teacherID,
+			signature, resultID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error updating remedial exam result: %v", err)
+		}
+	}
+
+	recordedExam, err = GetRemedialExamByID(examID, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return recordedExam, nil
+}
+
+// GetRemedialResultsForPupil returns the recorded remedial exams of a pupil in
+// a section, used on the certificate
+func GetRemedialResultsForPupil(
+	pupilID, sectionID int, tenantDB interfaces.DatabaseQuerier,
+) ([]tenantmodels.RemedialExamResult, error) {
+	query := `SELECT rr.id, rr.remedial_exam_id, rr.pupil_id,
+	COALESCE(rr.previous_grade, 0), COALESCE(rr.grade, 0), rr.attended,
+	COALESCE(rr.final_grade_id, 0), COALESCE(rr.signature, ''),
+	COALESCE(rr.recorded_at, ''), re.subject_code, s.subject_name, re.type,
+	re.exam_date
+	FROM remedial_exam_results rr
+	JOIN remedial_exams re ON re.id = rr.remedial_exam_id
+	JOIN ednevnik_workspace.subjects s ON s.subject_code = re.subject_code
+	WHERE rr.pupil_id = ? AND re.section_id = ? AND rr.recorded_at IS NOT NULL
+	ORDER BY re.exam_date, s.subject_name`
+
+	rows, err := tenantDB.Query(query, pupilID, sectionID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying remedial exam results: %v", err)
+	}
+	defer rows.Close()
+
+	results := []tenantmodels.RemedialExamResult{}
+	for rows.Next() {
+		var result tenantmodels.RemedialExamResult
+		var attended sql.NullBool
+		err := rows.Scan(
+			&result.ID, &result.RemedialExamID, &result.PupilID,
+			&result.PreviousGrade, &result.Grade, &attended,
+			&result.FinalGradeID, &result.Signature, &result.RecordedAt,
+			&result.SubjectCode, &result.SubjectName, &result.ExamType,
+			&result.ExamDate,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning remedial exam result: %v", err)
+		}
+		if attended.Valid {
+			result.Attended = &attended.Bool
+		}
+		result.ExamDate = normalizeDate(result.ExamDate)
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating remedial exam results: %v", err)
+	}
+
+	return results, nil
+}
+
+// getPendingRemedialExams maps the pupils of a section and their subjects to
+// the remedial exams they are booked for that have no result yet
+func getPendingRemedialExams(
+	sectionID int, tenantDB interfaces.DatabaseQuerier,
+) (map[int]map[string]int, error) {
+	query := `SELECT rr.pupil_id, re.subject_code, re.id
+	FROM remedial_exam_results rr
+	JOIN remedial_exams re ON re.id = rr.remedial_exam_id
+	WHERE re.section_id = ? AND rr.recorded_at IS NULL`
+
+	rows, err := tenantDB.Query(query, sectionID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying pending remedial exams: %v", err)
+	}
+	defer rows.Close()
+
+	pending := make(map[int]map[string]int)
+	for rows.Next() {
+		var pupilID, examID int
+		var subjectCode string
+		if err := rows.Scan(&pupilID, &subjectCode, &examID); err != nil {
+			return nil, fmt.Errorf("error scanning pending remedial exam: %v", err)
+		}
+		if pending[pupilID] == nil {
+			pending[pupilID] = make(map[string]int)
+		}
+		pending[pupilID][subjectCode] = examID
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pending remedial exams: %v", err)
+	}
+
+	return pending, nil
+}
+
+// loadRemedialExamDetails loads the commission and pupils of a remedial exam
+func loadRemedialExamDetails(
+	exam *tenantmodels.RemedialExam, tenantDB interfaces.DatabaseQuerier,
+) error {
+	commissionQuery := `SELECT rc.teacher_id, rc.role,
+	CONCAT(t.name, ' ', t.last_name)
+	FROM remedial_exam_commission rc
+	JOIN ednevnik_workspace.teachers t ON t.id = rc.teacher_id
+	WHERE rc.remedial_exam_id = ?
+	ORDER BY FIELD(rc.role, 'chair', 'examiner', 'member'), t.last_name`
+
+	rows, err := tenantDB.Query(commissionQuery, exam.ID)
+	if err != nil {
+		return fmt.Errorf("error querying remedial exam commission: %v", err)
+	}
+	defer rows.Close()
+
+	exam.Commission = []tenantmodels.RemedialCommissionMember{}
+	for rows.Next() {
+		var member tenantmodels.RemedialCommissionMember
+		if err := rows.Scan(&member.TeacherID, &member.Role, &member.Name); err != nil {
+			return fmt.Errorf("error scanning remedial exam commission: %v", err)
+		}
+		exam.Commission = append(exam.Commission, member)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating remedial exam commission: %v", err)
+	}
+
+	resultsQuery := `SELECT rr.id, rr.remedial_exam_id, rr.pupil_id, p.name,
+	p.last_name, COALESCE(rr.previous_grade, 0), COALESCE(rr.grade, 0),
+	rr.attended, COALESCE(rr.final_grade_id, 0), COALESCE(rr.signature, ''),
+	COALESCE(rr.recorded_at, '')
+	FROM remedial_exam_results rr
+	JOIN pupils p ON p.id = rr.pupil_id
+	WHERE rr.remedial_exam_id = ?
+	ORDER BY p.last_name, p.name`
+
+	resultRows, err := tenantDB.Query(resultsQuery, exam.ID)
+	if err != nil {
+		return fmt.Errorf("error querying remedial exam results: %v", err)
+	}
+	defer resultRows.Close()
+
+	exam.Results = []tenantmodels.RemedialExamResult{}
+	for resultRows.Next() {
+		var result tenantmodels.RemedialExamResult
+		var attended sql.NullBool
+		err := resultRows.Scan(
+			&result.ID, &result.RemedialExamID, &result.PupilID, &result.Name,
+			&result.LastName, &result.PreviousGrade, &result.Grade, &attended,
+			&result.FinalGradeID, &result.Signature, &result.RecordedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("error scanning remedial exam result: %v", err)
+		}
+		if attended.Valid {
+			result.Attended = &attended.Bool
+		}
+		exam.Results = append(exam.Results, result)
+	}
+
+	if err := resultRows.Err(); err != nil {
+		return fmt.Errorf("error iterating remedial exam results: %v", err)
+	}
+
+	return nil
+}
+
+func queryRemedialExams(
+	tenantDB interfaces.DatabaseQuerier, query string, args ...interface{},
+) ([]tenantmodels.RemedialExam, error) {
+	rows, err := tenantDB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying remedial exams: %v", err)
+	}
+	defer rows.Close()
+
+	exams := []tenantmodels.RemedialExam{}
+	for rows.Next() {
+		var exam tenantmodels.RemedialExam
+		err := rows.Scan(
+			&exam.ID, &exam.SectionID, &exam.SubjectCode, &exam.SubjectName,
+			&exam.SemesterCode, &exam.Type, &exam.ExamDate,
+			&exam.CreatedByAccountID, &exam.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning remedial exam: %v", err)
+		}
+		exam.ExamDate = normalizeDate(exam.ExamDate)
+		exams = append(exams, exam)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating remedial exams: %v", err)
+	}
+
+	return exams, nil
+}
@@ -0,0 +1,416 @@
+package util
+
+import (
+	"database/sql"
+	"ednevnik-backend/models/interfaces"
+	tenantmodels "ednevnik-backend/models/tenant"
+	wpmodels "ednevnik-backend/models/workspace"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// behaviourGrades are the behaviour grades from best to worst
+var behaviourGrades = []string{
+	"primjerno", "vrlodobro", "dobro", "zadovoljavajuće", "loše",
+}
+
+// GetSectionReport builds the success report of a section in a semester. The
+// grades, absences and behaviour of the whole section are each read with one
+// query.
+func GetSectionReport(
+	workspaceDB *sql.DB,
+	tenantDB interfaces.DatabaseQuerier,
+	tenantID string,
+	sectionID int,
+	semesterCode string,
+) (*tenantmodels.SectionReport, error) {
+	data, err := loadReportData(
+		workspaceDB, tenantDB, tenantID, sectionID, semesterCode,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	absences, err := getSectionAbsenceTotals(sectionID, data.semester, tenantDB)
+	if err != nil {
+		return nil, err
+	}
+	behaviours, err := getSectionBehaviours(sectionID, semesterCode, tenantDB)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &tenantmodels.SectionReport{
+		SectionID:    data.section.ID,
+		SemesterCode: semesterCode,
+		PupilCount:   len(data.pupils),
+		FailingPupils: tenantmodels.FailingGradeGroups{
+			One: []int{}, Two: []int{}, ThreeOrMore: []int{},
+		},
+		BehaviourDistribution: map[string]int{},
+		Subjects:              []tenantmodels.SubjectReport{},
+		Pupils:                []tenantmodels.PupilReport{},
+	}
+	for _, behaviour := range behaviourGrades {
+		report.BehaviourDistribution[behaviour] = 0
+	}
+
+	subjectGrades := map[int]map[string]int{}
+	for _, subject := range data.subjects {
+		subjectReport := buildSubjectReport(subject, data)
+		for _, pupil := range subjectReport.Pupils {
+			if pupil.SubjectGrade == 0 {
+				continue
+			}
+			if subjectGrades[pupil.PupilID] == nil {
+				subjectGrades[pupil.PupilID] = map[string]int{}
+			}
+			subjectGrades[pupil.PupilID][subject.SubjectCode] = pupil.SubjectGrade
+		}
+		subjectReport.Pupils = nil
+		report.Subjects = append(report.Subjects, subjectReport)
+	}
+
+	var averageTotal float64
+	var averageCount int
+	for _, pupil := range data.pupils {
+		pupilReport := tenantmodels.PupilReport{
+			PupilID:         pupil.ID,
+			Name:            pupil.Name,
+			LastName:        pupil.LastName,
+			FailingSubjects: []string{},
+			Absences:        absences[pupil.ID],
+			Behaviour:       behaviours[pupil.ID],
+		}
+
+		var gradeTotal int
+		for _, subject := range data.subjects {
+			grade, ok := subjectGrades[pupil.ID][subject.SubjectCode]
+			if !ok {
+				continue
+			}
+			gradeTotal += grade
+			if grade == 1 {
+				pupilReport.FailingSubjects = append(
+					pupilReport.FailingSubjects, subject.SubjectCode,
+				)
+			}
+		}
+		if graded := len(subjectGrades[pupil.ID]); graded > 0 {
+			pupilReport.AverageGrade = roundGrade(float64(gradeTotal) / float64(graded))
+			averageTotal += pupilReport.AverageGrade
+			averageCount++
+		}
+
+		switch failing := len(pupilReport.FailingSubjects); {
+		case failing == 0:
+			report.Passed++
+		case failing == 1:
+			report.FailingPupils.One = append(report.FailingPupils.One, pupil.ID)
+		case failing == 2:
+			report.FailingPupils.Two = append(report.FailingPupils.Two, pupil.ID)
+		default:
+			report.FailingPupils.ThreeOrMore = append(
+				report.FailingPupils.ThreeOrMore, pupil.ID,
+			)
+		}
+
+		report.Absences.Excused += pupilReport.Absences.Excused
+		report.Absences.Unexcused += pupilReport.Absences.Unexcused
+		report.Absences.Pending += pupilReport.Absences.Pending
+		if pupilReport.Behaviour != "" {
+			report.BehaviourDistribution[pupilReport.Behaviour]++
+		}
+
+		report.Pupils = append(report.Pupils, pupilReport)
+	}
+	if averageCount > 0 {
+		report.AverageGrade = roundGrade(averageTotal / float64(averageCount))
+	}
+
+	return report, nil
+}
+
+// GetSubjectReport builds the report of a section in one subject and
+// semester, with the standing of every pupil
+func GetSubjectReport(
+	workspaceDB *sql.DB,
+	tenantDB interfaces.DatabaseQuerier,
+	tenantID string,
+	sectionID int,
+	subjectCode,
+	semesterCode string,
+) (*tenantmodels.SubjectReport, error) {
+	data, err := loadReportData(
+		workspaceDB, tenantDB, tenantID, sectionID, semesterCode,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, subject := range data.subjects {
+		if subject.SubjectCode == subjectCode {
+			report := buildSubjectReport(subject, data)
+			return &report, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// reportData holds what the reports of a section in a semester are built from
+type reportData struct {
+	section  tenantmodels.Section
+	semester wpmodels.TenantSemester
+	subjects []wpmodels.Subject
+	pupils   []tenantmodels.Pupil
+	schemes  []tenantmodels.GradingScheme
+	// grades by pupil and subject code
+	grades map[int]map[string][]tenantmodels.Grade
+}
+
+func loadReportData(
+	workspaceDB *sql.DB,
+	tenantDB interfaces.DatabaseQuerier,
+	tenantID string,
+	sectionID int,
+	semesterCode string,
+) (*reportData, error) {
+	data := &reportData{}
+
+	var err error
+	data.section, err = GetSectionByID(int64(sectionID), tenantDB)
+	if err != nil {
+		return nil, err
+	}
+	if data.section.ID == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	semesters, err := GetSemestersForSectionHelper(
+		workspaceDB, tenantDB, tenantID, strconv.Itoa(sectionID),
+	)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, semester := range semesters {
+		if semester.SemesterCode == semesterCode {
+			data.semester = semester
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, sql.ErrNoRows
+	}
+
+	data.subjects, err = GetAllSubjectsForCurriculumCode(
+		data.section.CurriculumCode, workspaceDB,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	data.pupils, err = GetPupilsForSection(strconv.Itoa(sectionID), false, tenantDB)
+	if err != nil {
+		return nil, err
+	}
+
+	data.schemes, err = GetGradingSchemes(tenantDB)
+	if err != nil {
+		return nil, err
+	}
+
+	data.grades, err = getSectionSemesterGrades(sectionID, semesterCode, tenantDB)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// buildSubjectReport sums up the grades of the pupils of a section in a
+// subject. Pupils without grades in the subject are ungraded.
+func buildSubjectReport(
+	subject wpmodels.Subject, data *reportData,
+) tenantmodels.SubjectReport {
+	report := tenantmodels.SubjectReport{
+		SubjectCode:       subject.SubjectCode,
+		SubjectName:       subject.SubjectName,
+		GradeDistribution: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0},
+		Pupils:            []tenantmodels.SubjectPupilReport{},
+	}
+	scheme := GradingSchemeForSubject(data.schemes, subject.SubjectCode)
+
+	var gradeTotal, gradedPupils int
+	for _, pupil := range data.pupils {
+		grades := data.grades[pupil.ID][subject.SubjectCode]
+		proposal := ProposeFinalGrade(grades, scheme)
+		pupilReport := tenantmodels.SubjectPupilReport{
+			PupilID:      pupil.ID,
+			Name:         pupil.Name,
+			LastName:     pupil.LastName,
+			GradeCount:   proposal.GradeCount,
+			AverageGrade: proposal.AverageGrade,
+			SubjectGrade: proposal.ProposedGrade,
+		}
+
+		for _, grade := range grades {
+			if grade.Type == "final" {
+				pupilReport.SubjectGrade = grade.Grade
+				pupilReport.Final = true
+				continue
+			}
+			report.GradeDistribution[grade.Grade]++
+			report.GradeCount++
+		}
+
+		switch {
+		case pupilReport.SubjectGrade == 0:
+			report.Ungraded++
+		case pupilReport.SubjectGrade == 1:
+			report.Failed++
+		default:
+			report.Passed++
+		}
+		if pupilReport.Final {
+			report.FinalGrades++
+		}
+		if pupilReport.SubjectGrade > 0 {
+			gradeTotal += pupilReport.SubjectGrade
+			gradedPupils++
+		}
+
+		report.Pupils = append(report.Pupils, pupilReport)
+	}
+	if gradedPupils > 0 {
+		report.AverageGrade = roundGrade(float64(gradeTotal) / float64(gradedPupils))
+	}
+
+	return report
+}
+
+// getSectionSemesterGrades returns the current grades of a section in a
+// semester by pupil and subject code
+func getSectionSemesterGrades(
+	sectionID int, semesterCode string, tenantDB interfaces.DatabaseQuerier,
+) (map[int]map[string][]tenantmodels.Grade, error) {
+	query := `SELECT id, pupil_id, subject_code, grade, grade_date, type
+	FROM student_grades
+	WHERE section_id = ? AND semester_code = ?`
+
+	rows, err := tenantDB.Query(query, sectionID, semesterCode)
+	if err != nil {
+		return nil, fmt.Errorf("error querying section grades: %v", err)
+	}
+	defer rows.Close()
+
+	grades := map[int]map[string][]tenantmodels.Grade{}
+	for rows.Next() {
+		var grade tenantmodels.Grade
+		err := rows.Scan(
+			&grade.ID, &grade.PupilID, &grade.SubjectCode, &grade.Grade,
+			&grade.GradeDate, &grade.Type,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning section grade: %v", err)
+		}
+		if grades[grade.PupilID] == nil {
+			grades[grade.PupilID] = map[string][]tenantmodels.Grade{}
+		}
+		grades[grade.PupilID][grade.SubjectCode] = append(
+			grades[grade.PupilID][grade.SubjectCode], grade,
+		)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating section grades: %v", err)
+	}
+
+	return grades, nil
+}
+
+// getSectionAbsenceTotals counts the absent hours of each pupil of a section
+// within a semester
+func getSectionAbsenceTotals(
+	sectionID int,
+	semester wpmodels.TenantSemester,
+	tenantDB interfaces.DatabaseQuerier,
+) (map[int]tenantmodels.AbsenceTotals, error) {
+	query := `SELECT pa.pupil_id, pa.status, COUNT(*)
+	FROM pupil_attendance pa
+	JOIN class_lesson cl ON pa.lesson_id = cl.id
+	WHERE cl.section_id = ? AND pa.status IN ('absent', 'excused', 'unexcused')
+	AND cl.date BETWEEN ? AND ?
+	GROUP BY pa.pupil_id, pa.status`
+
+	rows, err := tenantDB.Query(
+		query, sectionID, semester.StartDate, semester.EndDate,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error counting absences: %v", err)
+	}
+	defer rows.Close()
+
+	totals := map[int]tenantmodels.AbsenceTotals{}
+	for rows.Next() {
+		var pupilID, count int
+		var status string
+		if err := rows.Scan(&pupilID, &status, &count); err != nil {
+			return nil, fmt.Errorf("error scanning absences: %v", err)
+		}
+		pupilTotals := totals[pupilID]
+		switch status {
+		case "excused":
+			pupilTotals.Excused += count
+		case "unexcused":
+			pupilTotals.Unexcused += count
+		default:
+			pupilTotals.Pending += count
+		}
+		totals[pupilID] = pupilTotals
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating absences: %v", err)
+	}
+
+	return totals, nil
+}
+
+// getSectionBehaviours returns the behaviour grade of each pupil of a section
+// in a semester
+func getSectionBehaviours(
+	sectionID int, semesterCode string, tenantDB interfaces.DatabaseQuerier,
+) (map[int]string, error) {
+	query := `SELECT pupil_id, behaviour FROM pupil_behaviour
+	WHERE section_id = ? AND semester_code = ?`
+
+	rows, err := tenantDB.Query(query, sectionID, semesterCode)
+	if err != nil {
+		return nil, fmt.Errorf("error querying behaviour grades: %v", err)
+	}
+	defer rows.Close()
+
+	behaviours := map[int]string{}
+	for rows.Next() {
+		var pupilID int
+		var behaviour string
+		if err := rows.Scan(&pupilID, &behaviour); err != nil {
+			return nil, fmt.Errorf("error scanning behaviour grade: %v", err)
+		}
+		behaviours[pupilID] = behaviour
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating behaviour grades: %v", err)
+	}
+
+	return behaviours, nil
+}
+
+// roundGrade rounds an average grade to 2 decimal places
+func roundGrade(average float64) float64 {
+	return math.Round(average*100) / 100
+}
@@ -0,0 +1,606 @@
+package util
+
+import (
+	"database/sql"
+	"ednevnik-backend/config"
+	tenantmodels "ednevnik-backend/models/tenant"
+	wpmodels "ednevnik-backend/models/workspace"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// classOrder lists the class codes in the order pupils go through them
+var classOrder = []string{"I", "II", "III", "IV", "V", "VI", "VII", "VIII", "IX"}
+
+// nextClassCode returns the class code pupils of a class are promoted to
+func nextClassCode(classCode string) (string, bool) {
+	for i, code := range classOrder {
+		if code == classCode && i+1 < len(classOrder) {
+			return classOrder[i+1], true
+		}
+	}
+	return "", false
+}
+
+// classIndex returns the position of a class code in classOrder, unknown
+// codes come last
+func classIndex(classCode string) int {
+	for i, code := range classOrder {
+		if code == classCode {
+			return i
+		}
+	}
+	return len(classOrder)
+}
+
+// NextSchoolYear returns the school year that follows a YYYY/YYYY year
+func NextSchoolYear(year string) (string, error) {
+	if len(year) != 9 || year[4] != '/' {
+		return "", fmt.Errorf("školska godina %s nije u obliku GGGG/GGGG", year)
+	}
+	start, err := strconv.Atoi(year[:4])
+	if err != nil {
+		return "", fmt.Errorf("školska godina %s nije u obliku GGGG/GGGG", year)
+	}
+	end, err := strconv.Atoi(year[5:])
+	if err != nil || end != start+1 {
+		return "", fmt.Errorf("školska godina %s nije u obliku GGGG/GGGG", year)
+	}
+	return fmt.Sprintf("%d/%d", end, end+1), nil
+}
+
+// rolloverSectionKey identifies a section within a school year, sections are
+// unique by section code and class code in a year
+func rolloverSectionKey(sectionCode, classCode string) string {
+	return classCode + "-" + sectionCode
+}
+
+// BuildRolloverPlan proposes the sections of the next school year for the
+// archived sections of a year. Passing pupils move to the section with the
+// same code in the next class and the matching curriculum, failing pupils
+// repeat the class in the section with the same code. Pupils of final
+// curriculums graduate. Pupils waiting for a remedial exam, pupils without a
+// final grade in a subject and pupils already enrolled in the next year are
+// left out. Nothing is written to the database.
+func BuildRolloverPlan(
+	request tenantmodels.RolloverRequest,
+	tenantID int64,
+	config *config.TenantConfig,
+	tenantDB *sql.DB,
+	workspaceDB *sql.DB,
+) (*tenantmodels.RolloverPlan, error) {
+	toYear, err := NextSchoolYear(request.FromYear)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &tenantmodels.RolloverPlan{
+		FromYear:  request.FromYear,
+		ToYear:    toYear,
+		DryRun:    true,
+		Sections:  []tenantmodels.RolloverSection{},
+		Graduated: []tenantmodels.RolloverPupil{},
+		Skipped:   []tenantmodels.RolloverSkip{},
+	}
+
+	sourceSections, err := getSectionsForYear(request.FromYear, tenantDB)
+	if err != nil {
+		return nil, err
+	}
+	if len(sourceSections) == 0 {
+		return nil, fmt.Errorf("škola nema odjeljenja u školskoj godini %s", request.FromYear)
+	}
+
+	targetSections, err := getSectionsForYear(toYear, tenantDB)
+	if err != nil {
+		return nil, err
+	}
+	existingTargets := make(map[string]tenantmodels.Section, len(targetSections))
+	for _, section := range targetSections {
+		existingTargets[rolloverSectionKey(section.SectionCode, section.ClassCode)] = section
+	}
+
+	enrolled, err := getPupilsEnrolledInYear(toYear, tenantDB)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]*tenantmodels.RolloverSection)
+	targetFor := func(sectionCode, classCode, curriculumCode string) *tenantmodels.RolloverSection {
+		key := rolloverSectionKey(sectionCode, classCode)
+		if target, ok := targets[key]; ok {
+			return target
+		}
+		target := &tenantmodels.RolloverSection{
+			SectionCode:     sectionCode,
+			ClassCode:       classCode,
+			Year:            toYear,
+			CurriculumCode:  curriculumCode,
+			SubjectTeachers: []tenantmodels.RolloverSubjectTeacher{},
+			Pupils:          []tenantmodels.RolloverPupil{},
+		}
+		if existing, ok := existingTargets[key]; ok {
+			target.SectionID = existing.ID
+			target.CurriculumCode = existing.CurriculumCode
+			target.Exists = true
+		}
+		targets[key] = target
+		return target
+	}
+
+	for _, source := range sourceSections {
+		if !source.Archived {
+			plan.Skipped = append(plan.Skipped, tenantmodels.RolloverSkip{
+				SectionID: source.ID,
+				Reason:    "odjeljenje nije arhivirano",
+			})
+			continue
+		}
+
+		var finalCurriculum bool
+		err := workspaceDB.QueryRow(
+			`SELECT final_curriculum FROM curriculum WHERE curriculum_code = ?`,
+			source.CurriculumCode,
+		).Scan(&finalCurriculum)
+		if err != nil {
+			return nil, fmt.Errorf("error querying curriculum: %v", err)
+		}
+
+		var promotedTarget *tenantmodels.RolloverSection
+		var nextCurriculumCode string
+		if !finalCurriculum {
+			nextCurriculumCode, err = getNextCurriculumCode(
+				source.CurriculumCode, source.ClassCode, tenantID, workspaceDB,
+			)
+			if err != nil {
+				return nil, err
+			}
+			if nextCurriculumCode == "" {
+				plan.Skipped = append(plan.Skipped, tenantmodels.RolloverSkip{
+					SectionID: source.ID,
+					Reason:    "škola nema nastavni plan i program za sljedeći razred",
+				})
+			} else {
+				nextClass, _ := nextClassCode(source.ClassCode)
+				promotedTarget = targetFor(source.SectionCode, nextClass, nextCurriculumCode)
+			}
+		}
+
+		pupils, err := GetPupilsForSection(strconv.FormatInt(source.ID, 10), false, tenantDB)
+		if err != nil {
+			return nil, err
+		}
+
+		// Descriptively assessed classes have no final grades, their pupils
+		// always pass
+		outcomes := map[int]int{}
+		descriptive, err := IsDescriptiveSection(int(source.ID), tenantDB)
+		if err != nil {
+			return nil, err
+		}
+		if !descriptive {
+			subjects, err := GetAllSubjectsForCurriculumCode(source.CurriculumCode, workspaceDB)
+			if err != nil {
+				return nil, err
+			}
+			grades, err := GetAllFinalGradesForSection(int(source.ID), tenantDB)
+			if err != nil {
+				return nil, err
+			}
+			outcomes = rolloverOutcomes(pupils, subjects, grades, config.MaxSemesterCode)
+		}
+
+		pendingExams, err := getPendingRemedialExams(int(source.ID), tenantDB)
+		if err != nil {
+			return nil, err
+		}
+
+		planSectionPupils(plan, source, pupils, rolloverSectionState{
+			outcomes:        outcomes,
+			pendingExams:    pendingExams,
+			enrolled:        enrolled,
+			finalCurriculum: finalCurriculum,
+			promotedTarget:  promotedTarget,
+			repeatTarget: func() *tenantmodels.RolloverSection {
+				return targetFor(source.SectionCode, source.ClassCode, source.CurriculumCode)
+			},
+		})
+
+		if promotedTarget == nil || promotedTarget.FromSectionID != 0 {
+			continue
+		}
+		promotedTarget.FromSectionID = source.ID
+
+		if request.CarryHomeroomTeachers && source.HomeroomTeacherID != 0 {
+			existing, ok := existingTargets[rolloverSectionKey(
+				promotedTarget.SectionCode, promotedTarget.ClassCode,
+			)]
+			if !ok || existing.HomeroomTeacherID == 0 {
+				promotedTarget.HomeroomTeacherID = source.HomeroomTeacherID
+			}
+		}
+
+		if request.CarrySubjectTeachers {
+			promotedTarget.SubjectTeachers, err = getCarriedSubjectTeachers(
+				int(source.ID), promotedTarget.CurriculumCode, tenantDB, workspaceDB,
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, target := range targets {
+		if len(target.Pupils) == 0 && target.HomeroomTeacherID == 0 &&
+			len(target.SubjectTeachers) == 0 {
+			continue
+		}
+		sort.Slice(target.Pupils, func(i, j int) bool {
+			if target.Pupils[i].LastName != target.Pupils[j].LastName {
+				return target.Pupils[i].LastName < target.Pupils[j].LastName
+			}
+			return target.Pupils[i].Name < target.Pupils[j].Name
+		})
+		plan.Sections = append(plan.Sections, *target)
+	}
+	sort.Slice(plan.Sections, func(i, j int) bool {
+		a, b := plan.Sections[i], plan.Sections[j]
+		if classIndex(a.ClassCode) != classIndex(b.ClassCode) {
+			return classIndex(a.ClassCode) < classIndex(b.ClassCode)
+		}
+		return a.SectionCode < b.SectionCode
+	})
+
+	return plan, nil
+}
+
+// Outcomes of the final grades of a pupil in the last semester
+const (
+	rolloverPassed = iota
+	rolloverFailed
+	rolloverUngraded
+)
+
+// rolloverOutcomes returns the outcome of every pupil that did not pass all
+// subjects of the curriculum in the semester. A pupil without a final grade
+// in a subject still has to take a class exam, which decides the outcome, so
+// a missing grade wins over a failing one.
+func rolloverOutcomes(
+	pupils []tenantmodels.Pupil,
+	subjects []wpmodels.Subject,
+	grades []tenantmodels.Grade,
+	semesterCode string,
+) map[int]int {
+	finalGrades := make(map[int]map[string]int)
+	for _, grade := range grades {
+		if grade.SemesterCode != semesterCode {
+			continue
+		}
+		if finalGrades[grade.PupilID] == nil {
+			finalGrades[grade.PupilID] = make(map[string]int)
+		}
+		finalGrades[grade.PupilID][grade.SubjectCode] = grade.Grade
+	}
+
+	outcomes := make(map[int]int)
+	for _, pupil := range pupils {
+		for _, subject := range subjects {
+			finalGrade, graded := finalGrades[pupil.ID][subject.SubjectCode]
+			switch {
+			case !graded:
+				outcomes[pupil.ID] = rolloverUngraded
+			case finalGrade < 2 && outcomes[pupil.ID] != rolloverUngraded:
+				outcomes[pupil.ID] = rolloverFailed
+			}
+		}
+	}
+	return outcomes
+}
+
+// rolloverSectionState is what placing the pupils of a source section needs
+// to know about it
+type rolloverSectionState struct {
+	outcomes        map[int]int
+	pendingExams    map[int]map[string]int
+	enrolled        map[int]bool
+	finalCurriculum bool
+	promotedTarget  *tenantmodels.RolloverSection
+	repeatTarget    func() *tenantmodels.RolloverSection
+}
+
+// planSectionPupils places the pupils of a source section in the plan.
+// Pupils waiting for an exam or without a final grade are skipped, failing
+// pupils repeat the class, the others graduate or are promoted.
+func planSectionPupils(
+	plan *tenantmodels.RolloverPlan,
+	source tenantmodels.Section,
+	pupils []tenantmodels.Pupil,
+	state rolloverSectionState,
+) {
+	for _, pupil := range pupils {
+		if state.enrolled[pupil.ID] {
+			continue
+		}
+		rolloverPupil := tenantmodels.RolloverPupil{
+			PupilID:       pupil.ID,
+			Name:          pupil.Name,
+			LastName:      pupil.LastName,
+			FromSectionID: source.ID,
+		}
+
+		switch {
+		case len(state.pendingExams[pupil.ID]) > 0:
+			plan.Skipped = append(plan.Skipped, tenantmodels.RolloverSkip{
+				SectionID: source.ID,
+				PupilID:   pupil.ID,
+				Reason:    "učenik čeka popravni ispit",
+			})
+		case state.outcomes[pupil.ID] == rolloverUngraded:
+			plan.Skipped = append(plan.Skipped, tenantmodels.RolloverSkip{
+				SectionID: source.ID,
+				PupilID:   pupil.ID,
+				Reason:    "učenik nema zaključnu ocjenu / čeka razredni ispit",
+			})
+		case state.outcomes[pupil.ID] == rolloverFailed:
+			rolloverPupil.Repeating = true
+			target := state.repeatTarget()
+			target.Pupils = append(target.Pupils, rolloverPupil)
+		case state.finalCurriculum:
+			plan.Graduated = append(plan.Graduated, rolloverPupil)
+		case state.promotedTarget != nil:
+			state.promotedTarget.Pupils = append(state.promotedTarget.Pupils, rolloverPupil)
+		}
+	}
+}
+
+// ApplyRolloverPlan creates the sections of a rollover plan that do not exist
+// yet and assigns the pupils and teachers to them in one transaction. The
+// IDs of created sections are set on the plan.
+func ApplyRolloverPlan(
+	plan *tenantmodels.RolloverPlan, tenantID int64, tenantDB *sql.DB,
+) (err error) {
+	tx, err := tenantDB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting tenantDB transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	for i := range plan.Sections {
+		section := &plan.Sections[i]
+
+		if !section.Exists {
+			var res sql.Result
+			res, err = tx.Exec(
+				`INSERT INTO sections (section_code, class_code, year, tenant_id,
+				curriculum_code) VALUES (?, ?, ?, ?, ?)`,
+				section.SectionCode, section.ClassCode, section.Year, tenantID,
+				section.CurriculumCode,
+			)
+			if err != nil {
+				return fmt.Errorf("error creating section: %v", err)
+			}
+			section.SectionID, err = res.LastInsertId()
+			if err != nil {
+				return err
+			}
+		}
+
+		// The homeroom teacher signs the behaviour grades created for
+		// enrolled pupils, so it is assigned first
+		if section.HomeroomTeacherID != 0 {
+			_, err = tx.Exec(
+				`INSERT IGNORE INTO teachers_sections (teacher_id, section_id)
+				VALUES (?, ?)`,
+				section.HomeroomTeacherID, section.SectionID,
+			)
+			if err != nil {
+				return fmt.Errorf("error inserting teachers_sections record: %v", err)
+			}
+			_, err = tx.Exec(
+				`INSERT IGNORE INTO homeroom_assignments (section_id, teacher_id)
+				VALUES (?, ?)`,
+				section.SectionID, section.HomeroomTeacherID,
+			)
+			if err != nil {
+				return fmt.Errorf("error inserting homeroom assignment: %v", err)
+			}
+		}
+
+		for _, assignment := range section.SubjectTeachers {
+			_, err = tx.Exec(
+				`INSERT IGNORE INTO teachers_sections (teacher_id, section_id)
+				VALUES (?, ?)`,
+				assignment.TeacherID, section.SectionID,
+			)
+			if err != nil {
+				return fmt.Errorf("error inserting teachers_sections record: %v", err)
+			}
+			_, err = tx.Exec(
+				`INSERT IGNORE INTO teachers_sections_subjects (section_id,
+				subject_code, teacher_id) VALUES (?, ?, ?)`,
+				section.SectionID, assignment.SubjectCode, assignment.TeacherID,
+			)
+			if err != nil {
+				return fmt.Errorf("error inserting subjects for teacher: %v", err)
+			}
+		}
+
+		for _, pupil := range section.Pupils {
+			var res sql.Result
+			res, err = tx.Exec(
+				`INSERT INTO pupils_sections (pupil_id, section_id) VALUES (?, ?)
+				ON DUPLICATE KEY UPDATE is_active = 1`,
+				pupil.PupilID, section.SectionID,
+			)
+			if err != nil {
+				return fmt.Errorf("error enrolling pupil %d: %v", pupil.PupilID, err)
+			}
+			if inserted, _ := res.RowsAffected(); inserted != 1 {
+				continue
+			}
+			err = AppendBehaviourLedgerEntries(
+				tx, pupil.PupilID, int(section.SectionID), "", LedgerActionCreate, 0,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	plan.DryRun = false
+	return nil
+}
+
+// getSectionsForYear returns the sections of a school year with their
+// homeroom teachers
+func getSectionsForYear(
+	year string, tenantDB *sql.DB,
+) ([]tenantmodels.Section, error) {
+	query := `SELECT s.id, s.section_code, s.class_code, s.year,
+	COALESCE(s.curriculum_code, ''), COALESCE(s.archived, FALSE),
+	COALESCE(ha.teacher_id, 0)
+	FROM sections s
+	LEFT JOIN homeroom_assignments ha ON ha.section_id = s.id
+	WHERE s.year = ?
+	ORDER BY s.section_code`
+
+	rows, err := tenantDB.Query(query, year)
+	if err != nil {
+		return nil, fmt.Errorf("error querying sections: %v", err)
+	}
+	defer rows.Close()
+
+	sections := []tenantmodels.Section{}
+	for rows.Next() {
+		var section tenantmodels.Section
+		err := rows.Scan(
+			&section.ID, &section.SectionCode, &section.ClassCode, &section.Year,
+			&section.CurriculumCode, &section.Archived, &section.HomeroomTeacherID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning section: %v", err)
+		}
+		sections = append(sections, section)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sections: %v", err)
+	}
+
+	return sections, nil
+}
+
+// getPupilsEnrolledInYear returns the pupils that are already enrolled in a
+// section of a school year
+func getPupilsEnrolledInYear(year string, tenantDB *sql.DB) (map[int]bool, error) {
+	query := `SELECT DISTINCT ps.pupil_id FROM pupils_sections ps
+	JOIN sections s ON s.id = ps.section_id
+	WHERE s.year = ? AND ps.is_active = 1`
+
+	rows, err := tenantDB.Query(query, year)
+	if err != nil {
+		return nil, fmt.Errorf("error querying enrolled pupils: %v", err)
+	}
+	defer rows.Close()
+
+	enrolled := make(map[int]bool)
+	for rows.Next() {
+		var pupilID int
+		if err := rows.Scan(&pupilID); err != nil {
+			return nil, fmt.Errorf("error scanning enrolled pupil: %v", err)
+		}
+		enrolled[pupilID] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating enrolled pupils: %v", err)
+	}
+
+	return enrolled, nil
+}
+
+// getNextCurriculumCode returns the curriculum of the tenant for the next
+// class with the same NPP, canton and course as a curriculum, or an empty
+// code when the tenant has none
+func getNextCurriculumCode(
+	curriculumCode, classCode string, tenantID int64, workspaceDB *sql.DB,
+) (string, error) {
+	nextClass, ok := nextClassCode(classCode)
+	if !ok {
+		return "", nil
+	}
+
+	query := `SELECT nc.curriculum_code FROM curriculum c
+	JOIN curriculum nc ON nc.class_code = ?
+		AND nc.npp_code = c.npp_code
+		AND nc.canton_code = c.canton_code
+		AND nc.tenant_type = c.tenant_type
+		AND nc.course_code <=> c.course_code
+	JOIN curriculum_tenant ct ON ct.curriculum_code = nc.curriculum_code
+		AND ct.tenant_id = ?
+	WHERE c.curriculum_code = ?
+	ORDER BY nc.curriculum_code
+	LIMIT 1`
+
+	var nextCurriculumCode string
+	err := workspaceDB.QueryRow(query, nextClass, tenantID, curriculumCode).Scan(
+		&nextCurriculumCode,
+	)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error querying next curriculum: %v", err)
+	}
+	return nextCurriculumCode, nil
+}
+
+// getCarriedSubjectTeachers returns the subject assignments of a section for
+// the subjects that are also in the curriculum of the next class
+func getCarriedSubjectTeachers(
+	sectionID int, curriculumCode string, tenantDB, workspaceDB *sql.DB,
+) ([]tenantmodels.RolloverSubjectTeacher, error) {
+	subjects, err := GetAllSubjectsForCurriculumCode(curriculumCode, workspaceDB)
+	if err != nil {
+		return nil, err
+	}
+	inCurriculum := make(map[string]bool, len(subjects))
+	for _, subject := range subjects {
+		inCurriculum[subject.SubjectCode] = true
+	}
+
+	query := `SELECT teacher_id, subject_code FROM teachers_sections_subjects
+	WHERE section_id = ? ORDER BY subject_code, teacher_id`
+
+	rows, err := tenantDB.Query(query, sectionID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying section subject teachers: %v", err)
+	}
+	defer rows.Close()
+
+	assignments := []tenantmodels.RolloverSubjectTeacher{}
+	for rows.Next() {
+		var assignment tenantmodels.RolloverSubjectTeacher
+		if err := rows.Scan(&assignment.TeacherID, &assignment.SubjectCode); err != nil {
+			return nil, fmt.Errorf("error scanning section subject teacher: %v", err)
+		}
+		if inCurriculum[assignment.SubjectCode] {
+			assignments = append(assignments, assignment)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating section subject teachers: %v", err)
+	}
+
+	return assignments, nil
+}
@@ -0,0 +1,80 @@
+package util
+
+import (
+	"testing"
+
+	tenantmodels "ednevnik-backend/models/tenant"
+	wpmodels "ednevnik-backend/models/workspace"
+)
+
+func TestRolloverPlanSkipsPupilsWithoutFinalGrade(t *testing.T) {
+	pupils := []tenantmodels.Pupil{
+		{ID: 1, Name: "Amra"},
+		{ID: 2, Name: "Haris"},
+		{ID: 3, Name: "Lejla"},
+		{ID: 4, Name: "Tarik"},
+	}
+	subjects := []wpmodels.Subject{{SubjectCode: "MAT"}, {SubjectCode: "BHS"}}
+	grades := []tenantmodels.Grade{
+		{PupilID: 1, SubjectCode: "MAT", Grade: 5, SemesterCode: "II"},
+		{PupilID: 1, SubjectCode: "BHS", Grade: 4, SemesterCode: "II"},
+		// Haris is graded in the first semester only
+		{PupilID: 2, SubjectCode: "MAT", Grade: 3, SemesterCode: "I"},
+		{PupilID: 2, SubjectCode: "BHS", Grade: 3, SemesterCode: "II"},
+		{PupilID: 3, SubjectCode: "MAT", Grade: 1, SemesterCode: "II"},
+		{PupilID: 3, SubjectCode: "BHS", Grade: 2, SemesterCode: "II"},
+		// Tarik failed one subject and has no grade in the other
+		{PupilID: 4, SubjectCode: "MAT", Grade: 1, SemesterCode: "II"},
+	}
+
+	plan := &tenantmodels.RolloverPlan{}
+	source := tenantmodels.Section{ID: 10, SectionCode: "A", ClassCode: "VIII"}
+	promoted := &tenantmodels.RolloverSection{}
+	repeating := &tenantmodels.RolloverSection{}
+	planSectionPupils(plan, source, pupils, rolloverSectionState{
+		outcomes:       rolloverOutcomes(pupils, subjects, grades, "II"),
+		promotedTarget: promoted,
+		repeatTarget:   func() *tenantmodels.RolloverSection { return repeating },
+	})
+
+	if len(promoted.Pupils) != 1 || promoted.Pupils[0].PupilID != 1 {
+		t.Errorf("promoted %+v, want only pupil 1", promoted.Pupils)
+	}
+	if len(repeating.Pupils) != 1 || repeating.Pupils[0].PupilID != 3 ||
+		!repeating.Pupils[0].Repeating {
+		t.Errorf("repeating %+v, want only pupil 3", repeating.Pupils)
+	}
+	skipped := map[int]string{}
+	for _, skip := range plan.Skipped {
+		skipped[skip.PupilID] = skip.Reason
+	}
+	for _, pupilID := range []int{2, 4} {
+		if skipped[pupilID] != "učenik nema zaključnu ocjenu / čeka razredni ispit" {
+			t.Errorf("pupil %d skipped with %q", pupilID, skipped[pupilID])
+		}
+	}
+	if len(plan.Skipped) != 2 || len(plan.Graduated) != 0 {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+}
+
+func TestRolloverPlanGraduatesOnlyPassingPupils(t *testing.T) {
+	pupils := []tenantmodels.Pupil{{ID: 1}, {ID: 2}}
+	subjects := []wpmodels.Subject{{SubjectCode: "MAT"}}
+	grades := []tenantmodels.Grade{
+		{PupilID: 1, SubjectCode: "MAT", Grade: 2, SemesterCode: "II"},
+	}
+
+	plan := &tenantmodels.RolloverPlan{}
+	planSectionPupils(plan, tenantmodels.Section{ID: 10}, pupils, rolloverSectionState{
+		outcomes:        rolloverOutcomes(pupils, subjects, grades, "II"),
+		finalCurriculum: true,
+	})
+
+	if len(plan.Graduated) != 1 || plan.Graduated[0].PupilID != 1 {
+		t.Errorf("graduated %+v, want only pupil 1", plan.Graduated)
+	}
+	if len(plan.Skipped) != 1 || plan.Skipped[0].PupilID != 2 {
+		t.Errorf("skipped %+v, want only pupil 2", plan.Skipped)
+	}
+}
@@ -344,39 +344,24 @@ func findFinalizedGradeHelper(
 	return false
 }
 
-// ArchiveSectionHelper updates the sections status to archived.
-// It archives only if all pupils in the section have finalized grades for all
-// section subjects.
-// If the section is already archived it does nothing.
-func ArchiveSectionHelper(
-	sectionID int,
-	tenant *wpmodels.Tenant,
-	config *config.TenantConfig,
+// ValidateSectionArchivable checks that a section can be archived, which
+// means every pupil of the section has finalized grades for all section
+// subjects in every semester. It returns the section pupils and final grades
+// used for archiving.
+func ValidateSectionArchivable(
+	section tenantmodels.Section,
 	tenantDB *sql.DB,
 	workspaceDB *sql.DB,
-) error {
-	var err error
-
-	// Get section
-	section, err := GetSectionByID(int64(sectionID), tenantDB)
-	if err != nil {
-		return err
-	}
-
-	// If section is archived do nothing
-	if section.Archived {
-		return nil
-	}
-
+) ([]tenantmodels.Pupil, []tenantmodels.Grade, error) {
 	// Get section pupils
 	pupils, err := GetPupilsForSection(
-		fmt.Sprintf("%d", sectionID), false, tenantDB,
+		fmt.Sprintf("%d", section.ID), false, tenantDB,
 	)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	if len(pupils) == 0 {
-		return fmt.Errorf("prazno odjeljenje se ne može arhivirati - potrebno je da bude upisan bar jedan učenik")
+		return nil, nil, fmt.Errorf("prazno odjeljenje se ne može arhivirati - potrebno je da bude upisan bar jedan učenik")
 	}
 
 	// Get subjects for section
@@ -384,7 +369,7 @@ func ArchiveSectionHelper(
 		section.CurriculumCode, workspaceDB,
 	)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// Get semesters for section
@@ -395,13 +380,13 @@ func ArchiveSectionHelper(
 		fmt.Sprintf("%d", section.ID),
 	)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// Get all final grades for section
-	grades, err := GetAllFinalGradesForSection(sectionID, tenantDB)
+	grades, err := GetAllFinalGradesForSection(int(section.ID), tenantDB)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// Initialize pupils without final grades just used for counting for now
@@ -430,11 +415,43 @@ func ArchiveSectionHelper(
 	}
 
 	if len(pupilsWithoutFinalizedGrades) > 0 {
-		return fmt.Errorf(
+		return nil, nil, fmt.Errorf(
 			"odjeljenje se ne može arhivirati dok svi učenici nemaju zaključene ocjene iz svih predmeta",
 		)
 	}
 
+	return pupils, grades, nil
+}
+
+// ArchiveSectionHelper updates the sections status to archived.
+// It archives only if all pupils in the section have finalized grades for all
+// section subjects.
+// If the section is already archived it does nothing.
+func ArchiveSectionHelper(
+	sectionID int,
+	tenant *wpmodels.Tenant,
+	config *config.TenantConfig,
+	tenantDB *sql.DB,
+	workspaceDB *sql.DB,
+) error {
+	var err error
+
+	// Get section
+	section, err := GetSectionByID(int64(sectionID), tenantDB)
+	if err != nil {
+		return err
+	}
+
+	// If section is archived do nothing
+	if section.Archived {
+		return nil
+	}
+
+	pupils, grades, err := ValidateSectionArchivable(section, tenantDB, workspaceDB)
+	if err != nil {
+		return err
+	}
+
 	var finalCurricum bool
 	finalCurriculumQuery := `SELECT final_curriculum FROM curriculum
 	WHERE curriculum_code = ?`
@@ -477,6 +494,127 @@ func ArchiveSectionHelper(
 		}
 	}
 
+	// Start transaction
+	workspaceTx, err := workspaceDB.Begin()
+	defer func() {
+		if err != nil {
+			_ = workspaceTx.Rollback()
+		}
+	}()
+
+	for _, pupil := range pupils {
+		err = archivePupilGrades(
+			workspaceTx, pupil.ID, gradesByPupil[pupil.ID],
+			behaviourGrades[pupil.ID], section, tenant, config, finalCurricum,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = workspaceTx.Commit()
+	if err != nil {
+		return err
+	}
+
+	// Finally update section to be archived
+	updateQuery := `UPDATE sections SET archived = ? WHERE id = ?`
+	_, err = tenantDB.Exec(updateQuery, 1, sectionID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ArchiveRemedialPupilHelper copies the final grades of a pupil of an
+// archived section into the workspace once a remedial exam changed them.
+// Sections that are not archived yet are left to ArchiveSectionHelper.
+func ArchiveRemedialPupilHelper(
+	sectionID,
+	pupilID int,
+	tenant *wpmodels.Tenant,
+	config *config.TenantConfig,
+	tenantDB *sql.DB,
+	workspaceDB *sql.DB,
+) error {
+	section, err := GetSectionByID(int64(sectionID), tenantDB)
+	if err != nil {
+		return err
+	}
+	if !section.Archived {
+		return nil
+	}
+
+	var finalCurriculum bool
+	finalCurriculumQuery := `SELECT final_curriculum FROM curriculum
+	WHERE curriculum_code = ?`
+	err = workspaceDB.QueryRow(
+		finalCurriculumQuery, section.CurriculumCode,
+	).Scan(&finalCurriculum)
+	if err != nil {
+		return err
+	}
+
+	grades, err := GetAllFinalGradesForSection(sectionID, tenantDB)
+	if err != nil {
+		return err
+	}
+	var pupilGrades []tenantmodels.Grade
+	for _, grade := range grades {
+		if grade.PupilID == pupilID && grade.SemesterCode == config.MaxSemesterCode {
+			pupilGrades = append(pupilGrades, grade)
+		}
+	}
+
+	var behaviourGrade tenantmodels.BehaviourGrade
+	behaviourQuery := `SELECT id, pupil_id, section_id, behaviour, semester_code
+	FROM pupil_behaviour
+	WHERE pupil_id = ? AND section_id = ? AND semester_code = ?`
+	err = tenantDB.QueryRow(
+		behaviourQuery, pupilID, sectionID, config.MaxSemesterCode,
+	).Scan(
+		&behaviourGrade.ID, &behaviourGrade.PupilID, &behaviourGrade.SectionID,
+		&behaviourGrade.Behaviour, &behaviourGrade.SemesterCode,
+	)
+	if err != nil {
+		return err
+	}
+
+	workspaceTx, err := workspaceDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = workspaceTx.Rollback()
+		}
+	}()
+
+	err = archivePupilGrades(
+		workspaceTx, pupilID, pupilGrades, behaviourGrade, section, tenant,
+		config, finalCurriculum,
+	)
+	if err != nil {
+		return err
+	}
+
+	return workspaceTx.Commit()
+}
+
+// archivePupilGrades copies the final and behaviour grades of a pupil into
+// the final grade tables of the workspace. Pupils with a failing final grade
+// are left out until they pass a remedial exam.
+func archivePupilGrades(
+	workspaceTx *sql.Tx,
+	pupilID int,
+	pupilGrades []tenantmodels.Grade,
+	behaviourGradeForPupil tenantmodels.BehaviourGrade,
+	section tenantmodels.Section,
+	tenant *wpmodels.Tenant,
+	config *config.TenantConfig,
+	finalCurricum bool,
+) error {
 	// This query is used delete overlapping final grades if they exist for this
 	// class and this school_specialization but this should not happen
 	deleteFinalGradesGuardQuery := `DELETE FROM ` + config.FinalGradeTable +
@@ -499,96 +637,65 @@ func ArchiveSectionHelper(
 		config.AvailableForEnrollmentField + ` = ? WHERE pupil_id = ?
 		AND tenant_id = ?`
 
-	// Start transaction
-	workspaceTx, err := workspaceDB.Begin()
-	defer func() {
-		if err != nil {
-			_ = workspaceTx.Rollback()
-		}
-	}()
-
-	for _, pupil := range pupils {
-		_, err = workspaceTx.Exec(
-			deleteFinalGradesGuardQuery, pupil.ID, section.ClassCode,
-			tenant.Specialization,
-		)
-		if err != nil {
-			return err
-		}
-
-		_, err = workspaceTx.Exec(
-			deleteBehaviourGradesGuardQuery, pupil.ID, section.ClassCode,
-			tenant.Specialization,
-		)
-		if err != nil {
-			return err
-		}
-
-		pupilGrades := gradesByPupil[pupil.ID]
-		hasFailingGrade := false
-
-		for _, grade := range pupilGrades {
-			if grade.Grade < 2 {
-				hasFailingGrade = true
-				break
-			}
-		}
+	_, err := workspaceTx.Exec(
+		deleteFinalGradesGuardQuery, pupilID, section.ClassCode,
+		tenant.Specialization,
+	)
+	if err != nil {
+		return err
+	}
 
-		if hasFailingGrade {
-			continue
-		}
+	_, err = workspaceTx.Exec(
+		deleteBehaviourGradesGuardQuery, pupilID, section.ClassCode,
+		tenant.Specialization,
+	)
+	if err != nil {
+		return err
+	}
 
-		for _, grade := range pupilGrades {
-			_, err = workspaceTx.Exec(
-				insertFinalGradeQuery,
-				grade.PupilID,
-				tenant.ID,
-				grade.SubjectCode,
-				section.ClassCode,
-				grade.Grade,
-				tenant.Specialization,
-			)
-			if err != nil {
-				return err
-			}
+	for _, grade := range pupilGrades {
+		if grade.Grade < 2 {
+			return nil
 		}
+	}
 
-		behaviourGradeForPupil := behaviourGrades[pupil.ID]
+	for _, grade := range pupilGrades {
 		_, err = workspaceTx.Exec(
-			insertBehaviourGradeQuery,
-			behaviourGradeForPupil.PupilID,
+			insertFinalGradeQuery,
+			grade.PupilID,
 			tenant.ID,
+			grade.SubjectCode,
 			section.ClassCode,
-			behaviourGradeForPupil.Behaviour,
+			grade.Grade,
 			tenant.Specialization,
 		)
 		if err != nil {
 			return err
 		}
-
-		if finalCurricum {
-			_, err = workspaceTx.Exec(
-				updateEnrollmentStatusQuery,
-				1,
-				pupil.ID,
-				tenant.ID,
-			)
-			if err != nil {
-				return err
-			}
-		}
 	}
 
-	err = workspaceTx.Commit()
+	_, err = workspaceTx.Exec(
+		insertBehaviourGradeQuery,
+		behaviourGradeForPupil.PupilID,
+		tenant.ID,
+		section.ClassCode,
+		behaviourGradeForPupil.Behaviour,
+		tenant.Specialization,
+	)
 	if err != nil {
 		return err
 	}
 
-	// Finally update section to be archived
-	updateQuery := `UPDATE sections SET archived = ? WHERE id = ?`
-	_, err = tenantDB.Exec(updateQuery, 1, sectionID)
-	if err != nil {
-		return err
+	if finalCurricum {
+		_, err = workspaceTx.Exec(
+			updateEnrollmentStatusQuery,
+			1,
+			pupilID,
+			tenant.ID,
+		)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
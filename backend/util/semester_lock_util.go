@@ -0,0 +1,266 @@
+package util
+
+import (
+	"database/sql"
+	"ednevnik-backend/models/interfaces"
+	tenantmodels "ednevnik-backend/models/tenant"
+	wpmodels "ednevnik-backend/models/workspace"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const semesterDateLayout = "2006-01-02"
+
+var (
+	// ErrSemesterLocked is returned when grades or attendance of a locked
+	// semester are changed
+	ErrSemesterLocked = errors.New("polugodište je zaključano")
+	// ErrOutsideSemester is returned when a date or semester can not be used
+	// for grades or attendance of a section
+	ErrOutsideSemester = errors.New("datum nije unutar polugodišta")
+)
+
+// GetLatestSemesterLockChanges returns the last manual lock or unlock of each
+// semester, keyed by semester code
+func GetLatestSemesterLockChanges(
+	tenantDB interfaces.DatabaseQuerier,
+) (map[string]tenantmodels.SemesterLockChange, error) {
+	query := `SELECT slc.id, slc.semester_code, slc.locked, slc.reason,
+	slc.changed_by_account_id, slc.changed_at
+	FROM semester_lock_changes slc
+	JOIN (
+		SELECT semester_code, MAX(id) AS id
+		FROM semester_lock_changes
+		GROUP BY semester_code
+	) latest ON latest.id = slc.id`
+
+	rows, err := tenantDB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying semester lock changes: %v", err)
+	}
+	defer rows.Close()
+
+	changes := map[string]tenantmodels.SemesterLockChange{}
+	for rows.Next() {
+		var change tenantmodels.SemesterLockChange
+		err := rows.Scan(
+			&change.ID,
+			&change.SemesterCode,
+			&change.Locked,
+			&change.Reason,
+			&change.ChangedByAccountID,
+			&change.ChangedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning semester lock change: %v", err)
+		}
+		changes[change.SemesterCode] = change
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating semester lock changes: %v", err)
+	}
+
+	return changes, nil
+}
+
+// SemesterLockStatus works out whether a semester is locked. The last manual
+// change of the tenant admin wins, otherwise the semester locks itself once
+// its grace period after the end date is over.
+func SemesterLockStatus(
+	semester wpmodels.TenantSemester,
+	changes map[string]tenantmodels.SemesterLockChange,
+	today string,
+) tenantmodels.SemesterLock {
+	status := tenantmodels.SemesterLock{TenantSemester: semester}
+
+	if endDate, err := time.Parse(semesterDateLayout, normalizeDate(semester.EndDate)); err == nil {
+		status.EditableUntil = endDate.AddDate(0, 0, semester.LockGraceDays).
+			Format(semesterDateLayout)
+		status.Locked = today > status.EditableUntil
+	}
+
+	if change, ok := changes[semester.SemesterCode]; ok {
+		status.Locked = change.Locked
+		status.LastChange = &change
+	}
+
+	return status
+}
+
+// GetSemesterLocks returns the lock status of all semesters of the tenant
+func GetSemesterLocks(
+	workspaceDB *sql.DB, tenantDB interfaces.DatabaseQuerier, tenantID string,
+) ([]tenantmodels.SemesterLock, error) {
+	semesters, err := GetSemestersForTenant(workspaceDB, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := GetLatestSemesterLockChanges(tenantDB)
+	if err != nil {
+		return nil, err
+	}
+
+	today := time.Now().Format(semesterDateLayout)
+	locks := []tenantmodels.SemesterLock{}
+	for _, semester := range semesters {
+		locks = append(locks, SemesterLockStatus(semester, changes, today))
+	}
+
+	return locks, nil
+}
+
+// SetSemesterLock records a manual lock or unlock of a semester
+func SetSemesterLock(
+	semesterCode string, locked bool, reason string, accountID int, tenantDB *sql.DB,
+) error {
+	if reason == "" {
+		return fmt.Errorf("razlog je obavezan")
+	}
+
+	query := `INSERT INTO semester_lock_changes (semester_code, locked, reason,
+	changed_by_account_id) VALUES (?, ?, ?, ?)`
+
+	_, err := tenantDB.Exec(query, semesterCode, locked, reason, accountID)
+	if err != nil {
+		return fmt.Errorf("error saving semester lock change: %v", err)
+	}
+
+	return nil
+}
+
+// CheckSemesterWritable returns an error when grades or attendance of a
+// section can not be written for a semester and date. The date must fall
+// within the semester, without a semester code the semester is found from the
+// date. An empty date only checks that the semester has started and is not
+// locked.
+func CheckSemesterWritable(
+	workspaceDB *sql.DB,
+	tenantDB interfaces.DatabaseQuerier,
+	tenantID string,
+	sectionID int,
+	semesterCode string,
+	date string,
+) error {
+	date = normalizeDate(date)
+	if date != "" {
+		if _, err := time.Parse(semesterDateLayout, date); err != nil {
+			return fmt.Errorf("%w: neispravan datum %s", ErrOutsideSemester, date)
+		}
+	}
+
+	semesters, err := GetSemestersForSectionHelper(
+		workspaceDB, tenantDB, tenantID, strconv.Itoa(sectionID),
+	)
+	if err != nil {
+		return err
+	}
+
+	var semester *wpmodels.TenantSemester
+	for i := range semesters {
+		if semesterCode != "" && semesters[i].SemesterCode != semesterCode {
+			continue
+		}
+		if semesterCode == "" && !dateInSemester(date, semesters[i]) {
+			continue
+		}
+		semester = &semesters[i]
+		break
+	}
+	if semester == nil {
+		if semesterCode != "" {
+			return fmt.Errorf(
+				"%w: polugodište %s ne postoji za ovo odjeljenje",
+				ErrOutsideSemester, semesterCode,
+			)
+		}
+		return fmt.Errorf("%w: %s", ErrOutsideSemester, date)
+	}
+
+	today := time.Now().Format(semesterDateLayout)
+	startDate := normalizeDate(semester.StartDate)
+	if today < startDate {
+		return fmt.Errorf(
+			"%w: polugodište %s počinje %s", ErrOutsideSemester,
+			semester.FullName, startDate,
+		)
+	}
+	if date != "" && !dateInSemester(date, *semester) {
+		return fmt.Errorf(
+			"%w: datum %s mora biti između %s i %s", ErrOutsideSemester,
+			date, startDate, normalizeDate(semester.EndDate),
+		)
+	}
+
+	changes, err := GetLatestSemesterLockChanges(tenantDB)
+	if err != nil {
+		return err
+	}
+	return checkSemesterUnlocked(*semester, changes, today)
+}
+
+// CheckSemesterRangeWritable returns an error when any semester of a section
+// overlapping the dates is locked
+func CheckSemesterRangeWritable(
+	workspaceDB *sql.DB,
+	tenantDB interfaces.DatabaseQuerier,
+	tenantID string,
+	sectionID int,
+	dateFrom, dateTo string,
+) error {
+	semesters, err := GetSemestersForSectionHelper(
+		workspaceDB, tenantDB, tenantID, strconv.Itoa(sectionID),
+	)
+	if err != nil {
+		return err
+	}
+
+	changes, err := GetLatestSemesterLockChanges(tenantDB)
+	if err != nil {
+		return err
+	}
+
+	today := time.Now().Format(semesterDateLayout)
+	dateFrom, dateTo = normalizeDate(dateFrom), normalizeDate(dateTo)
+	for _, semester := range semesters {
+		if dateTo < normalizeDate(semester.StartDate) ||
+			dateFrom > normalizeDate(semester.EndDate) {
+			continue
+		}
+		if err := checkSemesterUnlocked(semester, changes, today); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkSemesterUnlocked(
+	semester wpmodels.TenantSemester,
+	changes map[string]tenantmodels.SemesterLockChange,
+	today string,
+) error {
+	if SemesterLockStatus(semester, changes, today).Locked {
+		return fmt.Errorf(
+			"%w: %s, izmjene ocjena i prisustva nisu moguće",
+			ErrSemesterLocked, semester.FullName,
+		)
+	}
+	return nil
+}
+
+func dateInSemester(date string, semester wpmodels.TenantSemester) bool {
+	return date >= normalizeDate(semester.StartDate) &&
+		date <= normalizeDate(semester.EndDate)
+}
+
+// normalizeDate cuts the time off dates sent as timestamps
+func normalizeDate(date string) string {
+	if len(date) > len(semesterDateLayout) {
+		return date[:len(semesterDateLayout)]
+	}
+	return date
+}
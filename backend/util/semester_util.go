@@ -155,7 +155,7 @@ func TenantSemesterAssign(
 // GetSemestersForTenant TODO: Add description
 func GetSemestersForTenant(workspaceDB *sql.DB, tenantID string) ([]wpmodels.TenantSemester, error) {
 	query := `SELECT ts.tenant_id, npp.npp_name, ts.semester_code, s.semester_name,
-    ts.start_date, ts.end_date, ts.npp_code
+    ts.start_date, ts.end_date, ts.npp_code, ts.lock_grace_days
     FROM tenant_semester ts
     JOIN npp ON ts.npp_code = npp.npp_code
     JOIN semester s ON ts.semester_code = s.semester_code
@@ -180,6 +180,7 @@ func GetSemestersForTenant(workspaceDB *sql.DB, tenantID string) ([]wpmodels.Ten
 			&tenantSemester.StartDate,
 			&tenantSemester.EndDate,
 			&tenantSemester.NPPCode,
+			&tenantSemester.LockGraceDays,
 		)
 		if err != nil {
 			return nil, err
@@ -238,14 +239,19 @@ func GetSemestersForSectionHelper(
 	return sectionSemester, nil
 }
 
-// UpdateTenantSemesterDates TODO: Add description
+// UpdateTenantSemesterDates updates the dates of a tenant semester. The lock
+// grace period is kept when lockGraceDays is nil.
 func UpdateTenantSemesterDates(
 	workspaceDB *sql.DB, tenantID, semesterCode, startDate, endDate, nppCode string,
+	lockGraceDays *int,
 ) (wpmodels.TenantSemester, error) {
-	query := `UPDATE tenant_semester SET start_date = ?, end_date = ?
+	query := `UPDATE tenant_semester SET start_date = ?, end_date = ?,
+	lock_grace_days = COALESCE(?, lock_grace_days)
     WHERE tenant_id = ? AND semester_code = ? AND npp_code = ?`
 
-	_, err := workspaceDB.Exec(query, startDate, endDate, tenantID, semesterCode, nppCode)
+	_, err := workspaceDB.Exec(
+		query, startDate, endDate, lockGraceDays, tenantID, semesterCode, nppCode,
+	)
 	if err != nil {
 		return wpmodels.TenantSemester{}, err
 	}
@@ -265,7 +271,7 @@ func GetTenantSemesterByCodes(
 	workspaceDB *sql.DB, tenantID, semesterCode, nppCode string,
 ) (*wpmodels.TenantSemester, error) {
 	query := `SELECT ts.tenant_id, npp.npp_name, ts.semester_code, s.semester_name,
-    ts.start_date, ts.end_date, ts.npp_code
+    ts.start_date, ts.end_date, ts.npp_code, ts.lock_grace_days
     FROM tenant_semester ts
     JOIN npp ON ts.npp_code = npp.npp_code
     JOIN semester s ON ts.semester_code = s.semester_code
@@ -282,6 +288,7 @@ func GetTenantSemesterByCodes(
 		&tenantSemester.StartDate,
 		&tenantSemester.EndDate,
 		&tenantSemester.NPPCode,
+		&tenantSemester.LockGraceDays,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
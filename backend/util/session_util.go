@@ -0,0 +1,203 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	wpmodels "ednevnik-backend/models/workspace"
+)
+
+// GenerateRefreshToken returns a random, URL safe refresh token
+func GenerateRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("error generating refresh token: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// HashToken returns the hex encoded SHA-256 hash of a token. Only hashes
+// of refresh tokens are stored in the database.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAccountSession stores a new login session for an account and returns
+// its ID, which is embedded into the access token claims.
+func CreateAccountSession(
+	accountID int,
+	loginType string,
+	refreshToken string,
+	userAgent string,
+	ipAddress string,
+	ttl time.Duration,
+	workspaceDB *sql.DB,
+) (int, error) {
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	query := `INSERT INTO account_sessions (account_id, login_type,
+	refresh_token_hash, user_agent, ip_address, expires_at)
+	VALUES (?, ?, ?, ?, ?, DATE_ADD(NOW(), INTERVAL ? SECOND))`
+	res, err := workspaceDB.Exec(
+		query,
+		accountID,
+		loginType,
+		HashToken(refreshToken),
+		userAgent,
+		ipAddress,
+		int(ttl.Seconds()),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error creating account session: %v", err)
+	}
+
+	sessionID, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error getting session ID: %v", err)
+	}
+	return int(sessionID), nil
+}
+
+// RotateAccountSession exchanges a refresh token for a new one. The old token
+// becomes invalid immediately. Presenting an already rotated token is treated
+// as token theft and revokes the whole session.
+func RotateAccountSession(
+	refreshToken string,
+	newRefreshToken string,
+	ttl time.Duration,
+	workspaceDB *sql.DB,
+) (*wpmodels.AccountSession, error) {
+	tokenHash := HashToken(refreshToken)
+
+	tx, err := workspaceDB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var session wpmodels.AccountSession
+	var active bool
+	query := `SELECT id, account_id, login_type,
+	(revoked_at IS NULL AND expires_at > NOW()) AS active
+	FROM account_sessions
+	WHERE refresh_token_hash = ?
+	FOR UPDATE`
+	err = tx.QueryRow(query, tokenHash).Scan(
+		&session.ID,
+		&session.AccountID,
+		&session.LoginType,
+		&active,
+	)
+	if err == sql.ErrNoRows {
+		// The token may have been rotated already, in which case someone is
+		// replaying it and the session can no longer be trusted
+		reuseQuery := `UPDATE account_sessions SET revoked_at = NOW()
+		WHERE previous_token_hash = ? AND revoked_at IS NULL`
+		if _, err := tx.Exec(reuseQuery, tokenHash); err != nil {
+			return nil, fmt.Errorf("error revoking reused session: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("error committing transaction: %v", err)
+		}
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving session: %v", err)
+	}
+	if !active {
+		return nil, fmt.Errorf("session expired or revoked")
+	}
+
+	updateQuery := `UPDATE account_sessions SET refresh_token_hash = ?,
+	previous_token_hash = ?, last_used_at = NOW(),
+	expires_at = DATE_ADD(NOW(), INTERVAL ? SECOND)
+	WHERE id = ?`
+	_, err = tx.Exec(
+		updateQuery,
+		HashToken(newRefreshToken),
+		tokenHash,
+		int(ttl.Seconds()),
+		session.ID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error rotating refresh token: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	return &session, nil
+}
+
+// GetAccountSessionStatus checks that a session exists and was neither
+// revoked nor expired, and returns the current membership version of the
+// account the session belongs to.
+func GetAccountSessionStatus(
+	sessionID int, workspaceDB *sql.DB,
+) (active bool, membershipVersion int, err error) {
+	query := `SELECT (s.revoked_at IS NULL AND s.expires_at > NOW()),
+	a.membership_version
+	FROM account_sessions s
+	JOIN accounts a ON a.id = s.account_id
+	WHERE s.id = ?`
+	err = workspaceDB.QueryRow(query, sessionID).Scan(&active, &membershipVersion)
+	if err == sql.ErrNoRows {
+		return false, 0, nil
+	}
+	if err != nil {
+		return false, 0, fmt.Errorf("error checking session: %v", err)
+	}
+	return active, membershipVersion, nil
+}
+
+// GetAccountMembershipVersion returns the membership version of an account
+func GetAccountMembershipVersion(accountID int, workspaceDB *sql.DB) (int, error) {
+	var version int
+	query := `SELECT membership_version FROM accounts WHERE id = ?`
+	err := workspaceDB.QueryRow(query, accountID).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("error getting membership version: %v", err)
+	}
+	return version, nil
+}
+
+// RevokeAccountSession revokes a single session (logout from one device)
+func RevokeAccountSession(sessionID int, workspaceDB *sql.DB) error {
+	query := `UPDATE account_sessions SET revoked_at = NOW()
+	WHERE id = ? AND revoked_at IS NULL`
+	if _, err := workspaceDB.Exec(query, sessionID); err != nil {
+		return fmt.Errorf("error revoking session: %v", err)
+	}
+	return nil
+}
+
+// RevokeAllAccountSessions revokes every session of an account
+func RevokeAllAccountSessions(accountID int, workspaceDB *sql.DB) error {
+	query := `UPDATE account_sessions SET revoked_at = NOW()
+	WHERE account_id = ? AND revoked_at IS NULL`
+	if _, err := workspaceDB.Exec(query, accountID); err != nil {
+		return fmt.Errorf("error revoking account sessions: %v", err)
+	}
+	return nil
+}
+
+// RevokeOtherAccountSessions revokes every session of an account except the
+// one the current request was made with.
+func RevokeOtherAccountSessions(
+	accountID, keepSessionID int, workspaceDB *sql.DB,
+) error {
+	query := `UPDATE account_sessions SET revoked_at = NOW()
+	WHERE account_id = ? AND id != ? AND revoked_at IS NULL`
+	if _, err := workspaceDB.Exec(query, accountID, keepSessionID); err != nil {
+		return fmt.Errorf("error revoking account sessions: %v", err)
+	}
+	return nil
+}
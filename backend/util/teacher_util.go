@@ -6,7 +6,6 @@ import (
 	"ednevnik-backend/models/interfaces"
 	wpmodels "ednevnik-backend/models/workspace"
 	"fmt"
-	"os"
 	"regexp"
 	"strings"
 
@@ -167,6 +166,20 @@ func GetTeacherTablePrivileges() []struct {
 		{"class_lesson", "SELECT, INSERT, UPDATE, DELETE"},
 		{"pupil_attendance", "SELECT, INSERT, UPDATE, DELETE"},
 		{"pupil_behaviour", "SELECT, INSERT, UPDATE, DELETE"},
+		{"attendance_excuses", "SELECT, UPDATE"},
+		{"attendance_excuse_lessons", "SELECT, INSERT"},
+		{"absence_thresholds", "SELECT"},
+		{"pedagogical_measures", "SELECT, INSERT"},
+		{"grading_schemes", "SELECT"},
+		{"semester_lock_changes", "SELECT"},
+		{"descriptive_assessment_classes", "SELECT"},
+		{"descriptive_assessments", "SELECT, INSERT, UPDATE"},
+		{"exam_limits", "SELECT"},
+		{"planned_exams", "SELECT, INSERT, DELETE"},
+		{"remedial_exams", "SELECT"},
+		{"remedial_exam_commission", "SELECT"},
+		{"remedial_exam_results", "SELECT, UPDATE"},
+		{"gradebook_ledger", "SELECT, INSERT"},
 	}
 }
 
@@ -195,6 +208,20 @@ func GetServiceUserTablePrivileges() []struct {
 		{"class_lesson", "SELECT, INSERT, UPDATE, DELETE"},
 		{"pupil_attendance", "SELECT, INSERT, UPDATE, DELETE"},
 		{"pupil_behaviour", "SELECT, INSERT, UPDATE, DELETE"},
+		{"attendance_excuses", "SELECT, INSERT, UPDATE, DELETE"},
+		{"attendance_excuse_lessons", "SELECT, INSERT, UPDATE, DELETE"},
+		{"absence_thresholds", "SELECT, INSERT, UPDATE, DELETE"},
+		{"pedagogical_measures", "SELECT, INSERT, UPDATE, DELETE"},
+		{"grading_schemes", "SELECT, INSERT, UPDATE, DELETE"},
+		{"semester_lock_changes", "SELECT, INSERT"},
+		{"descriptive_assessment_classes", "SELECT, INSERT, UPDATE, DELETE"},
+		{"descriptive_assessments", "SELECT, INSERT, UPDATE, DELETE"},
+		{"exam_limits", "SELECT, INSERT, UPDATE, DELETE"},
+		{"planned_exams", "SELECT, INSERT, UPDATE, DELETE"},
+		{"remedial_exams", "SELECT, INSERT, UPDATE, DELETE"},
+		{"remedial_exam_commission", "SELECT, INSERT, UPDATE, DELETE"},
+		{"remedial_exam_results", "SELECT, INSERT, UPDATE, DELETE"},
+		{"gradebook_ledger", "SELECT, INSERT, UPDATE, DELETE"},
 	}
 }
 
@@ -462,24 +489,21 @@ func RegisterTeacher(
 		return fmt.Errorf("error inserting teacher data: %v", err)
 	}
 
-	err = tx.Commit()
+	err = EnqueueVerificationEmail(
+		tx,
+		accountID,
+		teacher.Email,
+		fmt.Sprintf("%s %s", teacher.Name, teacher.LastName),
+	)
 	if err != nil {
-		return fmt.Errorf("error committing transaction: %v", err)
+		return fmt.Errorf("error queueing verification email: %v", err)
 	}
 
-	verificationToken, err := GetPendingAccountVerificationToken(int(accountID), workspaceDB)
+	err = tx.Commit()
 	if err != nil {
-		return fmt.Errorf("error getting verification token: %v", err)
+		return fmt.Errorf("error committing transaction: %v", err)
 	}
 
-	go func() {
-		_ = SendVerificationEmail(
-			teacher.Email,
-			fmt.Sprintf("%s %s", teacher.Name, teacher.LastName),
-			fmt.Sprintf("%s/verify?token=%s", os.Getenv("FRONTEND_URL"), verificationToken),
-		)
-	}()
-
 	return nil
 }
 
@@ -0,0 +1,146 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238) supported by common authenticator apps
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// totpSkew is the number of periods before and after the current one
+	// that are accepted to allow for clock drift
+	totpSkew   = 1
+	totpIssuer = "eDnevnik"
+)
+
+// TwoFactorKey is the server secret TOTP secrets are encrypted with before
+// they are stored. Changing it makes every enrolled second factor unusable.
+var TwoFactorKey []byte
+
+// totpEncoding is the base32 alphabet without padding used for secrets
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a random base32 encoded 160 bit secret
+func GenerateTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("error generating TOTP secret: %v", err)
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// TOTPProvisioningURI returns the otpauth URI shown as a QR code to add the
+// account to an authenticator app
+func TOTPProvisioningURI(email, secret string) string {
+	label := url.PathEscape(totpIssuer + ":" + email)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", totpIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprintf("%d", totpDigits))
+	params.Set("period", fmt.Sprintf("%d", totpPeriod))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// ValidateTOTPCode checks a code against the secret for the current time
+// step and its neighbours. Only steps after lastUsedStep are accepted, so a
+// code can not be used twice. It returns the matching step.
+func ValidateTOTPCode(secret, code string, lastUsedStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	currentStep := time.Now().Unix() / totpPeriod
+	for step := currentStep - totpSkew; step <= currentStep+totpSkew; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+		if hmac.Equal([]byte(totpCode(key, step)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode computes the HOTP value (RFC 4226) of a time step
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// sealTOTPSecret encrypts a TOTP secret of an account with AES-GCM. The
+// account ID is authenticated with it, so a stored secret can not be copied
+// to another account.
+func sealTOTPSecret(accountID int, secret string) (string, error) {
+	aead, err := totpCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("error encrypting TOTP secret: %v", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(secret), totpAdditionalData(accountID))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openTOTPSecret decrypts a TOTP secret stored by sealTOTPSecret
+func openTOTPSecret(accountID int, stored string) (string, error) {
+	aead, err := totpCipher()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(stored)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("error decrypting TOTP secret")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	secret, err := aead.Open(nil, nonce, ciphertext, totpAdditionalData(accountID))
+	if err != nil {
+		return "", fmt.Errorf("error decrypting TOTP secret")
+	}
+	return string(secret), nil
+}
+
+// totpCipher returns AES-256-GCM keyed with a hash of TwoFactorKey
+func totpCipher() (cipher.AEAD, error) {
+	if len(TwoFactorKey) == 0 {
+		return nil, fmt.Errorf("TWO_FACTOR_SECRET is not set")
+	}
+	key := sha256.Sum256(TwoFactorKey)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func totpAdditionalData(accountID int) []byte {
+	return []byte(fmt.Sprintf("account_totp:%d", accountID))
+}
@@ -0,0 +1,330 @@
+package util
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// RecoveryCodeCount is the number of recovery codes generated at once
+	RecoveryCodeCount = 10
+	// recoveryCodeLength is the length of a recovery code without the dash
+	recoveryCodeLength = 10
+)
+
+// ErrInvalidSecondFactorCode is returned when a TOTP or recovery code does
+// not match. Callers count it as a failed login.
+var ErrInvalidSecondFactorCode = errors.New("kod nije ispravan")
+
+// GetTwoFactorEnabled checks if an account has a confirmed second factor
+func GetTwoFactorEnabled(accountID int, workspaceDB *sql.DB) (bool, error) {
+	var enabled bool
+	query := `SELECT enabled FROM account_totp WHERE account_id = ?`
+	err := workspaceDB.QueryRow(query, accountID).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error checking two-factor authentication: %v", err)
+	}
+	return enabled, nil
+}
+
+// IsTwoFactorRequired checks if an account must use a second factor. Root
+// and tenant admin accounts always must, teachers must if one of their
+// tenants requires it.
+func IsTwoFactorRequired(
+	accountID int, accountType string, workspaceDB *sql.DB,
+) (bool, error) {
+	switch accountType {
+	case "root", "tenant_admin":
+		return true, nil
+	case "teacher":
+		var required bool
+		query := `SELECT EXISTS (SELECT 1 FROM teachers t
+		JOIN teacher_tenant tt ON tt.teacher_id = t.id
+		JOIN tenant tn ON tn.id = tt.tenant_id
+		WHERE t.account_id = ? AND tn.require_teacher_2fa = TRUE)`
+		if err := workspaceDB.QueryRow(query, accountID).Scan(&required); err != nil {
+			return false, fmt.Errorf("error checking two-factor policy: %v", err)
+		}
+		return required, nil
+	default:
+		return false, nil
+	}
+}
+
+// StartTwoFactorEnrollment stores a new unconfirmed secret for an account
+// and returns it with the provisioning URI for authenticator apps
+func StartTwoFactorEnrollment(
+	accountID int, email string, workspaceDB *sql.DB,
+) (string, string, error) {
+	enabled, err := GetTwoFactorEnabled(accountID, workspaceDB)
+	if err != nil {
+		return "", "", err
+	}
+	if enabled {
+		return "", "", fmt.Errorf("dvofaktorska autentifikacija je već uključena")
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	sealedSecret, err := sealTOTPSecret(accountID, secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	query := `INSERT INTO account_totp (account_id, secret, enabled)
+	VALUES (?, ?, FALSE)
+	ON DUPLICATE KEY UPDATE secret = VALUES(secret), enabled = FALSE,
+	last_used_step = 0, created_at = NOW(), confirmed_at = NULL`
+	if _, err := workspaceDB.Exec(query, accountID, sealedSecret); err != nil {
+		return "", "", fmt.Errorf("error starting two-factor enrollment: %v", err)
+	}
+
+	return secret, TOTPProvisioningURI(email, secret), nil
+}
+
+// ConfirmTwoFactorEnrollment enables the second factor if the code matches
+// the secret from StartTwoFactorEnrollment and returns new recovery codes
+func ConfirmTwoFactorEnrollment(
+	accountID int, code string, workspaceDB *sql.DB,
+) ([]string, error) {
+	var sealedSecret string
+	var enabled bool
+	query := `SELECT secret, enabled FROM account_totp WHERE account_id = ?`
+	err := workspaceDB.QueryRow(query, accountID).Scan(&sealedSecret, &enabled)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("dvofaktorska autentifikacija nije započeta")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching two-factor secret: %v", err)
+	}
+	if enabled {
+		return nil, fmt.Errorf("dvofaktorska autentifikacija je već uključena")
+	}
+
+	secret, err := openTOTPSecret(accountID, sealedSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := ValidateTOTPCode(secret, code, 0)
+	if !ok {
+		return nil, ErrInvalidSecondFactorCode
+	}
+
+	updateQuery := `UPDATE account_totp
+	SET enabled = TRUE, confirmed_at = NOW(), last_used_step = ?
+	WHERE account_id = ?`
+	if _, err := workspaceDB.Exec(updateQuery, step, accountID); err != nil {
+		return nil, fmt.Errorf("error enabling two-factor authentication: %v", err)
+	}
+
+	return RegenerateRecoveryCodes(accountID, workspaceDB)
+}
+
+// VerifySecondFactor checks a TOTP code or an unused recovery code of an
+// account. Both can be used only once.
+func VerifySecondFactor(accountID int, code string, workspaceDB *sql.DB) error {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return fmt.Errorf("kod je obavezan")
+	}
+
+	var sealedSecret string
+	var lastUsedStep int64
+	query := `SELECT secret, last_used_step FROM account_totp
+	WHERE account_id = ? AND enabled = TRUE`
+	err := workspaceDB.QueryRow(query, accountID).Scan(&sealedSecret, &lastUsedStep)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("dvofaktorska autentifikacija nije uključena")
+	}
+	if err != nil {
+		return fmt.Errorf("error fetching two-factor secret: %v", err)
+	}
+	secret, err := openTOTPSecret(accountID, sealedSecret)
+	if err != nil {
+		return err
+	}
+
+	if step, ok := ValidateTOTPCode(secret, code, lastUsedStep); ok {
+		// The condition on the step rejects the same code used concurrently
+		updateQuery := `UPDATE account_totp SET last_used_step = ?
+		WHERE account_id = ? AND last_used_step < ?`
+		res, err := workspaceDB.Exec(updateQuery, step, accountID, step)
+		if err != nil {
+			return fmt.Errorf("error using two-factor code: %v", err)
+		}
+		if used, _ := res.RowsAffected(); used == 1 {
+			return nil
+		}
+		return ErrInvalidSecondFactorCode
+	}
+
+	used, err := useRecoveryCode(accountID, code, workspaceDB)
+	if err != nil {
+		return err
+	}
+	if used {
+		return nil
+	}
+
+	return ErrInvalidSecondFactorCode
+}
+
+// useRecoveryCode marks the unused recovery code of an account that matches
+// the code as used. Codes are stored as bcrypt hashes, so the code is
+// compared with every unused code of the account.
+func useRecoveryCode(accountID int, code string, workspaceDB *sql.DB) (bool, error) {
+	code = normalizeRecoveryCode(code)
+	if len(code) != recoveryCodeLength {
+		return false, nil
+	}
+
+	query := `SELECT id, code_hash FROM account_recovery_codes
+	WHERE account_id = ? AND used_at IS NULL`
+	rows, err := workspaceDB.Query(query, accountID)
+	if err != nil {
+		return false, fmt.Errorf("error fetching recovery codes: %v", err)
+	}
+	defer rows.Close()
+
+	matchingID := 0
+	for rows.Next() {
+		var id int
+		var codeHash string
+		if err := rows.Scan(&id, &codeHash); err != nil {
+			return false, fmt.Errorf("error scanning recovery code: %v", err)
+		}
+		if bcrypt.CompareHashAndPassword([]byte(codeHash), []byte(code)) == nil {
+			matchingID = id
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("error iterating recovery codes: %v", err)
+	}
+	if matchingID == 0 {
+		return false, nil
+	}
+
+	// The condition on used_at rejects the same code used concurrently
+	updateQuery := `UPDATE account_recovery_codes SET used_at = NOW()
+	WHERE id = ? AND used_at IS NULL`
+	res, err := workspaceDB.Exec(updateQuery, matchingID)
+	if err != nil {
+		return false, fmt.Errorf("error using recovery code: %v", err)
+	}
+	used, _ := res.RowsAffected()
+	return used == 1, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of an account and
+// returns the new codes. They are shown only once, only bcrypt hashes are
+// stored.
+func RegenerateRecoveryCodes(accountID int, workspaceDB *sql.DB) (codes []string, err error) {
+	tx, err := workspaceDB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	_, err = tx.Exec(`DELETE FROM account_recovery_codes WHERE account_id = ?`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting recovery codes: %v", err)
+	}
+
+	insertQuery := `INSERT INTO account_recovery_codes (account_id, code_hash)
+	VALUES (?, ?)`
+	for i := 0; i < RecoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err = rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("error generating recovery code: %v", err)
+		}
+		code := hex.EncodeToString(buf)
+
+		var codeHash []byte
+		codeHash, err = bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("error hashing recovery code: %v", err)
+		}
+		_, err = tx.Exec(insertQuery, accountID, string(codeHash))
+		if err != nil {
+			return nil, fmt.Errorf("error storing recovery code: %v", err)
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %v", err)
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor removes the second factor and recovery codes of an
+// account
+func DisableTwoFactor(accountID int, workspaceDB *sql.DB) (err error) {
+	tx, err := workspaceDB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec(`DELETE FROM account_totp WHERE account_id = ?`, accountID); err != nil {
+		return fmt.Errorf("error disabling two-factor authentication: %v", err)
+	}
+	if _, err = tx.Exec(`DELETE FROM account_recovery_codes WHERE account_id = ?`, accountID); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+	return nil
+}
+
+// GetTenantTwoFactorPolicy checks if a tenant requires two-factor
+// authentication for its teachers
+func GetTenantTwoFactorPolicy(tenantID int, workspaceDB *sql.DB) (bool, error) {
+	var required bool
+	query := `SELECT require_teacher_2fa FROM tenant WHERE id = ?`
+	if err := workspaceDB.QueryRow(query, tenantID).Scan(&required); err != nil {
+		return false, fmt.Errorf("error fetching two-factor policy: %v", err)
+	}
+	return required, nil
+}
+
+// SetTenantTwoFactorPolicy sets if a tenant requires two-factor
+// authentication for its teachers
+func SetTenantTwoFactorPolicy(tenantID int, required bool, workspaceDB *sql.DB) error {
+	query := `UPDATE tenant SET require_teacher_2fa = ? WHERE id = ?`
+	if _, err := workspaceDB.Exec(query, required, tenantID); err != nil {
+		return fmt.Errorf("error updating two-factor policy: %v", err)
+	}
+	return nil
+}
+
+// normalizeRecoveryCode lowercases a recovery code and removes separators,
+// so codes typed with or without the dash match
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
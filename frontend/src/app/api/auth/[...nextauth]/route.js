@@ -2,6 +2,37 @@ import NextAuth from "next-auth";
 import CredentialsProvider from "next-auth/providers/credentials";
 import { jwtDecode } from "jwt-decode";
 
+// Decodes the token pair returned by /login, /parent-login and /refresh
+function tokensToUser(tokens) {
+  const claims = jwtDecode(tokens.access_token);
+  return {
+    ...claims,
+    token: tokens.access_token,
+    refreshToken: tokens.refresh_token,
+    accessTokenExpires: tokens.access_expires_at * 1000,
+  };
+}
+
+async function refreshAccessToken(token) {
+  try {
+    const res = await fetch(`${process.env.NEXT_PUBLIC_API_BASE_URL}/refresh`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ refresh_token: token.refreshToken }),
+    });
+    if (!res.ok) throw new Error("Refresh failed");
+    const user = tokensToUser(await res.json());
+    return {
+      ...token,
+      accessToken: user.token,
+      refreshToken: user.refreshToken,
+      accessTokenExpires: user.accessTokenExpires,
+    };
+  } catch (error) {
+    return { ...token, error: "RefreshAccessTokenError" };
+  }
+}
+
 export const authOptions = {
   providers: [
     CredentialsProvider({
@@ -24,15 +55,13 @@ export const authOptions = {
             },
           );
           if (!res.ok) return null;
-          const token = await res.text(); // Get JWT token as plain text
-          let claims = {};
+          const tokens = await res.json();
           try {
-            claims = jwtDecode(token);
+            return tokensToUser(tokens);
           } catch (e) {
             console.error("JWT decode failed:", e);
             return null; // Prevent login if decode fails
           }
-          return { ...claims, token };
         } catch (error) {
           return null;
         }
@@ -57,15 +86,13 @@ export const authOptions = {
             },
           );
           if (!res.ok) return null;
-          const token = await res.text();
-          let claims = {};
+          const tokens = await res.json();
           try {
-            claims = jwtDecode(token);
+            return { ...tokensToUser(tokens), loginType: "parent" };
           } catch (e) {
             console.error("JWT decode failed:", e);
             return null;
           }
-          return { ...claims, token, loginType: "parent" };
         } catch (error) {
           return null;
         }
@@ -79,6 +106,8 @@ export const authOptions = {
     async jwt({ token, user }) {
       if (user) {
         token.accessToken = user.token;
+        token.refreshToken = user.refreshToken;
+        token.accessTokenExpires = user.accessTokenExpires;
         token.id = user.id;
         token.name = user.name;
         token.lastName = user.last_name;
@@ -90,11 +119,17 @@ export const authOptions = {
         if (user.account_type === "tenant_admin") {
           token.tenant_id = user.tenant_id;
         }
+        return token;
+      }
+      // Refresh a minute before the short-lived access token expires
+      if (Date.now() < token.accessTokenExpires - 60 * 1000) {
+        return token;
       }
-      return token;
+      return refreshAccessToken(token);
     },
     async session({ session, token }) {
       session.accessToken = token.accessToken;
+      session.error = token.error;
       session.user.id = token.id;
       session.user.name = token.name;
       session.user.lastName = token.lastName;
@@ -109,6 +144,15 @@ export const authOptions = {
       return session;
     },
   },
+  events: {
+    async signOut({ token }) {
+      if (!token?.accessToken) return;
+      await fetch(`${process.env.NEXT_PUBLIC_API_BASE_URL}/logout`, {
+        method: "POST",
+        headers: { Authorization: `Bearer ${token.accessToken}` },
+      }).catch(() => {});
+    },
+  },
   pages: {
     signIn: "/login",
   },